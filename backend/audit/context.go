@@ -0,0 +1,34 @@
+// Package audit records a tamper-evident trail of every data change made
+// through GORM. Who made the change travels in the context.Context of the
+// statement (see WithActor); services pass the request context down with
+// db.WithContext(ctx).
+package audit
+
+import "context"
+
+// Actor describes who made a change and from where
+type Actor struct {
+	UserID         *uint
+	Email          string
+	ImpersonatorID *uint
+	APIKeyID       *uint
+	IPAddress      string
+	UserAgent      string
+	RequestID      string
+}
+
+type actorKey struct{}
+
+// WithActor returns a copy of ctx carrying the actor
+func WithActor(ctx context.Context, actor Actor) context.Context {
+	return context.WithValue(ctx, actorKey{}, actor)
+}
+
+// ActorFrom returns the actor stored in ctx, if any
+func ActorFrom(ctx context.Context) (Actor, bool) {
+	if ctx == nil {
+		return Actor{}, false
+	}
+	actor, ok := ctx.Value(actorKey{}).(Actor)
+	return actor, ok
+}
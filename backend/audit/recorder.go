@@ -0,0 +1,313 @@
+package audit
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strings"
+
+	"ku-asset/models"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// Tables whose changes are written to the audit trail
+var Tables = []string{
+	"users", "user_roles", "roles", "role_permissions",
+	"departments", "categories", "products",
+	"requests", "request_items",
+	"service_accounts", "api_keys", "api_key_permissions",
+	"invitations", "webhook_subscriptions",
+}
+
+// ignoredColumns change on every login or request and would only add noise
+var ignoredColumns = map[string]bool{
+	"created_at":            true,
+	"updated_at":            true,
+	"last_login_at":         true,
+	"failed_login_attempts": true,
+	"locked_until":          true,
+	"totp_last_step":        true,
+	"last_used_at":          true,
+	"last_used_ip":          true,
+	"low_stock_alerted_at":  true,
+}
+
+// redactedColumns are recorded as changed but their values never leave the table
+var redactedColumns = map[string]bool{
+	"password":    true,
+	"totp_secret": true,
+	"key_hash":    true,
+	"token_hash":  true,
+	"secret":      true,
+}
+
+const (
+	redacted  = "[REDACTED]"
+	beforeKey = "audit:before"
+)
+
+type recorder struct {
+	tables map[string]bool
+}
+
+// Register installs the GORM callbacks that audit creates, updates and deletes on Tables
+func Register(db *gorm.DB) error {
+	r := &recorder{tables: make(map[string]bool, len(Tables))}
+	for _, t := range Tables {
+		r.tables[t] = true
+	}
+
+	cb := db.Callback()
+	if err := cb.Create().After("gorm:create").Before("gorm:commit_or_rollback_transaction").
+		Register("audit:after_create", r.afterCreate); err != nil {
+		return err
+	}
+	if err := cb.Update().After("gorm:setup_reflect_value").Before("gorm:update").
+		Register("audit:before_update", r.captureBefore); err != nil {
+		return err
+	}
+	if err := cb.Update().After("gorm:update").Before("gorm:commit_or_rollback_transaction").
+		Register("audit:after_update", r.afterUpdate); err != nil {
+		return err
+	}
+	if err := cb.Delete().After("gorm:begin_transaction").Before("gorm:delete").
+		Register("audit:before_delete", r.captureBefore); err != nil {
+		return err
+	}
+	return cb.Delete().After("gorm:delete").Before("gorm:commit_or_rollback_transaction").
+		Register("audit:after_delete", r.afterDelete)
+}
+
+func (r *recorder) audited(db *gorm.DB) bool {
+	return db.Error == nil && !db.DryRun && r.tables[db.Statement.Table]
+}
+
+func (r *recorder) afterCreate(db *gorm.DB) {
+	// RowsAffected = 0 คือ upsert ของ association ที่ไม่ได้เพิ่มแถวใหม่
+	if !r.audited(db) || db.RowsAffected == 0 {
+		return
+	}
+	for _, row := range createdRows(db.Statement) {
+		changes := make(map[string]interface{})
+		for col, v := range row {
+			if !ignoredColumns[col] {
+				changes[col] = map[string]interface{}{"new": redact(col, v)}
+			}
+		}
+		r.write(db, models.AuditActionCreate, entityID(db.Statement, row), changes)
+	}
+}
+
+// captureBefore loads (and locks) the rows an update or delete is about to touch
+func (r *recorder) captureBefore(db *gorm.DB) {
+	if !r.audited(db) {
+		return
+	}
+	exprs := targetConditions(db.Statement)
+	if len(exprs) == 0 {
+		return
+	}
+	rows, err := loadRows(db, exprs)
+	if err != nil {
+		db.AddError(fmt.Errorf("audit: %w", err))
+		return
+	}
+	db.InstanceSet(beforeKey, rows)
+}
+
+func (r *recorder) afterUpdate(db *gorm.DB) {
+	if !r.audited(db) {
+		return
+	}
+	for _, old := range beforeRows(db) {
+		current, err := loadRows(db, keyConditions(db.Statement, old))
+		if err != nil {
+			db.AddError(fmt.Errorf("audit: %w", err))
+			return
+		}
+		if len(current) == 0 {
+			continue
+		}
+		if changes := diffRows(old, current[0]); len(changes) > 0 {
+			r.write(db, models.AuditActionUpdate, entityID(db.Statement, old), changes)
+		}
+	}
+}
+
+func (r *recorder) afterDelete(db *gorm.DB) {
+	if !r.audited(db) {
+		return
+	}
+	for _, old := range beforeRows(db) {
+		changes := make(map[string]interface{})
+		for col, v := range old {
+			if !ignoredColumns[col] {
+				changes[col] = map[string]interface{}{"old": redact(col, v)}
+			}
+		}
+		r.write(db, models.AuditActionDelete, entityID(db.Statement, old), changes)
+	}
+}
+
+func (r *recorder) write(db *gorm.DB, action, entityID string, changes map[string]interface{}) {
+	payload, err := json.Marshal(changes)
+	if err != nil {
+		db.AddError(fmt.Errorf("audit: %w", err))
+		return
+	}
+
+	entry := &models.AuditLog{
+		Action:     action,
+		EntityType: db.Statement.Table,
+		EntityID:   entityID,
+		Changes:    string(payload),
+	}
+	if actor, ok := ActorFrom(db.Statement.Context); ok {
+		entry.ActorID = actor.UserID
+		entry.ActorEmail = actor.Email
+		entry.ImpersonatorID = actor.ImpersonatorID
+		entry.APIKeyID = actor.APIKeyID
+		entry.IPAddress = actor.IPAddress
+		entry.UserAgent = actor.UserAgent
+		entry.RequestID = actor.RequestID
+	}
+
+	// ⭐ เขียนใน transaction เดียวกับการเปลี่ยนแปลง - ถ้าบันทึก audit ไม่ได้ การเปลี่ยนแปลงจะถูก rollback
+	if err := Append(db.Session(&gorm.Session{NewDB: true}), entry); err != nil {
+		db.AddError(fmt.Errorf("audit: %w", err))
+	}
+}
+
+// targetConditions rebuilds the WHERE of an update/delete, including the primary key of the model value
+func targetConditions(stmt *gorm.Statement) []clause.Expression {
+	var exprs []clause.Expression
+	if c, ok := stmt.Clauses["WHERE"]; ok {
+		if where, ok := c.Expression.(clause.Where); ok {
+			exprs = append(exprs, where.Exprs...)
+		}
+	}
+
+	if stmt.Schema != nil && stmt.Model != nil {
+		rv := reflect.Indirect(reflect.ValueOf(stmt.Model))
+		if rv.Kind() == reflect.Struct {
+			for _, f := range stmt.Schema.PrimaryFields {
+				if v, zero := f.ValueOf(stmt.Context, rv); !zero {
+					exprs = append(exprs, clause.Eq{Column: clause.Column{Table: stmt.Table, Name: f.DBName}, Value: v})
+				}
+			}
+		}
+	}
+	return exprs
+}
+
+// loadRows reads rows of the statement's table as column maps inside the current transaction
+func loadRows(db *gorm.DB, exprs []clause.Expression) ([]map[string]interface{}, error) {
+	stmt := db.Statement
+	q := db.Session(&gorm.Session{NewDB: true})
+	if stmt.Schema != nil {
+		// Model ทำให้ clause.PrimaryColumn ใน WHERE เดิมแปลงเป็นชื่อคอลัมน์ได้
+		q = q.Model(reflect.New(stmt.Schema.ModelType).Interface())
+	}
+
+	var rows []map[string]interface{}
+	err := q.Table(stmt.Table).
+		Clauses(clause.Where{Exprs: exprs}, clause.Locking{Strength: "UPDATE"}).
+		Find(&rows).Error
+	return rows, err
+}
+
+func beforeRows(db *gorm.DB) []map[string]interface{} {
+	v, ok := db.InstanceGet(beforeKey)
+	if !ok {
+		return nil
+	}
+	rows, _ := v.([]map[string]interface{})
+	return rows
+}
+
+func primaryKeys(stmt *gorm.Statement) []string {
+	if stmt.Schema != nil && len(stmt.Schema.PrimaryFieldDBNames) > 0 {
+		return stmt.Schema.PrimaryFieldDBNames
+	}
+	return []string{"id"}
+}
+
+func keyConditions(stmt *gorm.Statement, row map[string]interface{}) []clause.Expression {
+	var exprs []clause.Expression
+	for _, col := range primaryKeys(stmt) {
+		exprs = append(exprs, clause.Eq{Column: clause.Column{Table: stmt.Table, Name: col}, Value: row[col]})
+	}
+	return exprs
+}
+
+func entityID(stmt *gorm.Statement, row map[string]interface{}) string {
+	var parts []string
+	for _, col := range primaryKeys(stmt) {
+		parts = append(parts, fmt.Sprint(row[col]))
+	}
+	return strings.Join(parts, ":")
+}
+
+// createdRows turns the inserted struct (or slice of structs) into column maps
+func createdRows(stmt *gorm.Statement) []map[string]interface{} {
+	if stmt.Schema == nil {
+		if row, ok := stmt.Dest.(map[string]interface{}); ok {
+			return []map[string]interface{}{row}
+		}
+		return nil
+	}
+
+	toRow := func(rv reflect.Value) map[string]interface{} {
+		row := make(map[string]interface{})
+		for _, f := range stmt.Schema.Fields {
+			if f.DBName == "" {
+				continue
+			}
+			v, _ := f.ValueOf(stmt.Context, rv)
+			row[f.DBName] = v
+		}
+		return row
+	}
+
+	var rows []map[string]interface{}
+	switch rv := stmt.ReflectValue; rv.Kind() {
+	case reflect.Struct:
+		rows = append(rows, toRow(rv))
+	case reflect.Slice, reflect.Array:
+		for i := 0; i < rv.Len(); i++ {
+			if elem := reflect.Indirect(rv.Index(i)); elem.Kind() == reflect.Struct {
+				rows = append(rows, toRow(elem))
+			}
+		}
+	}
+	return rows
+}
+
+// diffRows returns {column: {old, new}} for every column whose value changed
+func diffRows(old, current map[string]interface{}) map[string]interface{} {
+	changes := make(map[string]interface{})
+	for col, newValue := range current {
+		if ignoredColumns[col] {
+			continue
+		}
+		a, _ := json.Marshal(old[col])
+		b, _ := json.Marshal(newValue)
+		if string(a) == string(b) {
+			continue
+		}
+		changes[col] = map[string]interface{}{"old": redact(col, old[col]), "new": redact(col, newValue)}
+	}
+	return changes
+}
+
+func redact(col string, v interface{}) interface{} {
+	if !redactedColumns[col] || v == nil {
+		return v
+	}
+	if rv := reflect.ValueOf(v); rv.Kind() == reflect.Ptr && rv.IsNil() {
+		return nil
+	}
+	return redacted
+}
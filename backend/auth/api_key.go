@@ -0,0 +1,73 @@
+package auth
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"time"
+
+	"ku-asset/models"
+
+	"gorm.io/gorm"
+)
+
+// APIKeyPrefix is prepended to every key so leaked keys are easy to recognise in logs and scanners
+const APIKeyPrefix = "kua_"
+
+var (
+	ErrInvalidAPIKey   = errors.New("invalid API key")
+	ErrAPIKeyIPBlocked = errors.New("API key is not allowed from this IP address")
+)
+
+// GenerateAPIKey returns a new raw key, its display prefix and the hash to store
+func GenerateAPIKey() (raw, prefix, hash string, err error) {
+	b := make([]byte, 32)
+	if _, err = rand.Read(b); err != nil {
+		return "", "", "", err
+	}
+	raw = APIKeyPrefix + base64.RawURLEncoding.EncodeToString(b)
+	prefix = raw[:len(APIKeyPrefix)+8]
+	return raw, prefix, HashAPIKey(raw), nil
+}
+
+// HashAPIKey returns the hex SHA-256 digest stored instead of the raw key
+func HashAPIKey(raw string) string {
+	sum := sha256.Sum256([]byte(raw))
+	return hex.EncodeToString(sum[:])
+}
+
+// AuthenticateAPIKey resolves a raw API key to the key record and its service account user.
+// It rejects revoked/expired keys, inactive service accounts and clients outside the IP allowlist.
+func AuthenticateAPIKey(db *gorm.DB, raw, clientIP string) (*models.APIKey, *models.User, error) {
+	var key models.APIKey
+	if err := db.Preload("Permissions").Preload("ServiceAccount").
+		Where("key_hash = ?", HashAPIKey(raw)).First(&key).Error; err != nil {
+		return nil, nil, ErrInvalidAPIKey
+	}
+
+	now := time.Now()
+	// ServiceAccount ที่ถูกลบ (soft delete) จะ preload ไม่ขึ้น ทำให้ ID เป็น 0
+	if !key.IsUsable(now) || key.ServiceAccount.ID == 0 || !key.ServiceAccount.IsActive {
+		return nil, nil, ErrInvalidAPIKey
+	}
+	if !key.AllowsIP(clientIP) {
+		return nil, nil, ErrAPIKeyIPBlocked
+	}
+
+	var user models.User
+	if err := db.First(&user, key.ServiceAccount.UserID).Error; err != nil || !user.IsActive {
+		return nil, nil, ErrInvalidAPIKey
+	}
+
+	// ⭐ บันทึกเวลาใช้งานล่าสุดไม่เกินนาทีละครั้ง เพื่อลดการเขียน DB ทุก request
+	if key.LastUsedAt == nil || now.Sub(*key.LastUsedAt) > time.Minute || key.LastUsedIP != clientIP {
+		db.Model(&models.APIKey{}).Where("id = ?", key.ID).UpdateColumns(map[string]interface{}{
+			"last_used_at": now,
+			"last_used_ip": clientIP,
+		})
+	}
+
+	return &key, &user, nil
+}
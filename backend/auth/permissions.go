@@ -0,0 +1,199 @@
+package auth
+
+import (
+	"database/sql"
+	"ku-asset/models"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// UserPermissions returns the distinct permission codes granted to a user through their roles.
+// Users without any role assignment fall back to the system role matching their legacy Role column.
+func UserPermissions(db *gorm.DB, userID uint) ([]string, error) {
+	var assigned int64
+	if err := db.Model(&models.UserRole{}).Where("user_id = ?", userID).Count(&assigned).Error; err != nil {
+		return nil, err
+	}
+
+	query := db.Table("permissions").
+		Distinct("permissions.code").
+		Joins("JOIN role_permissions ON role_permissions.permission_id = permissions.id")
+
+	if assigned > 0 {
+		query = query.Joins("JOIN user_roles ON user_roles.role_id = role_permissions.role_id").
+			Where("user_roles.user_id = ?", userID).
+			Where(grantApplies())
+	} else {
+		query = query.Joins("JOIN roles ON roles.id = role_permissions.role_id").
+			Joins("JOIN users ON users.role = roles.name").
+			Where("users.id = ?", userID)
+	}
+
+	var codes []string
+	if err := query.Pluck("permissions.code", &codes).Error; err != nil {
+		return nil, err
+	}
+	return codes, nil
+}
+
+// grantApplies filters user_roles to the grants that take effect: university-wide grants, and
+// department-bound grants only for permissions that are enforced per department
+func grantApplies() clause.Expr {
+	return gorm.Expr("user_roles.department_id IS NULL OR permissions.code IN ?", models.ScopedPermissions)
+}
+
+// HasPermission reports whether code is present in the given permission set
+func HasPermission(permissions []string, code string) bool {
+	for _, p := range permissions {
+		if p == code {
+			return true
+		}
+	}
+	return false
+}
+
+// Scope describes which departments a permission applies to.
+// All is set for global grants; otherwise DepartmentIDs holds the granted departments and all their descendants.
+type Scope struct {
+	All           bool
+	DepartmentIDs []uint
+}
+
+// GlobalScope returns a scope without department restrictions
+func GlobalScope() *Scope {
+	return &Scope{All: true}
+}
+
+// Allows reports whether a record belonging to departmentID is inside the scope
+func (s *Scope) Allows(departmentID *uint) bool {
+	if s == nil || s.All {
+		return true
+	}
+	if departmentID == nil {
+		return false
+	}
+	for _, id := range s.DepartmentIDs {
+		if id == *departmentID {
+			return true
+		}
+	}
+	return false
+}
+
+// Covers reports whether every department in other is also inside s
+func (s *Scope) Covers(other *Scope) bool {
+	if s == nil || s.All {
+		return true
+	}
+	if other == nil || other.All {
+		return false
+	}
+	for _, id := range other.DepartmentIDs {
+		id := id
+		if !s.Allows(&id) {
+			return false
+		}
+	}
+	return true
+}
+
+// PermissionsCovered reports whether actor holds every permission of target, each in a scope
+// at least as wide as target's. Used so acting as target never reaches beyond actor's own access.
+func PermissionsCovered(db *gorm.DB, actorID, targetID uint) (bool, error) {
+	targetPermissions, err := UserPermissions(db, targetID)
+	if err != nil {
+		return false, err
+	}
+	actorPermissions, err := UserPermissions(db, actorID)
+	if err != nil {
+		return false, err
+	}
+
+	for _, code := range targetPermissions {
+		if !HasPermission(actorPermissions, code) {
+			return false, nil
+		}
+		// สิทธิ์ที่ไม่แบ่งตามหน่วยงานนับเฉพาะ grant ทั้งระบบอยู่แล้ว มีก็คือเท่ากัน
+		if !HasPermission(models.ScopedPermissions, code) {
+			continue
+		}
+		targetScope, err := PermissionScope(db, targetID, code)
+		if err != nil {
+			return false, err
+		}
+		actorScope, err := PermissionScope(db, actorID, code)
+		if err != nil {
+			return false, err
+		}
+		if !actorScope.Covers(targetScope) {
+			return false, nil
+		}
+	}
+	return true, nil
+}
+
+// PermissionScope resolves the department scope in which a user holds the given permission.
+// A grant without a department is global; department-bound grants cover that department's subtree.
+func PermissionScope(db *gorm.DB, userID uint, permission string) (*Scope, error) {
+	var assigned int64
+	if err := db.Model(&models.UserRole{}).Where("user_id = ?", userID).Count(&assigned).Error; err != nil {
+		return nil, err
+	}
+
+	// ผู้ใช้ที่ยังไม่มี role assignment ใช้สิทธิ์ของ role เดิมแบบทั้งระบบ
+	if assigned == 0 {
+		permissions, err := UserPermissions(db, userID)
+		if err != nil {
+			return nil, err
+		}
+		if HasPermission(permissions, permission) {
+			return GlobalScope(), nil
+		}
+		return &Scope{}, nil
+	}
+
+	// ⭐ Pluck ลง []*uint สแกน NULL ไม่ได้ จึงใช้ sql.NullInt64
+	var departmentIDs []sql.NullInt64
+	if err := db.Table("user_roles").
+		Joins("JOIN role_permissions ON role_permissions.role_id = user_roles.role_id").
+		Joins("JOIN permissions ON permissions.id = role_permissions.permission_id").
+		Where("user_roles.user_id = ? AND permissions.code = ?", userID, permission).
+		Where(grantApplies()).
+		Pluck("user_roles.department_id", &departmentIDs).Error; err != nil {
+		return nil, err
+	}
+
+	roots := make([]uint, 0, len(departmentIDs))
+	for _, id := range departmentIDs {
+		if !id.Valid {
+			return GlobalScope(), nil
+		}
+		roots = append(roots, uint(id.Int64))
+	}
+	if len(roots) == 0 {
+		return &Scope{}, nil
+	}
+
+	subtree, err := DepartmentSubtree(db, roots)
+	if err != nil {
+		return nil, err
+	}
+	return &Scope{DepartmentIDs: subtree}, nil
+}
+
+// DepartmentSubtree returns the given departments together with all of their descendants
+func DepartmentSubtree(db *gorm.DB, rootIDs []uint) ([]uint, error) {
+	var ids []uint
+	err := db.Raw(`
+		WITH RECURSIVE subtree AS (
+			SELECT id FROM departments WHERE id IN ? AND deleted_at IS NULL
+			UNION
+			SELECT departments.id FROM departments
+			JOIN subtree ON departments.parent_id = subtree.id
+			WHERE departments.deleted_at IS NULL
+		)
+		SELECT id FROM subtree
+	`, rootIDs).Scan(&ids).Error
+	return ids, err
+}
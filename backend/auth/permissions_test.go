@@ -0,0 +1,225 @@
+package auth
+
+import (
+	"database/sql/driver"
+	"regexp"
+	"testing"
+
+	"ku-asset/models"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+func newMockDB(t *testing.T) (*gorm.DB, sqlmock.Sqlmock) {
+	t.Helper()
+	sqlDB, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { sqlDB.Close() })
+
+	db, err := gorm.Open(postgres.New(postgres.Config{Conn: sqlDB}), &gorm.Config{
+		Logger: logger.Default.LogMode(logger.Silent),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return db, mock
+}
+
+// scopedArgs are the query arguments for models.ScopedPermissions
+func scopedArgs() []driver.Value {
+	args := make([]driver.Value, len(models.ScopedPermissions))
+	for i, code := range models.ScopedPermissions {
+		args[i] = code
+	}
+	return args
+}
+
+var grantFilter = regexp.QuoteMeta(`(user_roles.department_id IS NULL OR permissions.code IN (`)
+
+func TestUserPermissionsIgnoresDepartmentGrantsOfGlobalPermissions(t *testing.T) {
+	db, mock := newMockDB(t)
+	mock.ExpectQuery(`SELECT count\(\*\) FROM "user_roles"`).
+		WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(1))
+	mock.ExpectQuery(`user_roles.user_id = \$1 AND ` + grantFilter).
+		WithArgs(append([]driver.Value{uint(7)}, scopedArgs()...)...).
+		WillReturnRows(sqlmock.NewRows([]string{"code"}).AddRow(models.PermRequestView))
+
+	permissions, err := UserPermissions(db, 7)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !HasPermission(permissions, models.PermRequestView) {
+		t.Errorf("permissions = %v", permissions)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestPermissionScope(t *testing.T) {
+	dept := uint(5)
+	tests := []struct {
+		name    string
+		granted []*uint // department_id ของ grant ที่ผ่านตัวกรอง
+		wantAll bool
+		wantIDs []uint
+	}{
+		{"global grant", []*uint{nil}, true, nil},
+		{"department grant", []*uint{&dept}, false, []uint{5, 6}},
+		{"global grant wins over department grant", []*uint{&dept, nil}, true, nil},
+		{"no applicable grant", nil, false, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db, mock := newMockDB(t)
+			mock.ExpectQuery(`SELECT count\(\*\) FROM "user_roles"`).
+				WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(1))
+			rows := sqlmock.NewRows([]string{"department_id"})
+			for _, id := range tt.granted {
+				if id == nil {
+					rows.AddRow(nil)
+				} else {
+					rows.AddRow(*id)
+				}
+			}
+			mock.ExpectQuery(`permissions.code = \$2\) AND ` + grantFilter).
+				WithArgs(append([]driver.Value{uint(7), models.PermRequestView}, scopedArgs()...)...).
+				WillReturnRows(rows)
+			if len(tt.wantIDs) > 0 {
+				mock.ExpectQuery(`WITH RECURSIVE subtree`).
+					WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(5).AddRow(6))
+			}
+
+			scope, err := PermissionScope(db, 7, models.PermRequestView)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if scope.All != tt.wantAll || len(scope.DepartmentIDs) != len(tt.wantIDs) {
+				t.Errorf("scope = %+v, want all=%v ids=%v", scope, tt.wantAll, tt.wantIDs)
+			}
+			if err := mock.ExpectationsWereMet(); err != nil {
+				t.Error(err)
+			}
+		})
+	}
+}
+
+func TestScopeAllows(t *testing.T) {
+	in, out := uint(5), uint(9)
+	tests := []struct {
+		name       string
+		scope      *Scope
+		department *uint
+		want       bool
+	}{
+		{"nil scope", nil, &out, true},
+		{"global", GlobalScope(), nil, true},
+		{"inside", &Scope{DepartmentIDs: []uint{5}}, &in, true},
+		{"outside", &Scope{DepartmentIDs: []uint{5}}, &out, false},
+		{"no department", &Scope{DepartmentIDs: []uint{5}}, nil, false},
+		{"empty", &Scope{}, &in, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.scope.Allows(tt.department); got != tt.want {
+				t.Errorf("Allows() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestScopeCovers(t *testing.T) {
+	tests := []struct {
+		name  string
+		outer *Scope
+		inner *Scope
+		want  bool
+	}{
+		{"global covers global", GlobalScope(), GlobalScope(), true},
+		{"nil is global", nil, &Scope{DepartmentIDs: []uint{5}}, true},
+		{"department does not cover global", &Scope{DepartmentIDs: []uint{5, 6}}, GlobalScope(), false},
+		{"subtree covers part of it", &Scope{DepartmentIDs: []uint{5, 6}}, &Scope{DepartmentIDs: []uint{6}}, true},
+		{"other department", &Scope{DepartmentIDs: []uint{5, 6}}, &Scope{DepartmentIDs: []uint{6, 9}}, false},
+		{"anything covers empty", &Scope{}, &Scope{}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.outer.Covers(tt.inner); got != tt.want {
+				t.Errorf("Covers() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPermissionsCovered(t *testing.T) {
+	const actor, target = uint(1), uint(2)
+	dept := uint(5)
+	expectPermissions := func(mock sqlmock.Sqlmock, userID uint, codes ...string) {
+		mock.ExpectQuery(`SELECT count\(\*\) FROM "user_roles"`).WithArgs(userID).
+			WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(1))
+		rows := sqlmock.NewRows([]string{"code"})
+		for _, c := range codes {
+			rows.AddRow(c)
+		}
+		mock.ExpectQuery(`SELECT DISTINCT permissions.code`).WillReturnRows(rows)
+	}
+	expectScope := func(mock sqlmock.Sqlmock, userID uint, department *uint) {
+		mock.ExpectQuery(`SELECT count\(\*\) FROM "user_roles"`).WithArgs(userID).
+			WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(1))
+		if department == nil {
+			mock.ExpectQuery(`SELECT "user_roles"."department_id"`).WillReturnRows(sqlmock.NewRows([]string{"department_id"}).AddRow(nil))
+			return
+		}
+		mock.ExpectQuery(`SELECT "user_roles"."department_id"`).WillReturnRows(sqlmock.NewRows([]string{"department_id"}).AddRow(*department))
+		mock.ExpectQuery(`WITH RECURSIVE subtree`).WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(*department))
+	}
+
+	tests := []struct {
+		name   string
+		expect func(mock sqlmock.Sqlmock)
+		want   bool
+	}{
+		{"target holds a permission the actor lacks", func(mock sqlmock.Sqlmock) {
+			expectPermissions(mock, target, models.PermReportView)
+			expectPermissions(mock, actor, models.PermUserManage)
+		}, false},
+		{"target holds it university-wide, actor in one department", func(mock sqlmock.Sqlmock) {
+			expectPermissions(mock, target, models.PermReportView)
+			expectPermissions(mock, actor, models.PermUserManage, models.PermReportView)
+			expectScope(mock, target, nil)
+			expectScope(mock, actor, &dept)
+		}, false},
+		{"same department", func(mock sqlmock.Sqlmock) {
+			expectPermissions(mock, target, models.PermReportView)
+			expectPermissions(mock, actor, models.PermUserManage, models.PermReportView)
+			expectScope(mock, target, &dept)
+			expectScope(mock, actor, &dept)
+		}, true},
+		{"permission that is not per department", func(mock sqlmock.Sqlmock) {
+			expectPermissions(mock, target, models.PermProductManage)
+			expectPermissions(mock, actor, models.PermProductManage, models.PermUserManage)
+		}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db, mock := newMockDB(t)
+			tt.expect(mock)
+
+			got, err := PermissionsCovered(db, actor, target)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != tt.want {
+				t.Errorf("PermissionsCovered() = %v, want %v", got, tt.want)
+			}
+			if err := mock.ExpectationsWereMet(); err != nil {
+				t.Error(err)
+			}
+		})
+	}
+}
@@ -0,0 +1,43 @@
+package auth
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"fmt"
+	"os"
+)
+
+// requiredSecrets sign access and refresh tokens. With an empty key anyone could mint tokens,
+// and JWT_SECRET is also the base of every DerivedSecret.
+var requiredSecrets = []string{"JWT_SECRET", "JWT_REFRESH_SECRET"}
+
+// CheckSecrets reports a missing signing key; the server refuses to start without them
+func CheckSecrets() error {
+	for _, key := range requiredSecrets {
+		if os.Getenv(key) == "" {
+			return fmt.Errorf("%s must be set", key)
+		}
+	}
+	return nil
+}
+
+// JWTSecret returns the key that signs access tokens
+func JWTSecret() []byte {
+	return []byte(os.Getenv("JWT_SECRET"))
+}
+
+// DerivedSecret returns the key set in envKey, or one derived from JWT_SECRET for the given
+// purpose so existing deployments need no new setting. It panics when neither is set instead
+// of signing with a key anyone can guess; CheckSecrets rules that out at startup.
+func DerivedSecret(envKey, purpose string) []byte {
+	if value := os.Getenv(envKey); value != "" {
+		return []byte(value)
+	}
+	base := os.Getenv("JWT_SECRET")
+	if base == "" {
+		panic(fmt.Sprintf("auth: neither %s nor JWT_SECRET is set", envKey))
+	}
+	mac := hmac.New(sha256.New, []byte(base))
+	mac.Write([]byte(purpose))
+	return mac.Sum(nil)
+}
@@ -0,0 +1,61 @@
+package auth
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestCheckSecrets(t *testing.T) {
+	tests := []struct {
+		name         string
+		jwt, refresh string
+		wantErr      bool
+	}{
+		{"both set", "a", "b", false},
+		{"missing JWT_SECRET", "", "b", true},
+		{"missing JWT_REFRESH_SECRET", "a", "", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("JWT_SECRET", tt.jwt)
+			t.Setenv("JWT_REFRESH_SECRET", tt.refresh)
+			if err := CheckSecrets(); (err != nil) != tt.wantErr {
+				t.Errorf("CheckSecrets() = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestDerivedSecret(t *testing.T) {
+	t.Setenv("JWT_SECRET", "base")
+	t.Setenv("TEST_FILES_SECRET", "")
+
+	files := DerivedSecret("TEST_FILES_SECRET", "files")
+	if len(files) == 0 || bytes.Equal(files, DerivedSecret("TEST_FILES_SECRET", "verify")) {
+		t.Error("keys for different purposes must differ")
+	}
+	if bytes.Contains(files, []byte("files")) || bytes.Contains(files, []byte("base")) {
+		t.Error("derived key must not expose its inputs")
+	}
+
+	t.Setenv("JWT_SECRET", "other")
+	if bytes.Equal(files, DerivedSecret("TEST_FILES_SECRET", "files")) {
+		t.Error("derived key must depend on JWT_SECRET")
+	}
+
+	t.Setenv("TEST_FILES_SECRET", "explicit")
+	if got := DerivedSecret("TEST_FILES_SECRET", "files"); string(got) != "explicit" {
+		t.Errorf("explicit key ignored: %q", got)
+	}
+}
+
+func TestDerivedSecretWithoutBasePanics(t *testing.T) {
+	t.Setenv("JWT_SECRET", "")
+	t.Setenv("TEST_FILES_SECRET", "")
+	defer func() {
+		if recover() == nil {
+			t.Error("expected a panic instead of a key derived from an empty secret")
+		}
+	}()
+	DerivedSecret("TEST_FILES_SECRET", "files")
+}
@@ -0,0 +1,38 @@
+package auth
+
+import (
+	"os"
+	"strings"
+
+	"ku-asset/models"
+
+	"gorm.io/gorm"
+)
+
+// AdminTwoFactorRequired reports whether the REQUIRE_ADMIN_2FA policy is switched on
+func AdminTwoFactorRequired() bool {
+	switch strings.ToLower(os.Getenv("REQUIRE_ADMIN_2FA")) {
+	case "true", "1", "yes":
+		return true
+	}
+	return false
+}
+
+// TwoFactorRequired reports whether policy forces the user to enrol in 2FA: administrators and
+// anyone holding a permission (ผู้ใช้ทั่วไปไม่มี permission ใดเลย), since every permission
+// unlocks changes to stock, files or other people's data
+func TwoFactorRequired(user *models.User, permissions []string) bool {
+	return AdminTwoFactorRequired() && (user.Role == models.RoleAdmin || len(permissions) > 0)
+}
+
+// UserTwoFactorRequired is TwoFactorRequired with the user's permissions loaded from db
+func UserTwoFactorRequired(db *gorm.DB, user *models.User) (bool, error) {
+	if !AdminTwoFactorRequired() {
+		return false, nil
+	}
+	permissions, err := UserPermissions(db, user.ID)
+	if err != nil {
+		return false, err
+	}
+	return TwoFactorRequired(user, permissions), nil
+}
@@ -0,0 +1,101 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"log"
+
+	"ku-asset/database"
+	"ku-asset/models"
+	"ku-asset/services"
+	"ku-asset/storage"
+
+	"github.com/joho/godotenv"
+	"gorm.io/gorm"
+)
+
+func main() {
+	// Load environment variables
+	if err := godotenv.Load(); err != nil {
+		log.Println("No .env file found")
+	}
+
+	// Define flags
+	var (
+		action    = flag.String("action", "regenerate", "Action to perform: regenerate, gc")
+		productID = flag.Uint("product", 0, "Only process this product ID (default: all products with an image)")
+	)
+	flag.Parse()
+
+	if *action != "regenerate" && *action != "gc" {
+		log.Fatal("Invalid action. Use: regenerate or gc")
+	}
+
+	// Connect to database
+	database.ConnectDatabase()
+	db := database.GetDatabase()
+
+	store, err := storage.NewFromEnv()
+	if err != nil {
+		log.Fatalf("Could not configure file storage: %v", err)
+	}
+	uploads := services.NewUploadService(db, store)
+	config := uploads.GetProductImageConfig()
+	ctx := context.Background()
+
+	if *action == "gc" {
+		removed, err := uploads.CollectGarbage(ctx)
+		if err != nil {
+			log.Fatalf("Upload GC failed: %v", err)
+		}
+		log.Printf("✅ Removed %d unreferenced upload(s)", removed)
+		return
+	}
+
+	q := db.Where("image_url IS NOT NULL AND image_url <> ''")
+	if *productID > 0 {
+		q = q.Where("id = ?", *productID)
+	}
+	var products []models.Product
+	if err := q.Order("id").Find(&products).Error; err != nil {
+		log.Fatalf("Could not load products: %v", err)
+	}
+
+	log.Printf("🖼️ Regenerating image variants for %d product(s)...", len(products))
+	failed := 0
+	for _, p := range products {
+		result, err := uploads.RegenerateVariants(ctx, *p.ImageURL, config)
+		if err != nil {
+			log.Printf("❌ %s (%d): %v", p.Code, p.ID, err)
+			failed++
+			continue
+		}
+
+		previous := []string{*p.ImageURL}
+		for _, u := range p.ImageVariants {
+			previous = append(previous, u)
+		}
+		err = db.Transaction(func(tx *gorm.DB) error {
+			if err := tx.Model(&p).Updates(map[string]interface{}{
+				"image_url":      result.URL,
+				"image_variants": models.ImageVariants(result.Variants),
+			}).Error; err != nil {
+				return err
+			}
+			return services.SyncUploadReferences(tx, models.UploadRefProduct, p.ID, result.URL)
+		})
+		if err != nil {
+			log.Printf("❌ %s (%d): %v", p.Code, p.ID, err)
+			failed++
+			continue
+		}
+		// ⭐ ลบไฟล์เก่าหลังบันทึก URL ใหม่แล้วเท่านั้น
+		uploads.DeleteReplacedImages(ctx, previous, result)
+		log.Printf("✅ %s (%d): %d variant(s)", p.Code, p.ID, len(result.Variants))
+	}
+
+	if failed > 0 {
+		log.Fatalf("Finished with %d failure(s)", failed)
+	}
+	log.Println("✅ All image variants regenerated successfully")
+}
@@ -1,12 +1,19 @@
 package main
 
 import (
+	"context"
+	"ku-asset/audit"
+	"ku-asset/auth"
 	"ku-asset/controllers"
 	"ku-asset/database"
+	"ku-asset/line"
+	"ku-asset/mailer"
 	"ku-asset/middleware"
 	"ku-asset/migrations"
+	"ku-asset/realtime"
 	"ku-asset/routes"
 	"ku-asset/services"
+	"ku-asset/storage"
 	"log"
 	"os"
 
@@ -21,6 +28,9 @@ func main() {
 	if err := godotenv.Load(); err != nil {
 		log.Println("Warning: .env file not found")
 	}
+	if err := auth.CheckSecrets(); err != nil {
+		log.Fatalf("Invalid configuration: %v", err)
+	}
 
 	dbConfig := database.NewConfigFromEnv()
 	db, err := database.Connect(dbConfig)
@@ -40,8 +50,12 @@ func main() {
 
 	// ใช้ gin.New() และเรียงลำดับ Middleware เอง
 	router := gin.New()
+	if err := middleware.ConfigureTrustedProxies(router); err != nil {
+		log.Fatalf("Invalid TRUSTED_PROXIES: %v", err)
+	}
 	router.Use(middleware.PanicRecoveryMiddleware()) // 👈 MUST BE FIRST!
-	router.Use(gin.Logger())
+	router.Use(middleware.RequestContext())          // ⭐ X-Request-ID + audit context
+	router.Use(middleware.RequestLogger())           // ⭐ ไม่บันทึก ?access_token= ของ SSE ลง log
 	router.Use(middleware.CORSMiddleware())
 
 	// ⭐ เพิ่ม Database Middleware เพื่อให้ AuthMiddleware ใช้งานได้
@@ -50,7 +64,21 @@ func main() {
 		c.Next()
 	})
 
-	services := services.NewServices(db)
+	// ⭐ ลงทะเบียนหลัง migration เพื่อไม่ให้ข้อมูลตั้งต้นเข้า audit log
+	if err := audit.Register(db); err != nil {
+		log.Fatalf("Could not register audit callbacks: %v", err)
+	}
+
+	hub := realtime.NewFromEnv(db, dbConfig.DSN()) // ⭐ in-process หรือ Postgres LISTEN/NOTIFY
+	store, err := storage.NewFromEnv()             // ⭐ local disk หรือ S3-compatible
+	if err != nil {
+		log.Fatalf("Could not configure file storage: %v", err)
+	}
+	services := services.NewServices(db, mailer.NewFromEnv(), line.NewFromEnv(), hub, store)
+	services.Notification.Start(context.Background()) // ⭐ email outbox worker
+	services.Webhook.Start(context.Background())      // ⭐ webhook delivery worker
+	services.StockMonitor.Start(context.Background()) // ⭐ scheduled low-stock scan
+	services.Upload.Start(context.Background())       // ⭐ orphaned upload GC
 	controllers := controllers.NewControllers(services)
 	routes.SetupRoutes(router, controllers)
 
@@ -0,0 +1,44 @@
+// controllers/audit_controller.go
+package controllers
+
+import (
+	"ku-asset/dto"
+	"ku-asset/services"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+type AuditController struct {
+	auditService services.AuditService
+}
+
+func NewAuditController(auditService services.AuditService) *AuditController {
+	return &AuditController{auditService: auditService}
+}
+
+// GetLogs lists audit entries, newest first, with optional filters
+func (ctrl *AuditController) GetLogs(c *gin.Context) {
+	var query dto.AuditLogQuery
+	if err := c.ShouldBindQuery(&query); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "error": "Invalid query parameters", "details": err.Error()})
+		return
+	}
+
+	logs, err := ctrl.auditService.GetLogs(&query)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"success": false, "error": "Failed to get audit logs"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"success": true, "data": logs})
+}
+
+// VerifyChain checks that no audit entry was edited, removed or reordered
+func (ctrl *AuditController) VerifyChain(c *gin.Context) {
+	result, err := ctrl.auditService.VerifyChain()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"success": false, "error": "Failed to verify audit chain"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"success": true, "data": result})
+}
@@ -2,9 +2,13 @@
 package controllers
 
 import (
+	"errors"
 	"ku-asset/dto"
 	"ku-asset/services"
+	"log"
+	"math"
 	"net/http"
+	"strconv"
 
 	"github.com/gin-gonic/gin"
 )
@@ -27,20 +31,21 @@ func (ctrl *AuthController) Login(c *gin.Context) {
 		return
 	}
 
-	authResponse, err := ctrl.authService.Login(&req)
+	authResponse, err := ctrl.authService.Login(&req, clientInfo(c))
 	if err != nil {
+		if respondLoginBlocked(c, err) {
+			return
+		}
+		if errors.Is(err, services.ErrEmailNotVerified) {
+			c.JSON(http.StatusForbidden, gin.H{"success": false, "message": err.Error(), "code": "EMAIL_NOT_VERIFIED"})
+			return
+		}
 		c.JSON(http.StatusUnauthorized, gin.H{"success": false, "message": err.Error()})
 		return
 	}
 
 	// ⭐ แก้ไข Response ของ Login ให้เหมือนกับ GoogleOAuth
-	c.JSON(http.StatusOK, gin.H{
-		"success":       true,
-		"message":       "Login successful",
-		"user":          authResponse.User,
-		"access_token":  authResponse.AccessToken,
-		"refresh_token": authResponse.RefreshToken,
-	})
+	respondAuthSuccess(c, "Login successful", authResponse)
 }
 
 // Register handles user registration.
@@ -93,18 +98,168 @@ func (ctrl *AuthController) GoogleOAuth(c *gin.Context) {
 		return
 	}
 
-	authResponse, err := ctrl.authService.FindOrCreateUserByGoogle(&req)
+	authResponse, err := ctrl.authService.FindOrCreateUserByGoogle(&req, clientInfo(c))
 	if err != nil {
+		if respondLoginBlocked(c, err) {
+			return
+		}
 		c.JSON(http.StatusInternalServerError, gin.H{"success": false, "message": err.Error()})
 		return
 	}
 
 	// ⭐ ส่ง Response กลับแบบนี้แค่ครั้งเดียว
+	respondAuthSuccess(c, "Google OAuth successful", authResponse)
+}
+
+// VerifyMFA completes a two-step login with a TOTP or recovery code.
+func (ctrl *AuthController) VerifyMFA(c *gin.Context) {
+	var req dto.MFAVerifyRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "message": err.Error()})
+		return
+	}
+
+	authResponse, err := ctrl.authService.VerifyMFA(&req, clientInfo(c))
+	if err != nil {
+		if respondLoginBlocked(c, err) {
+			return
+		}
+		status := http.StatusUnauthorized
+		if !errors.Is(err, services.ErrInvalidMFAToken) && !errors.Is(err, services.ErrInvalidTwoFactorCode) {
+			status = http.StatusInternalServerError
+		}
+		c.JSON(status, gin.H{"success": false, "message": err.Error()})
+		return
+	}
+
+	respondAuthSuccess(c, "Login successful", authResponse)
+}
+
+// respondLoginBlocked handles lockout and disabled-account errors; returns false for other errors
+func respondLoginBlocked(c *gin.Context, err error) bool {
+	var locked *services.AccountLockedError
+	switch {
+	case errors.As(err, &locked):
+		retryAfter := int(math.Ceil(locked.RetryAfter.Seconds()))
+		c.Header("Retry-After", strconv.Itoa(retryAfter))
+		c.JSON(http.StatusTooManyRequests, gin.H{
+			"success":     false,
+			"message":     err.Error(),
+			"code":        "ACCOUNT_LOCKED",
+			"retry_after": retryAfter,
+		})
+		return true
+	case errors.Is(err, services.ErrAccountDisabled):
+		c.JSON(http.StatusForbidden, gin.H{"success": false, "message": err.Error(), "code": "ACCOUNT_DISABLED"})
+		return true
+	}
+	return false
+}
+
+// clientInfo collects request metadata for the login audit trail
+func clientInfo(c *gin.Context) *dto.ClientInfo {
+	return &dto.ClientInfo{
+		IPAddress: c.ClientIP(),
+		UserAgent: c.Request.UserAgent(),
+	}
+}
+
+// respondAuthSuccess writes either the token pair or, for 2FA users, the MFA challenge
+func respondAuthSuccess(c *gin.Context, message string, authResponse *dto.AuthResponse) {
+	if authResponse.MFARequired {
+		c.JSON(http.StatusOK, gin.H{
+			"success":      true,
+			"message":      "Two-factor authentication required",
+			"mfa_required": true,
+			"mfa_token":    authResponse.MFAToken,
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success":                 true,
+		"message":                 message,
+		"user":                    authResponse.User,
+		"access_token":            authResponse.AccessToken,
+		"refresh_token":           authResponse.RefreshToken,
+		"mfa_enrollment_required": authResponse.MFAEnrollmentRequired,
+	})
+}
+
+// ForgotPassword sends a password reset link. Always responds with success to avoid leaking which emails exist.
+func (ctrl *AuthController) ForgotPassword(c *gin.Context) {
+	var req dto.ForgotPasswordRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "message": err.Error()})
+		return
+	}
+
+	if err := ctrl.authService.ForgotPassword(&req); err != nil {
+		log.Printf("❌ Forgot password failed for %s: %v", req.Email, err)
+	}
+
 	c.JSON(http.StatusOK, gin.H{
-		"success":       true,
-		"message":       "Google OAuth successful",
-		"user":          authResponse.User,
-		"access_token":  authResponse.AccessToken,
-		"refresh_token": authResponse.RefreshToken,
+		"success": true,
+		"message": "If an account exists for this email, a password reset link has been sent",
 	})
 }
+
+// ResetPassword sets a new password using a token from the reset email.
+func (ctrl *AuthController) ResetPassword(c *gin.Context) {
+	var req dto.ResetPasswordRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "message": err.Error()})
+		return
+	}
+
+	if err := ctrl.authService.ResetPassword(&req); err != nil {
+		respondTokenError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"success": true, "message": "Password has been reset successfully"})
+}
+
+// VerifyEmail confirms a user's email address.
+func (ctrl *AuthController) VerifyEmail(c *gin.Context) {
+	var req dto.VerifyEmailRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "message": err.Error()})
+		return
+	}
+
+	if err := ctrl.authService.VerifyEmail(&req); err != nil {
+		respondTokenError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"success": true, "message": "Email verified successfully"})
+}
+
+// ResendVerification sends a new verification email.
+func (ctrl *AuthController) ResendVerification(c *gin.Context) {
+	var req dto.ResendVerificationRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "message": err.Error()})
+		return
+	}
+
+	if err := ctrl.authService.ResendVerification(&req); err != nil {
+		log.Printf("❌ Resend verification failed for %s: %v", req.Email, err)
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "If the account is awaiting verification, a new email has been sent",
+	})
+}
+
+func respondTokenError(c *gin.Context, err error) {
+	if errors.Is(err, services.ErrInvalidToken) {
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "message": err.Error()})
+		return
+	}
+	if errors.Is(err, services.ErrAccountDisabled) {
+		c.JSON(http.StatusForbidden, gin.H{"success": false, "message": err.Error(), "code": "ACCOUNT_DISABLED"})
+		return
+	}
+	c.JSON(http.StatusInternalServerError, gin.H{"success": false, "message": err.Error()})
+}
@@ -0,0 +1,63 @@
+package controllers
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"ku-asset/dto"
+	"ku-asset/middleware"
+	"ku-asset/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+// throttlingAuthService mimics the per-IP login throttle: every login fails and an IP is
+// blocked after limit failures
+type throttlingAuthService struct {
+	services.AuthService
+	limit    int
+	failures map[string]int
+}
+
+func (s *throttlingAuthService) Login(req *dto.LoginRequest, client *dto.ClientInfo) (*dto.AuthResponse, error) {
+	if s.failures[client.IPAddress] >= s.limit {
+		return nil, &services.AccountLockedError{RetryAfter: time.Minute}
+	}
+	s.failures[client.IPAddress]++
+	return nil, errors.New("invalid email or password")
+}
+
+func TestLoginThrottleIgnoresSpoofedForwardedFor(t *testing.T) {
+	t.Setenv("TRUSTED_PROXIES", "")
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	if err := middleware.ConfigureTrustedProxies(router); err != nil {
+		t.Fatal(err)
+	}
+	service := &throttlingAuthService{limit: 3, failures: map[string]int{}}
+	router.POST("/login", NewAuthController(service).Login)
+
+	// ⭐ เปลี่ยน X-Forwarded-For ทุกครั้งก็ต้องโดนนับเป็น IP เดียวกัน
+	want := []int{http.StatusUnauthorized, http.StatusUnauthorized, http.StatusUnauthorized, http.StatusTooManyRequests}
+	for i, status := range want {
+		req := httptest.NewRequest(http.MethodPost, "/login",
+			strings.NewReader(`{"email":"somchai@ku.ac.th","password":"guess"}`))
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("X-Forwarded-For", "198.51.100."+strconv.Itoa(i+1))
+		req.RemoteAddr = "203.0.113.9:4321"
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+
+		if w.Code != status {
+			t.Errorf("attempt %d: status = %d, want %d", i+1, w.Code, status)
+		}
+	}
+	if len(service.failures) != 1 || service.failures["203.0.113.9"] != 3 {
+		t.Errorf("failures by IP = %v, want only the peer address", service.failures)
+	}
+}
@@ -27,7 +27,7 @@ func (ctrl *CategoryController) CreateCategory(c *gin.Context) {
 		c.JSON(http.StatusBadRequest, gin.H{"success": false, "error": "Invalid input"})
 		return
 	}
-	category, err := ctrl.categoryService.Create(&req)
+	category, err := ctrl.categoryService.Create(c.Request.Context(), &req)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"success": false, "error": "Failed to create category"})
 		return
@@ -64,7 +64,7 @@ func (ctrl *CategoryController) UpdateCategory(c *gin.Context) {
 		c.JSON(http.StatusBadRequest, gin.H{"success": false, "error": "Invalid input"})
 		return
 	}
-	category, err := ctrl.categoryService.Update(id, &req)
+	category, err := ctrl.categoryService.Update(c.Request.Context(), id, &req)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"success": false, "error": "Failed to update category"})
 		return
@@ -75,7 +75,7 @@ func (ctrl *CategoryController) UpdateCategory(c *gin.Context) {
 // DeleteCategory deletes a category.
 func (ctrl *CategoryController) DeleteCategory(c *gin.Context) {
 	id := c.Param("id")
-	if err := ctrl.categoryService.Delete(id); err != nil {
+	if err := ctrl.categoryService.Delete(c.Request.Context(), id); err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"success": false, "error": "Failed to delete category"})
 		return
 	}
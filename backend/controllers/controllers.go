@@ -6,25 +6,51 @@ import (
 )
 
 type Controllers struct {
-	Auth       *AuthController
-	User       *UserController
-	Product    *ProductController
-	Request    *RequestController
-	Category   *CategoryController
-	Department *DepartmentController
-	Dashboard  *DashboardController
-	Upload     *UploadController
+	Auth              *AuthController
+	User              *UserController
+	Product           *ProductController
+	Request           *RequestController
+	Category          *CategoryController
+	Department        *DepartmentController
+	Dashboard         *DashboardController
+	Upload            *UploadController
+	Role              *RoleController
+	TwoFactor         *TwoFactorController
+	ServiceAccount    *ServiceAccountController
+	Impersonation     *ImpersonationController
+	Provisioning      *ProvisioningController
+	Audit             *AuditController
+	Notification      *NotificationController
+	Stream            *StreamController
+	Webhook           *WebhookController
+	Line              *LineController
+	Stock             *StockController
+	ProductMedia      *ProductMediaController
+	RequestAttachment *RequestAttachmentController
 }
 
 func NewControllers(s *services.Services) *Controllers {
 	return &Controllers{
-		Auth:       NewAuthController(s.Auth),
-		User:       NewUserController(s.User),
-		Product:    NewProductController(s.Product),
-		Request:    NewRequestController(s.Request),
-		Category:   NewCategoryController(s.Category),
-		Department: NewDepartmentController(s.Department),
-		Dashboard:  NewDashboardController(s.Dashboard),
-		Upload:     NewUploadController(),
+		Auth:              NewAuthController(s.Auth),
+		User:              NewUserController(s.User),
+		Product:           NewProductController(s.Product),
+		Request:           NewRequestController(s.Request),
+		Category:          NewCategoryController(s.Category),
+		Department:        NewDepartmentController(s.Department),
+		Dashboard:         NewDashboardController(s.Dashboard),
+		Upload:            NewUploadController(s.Upload),
+		Role:              NewRoleController(s.Role),
+		TwoFactor:         NewTwoFactorController(s.TwoFactor),
+		ServiceAccount:    NewServiceAccountController(s.ServiceAccount),
+		Impersonation:     NewImpersonationController(s.Impersonation),
+		Provisioning:      NewProvisioningController(s.Provisioning),
+		Audit:             NewAuditController(s.Audit),
+		Notification:      NewNotificationController(s.Notification),
+		Stream:            NewStreamController(s.Realtime),
+		Webhook:           NewWebhookController(s.Webhook),
+		Line:              NewLineController(s.Line),
+		Stock:             NewStockController(s.StockMonitor),
+		ProductMedia:      NewProductMediaController(s.ProductMedia),
+		RequestAttachment: NewRequestAttachmentController(s.RequestAttachment),
 	}
 }
@@ -2,6 +2,8 @@
 package controllers
 
 import (
+	"ku-asset/middleware"
+	"ku-asset/models"
 	"ku-asset/services"
 	"net/http"
 	"strconv"
@@ -18,7 +20,12 @@ func NewDashboardController(dashboardService services.DashboardService) *Dashboa
 }
 
 func (ctrl *DashboardController) GetAdminStats(c *gin.Context) {
-	stats, err := ctrl.dashboardService.GetAdminStats()
+	scope, err := middleware.GetPermissionScope(c, models.PermReportView)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+		return
+	}
+	stats, err := ctrl.dashboardService.GetAdminStats(scope)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get admin stats"})
 		return
@@ -29,7 +36,12 @@ func (ctrl *DashboardController) GetAdminStats(c *gin.Context) {
 // ⭐ เพิ่ม Handler สำหรับ RecentActivity
 func (ctrl *DashboardController) GetRecentActivity(c *gin.Context) {
 	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "5"))
-	activity, err := ctrl.dashboardService.GetRecentActivity(limit)
+	scope, err := middleware.GetPermissionScope(c, models.PermReportView)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+		return
+	}
+	activity, err := ctrl.dashboardService.GetRecentActivity(limit, scope)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get recent activity"})
 		return
@@ -39,7 +51,12 @@ func (ctrl *DashboardController) GetRecentActivity(c *gin.Context) {
 
 // ⭐ เพิ่ม Handler สำหรับ SystemStats
 func (ctrl *DashboardController) GetSystemStats(c *gin.Context) {
-	stats, err := ctrl.dashboardService.GetSystemStats()
+	scope, err := middleware.GetPermissionScope(c, models.PermReportView)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+		return
+	}
+	stats, err := ctrl.dashboardService.GetSystemStats(scope)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get system stats"})
 		return
@@ -65,7 +65,7 @@ func (ctrl *DepartmentController) CreateDepartment(c *gin.Context) {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body"})
 		return
 	}
-	department, err := ctrl.departmentService.CreateDepartment(&req)
+	department, err := ctrl.departmentService.CreateDepartment(c.Request.Context(), &req)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
@@ -84,7 +84,7 @@ func (ctrl *DepartmentController) UpdateDepartment(c *gin.Context) {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body"})
 		return
 	}
-	department, err := ctrl.departmentService.UpdateDepartment(uint(id), &req)
+	department, err := ctrl.departmentService.UpdateDepartment(c.Request.Context(), uint(id), &req)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
@@ -98,7 +98,7 @@ func (ctrl *DepartmentController) DeleteDepartment(c *gin.Context) {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid department ID"})
 		return
 	}
-	err = ctrl.departmentService.DeleteDepartment(uint(id))
+	err = ctrl.departmentService.DeleteDepartment(c.Request.Context(), uint(id))
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
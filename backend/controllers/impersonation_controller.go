@@ -0,0 +1,114 @@
+// controllers/impersonation_controller.go
+package controllers
+
+import (
+	"errors"
+	"ku-asset/dto"
+	"ku-asset/middleware"
+	"ku-asset/models"
+	"ku-asset/services"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+type ImpersonationController struct {
+	impersonationService services.ImpersonationService
+}
+
+func NewImpersonationController(impersonationService services.ImpersonationService) *ImpersonationController {
+	return &ImpersonationController{impersonationService: impersonationService}
+}
+
+// Impersonate ออก token อายุสั้นสำหรับดูระบบในมุมมองของผู้ใช้ (อ่านอย่างเดียว)
+func (ctrl *ImpersonationController) Impersonate(c *gin.Context) {
+	userID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "error": "Invalid user ID"})
+		return
+	}
+	var req dto.ImpersonateRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "error": "Invalid input", "details": err.Error()})
+		return
+	}
+	scope, err := middleware.GetPermissionScope(c, models.PermUserManage)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"success": false, "error": err.Error()})
+		return
+	}
+
+	adminID, _ := middleware.GetUserID(c)
+	token, err := ctrl.impersonationService.Impersonate(adminID, uint(userID), &req, clientInfo(c), scope)
+	if err != nil {
+		respondImpersonationError(c, err)
+		return
+	}
+	c.JSON(http.StatusCreated, gin.H{"success": true, "data": token})
+}
+
+func (ctrl *ImpersonationController) GetSessions(c *gin.Context) {
+	var req dto.PaginationRequest
+	if err := c.ShouldBindQuery(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "error": "Invalid query parameters"})
+		return
+	}
+	var userID *uint
+	if raw := c.Query("user_id"); raw != "" {
+		id, err := strconv.ParseUint(raw, 10, 32)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"success": false, "error": "Invalid user ID"})
+			return
+		}
+		uid := uint(id)
+		userID = &uid
+	}
+
+	sessions, err := ctrl.impersonationService.GetSessions(&req, userID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"success": false, "error": "Failed to get impersonation sessions"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"success": true, "data": sessions})
+}
+
+func (ctrl *ImpersonationController) GetSession(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "error": "Invalid session ID"})
+		return
+	}
+	session, err := ctrl.impersonationService.GetSession(uint(id))
+	if err != nil {
+		respondImpersonationError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"success": true, "data": session})
+}
+
+func (ctrl *ImpersonationController) EndSession(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "error": "Invalid session ID"})
+		return
+	}
+	adminID, _ := middleware.GetUserID(c)
+	if err := ctrl.impersonationService.EndSession(uint(id), adminID); err != nil {
+		respondImpersonationError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"success": true, "message": "Impersonation session ended"})
+}
+
+// respondImpersonationError maps impersonation service errors to HTTP status codes
+func respondImpersonationError(c *gin.Context, err error) {
+	status := http.StatusInternalServerError
+	switch {
+	case errors.Is(err, services.ErrImpersonationNotFound), err.Error() == "user not found":
+		status = http.StatusNotFound
+	case errors.Is(err, services.ErrCannotImpersonate), errors.Is(err, services.ErrImpersonationNotAllowed):
+		status = http.StatusForbidden
+	}
+	c.JSON(status, gin.H{"success": false, "error": err.Error()})
+}
@@ -0,0 +1,75 @@
+// controllers/line_controller.go
+package controllers
+
+import (
+	"errors"
+	"io"
+	"ku-asset/line"
+	"ku-asset/middleware"
+	"ku-asset/services"
+	"log"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// maxLineWebhookBody caps the body read from the LINE platform
+const maxLineWebhookBody = 1 << 20
+
+type LineController struct {
+	lineService services.LineService
+}
+
+func NewLineController(lineService services.LineService) *LineController {
+	return &LineController{lineService: lineService}
+}
+
+// CreateLinkCode ออกรหัสผูกบัญชี LINE แบบใช้ครั้งเดียว
+func (ctrl *LineController) CreateLinkCode(c *gin.Context) {
+	userID, err := middleware.GetUserID(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"success": false, "error": err.Error()})
+		return
+	}
+	code, err := ctrl.lineService.CreateLinkCode(c.Request.Context(), userID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"success": false, "error": "Failed to create link code"})
+		return
+	}
+	c.JSON(http.StatusCreated, gin.H{"success": true, "data": code})
+}
+
+func (ctrl *LineController) Unlink(c *gin.Context) {
+	userID, err := middleware.GetUserID(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"success": false, "error": err.Error()})
+		return
+	}
+	if err := ctrl.lineService.Unlink(c.Request.Context(), userID); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"success": false, "error": "Failed to unlink LINE account"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"success": true, "message": "LINE account unlinked"})
+}
+
+// Webhook รับ event จาก LINE platform (ข้อความแชท, follow/unfollow)
+func (ctrl *LineController) Webhook(c *gin.Context) {
+	body, err := io.ReadAll(io.LimitReader(c.Request.Body, maxLineWebhookBody))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "error": "Failed to read body"})
+		return
+	}
+
+	err = ctrl.lineService.HandleWebhook(c.Request.Context(), body, c.GetHeader(line.SignatureHeader))
+	switch {
+	case errors.Is(err, services.ErrLineNotConfigured):
+		c.JSON(http.StatusServiceUnavailable, gin.H{"success": false, "error": err.Error()})
+	case errors.Is(err, services.ErrInvalidLineSignature):
+		c.JSON(http.StatusUnauthorized, gin.H{"success": false, "error": err.Error()})
+	case err != nil:
+		log.Printf("❌ LINE webhook: %v", err)
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "error": "Invalid webhook payload"})
+	default:
+		c.JSON(http.StatusOK, gin.H{"success": true})
+	}
+}
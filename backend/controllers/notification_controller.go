@@ -0,0 +1,126 @@
+// controllers/notification_controller.go
+package controllers
+
+import (
+	"errors"
+	"ku-asset/dto"
+	"ku-asset/middleware"
+	"ku-asset/services"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+type NotificationController struct {
+	notificationService services.NotificationService
+}
+
+func NewNotificationController(notificationService services.NotificationService) *NotificationController {
+	return &NotificationController{notificationService: notificationService}
+}
+
+func (ctrl *NotificationController) GetNotifications(c *gin.Context) {
+	userID, err := middleware.GetUserID(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"success": false, "error": err.Error()})
+		return
+	}
+	var query dto.NotificationQuery
+	if err := c.ShouldBindQuery(&query); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "error": "Invalid query parameters"})
+		return
+	}
+
+	notifications, err := ctrl.notificationService.GetNotifications(userID, &query)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"success": false, "error": "Failed to get notifications"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"success": true, "data": notifications})
+}
+
+func (ctrl *NotificationController) GetUnreadCount(c *gin.Context) {
+	userID, err := middleware.GetUserID(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"success": false, "error": err.Error()})
+		return
+	}
+	count, err := ctrl.notificationService.GetUnreadCount(userID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"success": false, "error": "Failed to count notifications"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"success": true, "data": gin.H{"unread_count": count}})
+}
+
+func (ctrl *NotificationController) MarkRead(c *gin.Context) {
+	userID, err := middleware.GetUserID(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"success": false, "error": err.Error()})
+		return
+	}
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "error": "Invalid notification ID"})
+		return
+	}
+
+	if err := ctrl.notificationService.MarkRead(userID, uint(id)); err != nil {
+		if errors.Is(err, services.ErrNotificationNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"success": false, "error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"success": false, "error": "Failed to update notification"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"success": true, "message": "Notification marked as read"})
+}
+
+func (ctrl *NotificationController) MarkAllRead(c *gin.Context) {
+	userID, err := middleware.GetUserID(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"success": false, "error": err.Error()})
+		return
+	}
+	updated, err := ctrl.notificationService.MarkAllRead(userID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"success": false, "error": "Failed to update notifications"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"success": true, "data": gin.H{"updated": updated}})
+}
+
+func (ctrl *NotificationController) GetPreferences(c *gin.Context) {
+	userID, err := middleware.GetUserID(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"success": false, "error": err.Error()})
+		return
+	}
+	preferences, err := ctrl.notificationService.GetPreferences(userID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"success": false, "error": "Failed to get preferences"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"success": true, "data": preferences})
+}
+
+func (ctrl *NotificationController) UpdatePreferences(c *gin.Context) {
+	userID, err := middleware.GetUserID(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"success": false, "error": err.Error()})
+		return
+	}
+	var req dto.UpdateNotificationPreferencesRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "error": "Invalid input", "details": err.Error()})
+		return
+	}
+
+	preferences, err := ctrl.notificationService.UpdatePreferences(userID, &req)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"success": true, "data": preferences})
+}
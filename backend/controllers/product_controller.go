@@ -56,7 +56,7 @@ func (ctrl *ProductController) CreateProduct(c *gin.Context) {
 		c.JSON(http.StatusBadRequest, gin.H{"success": false, "error": "Invalid input"})
 		return
 	}
-	product, err := ctrl.productService.CreateProduct(&req)
+	product, err := ctrl.productService.CreateProduct(c.Request.Context(), &req)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"success": false, "error": "Failed to create product"})
 		return
@@ -79,7 +79,7 @@ func (ctrl *ProductController) UpdateProduct(c *gin.Context) {
 		return
 	}
 
-	product, err := ctrl.productService.UpdateProduct(uint(id), &req)
+	product, err := ctrl.productService.UpdateProduct(c.Request.Context(), uint(id), &req)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"success": false, "error": "Failed to update product"})
 		return
@@ -96,9 +96,35 @@ func (ctrl *ProductController) DeleteProduct(c *gin.Context) {
 		return
 	}
 
-	if err := ctrl.productService.DeleteProduct(uint(id)); err != nil {
+	if err := ctrl.productService.DeleteProduct(c.Request.Context(), uint(id)); err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"success": false, "error": "Failed to delete product"})
 		return
 	}
 	c.JSON(http.StatusOK, gin.H{"success": true, "message": "Product deleted successfully"})
 }
+
+// AdjustStock ปรับจำนวนสต็อกของครุภัณฑ์ (ADD / SET / REMOVE)
+func (ctrl *ProductController) AdjustStock(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "error": "Invalid product ID"})
+		return
+	}
+
+	var req dto.StockAdjustmentRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "error": "Invalid input", "details": err.Error()})
+		return
+	}
+
+	product, err := ctrl.productService.AdjustStock(c.Request.Context(), uint(id), &req)
+	if err != nil {
+		if err.Error() == "product not found" {
+			c.JSON(http.StatusNotFound, gin.H{"success": false, "error": err.Error()})
+		} else {
+			c.JSON(http.StatusBadRequest, gin.H{"success": false, "error": err.Error()})
+		}
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"success": true, "message": "Stock adjusted successfully", "data": product})
+}
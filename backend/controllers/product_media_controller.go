@@ -0,0 +1,158 @@
+// controllers/product_media_controller.go
+package controllers
+
+import (
+	"context"
+	"errors"
+	"ku-asset/dto"
+	"ku-asset/middleware"
+	"ku-asset/services"
+	"mime/multipart"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+type ProductMediaController struct {
+	mediaService services.ProductMediaService
+}
+
+func NewProductMediaController(mediaService services.ProductMediaService) *ProductMediaController {
+	return &ProductMediaController{mediaService: mediaService}
+}
+
+// GET /products/:id/media
+func (ctrl *ProductMediaController) GetMedia(c *gin.Context) {
+	productID, ok := parseProductID(c)
+	if !ok {
+		return
+	}
+	media, err := ctrl.mediaService.GetMedia(productID)
+	if err != nil {
+		respondProductMediaError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"success": true, "data": media})
+}
+
+// POST /products/:id/media/images (multipart: image, caption)
+func (ctrl *ProductMediaController) UploadImage(c *gin.Context) {
+	ctrl.upload(c, "image", ctrl.mediaService.AddImage)
+}
+
+// POST /products/:id/media/documents (multipart: file, caption)
+func (ctrl *ProductMediaController) UploadDocument(c *gin.Context) {
+	ctrl.upload(c, "file", ctrl.mediaService.AddDocument)
+}
+
+// addMediaFunc is ProductMediaService.AddImage or AddDocument
+type addMediaFunc func(ctx context.Context, productID uint, file multipart.File, header *multipart.FileHeader, caption string, ownerID *uint) (*dto.ProductMediaResponse, error)
+
+func (ctrl *ProductMediaController) upload(c *gin.Context, field string, add addMediaFunc) {
+	productID, ok := parseProductID(c)
+	if !ok {
+		return
+	}
+	file, header, err := c.Request.FormFile(field)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "error": "No " + field + " file provided"})
+		return
+	}
+	defer file.Close()
+
+	var ownerID *uint
+	if userID, err := middleware.GetUserID(c); err == nil {
+		ownerID = &userID
+	}
+	media, err := add(c.Request.Context(), productID, file, header, c.PostForm("caption"), ownerID)
+	if err != nil {
+		respondProductMediaError(c, err)
+		return
+	}
+	c.JSON(http.StatusCreated, gin.H{"success": true, "data": media})
+}
+
+// PUT /products/:id/media/:mediaId
+func (ctrl *ProductMediaController) UpdateMedia(c *gin.Context) {
+	productID, ok := parseProductID(c)
+	if !ok {
+		return
+	}
+	mediaID, err := strconv.ParseUint(c.Param("mediaId"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "error": "Invalid media ID"})
+		return
+	}
+	var req dto.UpdateProductMediaRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "error": "Invalid input", "details": err.Error()})
+		return
+	}
+	media, err := ctrl.mediaService.UpdateMedia(c.Request.Context(), productID, uint(mediaID), &req)
+	if err != nil {
+		respondProductMediaError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"success": true, "data": media})
+}
+
+// POST /products/:id/media/reorder
+func (ctrl *ProductMediaController) ReorderMedia(c *gin.Context) {
+	productID, ok := parseProductID(c)
+	if !ok {
+		return
+	}
+	var req dto.ReorderProductMediaRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "error": "Invalid input", "details": err.Error()})
+		return
+	}
+	media, err := ctrl.mediaService.ReorderMedia(c.Request.Context(), productID, &req)
+	if err != nil {
+		respondProductMediaError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"success": true, "data": media})
+}
+
+// DELETE /products/:id/media/:mediaId
+func (ctrl *ProductMediaController) DeleteMedia(c *gin.Context) {
+	productID, ok := parseProductID(c)
+	if !ok {
+		return
+	}
+	mediaID, err := strconv.ParseUint(c.Param("mediaId"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "error": "Invalid media ID"})
+		return
+	}
+	if err := ctrl.mediaService.DeleteMedia(c.Request.Context(), productID, uint(mediaID)); err != nil {
+		respondProductMediaError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"success": true, "message": "Media deleted successfully"})
+}
+
+func parseProductID(c *gin.Context) (uint, bool) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "error": "Invalid product ID"})
+		return 0, false
+	}
+	return uint(id), true
+}
+
+func respondProductMediaError(c *gin.Context, err error) {
+	status := http.StatusInternalServerError
+	switch {
+	case errors.Is(err, services.ErrProductNotFound), errors.Is(err, services.ErrProductMediaNotFound):
+		status = http.StatusNotFound
+	case errors.Is(err, services.ErrInvalidMediaFile), errors.Is(err, services.ErrInvalidMediaOrder),
+		errors.Is(err, services.ErrPrimaryNotImage):
+		status = http.StatusBadRequest
+	case errors.Is(err, services.ErrDuplicateMedia):
+		status = http.StatusConflict
+	}
+	c.JSON(status, gin.H{"success": false, "error": err.Error()})
+}
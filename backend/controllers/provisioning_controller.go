@@ -0,0 +1,186 @@
+// controllers/provisioning_controller.go
+package controllers
+
+import (
+	"errors"
+	"ku-asset/auth"
+	"ku-asset/dto"
+	"ku-asset/middleware"
+	"ku-asset/models"
+	"ku-asset/services"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+const maxImportFileSize = 5 << 20 // 5MB
+
+type ProvisioningController struct {
+	provisioningService services.UserProvisioningService
+}
+
+func NewProvisioningController(provisioningService services.UserProvisioningService) *ProvisioningController {
+	return &ProvisioningController{provisioningService: provisioningService}
+}
+
+// --- Admin: Invitations ---
+
+func (ctrl *ProvisioningController) CreateInvitation(c *gin.Context) {
+	var req dto.CreateInvitationRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "error": "Invalid input", "details": err.Error()})
+		return
+	}
+	userScope, roleScope, ok := provisioningScopes(c)
+	if !ok {
+		return
+	}
+	inviterID, _ := middleware.GetUserID(c)
+
+	invitation, err := ctrl.provisioningService.CreateInvitation(c.Request.Context(), &req, inviterID, userScope, roleScope)
+	if err != nil {
+		respondProvisioningError(c, err)
+		return
+	}
+	c.JSON(http.StatusCreated, gin.H{"success": true, "data": invitation})
+}
+
+func (ctrl *ProvisioningController) GetInvitations(c *gin.Context) {
+	userScope, err := middleware.GetPermissionScope(c, models.PermUserManage)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"success": false, "error": err.Error()})
+		return
+	}
+	invitations, err := ctrl.provisioningService.GetInvitations(c.Query("status"), userScope)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"success": false, "error": "Failed to get invitations"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"success": true, "data": invitations})
+}
+
+func (ctrl *ProvisioningController) RevokeInvitation(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "error": "Invalid invitation ID"})
+		return
+	}
+	userScope, err := middleware.GetPermissionScope(c, models.PermUserManage)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"success": false, "error": err.Error()})
+		return
+	}
+	if err := ctrl.provisioningService.RevokeInvitation(c.Request.Context(), uint(id), userScope); err != nil {
+		respondProvisioningError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"success": true, "message": "Invitation revoked successfully"})
+}
+
+// --- Public: Accept invitation ---
+
+func (ctrl *ProvisioningController) GetInvitation(c *gin.Context) {
+	invitation, err := ctrl.provisioningService.GetInvitationByToken(c.Param("token"))
+	if err != nil {
+		respondProvisioningError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"success": true, "data": invitation})
+}
+
+func (ctrl *ProvisioningController) AcceptInvitation(c *gin.Context) {
+	var req dto.AcceptInvitationRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "error": "Invalid input", "details": err.Error()})
+		return
+	}
+	user, err := ctrl.provisioningService.AcceptInvitation(c.Request.Context(), &req)
+	if err != nil {
+		respondProvisioningError(c, err)
+		return
+	}
+	c.JSON(http.StatusCreated, gin.H{"success": true, "message": "Account created successfully", "data": user})
+}
+
+// --- Admin: Bulk import ---
+
+// ImportUsers รับไฟล์ CSV/XLSX (field "file") ค่าเริ่มต้นเป็น dry run ต้องส่ง dry_run=false เพื่อบันทึกจริง
+func (ctrl *ProvisioningController) ImportUsers(c *gin.Context) {
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, maxImportFileSize)
+	fileHeader, err := c.FormFile("file")
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "error": "No file uploaded or file too large"})
+		return
+	}
+
+	file, err := fileHeader.Open()
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "error": "Cannot read uploaded file"})
+		return
+	}
+	defer file.Close()
+
+	rows, err := services.ParseUserImportFile(fileHeader.Filename, file)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "error": err.Error()})
+		return
+	}
+
+	userScope, roleScope, ok := provisioningScopes(c)
+	if !ok {
+		return
+	}
+
+	dryRun := c.DefaultQuery("dry_run", "true") != "false"
+	notify := c.Query("notify") == "true"
+	importerID, _ := middleware.GetUserID(c)
+	result, err := ctrl.provisioningService.ImportUsers(c.Request.Context(), rows, dryRun, notify, importerID, userScope, roleScope)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"success": false, "error": err.Error()})
+		return
+	}
+
+	if result.Failed > 0 {
+		c.JSON(http.StatusUnprocessableEntity, gin.H{
+			"success": false,
+			"error":   "Some rows are invalid; nothing was imported",
+			"data":    result,
+		})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"success": true, "data": result})
+}
+
+// provisioningScopes loads the caller's user.manage and role.manage scopes
+func provisioningScopes(c *gin.Context) (userScope, roleScope *auth.Scope, ok bool) {
+	userScope, err := middleware.GetPermissionScope(c, models.PermUserManage)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"success": false, "error": err.Error()})
+		return nil, nil, false
+	}
+	roleScope, err = middleware.GetPermissionScope(c, models.PermRoleManage)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"success": false, "error": err.Error()})
+		return nil, nil, false
+	}
+	return userScope, roleScope, true
+}
+
+// respondProvisioningError maps provisioning errors to HTTP status codes
+func respondProvisioningError(c *gin.Context, err error) {
+	status := http.StatusInternalServerError
+	switch {
+	case errors.Is(err, services.ErrInvitationNotFound), errors.Is(err, services.ErrRoleNotFound),
+		err.Error() == "department not found":
+		status = http.StatusNotFound
+	case errors.Is(err, services.ErrOutOfScope):
+		status = http.StatusForbidden
+	case errors.Is(err, services.ErrUserExists):
+		status = http.StatusConflict
+	case errors.Is(err, services.ErrInvalidToken), err.Error() == "name is required",
+		err.Error() == "invitation is no longer pending":
+		status = http.StatusBadRequest
+	}
+	c.JSON(status, gin.H{"success": false, "error": err.Error()})
+}
@@ -0,0 +1,166 @@
+// controllers/request_attachment_controller.go
+package controllers
+
+import (
+	"errors"
+	"ku-asset/auth"
+	"ku-asset/middleware"
+	"ku-asset/models"
+	"ku-asset/services"
+	"mime"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+type RequestAttachmentController struct {
+	attachmentService services.RequestAttachmentService
+}
+
+func NewRequestAttachmentController(attachmentService services.RequestAttachmentService) *RequestAttachmentController {
+	return &RequestAttachmentController{attachmentService: attachmentService}
+}
+
+// StageAttachment อัปโหลดไฟล์ก่อนสร้างคำขอ แล้วส่ง upload_id ใน attachments ตอน POST /requests
+// POST /requests/attachments (multipart: file)
+func (ctrl *RequestAttachmentController) StageAttachment(c *gin.Context) {
+	userID, err := middleware.GetUserID(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"success": false, "error": err.Error()})
+		return
+	}
+	file, header, err := c.Request.FormFile("file")
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "error": "No file provided"})
+		return
+	}
+	defer file.Close()
+
+	staged, err := ctrl.attachmentService.StageAttachment(c.Request.Context(), userID, file, header)
+	if err != nil {
+		respondRequestAttachmentError(c, err)
+		return
+	}
+	c.JSON(http.StatusCreated, gin.H{"success": true, "data": staged})
+}
+
+// GET /requests/:id/attachments
+func (ctrl *RequestAttachmentController) GetAttachments(c *gin.Context) {
+	requestID, userID, scope, ok := requestAttachmentAccess(c)
+	if !ok {
+		return
+	}
+	attachments, err := ctrl.attachmentService.GetAttachments(requestID, userID, scope)
+	if err != nil {
+		respondRequestAttachmentError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"success": true, "data": attachments})
+}
+
+// POST /requests/:id/attachments (multipart: file, type, caption)
+func (ctrl *RequestAttachmentController) AddAttachment(c *gin.Context) {
+	requestID, userID, scope, ok := requestAttachmentAccess(c)
+	if !ok {
+		return
+	}
+	file, header, err := c.Request.FormFile("file")
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "error": "No file provided"})
+		return
+	}
+	defer file.Close()
+
+	attachment, err := ctrl.attachmentService.AddAttachment(c.Request.Context(), requestID, userID, scope,
+		file, header, c.PostForm("type"), c.PostForm("caption"))
+	if err != nil {
+		respondRequestAttachmentError(c, err)
+		return
+	}
+	c.JSON(http.StatusCreated, gin.H{"success": true, "data": attachment})
+}
+
+// DownloadAttachment ส่งไฟล์ให้เฉพาะผู้ขอและผู้มีสิทธิ์ดูคำขอ
+// GET /requests/:id/attachments/:attachmentId
+func (ctrl *RequestAttachmentController) DownloadAttachment(c *gin.Context) {
+	requestID, userID, scope, ok := requestAttachmentAccess(c)
+	if !ok {
+		return
+	}
+	attachmentID, err := strconv.ParseUint(c.Param("attachmentId"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "error": "Invalid attachment ID"})
+		return
+	}
+
+	attachment, rc, obj, err := ctrl.attachmentService.OpenAttachment(c.Request.Context(), requestID, uint(attachmentID), userID, scope)
+	if err != nil {
+		respondRequestAttachmentError(c, err)
+		return
+	}
+	defer rc.Close()
+
+	contentType := obj.ContentType
+	if contentType == "" {
+		contentType = "application/octet-stream"
+	}
+	c.Header("Content-Disposition", mime.FormatMediaType("attachment", map[string]string{"filename": attachment.OriginalName}))
+	c.Header("Cache-Control", "private, no-store")
+	c.Header("X-Content-Type-Options", "nosniff")
+	c.DataFromReader(http.StatusOK, obj.Size, contentType, rc, nil)
+}
+
+// DELETE /requests/:id/attachments/:attachmentId
+func (ctrl *RequestAttachmentController) DeleteAttachment(c *gin.Context) {
+	requestID, userID, scope, ok := requestAttachmentAccess(c)
+	if !ok {
+		return
+	}
+	attachmentID, err := strconv.ParseUint(c.Param("attachmentId"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "error": "Invalid attachment ID"})
+		return
+	}
+	if err := ctrl.attachmentService.DeleteAttachment(c.Request.Context(), requestID, uint(attachmentID), userID, scope); err != nil {
+		respondRequestAttachmentError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"success": true, "message": "Attachment deleted successfully"})
+}
+
+// requestAttachmentAccess reads the request ID, the user and the user's request.view scope
+func requestAttachmentAccess(c *gin.Context) (uint, uint, *auth.Scope, bool) {
+	requestID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "error": "Invalid request ID"})
+		return 0, 0, nil, false
+	}
+	userID, err := middleware.GetUserID(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"success": false, "error": err.Error()})
+		return 0, 0, nil, false
+	}
+	scope, err := middleware.GetPermissionScope(c, models.PermRequestView)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"success": false, "error": err.Error()})
+		return 0, 0, nil, false
+	}
+	return uint(requestID), userID, scope, true
+}
+
+func respondRequestAttachmentError(c *gin.Context, err error) {
+	status := http.StatusInternalServerError
+	switch {
+	case errors.Is(err, services.ErrRequestNotFound), errors.Is(err, services.ErrRequestAttachmentNotFound),
+		errors.Is(err, services.ErrFileNotFound):
+		status = http.StatusNotFound
+	case errors.Is(err, services.ErrInvalidAttachment), errors.Is(err, services.ErrTooManyAttachments):
+		status = http.StatusBadRequest
+	case errors.Is(err, services.ErrDuplicateAttachment):
+		status = http.StatusConflict
+	case errors.Is(err, services.ErrAttachmentsLocked):
+		status = http.StatusForbidden
+	}
+	c.JSON(status, gin.H{"success": false, "error": err.Error()})
+}
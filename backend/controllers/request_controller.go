@@ -1,12 +1,14 @@
 package controllers
 
 import (
+	"errors"
 	"log"
 	"net/http"
 	"strconv"
 
 	"ku-asset/dto"
 	"ku-asset/middleware"
+	"ku-asset/models"
 	"ku-asset/services"
 
 	"github.com/gin-gonic/gin"
@@ -53,9 +55,17 @@ func (rc *RequestController) CreateRequest(c *gin.Context) {
 	log.Printf("✅ UserID extracted: %d", userID)
 
 	// สร้างคำขอ
-	request, err := rc.requestService.CreateRequest(userID, &input)
+	request, err := rc.requestService.CreateRequest(c.Request.Context(), userID, &input)
 	if err != nil {
 		log.Printf("❌ Failed to create request: %v", err)
+		if errors.Is(err, services.ErrInvalidAttachment) || errors.Is(err, services.ErrDuplicateAttachment) ||
+			errors.Is(err, services.ErrTooManyAttachments) {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error":   "Invalid attachments",
+				"message": err.Error(),
+			})
+			return
+		}
 		c.JSON(http.StatusInternalServerError, gin.H{
 			"error":   "Failed to create request",
 			"message": err.Error(),
@@ -149,7 +159,13 @@ func (rc *RequestController) GetRequest(c *gin.Context) {
 
 // GetAllRequests สำหรับ Admin ดูคำขอทั้งหมด
 func (rc *RequestController) GetAllRequests(c *gin.Context) {
-	requests, err := rc.requestService.GetAllRequests()
+	scope, err := middleware.GetPermissionScope(c, models.PermRequestView)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized", "message": err.Error()})
+		return
+	}
+
+	requests, err := rc.requestService.GetAllRequests(scope)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{
 			"error":   "Failed to get requests",
@@ -185,8 +201,46 @@ func (rc *RequestController) UpdateRequestStatus(c *gin.Context) {
 		return
 	}
 
-	request, err := rc.requestService.UpdateRequestStatus(uint(requestID), input.Status, input.Notes)
+	// ⭐ ตรวจสิทธิ์ตามสถานะที่ต้องการเปลี่ยน
+	requiredPermission := models.PermRequestApprove
+	if input.Status == string(models.RequestStatusIssued) || input.Status == string(models.RequestStatusCompleted) {
+		requiredPermission = models.PermRequestIssue
+	}
+	if !middleware.HasPermission(c, requiredPermission) {
+		c.JSON(http.StatusForbidden, gin.H{
+			"error":   "Access denied",
+			"message": "Required permission: " + requiredPermission,
+		})
+		return
+	}
+
+	scope, err := middleware.GetPermissionScope(c, requiredPermission)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized", "message": err.Error()})
+		return
+	}
+
+	// ผู้ทำรายการ (API key ไม่มีผู้ใช้ จึงไม่บันทึกชื่อ)
+	var actorID *uint
+	if userID, err := middleware.GetUserID(c); err == nil {
+		actorID = &userID
+	}
+	request, err := rc.requestService.UpdateRequestStatus(c.Request.Context(), uint(requestID), input.Status, input.Notes, actorID, scope)
 	if err != nil {
+		if err.Error() == "request not found" {
+			c.JSON(http.StatusNotFound, gin.H{
+				"error":   "Request not found",
+				"message": err.Error(),
+			})
+			return
+		}
+		if errors.Is(err, services.ErrInvalidStatusTransition) {
+			c.JSON(http.StatusConflict, gin.H{
+				"error":   "Invalid status transition",
+				"message": err.Error(),
+			})
+			return
+		}
 		c.JSON(http.StatusInternalServerError, gin.H{
 			"error":   "Failed to update request status",
 			"message": err.Error(),
@@ -214,12 +268,102 @@ func (rc *RequestController) DownloadRequestPDF(c *gin.Context) {
 		return
 	}
 
+	// ⭐ ผู้ดูแลระดับคณะดาวน์โหลดได้เฉพาะคำขอในหน่วยงานของตน
+	scope, err := middleware.GetPermissionScope(c, models.PermRequestView)
+	if err != nil || !scope.Allows(req.User.DepartmentID) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Request not found"})
+		return
+	}
+
 	pdfBytes, err := rc.requestService.GenerateRequestPDF(req)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to generate PDF"})
 		return
 	}
 
-	c.Header("Content-Disposition", "attachment; filename=request_receipt.pdf")
+	c.Header("Content-Disposition", "attachment; filename="+req.RequestNumber+".pdf")
 	c.Data(http.StatusOK, "application/pdf", pdfBytes)
 }
+
+// GetComments lists comments of a request visible to its requester and staff with request.view
+func (rc *RequestController) GetComments(c *gin.Context) {
+	requestID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "error": "Invalid request ID"})
+		return
+	}
+	userID, err := middleware.GetUserID(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"success": false, "error": err.Error()})
+		return
+	}
+	scope, err := middleware.GetPermissionScope(c, models.PermRequestView)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"success": false, "error": err.Error()})
+		return
+	}
+
+	comments, err := rc.requestService.GetComments(uint(requestID), userID, scope)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"success": false, "error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"success": true, "data": comments})
+}
+
+// AddComment posts a comment and notifies the other side of the request
+func (rc *RequestController) AddComment(c *gin.Context) {
+	requestID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "error": "Invalid request ID"})
+		return
+	}
+	var input dto.CreateRequestCommentInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "error": "Invalid input", "details": err.Error()})
+		return
+	}
+	userID, err := middleware.GetUserID(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"success": false, "error": err.Error()})
+		return
+	}
+	scope, err := middleware.GetPermissionScope(c, models.PermRequestView)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"success": false, "error": err.Error()})
+		return
+	}
+
+	comment, err := rc.requestService.AddComment(c.Request.Context(), uint(requestID), userID, &input, scope)
+	if err != nil {
+		status := http.StatusInternalServerError
+		switch err.Error() {
+		case "request not found":
+			status = http.StatusNotFound
+		case "comment is empty":
+			status = http.StatusBadRequest
+		}
+		c.JSON(status, gin.H{"success": false, "error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusCreated, gin.H{"success": true, "data": comment})
+}
+
+// VerifyRequest ให้เจ้าหน้าที่คลังสแกน QR code บนใบเบิกเพื่อเทียบกับข้อมูลในระบบ
+// GET /verify/requests/:number?sig=...
+func (rc *RequestController) VerifyRequest(c *gin.Context) {
+	c.Header("Cache-Control", "no-store")
+	result, err := rc.requestService.VerifyRequest(c.Param("number"), c.Query("sig"))
+	if err != nil {
+		switch {
+		case errors.Is(err, services.ErrInvalidVerificationSignature):
+			c.JSON(http.StatusForbidden, gin.H{"success": false, "error": "This verification link is not valid"})
+		case errors.Is(err, services.ErrRequestNotFound):
+			c.JSON(http.StatusNotFound, gin.H{"success": false, "error": "Request not found"})
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{"success": false, "error": "Failed to verify request"})
+		}
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"success": true, "data": result})
+}
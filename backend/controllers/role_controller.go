@@ -0,0 +1,181 @@
+// controllers/role_controller.go
+package controllers
+
+import (
+	"errors"
+	"ku-asset/dto"
+	"ku-asset/middleware"
+	"ku-asset/models"
+	"ku-asset/services"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+type RoleController struct {
+	roleService services.RoleService
+}
+
+func NewRoleController(roleService services.RoleService) *RoleController {
+	return &RoleController{roleService: roleService}
+}
+
+// GetPermissions แสดงรายการสิทธิ์ทั้งหมดที่ระบบรองรับ
+func (ctrl *RoleController) GetPermissions(c *gin.Context) {
+	permissions, err := ctrl.roleService.GetPermissions()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"success": false, "error": "Failed to get permissions"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"success": true, "data": permissions})
+}
+
+func (ctrl *RoleController) GetRoles(c *gin.Context) {
+	roles, err := ctrl.roleService.GetRoles()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"success": false, "error": "Failed to get roles"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"success": true, "data": roles})
+}
+
+func (ctrl *RoleController) GetRole(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "error": "Invalid role ID"})
+		return
+	}
+	role, err := ctrl.roleService.GetRoleByID(uint(id))
+	if err != nil {
+		respondRoleError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"success": true, "data": role})
+}
+
+func (ctrl *RoleController) CreateRole(c *gin.Context) {
+	var req dto.CreateRoleRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "error": "Invalid input", "details": err.Error()})
+		return
+	}
+	role, err := ctrl.roleService.CreateRole(c.Request.Context(), &req)
+	if err != nil {
+		respondRoleError(c, err)
+		return
+	}
+	c.JSON(http.StatusCreated, gin.H{"success": true, "data": role})
+}
+
+func (ctrl *RoleController) UpdateRole(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "error": "Invalid role ID"})
+		return
+	}
+	var req dto.UpdateRoleRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "error": "Invalid input", "details": err.Error()})
+		return
+	}
+	role, err := ctrl.roleService.UpdateRole(c.Request.Context(), uint(id), &req)
+	if err != nil {
+		respondRoleError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"success": true, "message": "Role updated successfully", "data": role})
+}
+
+func (ctrl *RoleController) DeleteRole(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "error": "Invalid role ID"})
+		return
+	}
+	if err := ctrl.roleService.DeleteRole(c.Request.Context(), uint(id)); err != nil {
+		respondRoleError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"success": true, "message": "Role deleted successfully"})
+}
+
+// --- User Role Assignment ---
+
+func (ctrl *RoleController) GetUserRoles(c *gin.Context) {
+	userID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "error": "Invalid user ID"})
+		return
+	}
+	roles, err := ctrl.roleService.GetUserRoles(uint(userID))
+	if err != nil {
+		respondRoleError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"success": true, "data": roles})
+}
+
+func (ctrl *RoleController) AssignRole(c *gin.Context) {
+	userID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "error": "Invalid user ID"})
+		return
+	}
+	var req dto.AssignRoleRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "error": "Invalid input", "details": err.Error()})
+		return
+	}
+	scope, err := middleware.GetPermissionScope(c, models.PermRoleManage)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"success": false, "error": err.Error()})
+		return
+	}
+	granterID, _ := middleware.GetUserID(c)
+	assignment, err := ctrl.roleService.AssignRole(c.Request.Context(), uint(userID), &req, granterID, scope)
+	if err != nil {
+		respondRoleError(c, err)
+		return
+	}
+	c.JSON(http.StatusCreated, gin.H{"success": true, "data": assignment})
+}
+
+func (ctrl *RoleController) RemoveRole(c *gin.Context) {
+	userID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "error": "Invalid user ID"})
+		return
+	}
+	roleID, err := strconv.ParseUint(c.Param("roleId"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "error": "Invalid role ID"})
+		return
+	}
+	scope, err := middleware.GetPermissionScope(c, models.PermRoleManage)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"success": false, "error": err.Error()})
+		return
+	}
+	if err := ctrl.roleService.RemoveRole(c.Request.Context(), uint(userID), uint(roleID), scope); err != nil {
+		respondRoleError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"success": true, "message": "Role removed successfully"})
+}
+
+// respondRoleError maps role service errors to HTTP status codes
+func respondRoleError(c *gin.Context, err error) {
+	status := http.StatusInternalServerError
+	switch {
+	case errors.Is(err, services.ErrRoleNotFound), err.Error() == "user not found", err.Error() == "department not found":
+		status = http.StatusNotFound
+	case errors.Is(err, services.ErrOutOfScope), errors.Is(err, services.ErrRoleNotGrantable):
+		status = http.StatusForbidden
+	case errors.Is(err, services.ErrRoleExists), errors.Is(err, services.ErrRoleAlreadyGranted):
+		status = http.StatusConflict
+	case errors.Is(err, services.ErrSystemRole), errors.Is(err, services.ErrUnknownPermission):
+		status = http.StatusBadRequest
+	}
+	c.JSON(status, gin.H{"success": false, "error": err.Error()})
+}
@@ -0,0 +1,136 @@
+// controllers/service_account_controller.go
+package controllers
+
+import (
+	"errors"
+	"ku-asset/dto"
+	"ku-asset/middleware"
+	"ku-asset/services"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+type ServiceAccountController struct {
+	serviceAccountService services.ServiceAccountService
+}
+
+func NewServiceAccountController(serviceAccountService services.ServiceAccountService) *ServiceAccountController {
+	return &ServiceAccountController{serviceAccountService: serviceAccountService}
+}
+
+func (ctrl *ServiceAccountController) GetServiceAccounts(c *gin.Context) {
+	accounts, err := ctrl.serviceAccountService.GetServiceAccounts()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"success": false, "error": "Failed to get service accounts"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"success": true, "data": accounts})
+}
+
+func (ctrl *ServiceAccountController) GetServiceAccount(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "error": "Invalid service account ID"})
+		return
+	}
+	account, err := ctrl.serviceAccountService.GetServiceAccount(uint(id))
+	if err != nil {
+		respondServiceAccountError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"success": true, "data": account})
+}
+
+func (ctrl *ServiceAccountController) CreateServiceAccount(c *gin.Context) {
+	var req dto.CreateServiceAccountRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "error": "Invalid input", "details": err.Error()})
+		return
+	}
+	userID, _ := middleware.GetUserID(c)
+	account, err := ctrl.serviceAccountService.CreateServiceAccount(c.Request.Context(), &req, userID)
+	if err != nil {
+		respondServiceAccountError(c, err)
+		return
+	}
+	c.JSON(http.StatusCreated, gin.H{"success": true, "data": account})
+}
+
+func (ctrl *ServiceAccountController) DeleteServiceAccount(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "error": "Invalid service account ID"})
+		return
+	}
+	if err := ctrl.serviceAccountService.DeleteServiceAccount(c.Request.Context(), uint(id)); err != nil {
+		respondServiceAccountError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"success": true, "message": "Service account deleted successfully"})
+}
+
+// CreateAPIKey คืน key เต็มเพียงครั้งเดียว หลังจากนี้เก็บไว้เฉพาะ hash
+func (ctrl *ServiceAccountController) CreateAPIKey(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "error": "Invalid service account ID"})
+		return
+	}
+	var req dto.CreateAPIKeyRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "error": "Invalid input", "details": err.Error()})
+		return
+	}
+	granterPermissions, err := middleware.GetUserPermissions(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"success": false, "error": err.Error()})
+		return
+	}
+	key, err := ctrl.serviceAccountService.CreateAPIKey(c.Request.Context(), uint(id), &req, granterPermissions)
+	if err != nil {
+		respondServiceAccountError(c, err)
+		return
+	}
+	c.JSON(http.StatusCreated, gin.H{
+		"success": true,
+		"message": "Store this key now - it will not be shown again",
+		"data":    key,
+	})
+}
+
+func (ctrl *ServiceAccountController) RevokeAPIKey(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "error": "Invalid service account ID"})
+		return
+	}
+	keyID, err := strconv.ParseUint(c.Param("keyId"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "error": "Invalid API key ID"})
+		return
+	}
+	if err := ctrl.serviceAccountService.RevokeAPIKey(c.Request.Context(), uint(id), uint(keyID)); err != nil {
+		respondServiceAccountError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"success": true, "message": "API key revoked successfully"})
+}
+
+// respondServiceAccountError maps service account errors to HTTP status codes
+func respondServiceAccountError(c *gin.Context, err error) {
+	status := http.StatusInternalServerError
+	switch {
+	case errors.Is(err, services.ErrServiceAccountNotFound), errors.Is(err, services.ErrAPIKeyNotFound):
+		status = http.StatusNotFound
+	case errors.Is(err, services.ErrPermissionNotHeld):
+		status = http.StatusForbidden
+	case errors.Is(err, services.ErrServiceAccountExists):
+		status = http.StatusConflict
+	case errors.Is(err, services.ErrUnknownPermission), errors.Is(err, services.ErrInvalidIPAllowlist),
+		errors.Is(err, services.ErrInvalidExpiry):
+		status = http.StatusBadRequest
+	}
+	c.JSON(status, gin.H{"success": false, "error": err.Error()})
+}
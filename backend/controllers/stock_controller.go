@@ -0,0 +1,65 @@
+// controllers/stock_controller.go
+package controllers
+
+import (
+	"fmt"
+	"ku-asset/dto"
+	"ku-asset/services"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+type StockController struct {
+	stockMonitorService services.StockMonitorService
+}
+
+func NewStockController(stockMonitorService services.StockMonitorService) *StockController {
+	return &StockController{stockMonitorService: stockMonitorService}
+}
+
+// GET /api/admin/stock/low-stock
+func (ctrl *StockController) GetLowStock(c *gin.Context) {
+	products, err := ctrl.stockMonitorService.GetLowStockProducts()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"success": false, "error": "Failed to get low stock products"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"success": true, "data": products})
+}
+
+// GET /api/admin/stock/reorder-suggestions
+func (ctrl *StockController) GetReorderSuggestions(c *gin.Context) {
+	var query dto.ReorderQuery
+	if err := c.ShouldBindQuery(&query); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "error": err.Error()})
+		return
+	}
+
+	suggestions, err := ctrl.stockMonitorService.GetReorderSuggestions(&query)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"success": false, "error": "Failed to calculate reorder suggestions"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"success": true, "data": suggestions})
+}
+
+// GET /api/admin/stock/reorder-suggestions/export
+func (ctrl *StockController) ExportPurchaseDraft(c *gin.Context) {
+	var query dto.ReorderQuery
+	if err := c.ShouldBindQuery(&query); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "error": err.Error()})
+		return
+	}
+
+	file, err := ctrl.stockMonitorService.ExportPurchaseDraft(&query)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"success": false, "error": "Failed to export purchase request draft"})
+		return
+	}
+
+	filename := fmt.Sprintf("purchase_request_draft_%s.xlsx", time.Now().Format("20060102"))
+	c.Header("Content-Disposition", "attachment; filename="+filename)
+	c.Data(http.StatusOK, "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet", file)
+}
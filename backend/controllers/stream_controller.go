@@ -0,0 +1,130 @@
+// controllers/stream_controller.go
+package controllers
+
+import (
+	"io"
+	"ku-asset/auth"
+	"ku-asset/middleware"
+	"ku-asset/models"
+	"ku-asset/realtime"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// streamPingInterval keeps proxies from closing an idle connection
+const streamPingInterval = 25 * time.Second
+
+// streamRecheckInterval is how often an open stream checks that the user may still receive its
+// events. The stream ends when that changes and EventSource reconnects through AuthMiddleware.
+const streamRecheckInterval = time.Minute
+
+// streamPermissions คือ permission ที่ event สำหรับเจ้าหน้าที่ใช้ (ดู realtime.Event.Permission)
+var streamPermissions = []string{models.PermRequestView, models.PermProductManage}
+
+type StreamController struct {
+	hub realtime.Hub
+}
+
+func NewStreamController(hub realtime.Hub) *StreamController {
+	return &StreamController{hub: hub}
+}
+
+// Stream ส่ง event แบบ Server-Sent Events ให้ client ที่ล็อกอิน กรองตามผู้ใช้ สิทธิ์ และหน่วยงาน
+func (ctrl *StreamController) Stream(c *gin.Context) {
+	userID, err := middleware.GetUserID(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"success": false, "error": err.Error()})
+		return
+	}
+
+	audience := &realtime.Audience{UserID: userID, Scopes: make(map[string]*auth.Scope)}
+	for _, perm := range streamPermissions {
+		scope, err := middleware.GetPermissionScope(c, perm)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"success": false, "error": "Failed to load permissions"})
+			return
+		}
+		if scope.All || len(scope.DepartmentIDs) > 0 {
+			audience.Scopes[perm] = scope
+		}
+	}
+
+	sub := ctrl.hub.Subscribe(audience.Accepts)
+	defer sub.Close()
+
+	c.Header("Content-Type", "text/event-stream")
+	c.Header("Cache-Control", "no-cache")
+	c.Header("Connection", "keep-alive")
+	c.Header("X-Accel-Buffering", "no") // nginx: ห้าม buffer
+	c.Status(http.StatusOK)
+	c.SSEvent("ready", gin.H{"user_id": userID})
+	c.Writer.Flush()
+
+	ticker := time.NewTicker(streamPingInterval)
+	defer ticker.Stop()
+	recheck := time.NewTicker(streamRecheckInterval)
+	defer recheck.Stop()
+
+	done := c.Request.Context().Done()
+	c.Stream(func(w io.Writer) bool {
+		select {
+		case <-done:
+			return false
+		case ev := <-sub.C:
+			c.SSEvent(ev.Type, ev.Data)
+		case <-ticker.C:
+			c.SSEvent("ping", gin.H{"time": time.Now().Unix()})
+		case <-recheck.C:
+			// ⭐ ผู้ใช้ที่ถูกปิดบัญชีหรือถูกถอด role ต้องไม่ได้รับ event ต่อจนกว่าจะ reconnect เอง
+			if !streamStillAllowed(c, audience) {
+				return false
+			}
+		}
+		return true
+	})
+}
+
+// streamStillAllowed re-checks the account, the impersonation session or API key the stream was
+// opened with, and the audience scopes
+func streamStillAllowed(c *gin.Context, audience *realtime.Audience) bool {
+	value, exists := c.Get("db")
+	if !exists {
+		return false
+	}
+	db := value.(*gorm.DB).WithContext(c.Request.Context())
+	now := time.Now()
+
+	var user models.User
+	if err := db.Select("id", "is_active").First(&user, audience.UserID).Error; err != nil || !user.IsActive {
+		return false
+	}
+	if sessionID, ok := c.Get("impersonationSessionID"); ok {
+		var session models.ImpersonationSession
+		if err := db.First(&session, sessionID).Error; err != nil || !session.IsActive(now) {
+			return false
+		}
+	}
+	if keyID, ok := c.Get("apiKeyID"); ok {
+		// permission ของ API key กำหนดตอนสร้าง ตรวจแค่ว่ายังใช้งานได้
+		var key models.APIKey
+		return db.First(&key, keyID).Error == nil && key.IsUsable(now)
+	}
+
+	for _, perm := range streamPermissions {
+		scope, err := auth.PermissionScope(db, audience.UserID, perm)
+		if err != nil {
+			return false
+		}
+		current, ok := audience.Scopes[perm]
+		if !ok {
+			current = &auth.Scope{}
+		}
+		if !current.Covers(scope) || !scope.Covers(current) {
+			return false
+		}
+	}
+	return true
+}
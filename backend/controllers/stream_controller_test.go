@@ -0,0 +1,90 @@
+package controllers
+
+import (
+	"net/http/httptest"
+	"testing"
+
+	"ku-asset/auth"
+	"ku-asset/models"
+	"ku-asset/realtime"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/gin-gonic/gin"
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+func newMockDB(t *testing.T) (*gorm.DB, sqlmock.Sqlmock) {
+	t.Helper()
+	sqlDB, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { sqlDB.Close() })
+
+	db, err := gorm.Open(postgres.New(postgres.Config{Conn: sqlDB}), &gorm.Config{
+		Logger: logger.Default.LogMode(logger.Silent),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return db, mock
+}
+
+func TestStreamStillAllowed(t *testing.T) {
+	expectUser := func(mock sqlmock.Sqlmock, active bool) {
+		mock.ExpectQuery(`SELECT "id","is_active" FROM "users"`).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "is_active"}).AddRow(7, active))
+	}
+	expectScope := func(mock sqlmock.Sqlmock, departments ...interface{}) {
+		mock.ExpectQuery(`SELECT count\(\*\) FROM "user_roles"`).
+			WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(1))
+		rows := sqlmock.NewRows([]string{"department_id"})
+		for _, d := range departments {
+			rows.AddRow(d)
+		}
+		mock.ExpectQuery(`SELECT "user_roles"."department_id"`).WillReturnRows(rows)
+	}
+
+	tests := []struct {
+		name   string
+		scopes map[string]*auth.Scope
+		expect func(mock sqlmock.Sqlmock)
+		want   bool
+	}{
+		{"deactivated", nil, func(mock sqlmock.Sqlmock) {
+			expectUser(mock, false)
+		}, false},
+		{"unchanged", map[string]*auth.Scope{models.PermRequestView: auth.GlobalScope()}, func(mock sqlmock.Sqlmock) {
+			expectUser(mock, true)
+			expectScope(mock, nil) // request.view ทั้งระบบ
+			expectScope(mock)      // ไม่มี product.manage
+		}, true},
+		{"role removed", map[string]*auth.Scope{models.PermRequestView: auth.GlobalScope()}, func(mock sqlmock.Sqlmock) {
+			expectUser(mock, true)
+			expectScope(mock)
+		}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db, mock := newMockDB(t)
+			tt.expect(mock)
+
+			c, _ := gin.CreateTestContext(httptest.NewRecorder())
+			c.Request = httptest.NewRequest("GET", "/api/v1/stream", nil)
+			c.Set("db", db)
+			audience := &realtime.Audience{UserID: 7, Scopes: tt.scopes}
+			if audience.Scopes == nil {
+				audience.Scopes = map[string]*auth.Scope{}
+			}
+
+			if got := streamStillAllowed(c, audience); got != tt.want {
+				t.Errorf("streamStillAllowed() = %v, want %v", got, tt.want)
+			}
+			if err := mock.ExpectationsWereMet(); err != nil {
+				t.Error(err)
+			}
+		})
+	}
+}
@@ -0,0 +1,102 @@
+// controllers/two_factor_controller.go
+package controllers
+
+import (
+	"errors"
+	"ku-asset/dto"
+	"ku-asset/middleware"
+	"ku-asset/services"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+type TwoFactorController struct {
+	twoFactorService services.TwoFactorService
+}
+
+func NewTwoFactorController(twoFactorService services.TwoFactorService) *TwoFactorController {
+	return &TwoFactorController{twoFactorService: twoFactorService}
+}
+
+func (ctrl *TwoFactorController) GetStatus(c *gin.Context) {
+	userID, _ := middleware.GetUserID(c)
+	status, err := ctrl.twoFactorService.GetStatus(userID)
+	if err != nil {
+		respondTwoFactorError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"success": true, "data": status})
+}
+
+// Setup คืน otpauth URI และ QR PNG สำหรับสแกนด้วยแอป Authenticator
+func (ctrl *TwoFactorController) Setup(c *gin.Context) {
+	userID, _ := middleware.GetUserID(c)
+	setup, err := ctrl.twoFactorService.Setup(c.Request.Context(), userID)
+	if err != nil {
+		respondTwoFactorError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"success": true, "data": setup})
+}
+
+func (ctrl *TwoFactorController) Confirm(c *gin.Context) {
+	var req dto.TwoFactorCodeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "error": "Invalid input", "details": err.Error()})
+		return
+	}
+	userID, _ := middleware.GetUserID(c)
+	codes, err := ctrl.twoFactorService.Confirm(c.Request.Context(), userID, &req)
+	if err != nil {
+		respondTwoFactorError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"success": true, "message": "Two-factor authentication enabled", "data": codes})
+}
+
+func (ctrl *TwoFactorController) Disable(c *gin.Context) {
+	var req dto.DisableTwoFactorRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "error": "Invalid input", "details": err.Error()})
+		return
+	}
+	userID, _ := middleware.GetUserID(c)
+	if err := ctrl.twoFactorService.Disable(c.Request.Context(), userID, &req); err != nil {
+		respondTwoFactorError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"success": true, "message": "Two-factor authentication disabled"})
+}
+
+func (ctrl *TwoFactorController) RegenerateRecoveryCodes(c *gin.Context) {
+	var req dto.TwoFactorCodeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "error": "Invalid input", "details": err.Error()})
+		return
+	}
+	userID, _ := middleware.GetUserID(c)
+	codes, err := ctrl.twoFactorService.RegenerateRecoveryCodes(c.Request.Context(), userID, &req)
+	if err != nil {
+		respondTwoFactorError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"success": true, "data": codes})
+}
+
+// respondTwoFactorError maps two-factor service errors to HTTP status codes
+func respondTwoFactorError(c *gin.Context, err error) {
+	status := http.StatusInternalServerError
+	switch {
+	case err.Error() == "user not found":
+		status = http.StatusNotFound
+	case errors.Is(err, services.ErrTwoFactorRequired):
+		status = http.StatusForbidden
+	case errors.Is(err, services.ErrTwoFactorAlreadyEnabled), errors.Is(err, services.ErrTwoFactorNotEnabled):
+		status = http.StatusConflict
+	case errors.Is(err, services.ErrTwoFactorSetupRequired), errors.Is(err, services.ErrInvalidTwoFactorCode),
+		err.Error() == "current password is incorrect":
+		status = http.StatusBadRequest
+	}
+	c.JSON(status, gin.H{"success": false, "error": err.Error()})
+}
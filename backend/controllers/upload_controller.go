@@ -1,8 +1,15 @@
 package controllers
 
 import (
+	"errors"
+	"io"
+	"ku-asset/middleware"
 	"ku-asset/services"
+	"ku-asset/storage"
 	"net/http"
+	"strconv"
+	"strings"
+	"time"
 
 	"github.com/gin-gonic/gin"
 )
@@ -11,9 +18,9 @@ type UploadController struct {
 	uploadService *services.UploadService
 }
 
-func NewUploadController() *UploadController {
+func NewUploadController(uploadService *services.UploadService) *UploadController {
 	return &UploadController{
-		uploadService: services.NewUploadService(),
+		uploadService: uploadService,
 	}
 }
 
@@ -34,7 +41,7 @@ func (uc *UploadController) UploadProductImage(c *gin.Context) {
 	config := uc.uploadService.GetProductImageConfig()
 
 	// Validate file
-	if err := uc.uploadService.ValidateFile(header, config); err != nil {
+	if err := uc.uploadService.ValidateFile(file, header, config); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{
 			"success": false,
 			"error":   err.Error(),
@@ -43,7 +50,11 @@ func (uc *UploadController) UploadProductImage(c *gin.Context) {
 	}
 
 	// Process and save image
-	result, err := uc.uploadService.ProcessAndSaveImage(file, header, config)
+	var ownerID *uint
+	if userID, err := middleware.GetUserID(c); err == nil {
+		ownerID = &userID
+	}
+	result, err := uc.uploadService.ProcessAndSaveImage(c.Request.Context(), file, header, config, ownerID)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{
 			"success": false,
@@ -71,12 +82,17 @@ func (uc *UploadController) DeleteProductImage(c *gin.Context) {
 
 	// Delete file using upload service
 	config := uc.uploadService.GetProductImageConfig()
-	if err := uc.uploadService.DeleteFile(filename, config.UploadDir); err != nil {
-		if err.Error() == "file not found" {
+	if err := uc.uploadService.DeleteFile(c.Request.Context(), filename, config.KeyPrefix); err != nil {
+		if errors.Is(err, services.ErrFileNotFound) {
 			c.JSON(http.StatusNotFound, gin.H{
 				"success": false,
 				"error":   "File not found",
 			})
+		} else if errors.Is(err, services.ErrFileInUse) {
+			c.JSON(http.StatusConflict, gin.H{
+				"success": false,
+				"error":   "File is still used by a product or request",
+			})
 		} else {
 			c.JSON(http.StatusInternalServerError, gin.H{
 				"success": false,
@@ -91,3 +107,63 @@ func (uc *UploadController) DeleteProductImage(c *gin.Context) {
 		"message": "File deleted successfully",
 	})
 }
+
+// ServeFile ส่งไฟล์จาก storage ให้ client (GET /uploads/*key) ใช้ได้กับทุก backend
+// ไฟล์ private (เช่นเอกสารแนบคำขอ) ต้องมี ?expires=&sig= จาก services.SignedFileURL
+func (uc *UploadController) ServeFile(c *gin.Context) {
+	key := strings.TrimPrefix(c.Param("key"), "/")
+	signed := c.Query("sig") != ""
+	var (
+		rc  io.ReadCloser
+		obj *storage.Object
+		err error
+	)
+	if signed {
+		rc, obj, err = uc.uploadService.OpenSignedFile(c.Request.Context(), key, c.Query("expires"), c.Query("sig"))
+	} else {
+		rc, obj, err = uc.uploadService.OpenFile(c.Request.Context(), key)
+	}
+	if err != nil {
+		switch {
+		case errors.Is(err, services.ErrFileNotFound):
+			c.Status(http.StatusNotFound)
+		case errors.Is(err, services.ErrInvalidFileSignature), errors.Is(err, services.ErrFileURLExpired):
+			c.Status(http.StatusForbidden)
+		default:
+			c.Status(http.StatusBadGateway)
+		}
+		return
+	}
+	defer rc.Close()
+
+	if obj.ETag != "" {
+		c.Header("ETag", obj.ETag)
+		if c.GetHeader("If-None-Match") == obj.ETag {
+			c.Status(http.StatusNotModified)
+			return
+		}
+	}
+	if !obj.ModTime.IsZero() {
+		c.Header("Last-Modified", obj.ModTime.UTC().Format(http.TimeFormat))
+	}
+	contentType := obj.ContentType
+	if contentType == "" {
+		contentType = "application/octet-stream"
+	}
+	if signed {
+		// ลิงก์มีอายุ ห้าม cache ร่วม (proxy/CDN) และไม่เกินเวลาหมดอายุ
+		expires, _ := strconv.ParseInt(c.Query("expires"), 10, 64)
+		c.Header("Cache-Control", "private, max-age="+strconv.FormatInt(max(expires-time.Now().Unix(), 0), 10))
+	} else {
+		// ⭐ ชื่อไฟล์สุ่มและไม่ถูกเขียนทับ จึง cache ได้นาน
+		c.Header("Cache-Control", "public, max-age="+strconv.Itoa(7*24*3600))
+	}
+	c.Header("X-Content-Type-Options", "nosniff")
+	if c.Request.Method == http.MethodHead {
+		c.Header("Content-Length", strconv.FormatInt(obj.Size, 10))
+		c.Header("Content-Type", contentType)
+		c.Status(http.StatusOK)
+		return
+	}
+	c.DataFromReader(http.StatusOK, obj.Size, contentType, rc, nil)
+}
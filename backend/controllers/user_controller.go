@@ -3,9 +3,11 @@
 package controllers
 
 import (
+	"errors"
 	"fmt"
 	"ku-asset/dto"
 	"ku-asset/middleware"
+	"ku-asset/models"
 	"ku-asset/services"
 	"net/http"
 	"strconv"
@@ -44,7 +46,7 @@ func (ctrl *UserController) UpdateProfile(c *gin.Context) {
 	}
 	fmt.Printf("📝 Update profile request: %+v\n", req)
 	userID, _ := middleware.GetUserID(c)
-	updatedUser, err := ctrl.userService.UpdateUserProfile(userID, &req)
+	updatedUser, err := ctrl.userService.UpdateUserProfile(c.Request.Context(), userID, &req)
 	if err != nil {
 		fmt.Printf("🚫 Service error: %v\n", err)
 		c.JSON(http.StatusInternalServerError, gin.H{"success": false, "error": err.Error()})
@@ -61,7 +63,7 @@ func (ctrl *UserController) ChangePassword(c *gin.Context) {
 		return
 	}
 	userID, _ := middleware.GetUserID(c)
-	err := ctrl.userService.ChangePassword(userID, &req)
+	err := ctrl.userService.ChangePassword(c.Request.Context(), userID, &req)
 	if err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"success": false, "error": err.Error()})
 		return
@@ -87,7 +89,12 @@ func (ctrl *UserController) GetUsers(c *gin.Context) {
 		c.JSON(http.StatusBadRequest, gin.H{"success": false, "error": "Invalid query parameters"})
 		return
 	}
-	paginatedResponse, err := ctrl.userService.GetUsers(&req)
+	scope, err := middleware.GetPermissionScope(c, models.PermUserManage)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"success": false, "error": err.Error()})
+		return
+	}
+	paginatedResponse, err := ctrl.userService.GetUsers(&req, scope)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"success": false, "error": err.Error()})
 		return
@@ -106,6 +113,11 @@ func (ctrl *UserController) GetUser(c *gin.Context) {
 		c.JSON(http.StatusNotFound, gin.H{"success": false, "error": err.Error()})
 		return
 	}
+	scope, err := middleware.GetPermissionScope(c, models.PermUserManage)
+	if err != nil || !scope.Allows(user.DepartmentID) {
+		c.JSON(http.StatusNotFound, gin.H{"success": false, "error": "user not found"})
+		return
+	}
 	c.JSON(http.StatusOK, gin.H{"success": true, "data": user})
 }
 
@@ -120,9 +132,20 @@ func (ctrl *UserController) UpdateUser(c *gin.Context) {
 		c.JSON(http.StatusBadRequest, gin.H{"success": false, "error": "Invalid input"})
 		return
 	}
-	updatedUser, err := ctrl.userService.AdminUpdateUser(uint(id), &req)
+	scope, err := middleware.GetPermissionScope(c, models.PermUserManage)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"success": false, "error": err.Error()})
+		c.JSON(http.StatusUnauthorized, gin.H{"success": false, "error": err.Error()})
+		return
+	}
+	roleScope, err := middleware.GetPermissionScope(c, models.PermRoleManage)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"success": false, "error": err.Error()})
+		return
+	}
+	actorID, _ := middleware.GetUserID(c)
+	updatedUser, err := ctrl.userService.AdminUpdateUser(c.Request.Context(), uint(id), &req, actorID, scope, roleScope)
+	if err != nil {
+		respondUserError(c, err)
 		return
 	}
 	c.JSON(http.StatusOK, gin.H{"success": true, "message": "User updated successfully", "data": updatedUser})
@@ -134,9 +157,65 @@ func (ctrl *UserController) DeleteUser(c *gin.Context) {
 		c.JSON(http.StatusBadRequest, gin.H{"success": false, "error": "Invalid user ID"})
 		return
 	}
-	if err := ctrl.userService.DeleteUser(uint(id)); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"success": false, "error": err.Error()})
+	scope, err := middleware.GetPermissionScope(c, models.PermUserManage)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"success": false, "error": err.Error()})
+		return
+	}
+	if err := ctrl.userService.DeleteUser(c.Request.Context(), uint(id), scope); err != nil {
+		respondUserError(c, err)
 		return
 	}
 	c.JSON(http.StatusOK, gin.H{"success": true, "message": "User deleted successfully"})
 }
+
+// UnlockUser ปลดล็อกบัญชีที่ถูกล็อกจากการใส่รหัสผิดหลายครั้ง
+func (ctrl *UserController) UnlockUser(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "error": "Invalid user ID"})
+		return
+	}
+	scope, err := middleware.GetPermissionScope(c, models.PermUserManage)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"success": false, "error": err.Error()})
+		return
+	}
+	if err := ctrl.userService.UnlockUser(c.Request.Context(), uint(id), scope); err != nil {
+		respondUserError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"success": true, "message": "User unlocked successfully"})
+}
+
+func (ctrl *UserController) GetLoginAttempts(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "error": "Invalid user ID"})
+		return
+	}
+	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "50"))
+	scope, err := middleware.GetPermissionScope(c, models.PermUserManage)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"success": false, "error": err.Error()})
+		return
+	}
+	attempts, err := ctrl.userService.GetLoginAttempts(uint(id), limit, scope)
+	if err != nil {
+		respondUserError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"success": true, "data": attempts})
+}
+
+// respondUserError maps admin user service errors to HTTP status codes
+func respondUserError(c *gin.Context, err error) {
+	switch {
+	case err.Error() == "user not found", errors.Is(err, services.ErrRoleNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"success": false, "error": err.Error()})
+	case err.Error() == "department is outside your scope", errors.Is(err, services.ErrRoleNotGrantable):
+		c.JSON(http.StatusForbidden, gin.H{"success": false, "error": err.Error()})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"success": false, "error": err.Error()})
+	}
+}
@@ -0,0 +1,168 @@
+// controllers/webhook_controller.go
+package controllers
+
+import (
+	"errors"
+	"ku-asset/dto"
+	"ku-asset/middleware"
+	"ku-asset/models"
+	"ku-asset/services"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+type WebhookController struct {
+	webhookService services.WebhookService
+}
+
+func NewWebhookController(webhookService services.WebhookService) *WebhookController {
+	return &WebhookController{webhookService: webhookService}
+}
+
+// GetWebhookEvents คืนรายการ event ที่ subscribe ได้
+func (ctrl *WebhookController) GetWebhookEvents(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{"success": true, "data": models.WebhookEvents})
+}
+
+func (ctrl *WebhookController) GetWebhooks(c *gin.Context) {
+	hooks, err := ctrl.webhookService.GetWebhooks()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"success": false, "error": "Failed to get webhooks"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"success": true, "data": hooks})
+}
+
+func (ctrl *WebhookController) GetWebhook(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "error": "Invalid webhook ID"})
+		return
+	}
+	hook, err := ctrl.webhookService.GetWebhook(uint(id))
+	if err != nil {
+		respondWebhookError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"success": true, "data": hook})
+}
+
+// CreateWebhook คืน secret เพียงครั้งเดียว
+func (ctrl *WebhookController) CreateWebhook(c *gin.Context) {
+	var req dto.CreateWebhookRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "error": "Invalid input", "details": err.Error()})
+		return
+	}
+	userID, _ := middleware.GetUserID(c)
+	hook, err := ctrl.webhookService.CreateWebhook(c.Request.Context(), &req, userID)
+	if err != nil {
+		respondWebhookError(c, err)
+		return
+	}
+	c.JSON(http.StatusCreated, gin.H{
+		"success": true,
+		"message": "Store this secret now - it will not be shown again",
+		"data":    hook,
+	})
+}
+
+func (ctrl *WebhookController) UpdateWebhook(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "error": "Invalid webhook ID"})
+		return
+	}
+	var req dto.UpdateWebhookRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "error": "Invalid input", "details": err.Error()})
+		return
+	}
+	hook, err := ctrl.webhookService.UpdateWebhook(c.Request.Context(), uint(id), &req)
+	if err != nil {
+		respondWebhookError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"success": true, "data": hook})
+}
+
+func (ctrl *WebhookController) DeleteWebhook(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "error": "Invalid webhook ID"})
+		return
+	}
+	if err := ctrl.webhookService.DeleteWebhook(c.Request.Context(), uint(id)); err != nil {
+		respondWebhookError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"success": true, "message": "Webhook deleted successfully"})
+}
+
+func (ctrl *WebhookController) GetDeliveries(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "error": "Invalid webhook ID"})
+		return
+	}
+	var query dto.WebhookDeliveryQuery
+	if err := c.ShouldBindQuery(&query); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "error": "Invalid query parameters"})
+		return
+	}
+	deliveries, err := ctrl.webhookService.GetDeliveries(uint(id), &query)
+	if err != nil {
+		respondWebhookError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"success": true, "data": deliveries})
+}
+
+// Redeliver ส่ง payload เดิมซ้ำเป็นรายการใหม่ (event ID เดิม)
+func (ctrl *WebhookController) Redeliver(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "error": "Invalid webhook ID"})
+		return
+	}
+	deliveryID, err := strconv.ParseUint(c.Param("deliveryId"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "error": "Invalid delivery ID"})
+		return
+	}
+	delivery, err := ctrl.webhookService.Redeliver(uint(id), uint(deliveryID))
+	if err != nil {
+		respondWebhookError(c, err)
+		return
+	}
+	c.JSON(http.StatusAccepted, gin.H{"success": true, "message": "Delivery queued", "data": delivery})
+}
+
+func (ctrl *WebhookController) Ping(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "error": "Invalid webhook ID"})
+		return
+	}
+	delivery, err := ctrl.webhookService.Ping(uint(id))
+	if err != nil {
+		respondWebhookError(c, err)
+		return
+	}
+	c.JSON(http.StatusAccepted, gin.H{"success": true, "message": "Ping queued", "data": delivery})
+}
+
+// respondWebhookError maps webhook errors to HTTP status codes
+func respondWebhookError(c *gin.Context, err error) {
+	status := http.StatusInternalServerError
+	switch {
+	case errors.Is(err, services.ErrWebhookNotFound), errors.Is(err, services.ErrWebhookDeliveryNotFound):
+		status = http.StatusNotFound
+	case errors.Is(err, services.ErrUnknownWebhookEvent), errors.Is(err, services.ErrInvalidWebhookURL),
+		errors.Is(err, services.ErrWebhookTargetNotAllowed):
+		status = http.StatusBadRequest
+	}
+	c.JSON(status, gin.H{"success": false, "error": err.Error()})
+}
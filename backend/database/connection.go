@@ -19,10 +19,15 @@ type Config struct {
 	SSLMode  string
 }
 
+// DSN returns the libpq connection string for the config.
+func (cfg Config) DSN() string {
+	return fmt.Sprintf("host=%s user=%s password=%s dbname=%s port=%s sslmode=%s TimeZone=Asia/Bangkok",
+		cfg.Host, cfg.User, cfg.Password, cfg.DBName, cfg.Port, cfg.SSLMode)
+}
+
 // Connect establishes and returns a database connection instance.
 func Connect(cfg Config) (*gorm.DB, error) {
-	dsn := fmt.Sprintf("host=%s user=%s password=%s dbname=%s port=%s sslmode=%s TimeZone=Asia/Bangkok",
-		cfg.Host, cfg.User, cfg.Password, cfg.DBName, cfg.Port, cfg.SSLMode)
+	dsn := cfg.DSN()
 
 	logLevel := logger.Info
 	if os.Getenv("GIN_MODE") == "release" {
@@ -0,0 +1,46 @@
+// dto/audit_dto.go
+package dto
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// --- Request DTOs ---
+
+type AuditLogQuery struct {
+	Page       int        `form:"page,default=1"`
+	Limit      int        `form:"limit,default=50"`
+	ActorID    *uint      `form:"actor_id"`
+	Action     string     `form:"action" binding:"omitempty,oneof=create update delete"`
+	EntityType string     `form:"entity_type"`
+	EntityID   string     `form:"entity_id"`
+	RequestID  string     `form:"request_id"`
+	From       *time.Time `form:"from" time_format:"2006-01-02T15:04:05Z07:00"`
+	To         *time.Time `form:"to" time_format:"2006-01-02T15:04:05Z07:00"`
+}
+
+// --- Response DTOs ---
+
+type AuditLogResponse struct {
+	ID             uint            `json:"id"`
+	ActorID        *uint           `json:"actor_id"`
+	ActorEmail     string          `json:"actor_email"`
+	ImpersonatorID *uint           `json:"impersonator_id,omitempty"`
+	APIKeyID       *uint           `json:"api_key_id,omitempty"`
+	Action         string          `json:"action"`
+	EntityType     string          `json:"entity_type"`
+	EntityID       string          `json:"entity_id"`
+	Changes        json.RawMessage `json:"changes"`
+	IPAddress      string          `json:"ip_address"`
+	UserAgent      string          `json:"user_agent"`
+	RequestID      string          `json:"request_id"`
+	PrevHash       string          `json:"prev_hash"`
+	Hash           string          `json:"hash"`
+	CreatedAt      time.Time       `json:"created_at"`
+}
+
+type PaginatedAuditLogResponse struct {
+	Logs       []AuditLogResponse `json:"logs"`
+	Pagination PaginationResponse `json:"pagination"`
+}
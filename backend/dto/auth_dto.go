@@ -2,6 +2,8 @@
 
 package dto
 
+import "time"
+
 // LoginRequest defines the structure for a login request.
 type LoginRequest struct {
 	Email    string `json:"email" binding:"required,email"`
@@ -17,10 +19,15 @@ type RegisterRequest struct {
 }
 
 // AuthResponse defines the successful authentication response.
+// When MFARequired is set, no tokens are issued; the client must call /auth/2fa/verify with MFAToken.
 type AuthResponse struct {
 	User         UserResponse `json:"user"`
 	AccessToken  string       `json:"access_token"`
 	RefreshToken string       `json:"refresh_token"`
+
+	MFARequired           bool   `json:"mfa_required,omitempty"`
+	MFAToken              string `json:"mfa_token,omitempty"`
+	MFAEnrollmentRequired bool   `json:"mfa_enrollment_required,omitempty"`
 }
 
 // UserResponse defines the user data sent back to the client.
@@ -52,3 +59,42 @@ type GoogleOAuthRequest struct {
 type AccessTokenResponse struct {
 	AccessToken string `json:"access_token"`
 }
+
+// ForgotPasswordRequest starts the password reset flow.
+type ForgotPasswordRequest struct {
+	Email string `json:"email" binding:"required,email"`
+}
+
+// ResetPasswordRequest sets a new password using a reset token.
+type ResetPasswordRequest struct {
+	Token       string `json:"token" binding:"required"`
+	NewPassword string `json:"new_password" binding:"required,min=6"`
+}
+
+// VerifyEmailRequest confirms ownership of an email address.
+type VerifyEmailRequest struct {
+	Token string `json:"token" binding:"required"`
+}
+
+// ResendVerificationRequest asks for a new verification email.
+type ResendVerificationRequest struct {
+	Email string `json:"email" binding:"required,email"`
+}
+
+// ClientInfo carries request metadata recorded with login attempts.
+type ClientInfo struct {
+	IPAddress string
+	UserAgent string
+}
+
+// LoginAttemptResponse is an audit entry of a login attempt.
+type LoginAttemptResponse struct {
+	ID        uint      `json:"id"`
+	UserID    *uint     `json:"user_id"`
+	Email     string    `json:"email"`
+	IPAddress string    `json:"ip_address"`
+	UserAgent string    `json:"user_agent"`
+	Success   bool      `json:"success"`
+	Result    string    `json:"result"`
+	CreatedAt time.Time `json:"created_at"`
+}
@@ -0,0 +1,37 @@
+// dto/impersonation_dto.go
+package dto
+
+import "time"
+
+// --- Request DTOs ---
+
+type ImpersonateRequest struct {
+	Reason string `json:"reason" binding:"required,max=500"` // เช่น เลขที่ ticket หรือปัญหาที่ผู้ใช้แจ้ง
+}
+
+// --- Response DTOs ---
+
+type ImpersonationTokenResponse struct {
+	AccessToken string                       `json:"access_token"`
+	ExpiresAt   time.Time                    `json:"expires_at"`
+	Session     ImpersonationSessionResponse `json:"session"`
+}
+
+type ImpersonationSessionResponse struct {
+	ID        uint                     `json:"id"`
+	Admin     UserResponse             `json:"admin"`
+	User      UserResponse             `json:"user"`
+	Reason    string                   `json:"reason"`
+	IPAddress string                   `json:"ip_address"`
+	ExpiresAt time.Time                `json:"expires_at"`
+	EndedAt   *time.Time               `json:"ended_at"`
+	Calls     []ImpersonationCallEntry `json:"calls,omitempty"`
+	CreatedAt time.Time                `json:"created_at"`
+}
+
+type ImpersonationCallEntry struct {
+	Method     string    `json:"method"`
+	Path       string    `json:"path"`
+	StatusCode int       `json:"status_code"`
+	CreatedAt  time.Time `json:"created_at"`
+}
@@ -0,0 +1,13 @@
+// dto/line_dto.go
+package dto
+
+import "time"
+
+// --- Response DTOs ---
+
+// LineLinkCodeResponse is shown on the profile page; the user sends Command to the LINE bot
+type LineLinkCodeResponse struct {
+	Code      string    `json:"code"`
+	Command   string    `json:"command"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
@@ -0,0 +1,63 @@
+// dto/notification_dto.go
+package dto
+
+import "time"
+
+// --- Request DTOs ---
+
+type NotificationQuery struct {
+	Page       int  `form:"page,default=1"`
+	Limit      int  `form:"limit,default=20"`
+	UnreadOnly bool `form:"unread"`
+}
+
+type NotificationPreferenceInput struct {
+	Event string `json:"event" binding:"required"`
+	InApp bool   `json:"in_app"`
+	Email bool   `json:"email"`
+	Line  *bool  `json:"line"` // nil = คงค่าเดิม
+}
+
+type UpdateNotificationPreferencesRequest struct {
+	Preferences []NotificationPreferenceInput `json:"preferences" binding:"required,min=1,dive"`
+}
+
+type CreateRequestCommentInput struct {
+	Body string `json:"body" binding:"required,max=2000"`
+}
+
+// --- Response DTOs ---
+
+type NotificationResponse struct {
+	ID        uint       `json:"id"`
+	Event     string     `json:"event"`
+	Title     string     `json:"title"`
+	Body      string     `json:"body"`
+	Link      string     `json:"link"`
+	RequestID *uint      `json:"request_id,omitempty"`
+	ProductID *uint      `json:"product_id,omitempty"`
+	ReadAt    *time.Time `json:"read_at"`
+	CreatedAt time.Time  `json:"created_at"`
+}
+
+type PaginatedNotificationResponse struct {
+	Notifications []NotificationResponse `json:"notifications"`
+	UnreadCount   int64                  `json:"unread_count"`
+	Pagination    PaginationResponse     `json:"pagination"`
+}
+
+type NotificationPreferenceResponse struct {
+	Event       string `json:"event"`
+	Description string `json:"description"`
+	InApp       bool   `json:"in_app"`
+	Email       bool   `json:"email"`
+	Line        bool   `json:"line"`
+}
+
+type RequestCommentResponse struct {
+	ID        uint         `json:"id"`
+	RequestID uint         `json:"request_id"`
+	User      UserResponse `json:"user"`
+	Body      string       `json:"body"`
+	CreatedAt time.Time    `json:"created_at"`
+}
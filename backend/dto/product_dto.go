@@ -24,8 +24,11 @@ type CreateProductRequest struct {
 	MinStock int    `json:"min_stock" binding:"min=0"`      // จำนวนขั้นต่ำ
 	Unit     string `json:"unit"`                           // หน่วยนับ
 
+	LeadTimeDays *int `json:"lead_time_days" binding:"omitempty,min=0,max=365"` // ระยะเวลารอของ (วัน)
+
 	// ⭐ เพิ่ม ImageURL field (optional)
-	ImageURL *string `json:"image_url"`
+	ImageURL      *string           `json:"image_url"`
+	ImageVariants map[string]string `json:"image_variants"` // variants จากผลการอัปโหลด
 }
 
 // UpdateProductRequest defines the request body for updating a product.
@@ -38,9 +41,18 @@ type UpdateProductRequest struct {
 	Stock        int    `json:"stock"` // ⭐ ใช้ stock แทน quantity
 	MinStock     int    `json:"min_stock"`
 	Unit         string `json:"unit"`
+	LeadTimeDays *int   `json:"lead_time_days" binding:"omitempty,min=0,max=365"`
 
 	// ⭐ เพิ่ม ImageURL field
-	ImageURL *string `json:"image_url"`
+	ImageURL      *string           `json:"image_url"`
+	ImageVariants map[string]string `json:"image_variants"` // ส่งพร้อม image_url เสมอ
+}
+
+// StockAdjustmentRequest defines the request body for adjusting a product's stock.
+type StockAdjustmentRequest struct {
+	AdjustmentType string `json:"adjustment_type" binding:"required,oneof=ADD SET REMOVE"`
+	Quantity       int    `json:"quantity" binding:"min=0"`
+	Notes          string `json:"notes"`
 }
 
 // ProductResponse is the standard representation of a product returned by the API.
@@ -58,8 +70,15 @@ type ProductResponse struct {
 	Unit     string `json:"unit"`      // หน่วยนับ
 	Status   string `json:"status"`
 
+	LeadTimeDays *int `json:"lead_time_days"`
+	IsLowStock   bool `json:"is_low_stock"`
+
 	// ⭐ เพิ่ม ImageURL field
-	ImageURL *string `json:"image_url"`
+	ImageURL      *string           `json:"image_url"`
+	ImageVariants map[string]string `json:"image_variants,omitempty"` // 🆕 thumb, card, full
+
+	// 🆕 แกลเลอรีรูปและเอกสารแนบ (เฉพาะ GET /products/:id)
+	Media []ProductMediaResponse `json:"media,omitempty"`
 
 	Category  *CategoryResponse `json:"category,omitempty"`
 	CreatedAt time.Time         `json:"created_at"`
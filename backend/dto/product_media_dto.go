@@ -0,0 +1,33 @@
+// dto/product_media_dto.go
+package dto
+
+import "time"
+
+// --- Request DTOs ---
+
+type UpdateProductMediaRequest struct {
+	Caption   *string `json:"caption" binding:"omitempty,max=255"`
+	IsPrimary *bool   `json:"is_primary"` // ใช้ได้กับรูปภาพเท่านั้น ตั้ง true เพื่อเป็นรูปหลัก
+}
+
+type ReorderProductMediaRequest struct {
+	MediaIDs []uint `json:"media_ids" binding:"required,min=1"` // ลำดับใหม่ ต้องครบทุกรายการของสินค้า
+}
+
+// --- Response DTOs ---
+
+type ProductMediaResponse struct {
+	ID           uint              `json:"id"`
+	Type         string            `json:"type"`
+	URL          string            `json:"url"`
+	Variants     map[string]string `json:"variants,omitempty"`
+	Caption      string            `json:"caption"`
+	Position     int               `json:"position"`
+	IsPrimary    bool              `json:"is_primary"`
+	MimeType     string            `json:"mime_type"`
+	Size         int64             `json:"size"`
+	OriginalName string            `json:"original_name"`
+	Width        int               `json:"width,omitempty"`
+	Height       int               `json:"height,omitempty"`
+	CreatedAt    time.Time         `json:"created_at"`
+}
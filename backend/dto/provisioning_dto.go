@@ -0,0 +1,64 @@
+// dto/provisioning_dto.go
+package dto
+
+import "time"
+
+// --- Invitation DTOs ---
+
+type CreateInvitationRequest struct {
+	Email        string `json:"email" binding:"required,email"`
+	Name         string `json:"name"`
+	Role         string `json:"role"` // ชื่อ role เช่น USER, STORE_CLERK (ค่าเริ่มต้น USER)
+	DepartmentID *uint  `json:"department_id"`
+}
+
+type AcceptInvitationRequest struct {
+	Token    string `json:"token" binding:"required"`
+	Name     string `json:"name"`
+	Password string `json:"password" binding:"required,min=6"`
+	Phone    string `json:"phone"`
+}
+
+type InvitationResponse struct {
+	ID           uint                    `json:"id"`
+	Email        string                  `json:"email"`
+	Name         string                  `json:"name"`
+	Role         string                  `json:"role"`
+	DepartmentID *uint                   `json:"department_id"`
+	Department   *DepartmentInfoResponse `json:"department,omitempty"`
+	Status       string                  `json:"status"`
+	ExpiresAt    time.Time               `json:"expires_at"`
+	AcceptedAt   *time.Time              `json:"accepted_at,omitempty"`
+	CreatedAt    time.Time               `json:"created_at"`
+}
+
+// --- Bulk import DTOs ---
+
+// UserImportRow is one parsed row of the import file
+type UserImportRow struct {
+	Row            int    `json:"row"` // เลขแถวในไฟล์ (รวม header)
+	Email          string `json:"email"`
+	Name           string `json:"name"`
+	DepartmentCode string `json:"department_code"`
+	Role           string `json:"role"`
+	Phone          string `json:"phone"`
+	IsActive       *bool  `json:"is_active,omitempty"`
+	IsActiveRaw    string `json:"-"` // ค่าดิบในไฟล์ ใช้รายงานเมื่อแปลงเป็น true/false ไม่ได้
+}
+
+type UserImportRowResult struct {
+	Row    int      `json:"row"`
+	Email  string   `json:"email"`
+	Action string   `json:"action"` // CREATE, UPDATE
+	Errors []string `json:"errors,omitempty"`
+}
+
+type UserImportResult struct {
+	DryRun  bool                  `json:"dry_run"`
+	Applied bool                  `json:"applied"`
+	Total   int                   `json:"total"`
+	Created int                   `json:"created"`
+	Updated int                   `json:"updated"`
+	Failed  int                   `json:"failed"`
+	Rows    []UserImportRowResult `json:"rows"`
+}
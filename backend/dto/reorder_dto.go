@@ -0,0 +1,41 @@
+// dto/reorder_dto.go
+package dto
+
+import "time"
+
+// --- Request DTOs ---
+
+type ReorderQuery struct {
+	WindowDays   int  `form:"window_days"`   // ช่วงเวลาที่ใช้คำนวณอัตราการใช้ (วัน), 0 = ค่าตั้งต้น
+	CoverageDays int  `form:"coverage_days"` // สั่งให้พอใช้อีกกี่วันหลังของมาถึง, 0 = ค่าตั้งต้น
+	CategoryID   uint `form:"category_id"`
+	All          bool `form:"all"` // รวมสินค้าที่ยังไม่ถึงจุดสั่งซื้อด้วย
+}
+
+// --- Response DTOs ---
+
+type ReorderSuggestionResponse struct {
+	ProductID         uint     `json:"product_id"`
+	Code              string   `json:"code"`
+	Name              string   `json:"name"`
+	Category          string   `json:"category"`
+	Unit              string   `json:"unit"`
+	Stock             int      `json:"stock"`
+	MinStock          int      `json:"min_stock"`
+	PendingQuantity   int      `json:"pending_quantity"`  // จำนวนในคำขอที่รออนุมัติ
+	ConsumedQuantity  int      `json:"consumed_quantity"` // จำนวนที่จ่ายไปในช่วง window
+	DailyConsumption  float64  `json:"daily_consumption"`
+	LeadTimeDays      int      `json:"lead_time_days"`
+	DaysOfStockLeft   *float64 `json:"days_of_stock_left"` // nil = ไม่มีการใช้ในช่วงนี้
+	ReorderPoint      int      `json:"reorder_point"`
+	SuggestedQuantity int      `json:"suggested_quantity"`
+	NeedsReorder      bool     `json:"needs_reorder"`
+	IsLowStock        bool     `json:"is_low_stock"`
+}
+
+type ReorderSuggestionsResponse struct {
+	WindowDays   int                         `json:"window_days"`
+	CoverageDays int                         `json:"coverage_days"`
+	GeneratedAt  time.Time                   `json:"generated_at"`
+	Suggestions  []ReorderSuggestionResponse `json:"suggestions"`
+}
@@ -10,9 +10,17 @@ type CreateRequestItemInput struct {
 }
 
 type CreateRequestInput struct {
-	Purpose string                   `json:"purpose" binding:"required"`
-	Notes   string                   `json:"notes"`
-	Items   []CreateRequestItemInput `json:"items" binding:"required,min=1"`
+	Purpose     string                         `json:"purpose" binding:"required"`
+	Notes       string                         `json:"notes"`
+	Items       []CreateRequestItemInput       `json:"items" binding:"required,min=1"`
+	Attachments []CreateRequestAttachmentInput `json:"attachments" binding:"omitempty,max=10,dive"` // 🆕 ไฟล์ที่อัปโหลดไว้ก่อนผ่าน POST /requests/attachments
+}
+
+// CreateRequestAttachmentInput attaches a file the requester uploaded beforehand
+type CreateRequestAttachmentInput struct {
+	UploadID uint   `json:"upload_id" binding:"required"`
+	Type     string `json:"type" binding:"omitempty,oneof=MEMO QUOTATION APPROVAL OTHER"`
+	Caption  string `json:"caption" binding:"max=255"`
 }
 
 type UpdateRequestStatusInput struct {
@@ -42,22 +50,74 @@ type RequestItemResponse struct {
 }
 
 type RequestResponse struct {
-	ID            uint                  `json:"id"`
-	RequestNumber string                `json:"request_number"`
-	UserID        uint                  `json:"user_id"`
-	User          *UserProfileResponse  `json:"user,omitempty"`
-	Purpose       string                `json:"purpose"`
-	Notes         string                `json:"notes"`
-	Status        string                `json:"status"`
-	AdminNote     string                `json:"admin_note"`
-	RequestDate   time.Time             `json:"request_date"`
-	ApprovedDate  *time.Time            `json:"approved_date,omitempty"`
-	IssuedDate    *time.Time            `json:"issued_date,omitempty"`
-	CompletedDate *time.Time            `json:"completed_date,omitempty"`
-	ApprovedBy    *UserProfileResponse  `json:"approved_by,omitempty"`
-	Items         []RequestItemResponse `json:"items"`
-	CreatedAt     time.Time             `json:"created_at"`
-	UpdatedAt     time.Time             `json:"updated_at"`
+	ID            uint                        `json:"id"`
+	RequestNumber string                      `json:"request_number"`
+	UserID        uint                        `json:"user_id"`
+	User          *UserProfileResponse        `json:"user,omitempty"`
+	Purpose       string                      `json:"purpose"`
+	Notes         string                      `json:"notes"`
+	Status        string                      `json:"status"`
+	AdminNote     string                      `json:"admin_note"`
+	RequestDate   time.Time                   `json:"request_date"`
+	ApprovedDate  *time.Time                  `json:"approved_date,omitempty"`
+	IssuedDate    *time.Time                  `json:"issued_date,omitempty"`
+	CompletedDate *time.Time                  `json:"completed_date,omitempty"`
+	ApprovedBy    *UserProfileResponse        `json:"approved_by,omitempty"`
+	IssuedBy      *UserProfileResponse        `json:"issued_by,omitempty"`
+	Items         []RequestItemResponse       `json:"items"`
+	Attachments   []RequestAttachmentResponse `json:"attachments,omitempty"`
+	CreatedAt     time.Time                   `json:"created_at"`
+	UpdatedAt     time.Time                   `json:"updated_at"`
+}
+
+// RequestAttachmentResponse describes an attachment; the file itself is only available
+// from DownloadURL to the requester and staff who can view the request
+type RequestAttachmentResponse struct {
+	ID           uint                 `json:"id"`
+	Type         string               `json:"type"`
+	Caption      string               `json:"caption"`
+	OriginalName string               `json:"original_name"`
+	MimeType     string               `json:"mime_type"`
+	Size         int64                `json:"size"`
+	UploadedBy   *UserProfileResponse `json:"uploaded_by,omitempty"`
+	DownloadURL  string               `json:"download_url"` // ต้องส่ง token (Authorization header)
+	URL          string               `json:"url"`          // 🆕 ลิงก์แบบมีลายเซ็น HMAC เปิดได้โดยไม่ต้องล็อกอินจนถึง URLExpiresAt
+	URLExpiresAt time.Time            `json:"url_expires_at"`
+	CreatedAt    time.Time            `json:"created_at"`
+}
+
+// StagedAttachmentResponse is a file uploaded before the request exists, to be listed
+// in CreateRequestInput.Attachments
+type StagedAttachmentResponse struct {
+	UploadID     uint   `json:"upload_id"`
+	OriginalName string `json:"original_name"`
+	MimeType     string `json:"mime_type"`
+	Size         int64  `json:"size"`
+}
+
+// RequestVerificationResponse is the authoritative state of a request returned to anyone
+// holding the signed link from the QR code on its printed form
+type RequestVerificationResponse struct {
+	RequestNumber string                    `json:"request_number"`
+	Status        string                    `json:"status"`
+	StatusLabel   string                    `json:"status_label"`
+	RequestDate   time.Time                 `json:"request_date"`
+	Requester     string                    `json:"requester"`
+	Department    string                    `json:"department,omitempty"`
+	Faculty       string                    `json:"faculty,omitempty"`
+	Items         []RequestVerificationItem `json:"items"`
+	ApprovedBy    string                    `json:"approved_by,omitempty"`
+	ApprovedDate  *time.Time                `json:"approved_date,omitempty"`
+	IssuedBy      string                    `json:"issued_by,omitempty"`
+	IssuedDate    *time.Time                `json:"issued_date,omitempty"`
+	VerifiedAt    time.Time                 `json:"verified_at"`
+}
+
+type RequestVerificationItem struct {
+	Code     string `json:"code"`
+	Name     string `json:"name"`
+	Unit     string `json:"unit"`
+	Quantity int    `json:"quantity"`
 }
 
 type PaginatedRequestResponse struct {
@@ -0,0 +1,51 @@
+// dto/role_dto.go
+package dto
+
+import "time"
+
+// --- Request DTOs ---
+
+type CreateRoleRequest struct {
+	Name        string   `json:"name" binding:"required,max=50"`
+	DisplayName string   `json:"display_name"`
+	Description string   `json:"description"`
+	Permissions []string `json:"permissions"`
+}
+
+type UpdateRoleRequest struct {
+	DisplayName *string  `json:"display_name"`
+	Description *string  `json:"description"`
+	Permissions []string `json:"permissions"` // ถ้าส่งมา จะแทนที่สิทธิ์เดิมทั้งหมด
+}
+
+type AssignRoleRequest struct {
+	RoleID       uint  `json:"role_id" binding:"required"`
+	DepartmentID *uint `json:"department_id"` // จำกัดสิทธิ์เฉพาะหน่วยงานนี้และหน่วยงานย่อย (nil = ทั้งมหาวิทยาลัย)
+}
+
+// --- Response DTOs ---
+
+type PermissionResponse struct {
+	Code        string `json:"code"`
+	Description string `json:"description"`
+}
+
+type RoleResponse struct {
+	ID          uint      `json:"id"`
+	Name        string    `json:"name"`
+	DisplayName string    `json:"display_name"`
+	Description string    `json:"description"`
+	IsSystem    bool      `json:"is_system"`
+	Permissions []string  `json:"permissions"`
+	CreatedAt   time.Time `json:"created_at"`
+	UpdatedAt   time.Time `json:"updated_at"`
+}
+
+type UserRoleResponse struct {
+	ID           uint                    `json:"id"`
+	UserID       uint                    `json:"user_id"`
+	Role         RoleResponse            `json:"role"`
+	DepartmentID *uint                   `json:"department_id"`
+	Department   *DepartmentInfoResponse `json:"department,omitempty"`
+	CreatedAt    time.Time               `json:"created_at"`
+}
@@ -0,0 +1,50 @@
+// dto/service_account_dto.go
+package dto
+
+import "time"
+
+// --- Request DTOs ---
+
+type CreateServiceAccountRequest struct {
+	Name        string `json:"name" binding:"required,max=100"`
+	Description string `json:"description"`
+}
+
+type CreateAPIKeyRequest struct {
+	Name        string     `json:"name" binding:"required,max=100"`
+	Permissions []string   `json:"permissions" binding:"required,min=1"`
+	AllowedIPs  []string   `json:"allowed_ips"` // IP หรือ CIDR, ว่าง = ทุก IP
+	ExpiresAt   *time.Time `json:"expires_at"`  // nil = ไม่หมดอายุ
+}
+
+// --- Response DTOs ---
+
+type APIKeyResponse struct {
+	ID          uint       `json:"id"`
+	Name        string     `json:"name"`
+	Prefix      string     `json:"prefix"`
+	Permissions []string   `json:"permissions"`
+	AllowedIPs  []string   `json:"allowed_ips"`
+	ExpiresAt   *time.Time `json:"expires_at"`
+	LastUsedAt  *time.Time `json:"last_used_at"`
+	LastUsedIP  string     `json:"last_used_ip,omitempty"`
+	RevokedAt   *time.Time `json:"revoked_at"`
+	CreatedAt   time.Time  `json:"created_at"`
+}
+
+// CreatedAPIKeyResponse includes the raw key, which is shown only once
+type CreatedAPIKeyResponse struct {
+	APIKeyResponse
+	Key string `json:"key"`
+}
+
+type ServiceAccountResponse struct {
+	ID          uint             `json:"id"`
+	Name        string           `json:"name"`
+	Description string           `json:"description"`
+	UserID      uint             `json:"user_id"`
+	IsActive    bool             `json:"is_active"`
+	APIKeys     []APIKeyResponse `json:"api_keys"`
+	CreatedAt   time.Time        `json:"created_at"`
+	UpdatedAt   time.Time        `json:"updated_at"`
+}
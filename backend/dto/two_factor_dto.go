@@ -0,0 +1,39 @@
+// dto/two_factor_dto.go
+package dto
+
+// --- Request DTOs ---
+
+// TwoFactorCodeRequest carries a 6-digit TOTP code (or a recovery code where allowed)
+type TwoFactorCodeRequest struct {
+	Code string `json:"code" binding:"required"`
+}
+
+// DisableTwoFactorRequest ต้องยืนยันทั้งรหัสผ่านและรหัส 2FA ก่อนปิด
+type DisableTwoFactorRequest struct {
+	Password string `json:"password"`
+	Code     string `json:"code" binding:"required"`
+}
+
+// MFAVerifyRequest completes a login that returned an MFA challenge
+type MFAVerifyRequest struct {
+	MFAToken string `json:"mfa_token" binding:"required"`
+	Code     string `json:"code" binding:"required"` // TOTP หรือ recovery code
+}
+
+// --- Response DTOs ---
+
+type TwoFactorSetupResponse struct {
+	Secret     string `json:"secret"`
+	OTPAuthURI string `json:"otpauth_uri"`
+	QRCodePNG  string `json:"qr_code_png"` // data:image/png;base64,...
+}
+
+type TwoFactorStatusResponse struct {
+	Enabled                bool  `json:"enabled"`
+	Required               bool  `json:"required"`
+	RecoveryCodesRemaining int64 `json:"recovery_codes_remaining"`
+}
+
+type RecoveryCodesResponse struct {
+	RecoveryCodes []string `json:"recovery_codes"`
+}
@@ -1,12 +1,15 @@
 // dto/user_dto.go
 package dto
 
+import "time"
+
 // --- DTOs for General User Actions ---
 type UpdateProfileRequest struct {
 	Name         *string `json:"name" binding:"omitempty,min=2"` // เปลี่ยนเป็น optional สำหรับ PATCH
 	Phone        *string `json:"phone"`
 	DepartmentID *uint   `json:"department_id"`
 	Avatar       *string `json:"avatar"`
+	Locale       *string `json:"locale" binding:"omitempty,oneof=th en"`
 }
 
 type ChangePasswordRequest struct {
@@ -24,6 +27,9 @@ type UserProfileResponse struct {
 	DepartmentID *uint                   `json:"department_id"`        // ลบ omitempty เพื่อให้ส่ง null มาด้วย
 	Department   *DepartmentInfoResponse `json:"department,omitempty"` // เพิ่ม Department object
 	Avatar       *string                 `json:"avatar,omitempty"`
+	Locale       string                  `json:"locale"`
+	LineLinked   bool                    `json:"line_linked"`
+	LineLinkedAt *time.Time              `json:"line_linked_at,omitempty"`
 }
 
 // DTO สำหรับข้อมูล Department ที่ส่งกลับไปใน User Profile
@@ -40,7 +46,7 @@ type DepartmentInfoResponse struct {
 // --- DTOs for Admin Actions ---
 type AdminUpdateUserRequest struct {
 	Name         string `json:"name"`
-	Role         string `json:"role"`
+	Role         string `json:"role" binding:"omitempty,oneof=ADMIN USER"`
 	DepartmentID *uint  `json:"department_id"`
 	IsActive     *bool  `json:"is_active"`
 }
@@ -0,0 +1,66 @@
+// dto/webhook_dto.go
+package dto
+
+import "time"
+
+// --- Request DTOs ---
+
+type CreateWebhookRequest struct {
+	Name     string   `json:"name" binding:"required,max=100"`
+	URL      string   `json:"url" binding:"required,url,max=2048"`
+	Secret   string   `json:"secret" binding:"omitempty,min=16,max=255"` // ว่าง = สุ่มให้
+	Events   []string `json:"events" binding:"required,min=1"`
+	IsActive *bool    `json:"is_active"`
+}
+
+type UpdateWebhookRequest struct {
+	Name         *string  `json:"name" binding:"omitempty,max=100"`
+	URL          *string  `json:"url" binding:"omitempty,url,max=2048"`
+	Events       []string `json:"events"`
+	IsActive     *bool    `json:"is_active"`
+	RotateSecret bool     `json:"rotate_secret"` // สุ่ม secret ใหม่และคืนค่าให้ครั้งเดียว
+}
+
+type WebhookDeliveryQuery struct {
+	Status string `form:"status"`
+	Event  string `form:"event"`
+	Page   int    `form:"page,default=1"`
+	Limit  int    `form:"limit,default=20"`
+}
+
+// --- Response DTOs ---
+
+type WebhookResponse struct {
+	ID        uint      `json:"id"`
+	Name      string    `json:"name"`
+	URL       string    `json:"url"`
+	Events    []string  `json:"events"`
+	IsActive  bool      `json:"is_active"`
+	Secret    string    `json:"secret,omitempty"` // มีเฉพาะตอนสร้างหรือ rotate
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+type WebhookDeliveryResponse struct {
+	ID             uint       `json:"id"`
+	SubscriptionID uint       `json:"subscription_id"`
+	EventID        string     `json:"event_id"`
+	Event          string     `json:"event"`
+	Payload        string     `json:"payload"`
+	Status         string     `json:"status"`
+	Attempts       int        `json:"attempts"`
+	NextAttemptAt  time.Time  `json:"next_attempt_at"`
+	LastAttemptAt  *time.Time `json:"last_attempt_at"`
+	ResponseCode   *int       `json:"response_code"`
+	ResponseBody   string     `json:"response_body"`
+	DurationMs     int64      `json:"duration_ms"`
+	LastError      string     `json:"last_error"`
+	DeliveredAt    *time.Time `json:"delivered_at"`
+	RedeliveryOfID *uint      `json:"redelivery_of_id"`
+	CreatedAt      time.Time  `json:"created_at"`
+}
+
+type PaginatedWebhookDeliveryResponse struct {
+	Deliveries []WebhookDeliveryResponse `json:"deliveries"`
+	Pagination PaginationResponse        `json:"pagination"`
+}
@@ -0,0 +1,174 @@
+// Package line talks to the LINE Messaging API: pushing and replying to messages and
+// verifying incoming webhook calls. The API base URL is configurable so the client can be
+// pointed at a local stub server during development and testing.
+package line
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// DefaultBaseURL is the production Messaging API endpoint
+const DefaultBaseURL = "https://api.line.me"
+
+// maxTextLength is the LINE limit for a single text message
+const maxTextLength = 5000
+
+// Message is one message object of the Messaging API. Only text messages are used.
+type Message struct {
+	Type string `json:"type"`
+	Text string `json:"text"`
+}
+
+// Text builds a text message, truncated to the LINE length limit
+func Text(text string) Message {
+	if r := []rune(text); len(r) > maxTextLength {
+		text = string(r[:maxTextLength-1]) + "…"
+	}
+	return Message{Type: "text", Text: text}
+}
+
+// Client sends messages through LINE. Implementations must be safe for concurrent use.
+type Client interface {
+	// Push sends messages to a LINE user ID at any time
+	Push(to string, messages ...Message) error
+	// Reply answers a webhook event using its reply token (valid for a short time only)
+	Reply(replyToken string, messages ...Message) error
+}
+
+// APIError is returned when the Messaging API answers with a non-2xx status
+type APIError struct {
+	StatusCode int
+	Body       string
+}
+
+func (e *APIError) Error() string {
+	return fmt.Sprintf("LINE API responded with HTTP %d: %s", e.StatusCode, e.Body)
+}
+
+// NewFromEnv builds an HTTP client from LINE_CHANNEL_ACCESS_TOKEN and LINE_API_BASE_URL,
+// or a log-only client when no access token is configured
+func NewFromEnv() Client {
+	token := os.Getenv("LINE_CHANNEL_ACCESS_TOKEN")
+	if token == "" {
+		log.Println("💬 LINE: log only (set LINE_CHANNEL_ACCESS_TOKEN to push messages)")
+		return &LogClient{}
+	}
+	baseURL := os.Getenv("LINE_API_BASE_URL")
+	if baseURL == "" {
+		baseURL = DefaultBaseURL
+	}
+	log.Printf("💬 LINE: Messaging API (%s)", baseURL)
+	return NewHTTPClient(baseURL, token)
+}
+
+// HTTPClient calls the Messaging API over HTTP
+type HTTPClient struct {
+	baseURL     string
+	accessToken string
+	http        *http.Client
+}
+
+func NewHTTPClient(baseURL, accessToken string) *HTTPClient {
+	return &HTTPClient{
+		baseURL:     strings.TrimRight(baseURL, "/"),
+		accessToken: accessToken,
+		http:        &http.Client{Timeout: 10 * time.Second},
+	}
+}
+
+func (c *HTTPClient) Push(to string, messages ...Message) error {
+	return c.post("/v2/bot/message/push", map[string]interface{}{"to": to, "messages": messages})
+}
+
+func (c *HTTPClient) Reply(replyToken string, messages ...Message) error {
+	return c.post("/v2/bot/message/reply", map[string]interface{}{"replyToken": replyToken, "messages": messages})
+}
+
+func (c *HTTPClient) post(path string, body interface{}) error {
+	payload, err := json.Marshal(body)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequest(http.MethodPost, c.baseURL+path, bytes.NewReader(payload))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", "Bearer "+c.accessToken)
+
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		excerpt, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return &APIError{StatusCode: resp.StatusCode, Body: string(excerpt)}
+	}
+	return nil
+}
+
+// LogClient only writes messages to the application log
+type LogClient struct{}
+
+func (LogClient) Push(to string, messages ...Message) error {
+	for _, m := range messages {
+		log.Printf("💬 [LINE push] to=%s\n%s", to, m.Text)
+	}
+	return nil
+}
+
+func (LogClient) Reply(replyToken string, messages ...Message) error {
+	for _, m := range messages {
+		log.Printf("💬 [LINE reply]\n%s", m.Text)
+	}
+	return nil
+}
+
+// SentMessage is one call recorded by MemoryClient
+type SentMessage struct {
+	To         string // LINE user ID for Push
+	ReplyToken string // reply token for Reply
+	Messages   []Message
+}
+
+// MemoryClient records every call so tests can inspect them
+type MemoryClient struct {
+	mu   sync.Mutex
+	sent []SentMessage
+}
+
+func NewMemoryClient() *MemoryClient {
+	return &MemoryClient{}
+}
+
+func (c *MemoryClient) Push(to string, messages ...Message) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.sent = append(c.sent, SentMessage{To: to, Messages: messages})
+	return nil
+}
+
+func (c *MemoryClient) Reply(replyToken string, messages ...Message) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.sent = append(c.sent, SentMessage{ReplyToken: replyToken, Messages: messages})
+	return nil
+}
+
+// Sent returns a copy of the recorded calls
+func (c *MemoryClient) Sent() []SentMessage {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return append([]SentMessage(nil), c.sent...)
+}
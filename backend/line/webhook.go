@@ -0,0 +1,60 @@
+package line
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+)
+
+// SignatureHeader carries the base64 HMAC-SHA256 of the request body
+const SignatureHeader = "X-Line-Signature"
+
+// Webhook event and source types used by the bot
+const (
+	EventTypeMessage  = "message"
+	EventTypeFollow   = "follow"
+	EventTypeUnfollow = "unfollow"
+
+	MessageTypeText = "text"
+)
+
+// WebhookPayload is the body LINE posts to the bot's webhook URL
+type WebhookPayload struct {
+	Destination string  `json:"destination"`
+	Events      []Event `json:"events"`
+}
+
+// Event is one webhook event. Only the fields the bot uses are decoded.
+type Event struct {
+	Type       string        `json:"type"`
+	ReplyToken string        `json:"replyToken"`
+	Timestamp  int64         `json:"timestamp"`
+	Source     EventSource   `json:"source"`
+	Message    *EventMessage `json:"message,omitempty"`
+}
+
+type EventSource struct {
+	Type    string `json:"type"` // user, group, room
+	UserID  string `json:"userId"`
+	GroupID string `json:"groupId,omitempty"`
+}
+
+type EventMessage struct {
+	ID   string `json:"id"`
+	Type string `json:"type"`
+	Text string `json:"text"`
+}
+
+// VerifySignature reports whether signature matches body for the channel secret
+func VerifySignature(channelSecret string, body []byte, signature string) bool {
+	if channelSecret == "" || signature == "" {
+		return false
+	}
+	decoded, err := base64.StdEncoding.DecodeString(signature)
+	if err != nil {
+		return false
+	}
+	mac := hmac.New(sha256.New, []byte(channelSecret))
+	mac.Write(body)
+	return hmac.Equal(decoded, mac.Sum(nil))
+}
@@ -0,0 +1,34 @@
+package line
+
+import "testing"
+
+func TestVerifySignature(t *testing.T) {
+	const (
+		secret = "channel-secret"
+		body   = `{"events":[]}`
+		valid  = "nENZU9xVqQeZgEX6Nd5huQOCgqTEa5S67sXEhrGuTDk="
+	)
+
+	tests := []struct {
+		name      string
+		secret    string
+		body      string
+		signature string
+		want      bool
+	}{
+		{"valid", secret, body, valid, true},
+		{"body changed", secret, `{"events":[{}]}`, valid, false},
+		{"wrong secret", "other-secret", body, valid, false},
+		{"missing signature", secret, body, "", false},
+		{"no channel secret configured", "", body, valid, false},
+		{"not base64", secret, body, "not base64!", false},
+		{"truncated", secret, body, valid[:20], false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := VerifySignature(tt.secret, []byte(tt.body), tt.signature); got != tt.want {
+				t.Errorf("VerifySignature() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
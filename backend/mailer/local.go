@@ -0,0 +1,105 @@
+package mailer
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+// FileMailer writes each message as an .eml file, handy for local development
+type FileMailer struct {
+	dir  string
+	from string
+	mu   sync.Mutex
+}
+
+func NewFileMailer(dir, from string) *FileMailer {
+	return &FileMailer{dir: dir, from: from}
+}
+
+func (m *FileMailer) Send(msg Message) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if err := os.MkdirAll(m.dir, 0755); err != nil {
+		return fmt.Errorf("failed to create mail directory: %w", err)
+	}
+
+	filename := fmt.Sprintf("%s.eml", time.Now().Format("20060102-150405.000000000"))
+	return os.WriteFile(filepath.Join(m.dir, filename), buildMIME(m.from, msg), 0644)
+}
+
+// MemoryMailer keeps sent messages in memory so tests can inspect them
+type MemoryMailer struct {
+	mu       sync.Mutex
+	messages []Message
+}
+
+func NewMemoryMailer() *MemoryMailer {
+	return &MemoryMailer{}
+}
+
+func (m *MemoryMailer) Send(msg Message) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.messages = append(m.messages, msg)
+	return nil
+}
+
+// Messages returns a copy of every message sent so far
+func (m *MemoryMailer) Messages() []Message {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return append([]Message(nil), m.messages...)
+}
+
+// Reset discards all stored messages
+func (m *MemoryMailer) Reset() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.messages = nil
+}
+
+// MboxMailer appends every message to a single mbox file that any mail client can open
+type MboxMailer struct {
+	path string
+	from string
+	mu   sync.Mutex
+}
+
+func NewMboxMailer(path, from string) *MboxMailer {
+	return &MboxMailer{path: path, from: from}
+}
+
+func (m *MboxMailer) Send(msg Message) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if err := os.MkdirAll(filepath.Dir(m.path), 0755); err != nil {
+		return fmt.Errorf("failed to create mailbox directory: %w", err)
+	}
+	f, err := os.OpenFile(m.path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0644)
+	if err != nil {
+		return fmt.Errorf("failed to open mailbox: %w", err)
+	}
+	defer f.Close()
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "From ku-asset %s\n", time.Now().Format("Mon Jan _2 15:04:05 2006"))
+	body := strings.ReplaceAll(string(buildMIME(m.from, msg)), "\r\n", "\n")
+	for _, line := range strings.Split(body, "\n") {
+		// mboxrd: บรรทัดที่ขึ้นต้นด้วย "From " ต้อง escape
+		if strings.HasPrefix(strings.TrimLeft(line, ">"), "From ") {
+			line = ">" + line
+		}
+		b.WriteString(line)
+		b.WriteString("\n")
+	}
+	b.WriteString("\n")
+
+	_, err = f.WriteString(b.String())
+	return err
+}
@@ -0,0 +1,100 @@
+// Package mailer sends transactional e-mail through a pluggable driver.
+package mailer
+
+import (
+	"fmt"
+	"log"
+	"mime"
+	"os"
+	"strings"
+	"time"
+)
+
+// Message is a single e-mail to be delivered
+type Message struct {
+	To       []string
+	Subject  string
+	TextBody string
+	HTMLBody string
+}
+
+// Mailer delivers messages. Implementations must be safe for concurrent use.
+type Mailer interface {
+	Send(msg Message) error
+}
+
+// NewFromEnv builds a mailer from MAIL_DRIVER (smtp, file, mbox, memory or log; default log)
+func NewFromEnv() Mailer {
+	driver := strings.ToLower(getEnv("MAIL_DRIVER", "log"))
+	from := getEnv("MAIL_FROM", "KU Asset <no-reply@ku.ac.th>")
+
+	switch driver {
+	case "smtp":
+		log.Printf("📧 Mailer: SMTP (%s:%s)", os.Getenv("SMTP_HOST"), getEnv("SMTP_PORT", "587"))
+		return NewSMTPMailer(SMTPConfig{
+			Host:     os.Getenv("SMTP_HOST"),
+			Port:     getEnv("SMTP_PORT", "587"),
+			Username: os.Getenv("SMTP_USERNAME"),
+			Password: os.Getenv("SMTP_PASSWORD"),
+			From:     from,
+		})
+	case "file":
+		dir := getEnv("MAIL_FILE_DIR", "tmp/mail")
+		log.Printf("📧 Mailer: file (%s)", dir)
+		return NewFileMailer(dir, from)
+	case "mbox":
+		path := getEnv("MAIL_MBOX_PATH", "tmp/mail/ku-asset.mbox")
+		log.Printf("📧 Mailer: mbox (%s)", path)
+		return NewMboxMailer(path, from)
+	case "memory":
+		log.Println("📧 Mailer: in-memory")
+		return NewMemoryMailer()
+	default:
+		log.Println("📧 Mailer: log only (set MAIL_DRIVER to smtp to deliver e-mail)")
+		return &LogMailer{}
+	}
+}
+
+// LogMailer only writes messages to the application log
+type LogMailer struct{}
+
+func (m *LogMailer) Send(msg Message) error {
+	log.Printf("📧 [mail] to=%s subject=%q\n%s", strings.Join(msg.To, ","), msg.Subject, msg.TextBody)
+	return nil
+}
+
+// buildMIME renders a message as an RFC 5322 document with text and optional HTML parts
+func buildMIME(from string, msg Message) []byte {
+	var b strings.Builder
+	boundary := fmt.Sprintf("ku-asset-%d", time.Now().UnixNano())
+
+	fmt.Fprintf(&b, "From: %s\r\n", from)
+	fmt.Fprintf(&b, "To: %s\r\n", strings.Join(msg.To, ", "))
+	fmt.Fprintf(&b, "Subject: %s\r\n", encodeHeader(msg.Subject))
+	fmt.Fprintf(&b, "Date: %s\r\n", time.Now().Format(time.RFC1123Z))
+	b.WriteString("MIME-Version: 1.0\r\n")
+
+	if msg.HTMLBody == "" {
+		b.WriteString("Content-Type: text/plain; charset=UTF-8\r\n\r\n")
+		b.WriteString(msg.TextBody)
+		return []byte(b.String())
+	}
+
+	fmt.Fprintf(&b, "Content-Type: multipart/alternative; boundary=%q\r\n\r\n", boundary)
+	fmt.Fprintf(&b, "--%s\r\nContent-Type: text/plain; charset=UTF-8\r\n\r\n%s\r\n", boundary, msg.TextBody)
+	fmt.Fprintf(&b, "--%s\r\nContent-Type: text/html; charset=UTF-8\r\n\r\n%s\r\n", boundary, msg.HTMLBody)
+	fmt.Fprintf(&b, "--%s--\r\n", boundary)
+	return []byte(b.String())
+}
+
+// encodeHeader encodes non-ASCII header values (เช่น หัวเรื่องภาษาไทย) as RFC 2047 words
+func encodeHeader(value string) string {
+	return mime.BEncoding.Encode("UTF-8", value)
+}
+
+func getEnv(key, defaultValue string) string {
+	if value := os.Getenv(key); value != "" {
+		return value
+	}
+	return defaultValue
+}
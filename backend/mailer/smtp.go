@@ -0,0 +1,48 @@
+package mailer
+
+import (
+	"errors"
+	"fmt"
+	"net/mail"
+	"net/smtp"
+)
+
+// SMTPConfig holds connection settings for an SMTP relay
+type SMTPConfig struct {
+	Host     string
+	Port     string
+	Username string
+	Password string
+	From     string
+}
+
+// SMTPMailer delivers messages through an SMTP server using STARTTLS when offered
+type SMTPMailer struct {
+	config SMTPConfig
+}
+
+func NewSMTPMailer(config SMTPConfig) *SMTPMailer {
+	return &SMTPMailer{config: config}
+}
+
+func (m *SMTPMailer) Send(msg Message) error {
+	if m.config.Host == "" {
+		return errors.New("smtp host is not configured")
+	}
+	if len(msg.To) == 0 {
+		return errors.New("message has no recipients")
+	}
+
+	sender, err := mail.ParseAddress(m.config.From)
+	if err != nil {
+		return fmt.Errorf("invalid MAIL_FROM address: %w", err)
+	}
+
+	var auth smtp.Auth
+	if m.config.Username != "" {
+		auth = smtp.PlainAuth("", m.config.Username, m.config.Password, m.config.Host)
+	}
+
+	addr := m.config.Host + ":" + m.config.Port
+	return smtp.SendMail(addr, auth, sender.Address, msg.To, buildMIME(m.config.From, msg))
+}
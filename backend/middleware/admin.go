@@ -11,6 +11,8 @@ import (
 )
 
 // AdminMiddleware checks if the authenticated user has admin role
+//
+// Deprecated: routes are now guarded by RequirePermission; this only checks the legacy users.role column.
 func AdminMiddleware() gin.HandlerFunc {
 	return func(c *gin.Context) {
 		log.Println("--- ENTER AdminMiddleware ---")
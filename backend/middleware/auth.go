@@ -2,10 +2,10 @@ package middleware
 
 import (
 	"fmt"
+	"ku-asset/auth"
 	"ku-asset/models"
 	"log"
 	"net/http"
-	"os"
 	"strings"
 
 	"github.com/gin-gonic/gin"
@@ -19,6 +19,13 @@ func AuthMiddleware() gin.HandlerFunc {
 		log.Println("--- ENTER AuthMiddleware (REAL VERSION) ---")
 
 		authHeader := c.GetHeader("Authorization")
+
+		// ⭐ Service account ใช้ X-API-Key แทน JWT
+		if apiKey := c.GetHeader("X-API-Key"); apiKey != "" && authHeader == "" {
+			authenticateAPIKey(c, apiKey)
+			return
+		}
+
 		if authHeader == "" {
 			log.Println("❌ No Authorization header")
 			c.JSON(http.StatusUnauthorized, gin.H{"error": "Missing authorization header"})
@@ -43,9 +50,8 @@ func AuthMiddleware() gin.HandlerFunc {
 			if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
 				return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
 			}
-			// Use JWT secret from environment
-			jwtSecret := getEnv("JWT_SECRET", "default-secret-key")
-			return []byte(jwtSecret), nil
+			// ⭐ ไม่มีค่า default - server ไม่ start ถ้าไม่ได้ตั้ง JWT_SECRET (auth.CheckSecrets)
+			return auth.JWTSecret(), nil
 		})
 
 		if err != nil || !token.Valid {
@@ -64,6 +70,14 @@ func AuthMiddleware() gin.HandlerFunc {
 			return
 		}
 
+		// ⭐ Token ที่มี purpose (เช่น MFA challenge) ใช้เป็น access token ไม่ได้
+		if _, hasPurpose := claims["purpose"]; hasPurpose {
+			log.Println("❌ Purpose-bound token used as access token")
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid token"})
+			c.Abort()
+			return
+		}
+
 		// Get user ID from claims
 		userIDFloat, ok := claims["user_id"].(float64)
 		if !ok {
@@ -94,27 +108,42 @@ func AuthMiddleware() gin.HandlerFunc {
 			return
 		}
 
+		// ⭐ บัญชีที่ถูกปิดใช้งานใช้ token เดิมต่อไม่ได้
+		if !user.IsActive {
+			log.Printf("❌ Inactive user tried to authenticate: %s", user.Email)
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "Account is disabled"})
+			c.Abort()
+			return
+		}
+
+		// ⭐ Token สวมรอย (impersonation) มีทั้ง ID ผู้ดูแลจริงและผู้ใช้ที่ถูกสวมรอย
+		_, impersonating := claims["impersonator_id"]
+		if impersonating && !startImpersonation(c, db.(*gorm.DB), claims) {
+			return
+		}
+
 		// Set user context
 		c.Set("userID", user.ID)
 		c.Set("userRole", string(user.Role))
 		c.Set("userEmail", user.Email)
 		c.Set("user", user)
+		setAuditActor(c, &user)
 
 		log.Printf("✅ User authenticated: %s (%s)", user.Email, user.Role)
 		c.Next()
-		log.Println("--- EXIT AuthMiddleware ---")
-	}
-}
 
-// Helper function to get environment variable
-func getEnv(key, defaultValue string) string {
-	if value := os.Getenv(key); value != "" {
-		return value
+		if sessionID, ok := c.Get("impersonationSessionID"); ok {
+			log.Printf("🕵️ %v as %s: %s %s -> %d", c.MustGet("impersonatorEmail"), user.Email,
+				c.Request.Method, c.Request.URL.Path, c.Writer.Status())
+			recordImpersonatedCall(c, db.(*gorm.DB), sessionID.(uint), c.Writer.Status())
+		}
+		log.Println("--- EXIT AuthMiddleware ---")
 	}
-	return defaultValue
 }
 
 // AuthorizeRole is kept here but might not function correctly in this test
+//
+// Deprecated: use RequirePermission instead.
 func AuthorizeRole(requiredRole string) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		log.Println("--- ENTER AuthorizeRole (This will likely fail) ---")
@@ -0,0 +1,64 @@
+package middleware
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// sensitiveQueryParams are removed from access logs. ?access_token= carries a full JWT for the
+// SSE stream (see TokenFromQuery) and must never reach log files.
+var sensitiveQueryParams = []string{"access_token", "token"}
+
+// RequestLogger is gin.Logger with sensitive query parameters redacted
+func RequestLogger() gin.HandlerFunc {
+	return gin.LoggerWithConfig(gin.LoggerConfig{
+		Formatter: func(param gin.LogFormatterParams) string {
+			var statusColor, methodColor, resetColor string
+			if param.IsOutputColor() {
+				statusColor = param.StatusCodeColor()
+				methodColor = param.MethodColor()
+				resetColor = param.ResetColor()
+			}
+			if param.Latency > time.Minute {
+				param.Latency = param.Latency.Truncate(time.Second)
+			}
+			// รูปแบบเดียวกับ formatter ตั้งต้นของ gin
+			return fmt.Sprintf("[GIN] %v |%s %3d %s| %13v | %15s |%s %-7s %s %#v\n%s",
+				param.TimeStamp.Format("2006/01/02 - 15:04:05"),
+				statusColor, param.StatusCode, resetColor,
+				param.Latency,
+				param.ClientIP,
+				methodColor, param.Method, resetColor,
+				redactQuery(param.Path),
+				param.ErrorMessage,
+			)
+		},
+	})
+}
+
+// redactQuery replaces the values of sensitiveQueryParams in a path with its raw query
+func redactQuery(path string) string {
+	base, rawQuery, found := strings.Cut(path, "?")
+	if !found {
+		return path
+	}
+	query, err := url.ParseQuery(rawQuery)
+	if err != nil {
+		return base + "?[unparsable query]"
+	}
+	redacted := false
+	for _, key := range sensitiveQueryParams {
+		if _, ok := query[key]; ok {
+			query.Set(key, "REDACTED")
+			redacted = true
+		}
+	}
+	if !redacted {
+		return path
+	}
+	return base + "?" + query.Encode()
+}
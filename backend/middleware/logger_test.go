@@ -0,0 +1,24 @@
+package middleware
+
+import "testing"
+
+func TestRedactQuery(t *testing.T) {
+	tests := []struct {
+		path string
+		want string
+	}{
+		{"/api/v1/products", "/api/v1/products"},
+		{"/api/v1/products?page=2&limit=20", "/api/v1/products?page=2&limit=20"},
+		{"/api/v1/stream?access_token=eyJhbGciOiJIUzI1NiJ9.e30.sig", "/api/v1/stream?access_token=REDACTED"},
+		{"/api/v1/stream?last_event=5&access_token=eyJ", "/api/v1/stream?access_token=REDACTED&last_event=5"},
+		{"/verify?token=abc", "/verify?token=REDACTED"},
+		{"/api/v1/stream?access_token=%zz", "/api/v1/stream?[unparsable query]"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			if got := redactQuery(tt.path); got != tt.want {
+				t.Errorf("redactQuery() = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,147 @@
+package middleware
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+
+	"ku-asset/auth"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// RequirePermission checks that the authenticated user has been granted the given permission
+// through one of their roles. It must run after AuthMiddleware.
+func RequirePermission(permission string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		permissions, err := GetUserPermissions(c)
+		if err != nil {
+			log.Printf("❌ Failed to load permissions: %v", err)
+			c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+			c.Abort()
+			return
+		}
+
+		if !auth.HasPermission(permissions, permission) {
+			userEmail, _ := c.Get("userEmail")
+			log.Printf("❌ Access denied for user %v: missing permission %s", userEmail, permission)
+			c.JSON(http.StatusForbidden, gin.H{
+				"error": fmt.Sprintf("Access denied. Required permission: %s", permission),
+			})
+			c.Abort()
+			return
+		}
+		// ⭐ REQUIRE_ADMIN_2FA ครอบทุกเส้นทางที่ต้องใช้ permission ไม่ใช่เฉพาะกลุ่ม /admin
+		if !checkTwoFactorEnrollment(c, permissions) {
+			return
+		}
+
+		c.Next()
+	}
+}
+
+// RequireGlobalPermission is RequirePermission for routes that are not limited to a department,
+// such as editing role definitions: a department-bound grant of the permission is not enough.
+func RequireGlobalPermission(permission string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		scope, err := GetPermissionScope(c, permission)
+		if err != nil {
+			log.Printf("❌ Failed to load permission scope: %v", err)
+			c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+			c.Abort()
+			return
+		}
+
+		if !scope.All {
+			userEmail, _ := c.Get("userEmail")
+			log.Printf("❌ Access denied for user %v: permission %s is not granted university-wide", userEmail, permission)
+			c.JSON(http.StatusForbidden, gin.H{
+				"error": fmt.Sprintf("Access denied. Required university-wide permission: %s", permission),
+			})
+			c.Abort()
+			return
+		}
+		if !checkTwoFactorEnrollment(c, []string{permission}) {
+			return
+		}
+
+		c.Next()
+	}
+}
+
+// GetUserPermissions returns the permission codes of the authenticated user.
+// The result is cached in the context so several checks in one request hit the database once.
+func GetUserPermissions(c *gin.Context) ([]string, error) {
+	if cached, exists := c.Get("userPermissions"); exists {
+		if permissions, ok := cached.([]string); ok {
+			return permissions, nil
+		}
+	}
+
+	userID, err := GetUserID(c)
+	if err != nil {
+		return nil, err
+	}
+
+	db, exists := c.Get("db")
+	if !exists {
+		return nil, errors.New("database not available in context")
+	}
+
+	permissions, err := auth.UserPermissions(db.(*gorm.DB), userID)
+	if err != nil {
+		return nil, err
+	}
+
+	c.Set("userPermissions", permissions)
+	return permissions, nil
+}
+
+// HasPermission reports whether the authenticated user holds the given permission
+func HasPermission(c *gin.Context, permission string) bool {
+	permissions, err := GetUserPermissions(c)
+	if err != nil {
+		return false
+	}
+	return auth.HasPermission(permissions, permission)
+}
+
+// GetPermissionScope returns the departments in which the authenticated user holds the given permission
+func GetPermissionScope(c *gin.Context, permission string) (*auth.Scope, error) {
+	cacheKey := "permissionScope:" + permission
+	if cached, exists := c.Get(cacheKey); exists {
+		if scope, ok := cached.(*auth.Scope); ok {
+			return scope, nil
+		}
+	}
+
+	// API key ได้สิทธิ์ตามที่ประกาศไว้ทั้งระบบ ไม่ผูกกับหน่วยงาน
+	if IsAPIKeyRequest(c) {
+		scope := &auth.Scope{}
+		if HasPermission(c, permission) {
+			scope = auth.GlobalScope()
+		}
+		c.Set(cacheKey, scope)
+		return scope, nil
+	}
+
+	userID, err := GetUserID(c)
+	if err != nil {
+		return nil, err
+	}
+
+	db, exists := c.Get("db")
+	if !exists {
+		return nil, errors.New("database not available in context")
+	}
+
+	scope, err := auth.PermissionScope(db.(*gorm.DB), userID, permission)
+	if err != nil {
+		return nil, err
+	}
+
+	c.Set(cacheKey, scope)
+	return scope, nil
+}
@@ -132,3 +132,9 @@ var (
 	// 50 uploads per hour per user
 	UploadHourlyLimiter = NewRateLimiter(50, time.Hour)
 )
+
+// Auth-specific rate limiter (per IP) สำหรับ login, 2FA และลืมรหัสผ่าน
+var AuthRateLimiter = NewRateLimiter(20, time.Minute)
+
+// Public request verification (QR code on printed forms), per IP
+var VerifyRateLimiter = NewRateLimiter(30, time.Minute)
@@ -0,0 +1,58 @@
+package middleware
+
+import (
+	"regexp"
+
+	"ku-asset/audit"
+	"ku-asset/models"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+const RequestIDHeader = "X-Request-ID"
+
+var validRequestID = regexp.MustCompile(`^[A-Za-z0-9._-]{1,64}$`)
+
+// RequestContext gives every request an ID (reusing a sane incoming X-Request-ID)
+// and puts the caller's IP and user agent into the request context for the audit trail
+func RequestContext() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		requestID := c.GetHeader(RequestIDHeader)
+		if !validRequestID.MatchString(requestID) {
+			requestID = uuid.New().String()
+		}
+		c.Set("requestID", requestID)
+		c.Header(RequestIDHeader, requestID)
+
+		ctx := audit.WithActor(c.Request.Context(), audit.Actor{
+			IPAddress: c.ClientIP(),
+			UserAgent: c.Request.UserAgent(),
+			RequestID: requestID,
+		})
+		c.Request = c.Request.WithContext(ctx)
+		c.Next()
+	}
+}
+
+// setAuditActor records the authenticated user (and the real admin or API key behind it)
+// as the actor of every change made while handling this request
+func setAuditActor(c *gin.Context, user *models.User) {
+	actor, ok := audit.ActorFrom(c.Request.Context())
+	if !ok {
+		actor = audit.Actor{IPAddress: c.ClientIP(), UserAgent: c.Request.UserAgent()}
+	}
+
+	userID := user.ID
+	actor.UserID = &userID
+	actor.Email = user.Email
+	if id, ok := c.Get("impersonatorID"); ok {
+		impersonatorID := id.(uint)
+		actor.ImpersonatorID = &impersonatorID
+	}
+	if id, ok := c.Get("apiKeyID"); ok {
+		apiKeyID := id.(uint)
+		actor.APIKeyID = &apiKeyID
+	}
+	c.Request = c.Request.WithContext(audit.WithActor(c.Request.Context(), actor))
+}
@@ -0,0 +1,16 @@
+package middleware
+
+import "github.com/gin-gonic/gin"
+
+// TokenFromQuery copies ?access_token= into the Authorization header when the header is
+// missing. Browsers' EventSource cannot send custom headers, so the SSE stream needs this.
+func TokenFromQuery() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if c.GetHeader("Authorization") == "" {
+			if token := c.Query("access_token"); token != "" {
+				c.Request.Header.Set("Authorization", "Bearer "+token)
+			}
+		}
+		c.Next()
+	}
+}
@@ -0,0 +1,29 @@
+package middleware
+
+import (
+	"log"
+	"os"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ConfigureTrustedProxies sets which peers may report the client address through
+// X-Forwarded-For / X-Real-IP. TRUSTED_PROXIES is a comma-separated list of IPs or CIDRs
+// (e.g. the load balancer). When it is unset no proxy is trusted and c.ClientIP() is the
+// TCP peer address, so a client cannot pick its own IP for allowlists, throttles or audit logs.
+func ConfigureTrustedProxies(router *gin.Engine) error {
+	var proxies []string
+	for _, entry := range strings.Split(os.Getenv("TRUSTED_PROXIES"), ",") {
+		if entry = strings.TrimSpace(entry); entry != "" {
+			proxies = append(proxies, entry)
+		}
+	}
+
+	if len(proxies) == 0 {
+		log.Println("🛡️ Trusted proxies: none (X-Forwarded-For is ignored)")
+	} else {
+		log.Printf("🛡️ Trusted proxies: %s", strings.Join(proxies, ", "))
+	}
+	return router.SetTrustedProxies(proxies)
+}
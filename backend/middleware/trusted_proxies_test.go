@@ -0,0 +1,108 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"testing"
+
+	"ku-asset/auth"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/gin-gonic/gin"
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+func newMockDB(t *testing.T) (*gorm.DB, sqlmock.Sqlmock) {
+	t.Helper()
+	sqlDB, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { sqlDB.Close() })
+
+	db, err := gorm.Open(postgres.New(postgres.Config{Conn: sqlDB}), &gorm.Config{
+		Logger: logger.Default.LogMode(logger.Silent),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return db, mock
+}
+
+func newTestRouter(t *testing.T) *gin.Engine {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	if err := ConfigureTrustedProxies(router); err != nil {
+		t.Fatal(err)
+	}
+	return router
+}
+
+func TestConfigureTrustedProxies(t *testing.T) {
+	tests := []struct {
+		name    string
+		proxies string
+		peer    string
+		xff     string
+		want    string
+	}{
+		{"unset ignores spoofed header", "", "203.0.113.9:4321", "10.0.0.5", "203.0.113.9"},
+		{"untrusted peer ignored", "192.0.2.1", "203.0.113.9:4321", "10.0.0.5", "203.0.113.9"},
+		{"trusted proxy IP", "192.0.2.1", "192.0.2.1:4321", "198.51.100.7", "198.51.100.7"},
+		{"trusted proxy CIDR", "192.0.2.0/24, 10.1.0.0/16", "10.1.2.3:4321", "198.51.100.7", "198.51.100.7"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("TRUSTED_PROXIES", tt.proxies)
+			router := newTestRouter(t)
+			router.GET("/ip", func(c *gin.Context) { c.String(http.StatusOK, c.ClientIP()) })
+
+			req := httptest.NewRequest(http.MethodGet, "/ip", nil)
+			req.RemoteAddr = tt.peer
+			req.Header.Set("X-Forwarded-For", tt.xff)
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+
+			if got := w.Body.String(); got != tt.want {
+				t.Errorf("ClientIP() = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestAPIKeyAllowlistIgnoresSpoofedForwardedFor(t *testing.T) {
+	t.Setenv("TRUSTED_PROXIES", "")
+	db, mock := newMockDB(t)
+	mock.ExpectQuery(`SELECT \* FROM "api_keys" WHERE key_hash = \$1`).
+		WithArgs(auth.HashAPIKey("kua_leaked"), 1).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "service_account_id", "prefix", "allowed_ips"}).
+			AddRow(1, 2, "kua_leak", "10.0.0.0/8"))
+	mock.ExpectQuery(regexp.QuoteMeta(`SELECT * FROM "api_key_permissions"`)).
+		WillReturnRows(sqlmock.NewRows([]string{"api_key_id", "permission_id"}))
+	mock.ExpectQuery(regexp.QuoteMeta(`SELECT * FROM "service_accounts"`)).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "name", "user_id", "is_active"}).AddRow(2, "erp", 3, true))
+	mock.MatchExpectationsInOrder(false)
+
+	router := newTestRouter(t)
+	router.GET("/products", func(c *gin.Context) {
+		c.Set("db", db)
+		authenticateAPIKey(c, "kua_leaked")
+	}, func(c *gin.Context) { c.Status(http.StatusOK) })
+
+	req := httptest.NewRequest(http.MethodGet, "/products", nil)
+	req.RemoteAddr = "203.0.113.9:4321"
+	req.Header.Set("X-Forwarded-For", "10.0.0.5") // อยู่ใน allowlist แต่ปลอมมาจาก peer ที่ไม่น่าเชื่อถือ
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	if w.Code != http.StatusForbidden {
+		t.Errorf("status = %d, want %d", w.Code, http.StatusForbidden)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Error(err)
+	}
+}
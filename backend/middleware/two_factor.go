@@ -0,0 +1,62 @@
+package middleware
+
+import (
+	"log"
+	"net/http"
+
+	"ku-asset/auth"
+	"ku-asset/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+// RequireTwoFactorEnrollment blocks users whom policy forces to use 2FA (see REQUIRE_ADMIN_2FA)
+// until they have enrolled. It must run after AuthMiddleware. RequirePermission applies the
+// same check, so every permission-gated route is covered even outside the admin group.
+func RequireTwoFactorEnrollment() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		permissions, err := GetUserPermissions(c)
+		if err != nil {
+			log.Printf("❌ Failed to load permissions: %v", err)
+			c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+			c.Abort()
+			return
+		}
+		if !checkTwoFactorEnrollment(c, permissions) {
+			return
+		}
+		c.Next()
+	}
+}
+
+// checkTwoFactorEnrollment aborts with 403 when the user must enrol in 2FA first.
+// API keys and impersonation sessions cannot enrol and are checked when they are created.
+func checkTwoFactorEnrollment(c *gin.Context, permissions []string) bool {
+	if !auth.AdminTwoFactorRequired() || IsAPIKeyRequest(c) || IsImpersonating(c) {
+		return true
+	}
+
+	value, exists := c.Get("user")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not found in context"})
+		c.Abort()
+		return false
+	}
+	user, ok := value.(models.User)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid user in context"})
+		c.Abort()
+		return false
+	}
+
+	if auth.TwoFactorRequired(&user, permissions) && !user.HasTwoFactor() {
+		log.Printf("❌ Access denied for %s: 2FA enrolment required", user.Email)
+		c.JSON(http.StatusForbidden, gin.H{
+			"error": "Two-factor authentication must be enabled for your account",
+			"code":  "MFA_ENROLLMENT_REQUIRED",
+		})
+		c.Abort()
+		return false
+	}
+	return true
+}
@@ -0,0 +1,59 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"ku-asset/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestRequirePermissionEnforcesTwoFactorEnrollment(t *testing.T) {
+	secret := "JBSWY3DPEHPK3PXP"
+	clerk := models.User{ID: 7, Email: "clerk@ku.ac.th", Role: models.RoleUser}
+	enrolled := clerk
+	enrolled.TwoFactorEnabled = true
+	enrolled.TOTPSecret = &secret
+
+	tests := []struct {
+		name    string
+		policy  string
+		user    models.User
+		apiKey  bool
+		want    int
+		wantMFA bool
+	}{
+		{"policy off", "false", clerk, false, http.StatusOK, false},
+		{"permission holder without 2FA", "true", clerk, false, http.StatusForbidden, true},
+		{"permission holder with 2FA", "true", enrolled, false, http.StatusOK, false},
+		{"API key", "true", clerk, true, http.StatusOK, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("REQUIRE_ADMIN_2FA", tt.policy)
+			gin.SetMode(gin.TestMode)
+			router := gin.New()
+			router.POST("/products/:id/stock", func(c *gin.Context) {
+				c.Set("userID", tt.user.ID)
+				c.Set("user", tt.user)
+				c.Set("userPermissions", []string{models.PermStockAdjust})
+				if tt.apiKey {
+					c.Set("apiKeyID", uint(1))
+				}
+			}, RequirePermission(models.PermStockAdjust), func(c *gin.Context) { c.Status(http.StatusOK) })
+
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/products/1/stock", nil))
+
+			if w.Code != tt.want {
+				t.Errorf("status = %d, want %d", w.Code, tt.want)
+			}
+			if got := w.Code == http.StatusForbidden && strings.Contains(w.Body.String(), `"code":"MFA_ENROLLMENT_REQUIRED"`); got != tt.wantMFA {
+				t.Errorf("MFA_ENROLLMENT_REQUIRED = %v, want %v (body %s)", got, tt.wantMFA, w.Body.String())
+			}
+		})
+	}
+}
@@ -0,0 +1,98 @@
+package migrations
+
+import (
+	"ku-asset/models"
+	"log"
+
+	"github.com/go-gormigrate/gormigrate/v2"
+	"gorm.io/gorm"
+)
+
+// M25691019001CreateRBACTables creates roles/permissions tables, seeds the default roles
+// and maps existing ADMIN/USER users onto them
+var M25691019001CreateRBACTables = &gormigrate.Migration{
+	ID: "25691019001_create_rbac_tables",
+	Migrate: func(tx *gorm.DB) error {
+		if err := tx.AutoMigrate(&models.Permission{}, &models.RoleDefinition{}, &models.UserRole{}); err != nil {
+			return err
+		}
+
+		if err := tx.Exec(`
+            CREATE UNIQUE INDEX IF NOT EXISTS idx_user_roles_user_role ON user_roles(user_id, role_id)
+        `).Error; err != nil {
+			return err
+		}
+
+		// ⭐ Seed permissions
+		permissions := make(map[string]models.Permission)
+		for _, p := range models.PermissionCatalog {
+			permission := p
+			if err := tx.Where("code = ?", permission.Code).FirstOrCreate(&permission).Error; err != nil {
+				return err
+			}
+			permissions[permission.Code] = permission
+		}
+
+		pick := func(codes ...string) []models.Permission {
+			result := make([]models.Permission, 0, len(codes))
+			for _, code := range codes {
+				result = append(result, permissions[code])
+			}
+			return result
+		}
+
+		allCodes := make([]string, 0, len(models.PermissionCatalog))
+		for _, p := range models.PermissionCatalog {
+			allCodes = append(allCodes, p.Code)
+		}
+
+		// ⭐ Seed roles - ADMIN และ USER ตรงกับค่าใน users.role เดิม
+		roles := []models.RoleDefinition{
+			{
+				Name:        string(models.RoleAdmin),
+				DisplayName: "ผู้ดูแลระบบ",
+				Description: "เข้าถึงได้ทุกส่วนของระบบ",
+				IsSystem:    true,
+				Permissions: pick(allCodes...),
+			},
+			{
+				Name:        string(models.RoleUser),
+				DisplayName: "ผู้ใช้งานทั่วไป",
+				Description: "สร้างและติดตามคำขอเบิกของตนเอง",
+				IsSystem:    true,
+				Permissions: []models.Permission{},
+			},
+			{
+				Name:        "STORE_CLERK",
+				DisplayName: "เจ้าหน้าที่พัสดุ",
+				Description: "จ่ายของตามคำขอและปรับสต็อก แต่จัดการผู้ใช้ไม่ได้",
+				Permissions: pick(models.PermRequestView, models.PermRequestIssue, models.PermStockAdjust, models.PermProductManage),
+			},
+		}
+
+		for _, role := range roles {
+			var existing models.RoleDefinition
+			if err := tx.Where("name = ?", role.Name).First(&existing).Error; err == nil {
+				log.Printf("⚠️ Role %s already exists, skipping...", role.Name)
+				continue
+			}
+			if err := tx.Create(&role).Error; err != nil {
+				return err
+			}
+			log.Printf("✅ Created role: %s", role.Name)
+		}
+
+		// ⭐ Map ผู้ใช้เดิมเข้ากับ role ตามค่า users.role
+		return tx.Exec(`
+            INSERT INTO user_roles (user_id, role_id, created_at)
+            SELECT users.id, roles.id, NOW()
+            FROM users
+            JOIN roles ON roles.name = users.role
+            WHERE users.deleted_at IS NULL
+            ON CONFLICT (user_id, role_id) DO NOTHING
+        `).Error
+	},
+	Rollback: func(tx *gorm.DB) error {
+		return tx.Migrator().DropTable("user_roles", "role_permissions", "roles", "permissions")
+	},
+}
@@ -0,0 +1,79 @@
+package migrations
+
+import (
+	"ku-asset/models"
+	"log"
+
+	"github.com/go-gormigrate/gormigrate/v2"
+	"gorm.io/gorm"
+)
+
+// M25691019002AddRoleDepartmentScope makes role assignments department-scoped
+// and seeds a FACULTY_ADMIN role intended to be granted per faculty
+var M25691019002AddRoleDepartmentScope = &gormigrate.Migration{
+	ID: "25691019002_add_role_department_scope",
+	Migrate: func(tx *gorm.DB) error {
+		// ⭐ เพิ่ม department_id ให้ user_roles (NULL = ทั้งมหาวิทยาลัย)
+		if err := tx.Exec(`
+            ALTER TABLE user_roles
+            ADD COLUMN IF NOT EXISTS department_id BIGINT REFERENCES departments(id)
+        `).Error; err != nil {
+			return err
+		}
+
+		if err := tx.Exec(`
+            CREATE INDEX IF NOT EXISTS idx_user_roles_department_id ON user_roles(department_id)
+        `).Error; err != nil {
+			return err
+		}
+
+		// ⭐ role เดียวกันมอบได้หลายหน่วยงาน จึงเปลี่ยน unique index ให้รวม department_id
+		if err := tx.Exec(`DROP INDEX IF EXISTS idx_user_roles_user_role`).Error; err != nil {
+			return err
+		}
+		if err := tx.Exec(`
+            CREATE UNIQUE INDEX IF NOT EXISTS idx_user_roles_user_role_department
+            ON user_roles(user_id, role_id, COALESCE(department_id, 0))
+        `).Error; err != nil {
+			return err
+		}
+
+		var existing models.RoleDefinition
+		if err := tx.Where("name = ?", "FACULTY_ADMIN").First(&existing).Error; err == nil {
+			return nil
+		}
+
+		var permissions []models.Permission
+		if err := tx.Where("code IN ?", []string{
+			models.PermRequestView,
+			models.PermRequestApprove,
+			models.PermUserManage,
+			models.PermReportView,
+		}).Find(&permissions).Error; err != nil {
+			return err
+		}
+
+		role := models.RoleDefinition{
+			Name:        "FACULTY_ADMIN",
+			DisplayName: "ผู้ดูแลระดับคณะ",
+			Description: "อนุมัติคำขอและจัดการผู้ใช้ภายในคณะ/หน่วยงานที่ได้รับมอบหมาย",
+			Permissions: permissions,
+		}
+		if err := tx.Create(&role).Error; err != nil {
+			return err
+		}
+		log.Printf("✅ Created role: %s", role.Name)
+		return nil
+	},
+	Rollback: func(tx *gorm.DB) error {
+		tx.Exec(`DROP INDEX IF EXISTS idx_user_roles_user_role_department`)
+		tx.Exec(`DROP INDEX IF EXISTS idx_user_roles_department_id`)
+		tx.Exec(`DELETE FROM user_roles WHERE department_id IS NOT NULL`)
+		if err := tx.Exec(`
+            CREATE UNIQUE INDEX IF NOT EXISTS idx_user_roles_user_role ON user_roles(user_id, role_id)
+        `).Error; err != nil {
+			return err
+		}
+		return tx.Exec(`ALTER TABLE user_roles DROP COLUMN IF EXISTS department_id`).Error
+	},
+}
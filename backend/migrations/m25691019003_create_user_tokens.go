@@ -0,0 +1,42 @@
+package migrations
+
+import (
+	"ku-asset/models"
+
+	"github.com/go-gormigrate/gormigrate/v2"
+	"gorm.io/gorm"
+)
+
+// M25691019003CreateUserTokens adds single-use tokens for password reset / email verification
+// and marks existing users as verified so they are not locked out
+var M25691019003CreateUserTokens = &gormigrate.Migration{
+	ID: "25691019003_create_user_tokens",
+	Migrate: func(tx *gorm.DB) error {
+		if err := tx.Exec(`
+            ALTER TABLE users ADD COLUMN IF NOT EXISTS email_verified_at TIMESTAMPTZ
+        `).Error; err != nil {
+			return err
+		}
+
+		if err := tx.AutoMigrate(&models.UserToken{}); err != nil {
+			return err
+		}
+
+		if err := tx.Exec(`
+            CREATE INDEX IF NOT EXISTS idx_user_tokens_user_purpose ON user_tokens(user_id, purpose)
+        `).Error; err != nil {
+			return err
+		}
+
+		// ⭐ ผู้ใช้เดิมถือว่ายืนยันอีเมลแล้ว
+		return tx.Exec(`
+            UPDATE users SET email_verified_at = created_at WHERE email_verified_at IS NULL
+        `).Error
+	},
+	Rollback: func(tx *gorm.DB) error {
+		if err := tx.Migrator().DropTable("user_tokens"); err != nil {
+			return err
+		}
+		return tx.Exec(`ALTER TABLE users DROP COLUMN IF EXISTS email_verified_at`).Error
+	},
+}
@@ -0,0 +1,36 @@
+package migrations
+
+import (
+	"ku-asset/models"
+
+	"github.com/go-gormigrate/gormigrate/v2"
+	"gorm.io/gorm"
+)
+
+// M25691019004AddTwoFactorAuth adds TOTP columns to users and the recovery code table
+var M25691019004AddTwoFactorAuth = &gormigrate.Migration{
+	ID: "25691019004_add_two_factor_auth",
+	Migrate: func(tx *gorm.DB) error {
+		if err := tx.Exec(`
+            ALTER TABLE users
+                ADD COLUMN IF NOT EXISTS two_factor_enabled BOOLEAN DEFAULT false,
+                ADD COLUMN IF NOT EXISTS totp_secret VARCHAR(64),
+                ADD COLUMN IF NOT EXISTS two_factor_enabled_at TIMESTAMPTZ
+        `).Error; err != nil {
+			return err
+		}
+
+		return tx.AutoMigrate(&models.RecoveryCode{})
+	},
+	Rollback: func(tx *gorm.DB) error {
+		if err := tx.Migrator().DropTable("user_recovery_codes"); err != nil {
+			return err
+		}
+		return tx.Exec(`
+            ALTER TABLE users
+                DROP COLUMN IF EXISTS two_factor_enabled,
+                DROP COLUMN IF EXISTS totp_secret,
+                DROP COLUMN IF EXISTS two_factor_enabled_at
+        `).Error
+	},
+}
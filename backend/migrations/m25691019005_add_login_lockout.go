@@ -0,0 +1,41 @@
+package migrations
+
+import (
+	"ku-asset/models"
+
+	"github.com/go-gormigrate/gormigrate/v2"
+	"gorm.io/gorm"
+)
+
+// M25691019005AddLoginLockout adds failed-login tracking to users and the login_attempts audit table
+var M25691019005AddLoginLockout = &gormigrate.Migration{
+	ID: "25691019005_add_login_lockout",
+	Migrate: func(tx *gorm.DB) error {
+		if err := tx.Exec(`
+            ALTER TABLE users
+                ADD COLUMN IF NOT EXISTS failed_login_attempts INTEGER DEFAULT 0,
+                ADD COLUMN IF NOT EXISTS locked_until TIMESTAMPTZ
+        `).Error; err != nil {
+			return err
+		}
+
+		if err := tx.AutoMigrate(&models.LoginAttempt{}); err != nil {
+			return err
+		}
+
+		// ⭐ ใช้ตรวจจำนวนครั้งที่ผิดต่อ IP ในช่วงเวลาล่าสุด
+		return tx.Exec(`
+            CREATE INDEX IF NOT EXISTS idx_login_attempts_ip_created ON login_attempts(ip_address, created_at)
+        `).Error
+	},
+	Rollback: func(tx *gorm.DB) error {
+		if err := tx.Migrator().DropTable("login_attempts"); err != nil {
+			return err
+		}
+		return tx.Exec(`
+            ALTER TABLE users
+                DROP COLUMN IF EXISTS failed_login_attempts,
+                DROP COLUMN IF EXISTS locked_until
+        `).Error
+	},
+}
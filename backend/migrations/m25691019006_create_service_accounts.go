@@ -0,0 +1,43 @@
+package migrations
+
+import (
+	"ku-asset/models"
+
+	"github.com/go-gormigrate/gormigrate/v2"
+	"gorm.io/gorm"
+)
+
+// M25691019006CreateServiceAccounts creates service account / API key tables
+// and grants the new service_account.manage permission to ADMIN
+var M25691019006CreateServiceAccounts = &gormigrate.Migration{
+	ID: "25691019006_create_service_accounts",
+	Migrate: func(tx *gorm.DB) error {
+		if err := tx.AutoMigrate(&models.ServiceAccount{}, &models.APIKey{}); err != nil {
+			return err
+		}
+
+		permission := models.Permission{Code: models.PermServiceAccountManage}
+		for _, p := range models.PermissionCatalog {
+			if p.Code == permission.Code {
+				permission.Description = p.Description
+			}
+		}
+		if err := tx.Where("code = ?", permission.Code).FirstOrCreate(&permission).Error; err != nil {
+			return err
+		}
+
+		// ⭐ ฐานข้อมูลใหม่ได้สิทธิ์นี้จาก migration RBAC แล้ว จึงใช้ ON CONFLICT กันซ้ำ
+		return tx.Exec(`
+            INSERT INTO role_permissions (role_id, permission_id)
+            SELECT roles.id, ? FROM roles WHERE roles.name = ?
+            ON CONFLICT DO NOTHING
+        `, permission.ID, string(models.RoleAdmin)).Error
+	},
+	Rollback: func(tx *gorm.DB) error {
+		if err := tx.Migrator().DropTable("api_key_permissions", "api_keys", "service_accounts"); err != nil {
+			return err
+		}
+		tx.Exec(`DELETE FROM role_permissions WHERE permission_id IN (SELECT id FROM permissions WHERE code = ?)`, models.PermServiceAccountManage)
+		return tx.Exec(`DELETE FROM permissions WHERE code = ?`, models.PermServiceAccountManage).Error
+	},
+}
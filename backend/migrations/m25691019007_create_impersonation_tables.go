@@ -0,0 +1,19 @@
+package migrations
+
+import (
+	"ku-asset/models"
+
+	"github.com/go-gormigrate/gormigrate/v2"
+	"gorm.io/gorm"
+)
+
+// M25691019007CreateImpersonationTables creates the impersonation session and call log tables
+var M25691019007CreateImpersonationTables = &gormigrate.Migration{
+	ID: "25691019007_create_impersonation_tables",
+	Migrate: func(tx *gorm.DB) error {
+		return tx.AutoMigrate(&models.ImpersonationSession{}, &models.ImpersonationLog{})
+	},
+	Rollback: func(tx *gorm.DB) error {
+		return tx.Migrator().DropTable("impersonation_logs", "impersonation_sessions")
+	},
+}
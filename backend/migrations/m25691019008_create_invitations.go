@@ -0,0 +1,19 @@
+package migrations
+
+import (
+	"ku-asset/models"
+
+	"github.com/go-gormigrate/gormigrate/v2"
+	"gorm.io/gorm"
+)
+
+// M25691019008CreateInvitations creates the invitations table
+var M25691019008CreateInvitations = &gormigrate.Migration{
+	ID: "25691019008_create_invitations",
+	Migrate: func(tx *gorm.DB) error {
+		return tx.AutoMigrate(&models.Invitation{})
+	},
+	Rollback: func(tx *gorm.DB) error {
+		return tx.Migrator().DropTable("invitations")
+	},
+}
@@ -0,0 +1,64 @@
+package migrations
+
+import (
+	"ku-asset/models"
+
+	"github.com/go-gormigrate/gormigrate/v2"
+	"gorm.io/gorm"
+)
+
+// M25691019009CreateAuditLogs creates the hash-chained audit log, makes it append-only
+// and grants the new audit.view permission to ADMIN
+var M25691019009CreateAuditLogs = &gormigrate.Migration{
+	ID: "25691019009_create_audit_logs",
+	Migrate: func(tx *gorm.DB) error {
+		if err := tx.AutoMigrate(&models.AuditLog{}); err != nil {
+			return err
+		}
+
+		// ⭐ กันแก้/ลบผ่าน SQL ปกติ (hash chain ยังตรวจจับได้ถ้ามีคนปิด trigger)
+		if err := tx.Exec(`
+            CREATE OR REPLACE FUNCTION audit_logs_append_only() RETURNS trigger AS $$
+            BEGIN
+                RAISE EXCEPTION 'audit_logs is append-only';
+            END;
+            $$ LANGUAGE plpgsql
+        `).Error; err != nil {
+			return err
+		}
+		if err := tx.Exec(`DROP TRIGGER IF EXISTS audit_logs_append_only ON audit_logs`).Error; err != nil {
+			return err
+		}
+		if err := tx.Exec(`
+            CREATE TRIGGER audit_logs_append_only
+                BEFORE UPDATE OR DELETE ON audit_logs
+                FOR EACH ROW EXECUTE FUNCTION audit_logs_append_only()
+        `).Error; err != nil {
+			return err
+		}
+
+		permission := models.Permission{Code: models.PermAuditView}
+		for _, p := range models.PermissionCatalog {
+			if p.Code == permission.Code {
+				permission.Description = p.Description
+			}
+		}
+		if err := tx.Where("code = ?", permission.Code).FirstOrCreate(&permission).Error; err != nil {
+			return err
+		}
+
+		return tx.Exec(`
+            INSERT INTO role_permissions (role_id, permission_id)
+            SELECT roles.id, ? FROM roles WHERE roles.name = ?
+            ON CONFLICT DO NOTHING
+        `, permission.ID, string(models.RoleAdmin)).Error
+	},
+	Rollback: func(tx *gorm.DB) error {
+		if err := tx.Migrator().DropTable("audit_logs"); err != nil {
+			return err
+		}
+		tx.Exec(`DROP FUNCTION IF EXISTS audit_logs_append_only()`)
+		tx.Exec(`DELETE FROM role_permissions WHERE permission_id IN (SELECT id FROM permissions WHERE code = ?)`, models.PermAuditView)
+		return tx.Exec(`DELETE FROM permissions WHERE code = ?`, models.PermAuditView).Error
+	},
+}
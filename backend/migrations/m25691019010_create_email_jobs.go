@@ -0,0 +1,36 @@
+package migrations
+
+import (
+	"ku-asset/models"
+
+	"github.com/go-gormigrate/gormigrate/v2"
+	"gorm.io/gorm"
+)
+
+// M25691019010CreateEmailJobs creates the e-mail outbox and adds users.locale and
+// requests.overdue_notified_at for request notifications
+var M25691019010CreateEmailJobs = &gormigrate.Migration{
+	ID: "25691019010_create_email_jobs",
+	Migrate: func(tx *gorm.DB) error {
+		if err := tx.Exec(`
+            ALTER TABLE users ADD COLUMN IF NOT EXISTS locale VARCHAR(5) DEFAULT 'th'
+        `).Error; err != nil {
+			return err
+		}
+		if err := tx.Exec(`
+            ALTER TABLE requests ADD COLUMN IF NOT EXISTS overdue_notified_at TIMESTAMPTZ
+        `).Error; err != nil {
+			return err
+		}
+		return tx.AutoMigrate(&models.EmailJob{})
+	},
+	Rollback: func(tx *gorm.DB) error {
+		if err := tx.Migrator().DropTable("email_jobs"); err != nil {
+			return err
+		}
+		if err := tx.Exec(`ALTER TABLE requests DROP COLUMN IF EXISTS overdue_notified_at`).Error; err != nil {
+			return err
+		}
+		return tx.Exec(`ALTER TABLE users DROP COLUMN IF EXISTS locale`).Error
+	},
+}
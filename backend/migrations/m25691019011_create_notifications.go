@@ -0,0 +1,24 @@
+package migrations
+
+import (
+	"ku-asset/models"
+
+	"github.com/go-gormigrate/gormigrate/v2"
+	"gorm.io/gorm"
+)
+
+// M25691019011CreateNotifications creates the in-app notification center, per-user
+// notification preferences and request comments
+var M25691019011CreateNotifications = &gormigrate.Migration{
+	ID: "25691019011_create_notifications",
+	Migrate: func(tx *gorm.DB) error {
+		return tx.AutoMigrate(
+			&models.Notification{},
+			&models.NotificationPreference{},
+			&models.RequestComment{},
+		)
+	},
+	Rollback: func(tx *gorm.DB) error {
+		return tx.Migrator().DropTable("request_comments", "notification_preferences", "notifications")
+	},
+}
@@ -0,0 +1,42 @@
+package migrations
+
+import (
+	"ku-asset/models"
+
+	"github.com/go-gormigrate/gormigrate/v2"
+	"gorm.io/gorm"
+)
+
+// M25691019012CreateWebhooks creates webhook subscriptions and the delivery queue/log
+// and grants the new webhook.manage permission to ADMIN
+var M25691019012CreateWebhooks = &gormigrate.Migration{
+	ID: "25691019012_create_webhooks",
+	Migrate: func(tx *gorm.DB) error {
+		if err := tx.AutoMigrate(&models.WebhookSubscription{}, &models.WebhookDelivery{}); err != nil {
+			return err
+		}
+
+		permission := models.Permission{Code: models.PermWebhookManage}
+		for _, p := range models.PermissionCatalog {
+			if p.Code == permission.Code {
+				permission.Description = p.Description
+			}
+		}
+		if err := tx.Where("code = ?", permission.Code).FirstOrCreate(&permission).Error; err != nil {
+			return err
+		}
+
+		return tx.Exec(`
+            INSERT INTO role_permissions (role_id, permission_id)
+            SELECT roles.id, ? FROM roles WHERE roles.name = ?
+            ON CONFLICT DO NOTHING
+        `, permission.ID, string(models.RoleAdmin)).Error
+	},
+	Rollback: func(tx *gorm.DB) error {
+		if err := tx.Migrator().DropTable("webhook_deliveries", "webhook_subscriptions"); err != nil {
+			return err
+		}
+		tx.Exec(`DELETE FROM role_permissions WHERE permission_id IN (SELECT id FROM permissions WHERE code = ?)`, models.PermWebhookManage)
+		return tx.Exec(`DELETE FROM permissions WHERE code = ?`, models.PermWebhookManage).Error
+	},
+}
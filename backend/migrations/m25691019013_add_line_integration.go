@@ -0,0 +1,45 @@
+package migrations
+
+import (
+	"ku-asset/models"
+
+	"github.com/go-gormigrate/gormigrate/v2"
+	"gorm.io/gorm"
+)
+
+// M25691019013AddLineIntegration links users to LINE accounts, adds the LINE channel to
+// notification preferences and creates the LINE push outbox
+var M25691019013AddLineIntegration = &gormigrate.Migration{
+	ID: "25691019013_add_line_integration",
+	Migrate: func(tx *gorm.DB) error {
+		statements := []string{
+			`ALTER TABLE users ADD COLUMN IF NOT EXISTS line_user_id VARCHAR(64)`,
+			`ALTER TABLE users ADD COLUMN IF NOT EXISTS line_linked_at TIMESTAMPTZ`,
+			`CREATE UNIQUE INDEX IF NOT EXISTS idx_users_line_user_id ON users (line_user_id)`,
+			`ALTER TABLE notification_preferences ADD COLUMN IF NOT EXISTS line BOOLEAN NOT NULL DEFAULT true`,
+		}
+		for _, stmt := range statements {
+			if err := tx.Exec(stmt).Error; err != nil {
+				return err
+			}
+		}
+		return tx.AutoMigrate(&models.LineJob{})
+	},
+	Rollback: func(tx *gorm.DB) error {
+		if err := tx.Migrator().DropTable("line_jobs"); err != nil {
+			return err
+		}
+		statements := []string{
+			`ALTER TABLE notification_preferences DROP COLUMN IF EXISTS line`,
+			`DROP INDEX IF EXISTS idx_users_line_user_id`,
+			`ALTER TABLE users DROP COLUMN IF EXISTS line_linked_at`,
+			`ALTER TABLE users DROP COLUMN IF EXISTS line_user_id`,
+		}
+		for _, stmt := range statements {
+			if err := tx.Exec(stmt).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	},
+}
@@ -0,0 +1,27 @@
+package migrations
+
+import (
+	"github.com/go-gormigrate/gormigrate/v2"
+	"gorm.io/gorm"
+)
+
+// M25691019014AddStockMonitoring adds per-product lead time and low-stock alert state
+var M25691019014AddStockMonitoring = &gormigrate.Migration{
+	ID: "25691019014_add_stock_monitoring",
+	Migrate: func(tx *gorm.DB) error {
+		if err := tx.Exec(`
+            ALTER TABLE products ADD COLUMN IF NOT EXISTS lead_time_days INTEGER
+        `).Error; err != nil {
+			return err
+		}
+		return tx.Exec(`
+            ALTER TABLE products ADD COLUMN IF NOT EXISTS low_stock_alerted_at TIMESTAMPTZ
+        `).Error
+	},
+	Rollback: func(tx *gorm.DB) error {
+		if err := tx.Exec(`ALTER TABLE products DROP COLUMN IF EXISTS low_stock_alerted_at`).Error; err != nil {
+			return err
+		}
+		return tx.Exec(`ALTER TABLE products DROP COLUMN IF EXISTS lead_time_days`).Error
+	},
+}
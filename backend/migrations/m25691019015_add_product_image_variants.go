@@ -0,0 +1,19 @@
+package migrations
+
+import (
+	"github.com/go-gormigrate/gormigrate/v2"
+	"gorm.io/gorm"
+)
+
+// M25691019015AddProductImageVariants stores the URLs of each resized product image
+var M25691019015AddProductImageVariants = &gormigrate.Migration{
+	ID: "25691019015_add_product_image_variants",
+	Migrate: func(tx *gorm.DB) error {
+		return tx.Exec(`
+            ALTER TABLE products ADD COLUMN IF NOT EXISTS image_variants JSONB
+        `).Error
+	},
+	Rollback: func(tx *gorm.DB) error {
+		return tx.Exec(`ALTER TABLE products DROP COLUMN IF EXISTS image_variants`).Error
+	},
+}
@@ -0,0 +1,19 @@
+package migrations
+
+import (
+	"ku-asset/models"
+
+	"github.com/go-gormigrate/gormigrate/v2"
+	"gorm.io/gorm"
+)
+
+// M25691019016CreateUploads creates the upload registry and its reference table
+var M25691019016CreateUploads = &gormigrate.Migration{
+	ID: "25691019016_create_uploads",
+	Migrate: func(tx *gorm.DB) error {
+		return tx.AutoMigrate(&models.Upload{}, &models.UploadReference{})
+	},
+	Rollback: func(tx *gorm.DB) error {
+		return tx.Migrator().DropTable("upload_references", "uploads")
+	},
+}
@@ -0,0 +1,19 @@
+package migrations
+
+import (
+	"ku-asset/models"
+
+	"github.com/go-gormigrate/gormigrate/v2"
+	"gorm.io/gorm"
+)
+
+// M25691019017CreateProductMedia creates the product image gallery and document attachments
+var M25691019017CreateProductMedia = &gormigrate.Migration{
+	ID: "25691019017_create_product_media",
+	Migrate: func(tx *gorm.DB) error {
+		return tx.AutoMigrate(&models.ProductMedia{})
+	},
+	Rollback: func(tx *gorm.DB) error {
+		return tx.Migrator().DropTable("product_media")
+	},
+}
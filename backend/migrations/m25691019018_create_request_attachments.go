@@ -0,0 +1,19 @@
+package migrations
+
+import (
+	"ku-asset/models"
+
+	"github.com/go-gormigrate/gormigrate/v2"
+	"gorm.io/gorm"
+)
+
+// M25691019018CreateRequestAttachments creates supporting files (memos, quotations) on requests
+var M25691019018CreateRequestAttachments = &gormigrate.Migration{
+	ID: "25691019018_create_request_attachments",
+	Migrate: func(tx *gorm.DB) error {
+		return tx.AutoMigrate(&models.RequestAttachment{})
+	},
+	Rollback: func(tx *gorm.DB) error {
+		return tx.Migrator().DropTable("request_attachments")
+	},
+}
@@ -0,0 +1,18 @@
+package migrations
+
+import (
+	"github.com/go-gormigrate/gormigrate/v2"
+	"gorm.io/gorm"
+)
+
+// M25691019019AddTOTPLastStep records the time step of the last accepted TOTP code,
+// so a code cannot be used twice within its validity window
+var M25691019019AddTOTPLastStep = &gormigrate.Migration{
+	ID: "25691019019_add_totp_last_step",
+	Migrate: func(tx *gorm.DB) error {
+		return tx.Exec(`ALTER TABLE users ADD COLUMN IF NOT EXISTS totp_last_step BIGINT`).Error
+	},
+	Rollback: func(tx *gorm.DB) error {
+		return tx.Exec(`ALTER TABLE users DROP COLUMN IF EXISTS totp_last_step`).Error
+	},
+}
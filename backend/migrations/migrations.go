@@ -19,6 +19,25 @@ func GetAllMigrations() []*gormigrate.Migration {
 		M25680621173000SeedCoreData,                 // 7. Seed ข้อมูลสุดท้าย
 		M25680624001SeedFacultiesAndDepartments,     // 8. 🆕 Seed Faculty และ Department data
 		M25680628001_seed_mock_users,                // 9. 🆕 Seed Mock Users สำหรับ Testing
+		M25691019001CreateRBACTables,                // 10. 🆕 Roles & Permissions (RBAC)
+		M25691019002AddRoleDepartmentScope,          // 11. 🆕 Role assignment ตามหน่วยงาน
+		M25691019003CreateUserTokens,                // 12. 🆕 Password reset & email verification
+		M25691019004AddTwoFactorAuth,                // 13. 🆕 TOTP 2FA & recovery codes
+		M25691019005AddLoginLockout,                 // 14. 🆕 Login lockout & login attempt audit
+		M25691019006CreateServiceAccounts,           // 15. 🆕 Service accounts & API keys
+		M25691019007CreateImpersonationTables,       // 16. 🆕 Admin impersonation audit
+		M25691019008CreateInvitations,               // 17. 🆕 User invitations
+		M25691019009CreateAuditLogs,                 // 18. 🆕 Hash-chained audit log
+		M25691019010CreateEmailJobs,                 // 19. 🆕 E-mail notification outbox
+		M25691019011CreateNotifications,             // 20. 🆕 In-app notifications & request comments
+		M25691019012CreateWebhooks,                  // 21. 🆕 Outgoing webhooks
+		M25691019013AddLineIntegration,              // 22. 🆕 LINE notifications & chatbot
+		M25691019014AddStockMonitoring,              // 23. 🆕 Low-stock alerts & reorder suggestions
+		M25691019015AddProductImageVariants,         // 24. 🆕 Responsive product image variants
+		M25691019016CreateUploads,                   // 25. 🆕 Upload registry & garbage collection
+		M25691019017CreateProductMedia,              // 26. 🆕 Product gallery & document attachments
+		M25691019018CreateRequestAttachments,        // 27. 🆕 Request attachments (memos, quotations)
+		M25691019019AddTOTPLastStep,                 // 28. 🆕 Reject replayed TOTP codes
 	}
 }
 
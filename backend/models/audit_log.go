@@ -0,0 +1,38 @@
+package models
+
+import (
+	"time"
+)
+
+// Audit actions
+const (
+	AuditActionCreate = "create"
+	AuditActionUpdate = "update"
+	AuditActionDelete = "delete"
+)
+
+// AuditLog is one entry of the hash-chained audit trail of data changes.
+// Hash covers PrevHash and every recorded field, so editing, deleting or
+// reordering rows breaks the chain.
+type AuditLog struct {
+	ID             uint      `json:"id" gorm:"primaryKey"`
+	ActorID        *uint     `json:"actor_id" gorm:"index"`
+	ActorEmail     string    `json:"actor_email" gorm:"size:255"`
+	ImpersonatorID *uint     `json:"impersonator_id"`
+	APIKeyID       *uint     `json:"api_key_id"`
+	Action         string    `json:"action" gorm:"size:20;not null;index"`
+	EntityType     string    `json:"entity_type" gorm:"size:100;not null;index:idx_audit_entity"`
+	EntityID       string    `json:"entity_id" gorm:"size:100;index:idx_audit_entity"`
+	Changes        string    `json:"-" gorm:"type:text;not null"` // เก็บเป็น text เพื่อให้ byte ตรงกับที่ใช้คำนวณ hash
+	IPAddress      string    `json:"ip_address" gorm:"size:45"`
+	UserAgent      string    `json:"user_agent" gorm:"type:text"`
+	RequestID      string    `json:"request_id" gorm:"size:64;index"`
+	PrevHash       string    `json:"prev_hash" gorm:"size:64;not null"`
+	Hash           string    `json:"hash" gorm:"size:64;not null;uniqueIndex"`
+	CreatedAt      time.Time `json:"created_at" gorm:"not null;index"`
+}
+
+// TableName specifies the table name for AuditLog model
+func (AuditLog) TableName() string {
+	return "audit_logs"
+}
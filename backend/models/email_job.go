@@ -0,0 +1,38 @@
+package models
+
+import (
+	"time"
+)
+
+// EmailJobStatus is the delivery state of a queued e-mail
+type EmailJobStatus string
+
+const (
+	EmailJobPending EmailJobStatus = "PENDING"
+	EmailJobSent    EmailJobStatus = "SENT"
+	EmailJobFailed  EmailJobStatus = "FAILED" // เกินจำนวนครั้งที่ลองส่งแล้ว
+)
+
+// EmailJob is one rendered e-mail waiting in the outbox. Jobs are inserted in the same
+// transaction as the change that triggered them and delivered by a background worker.
+type EmailJob struct {
+	ID            uint           `json:"id" gorm:"primaryKey"`
+	Event         string         `json:"event" gorm:"size:50;not null;index"`
+	Recipient     string         `json:"recipient" gorm:"size:255;not null"`
+	Subject       string         `json:"subject" gorm:"size:255;not null"`
+	TextBody      string         `json:"-" gorm:"type:text;not null"`
+	HTMLBody      string         `json:"-" gorm:"type:text"`
+	RequestID     *uint          `json:"request_id" gorm:"index"`
+	Status        EmailJobStatus `json:"status" gorm:"type:varchar(20);not null;default:'PENDING';index:idx_email_jobs_due"`
+	Attempts      int            `json:"attempts" gorm:"not null;default:0"`
+	NextAttemptAt time.Time      `json:"next_attempt_at" gorm:"not null;index:idx_email_jobs_due"`
+	LastError     string         `json:"last_error" gorm:"type:text"`
+	SentAt        *time.Time     `json:"sent_at"`
+	CreatedAt     time.Time      `json:"created_at"`
+	UpdatedAt     time.Time      `json:"updated_at"`
+}
+
+// TableName specifies the table name for EmailJob model
+func (EmailJob) TableName() string {
+	return "email_jobs"
+}
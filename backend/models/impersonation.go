@@ -0,0 +1,47 @@
+package models
+
+import (
+	"time"
+)
+
+// ImpersonationSession records an administrator viewing the system as another user
+type ImpersonationSession struct {
+	ID        uint               `json:"id" gorm:"primaryKey"`
+	AdminID   uint               `json:"admin_id" gorm:"not null;index"`
+	Admin     User               `json:"admin" gorm:"foreignKey:AdminID"`
+	UserID    uint               `json:"user_id" gorm:"not null;index"`
+	User      User               `json:"user" gorm:"foreignKey:UserID"`
+	Reason    string             `json:"reason" gorm:"type:text;not null"`
+	IPAddress string             `json:"ip_address" gorm:"size:45"`
+	UserAgent string             `json:"user_agent" gorm:"type:text"`
+	ExpiresAt time.Time          `json:"expires_at" gorm:"not null"`
+	EndedAt   *time.Time         `json:"ended_at"`
+	Calls     []ImpersonationLog `json:"calls,omitempty" gorm:"foreignKey:SessionID"`
+	CreatedAt time.Time          `json:"created_at"`
+}
+
+// TableName specifies the table name for ImpersonationSession model
+func (ImpersonationSession) TableName() string {
+	return "impersonation_sessions"
+}
+
+// IsActive reports whether the session can still be used
+func (s *ImpersonationSession) IsActive(now time.Time) bool {
+	return s.EndedAt == nil && now.Before(s.ExpiresAt)
+}
+
+// ImpersonationLog is one API call made while impersonating
+type ImpersonationLog struct {
+	ID         uint      `json:"id" gorm:"primaryKey"`
+	SessionID  uint      `json:"session_id" gorm:"not null;index"`
+	Method     string    `json:"method" gorm:"size:10"`
+	Path       string    `json:"path" gorm:"type:text"`
+	StatusCode int       `json:"status_code"`
+	IPAddress  string    `json:"ip_address" gorm:"size:45"`
+	CreatedAt  time.Time `json:"created_at"`
+}
+
+// TableName specifies the table name for ImpersonationLog model
+func (ImpersonationLog) TableName() string {
+	return "impersonation_logs"
+}
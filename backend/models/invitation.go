@@ -0,0 +1,51 @@
+package models
+
+import (
+	"time"
+)
+
+// Invitation is a single-use link that lets a person create an account with a
+// role and department chosen in advance by an administrator.
+type Invitation struct {
+	ID             uint        `json:"id" gorm:"primaryKey"`
+	Email          string      `json:"email" gorm:"size:255;not null;index"`
+	Name           string      `json:"name" gorm:"size:255"`
+	RoleName       string      `json:"role_name" gorm:"size:50;not null;default:'USER'"` // ชื่อใน roles.name
+	DepartmentID   *uint       `json:"department_id" gorm:"index"`
+	Department     *Department `json:"department,omitempty" gorm:"foreignKey:DepartmentID"`
+	TokenHash      string      `json:"-" gorm:"uniqueIndex;size:64;not null"`
+	InvitedByID    uint        `json:"invited_by_id" gorm:"not null"`
+	InvitedBy      User        `json:"-" gorm:"foreignKey:InvitedByID"`
+	ExpiresAt      time.Time   `json:"expires_at" gorm:"not null"`
+	AcceptedAt     *time.Time  `json:"accepted_at"`
+	AcceptedUserID *uint       `json:"accepted_user_id"`
+	RevokedAt      *time.Time  `json:"revoked_at"`
+	CreatedAt      time.Time   `json:"created_at"`
+	UpdatedAt      time.Time   `json:"updated_at"`
+}
+
+// TableName specifies the table name for Invitation model
+func (Invitation) TableName() string {
+	return "invitations"
+}
+
+// InvitationStatus values derived from the timestamps
+const (
+	InvitationPending  = "PENDING"
+	InvitationAccepted = "ACCEPTED"
+	InvitationRevoked  = "REVOKED"
+	InvitationExpired  = "EXPIRED"
+)
+
+// Status returns the current state of the invitation
+func (i *Invitation) Status(now time.Time) string {
+	switch {
+	case i.AcceptedAt != nil:
+		return InvitationAccepted
+	case i.RevokedAt != nil:
+		return InvitationRevoked
+	case !now.Before(i.ExpiresAt):
+		return InvitationExpired
+	}
+	return InvitationPending
+}
@@ -0,0 +1,37 @@
+package models
+
+import (
+	"time"
+)
+
+// LineJobStatus is the delivery state of a queued LINE message
+type LineJobStatus string
+
+const (
+	LineJobPending LineJobStatus = "PENDING"
+	LineJobSent    LineJobStatus = "SENT"
+	LineJobFailed  LineJobStatus = "FAILED" // เกินจำนวนครั้งที่ลองส่ง หรือ LINE ปฏิเสธถาวร
+)
+
+// LineJob is one LINE push message waiting in the outbox. Like EmailJob it is inserted in
+// the same transaction as the change that triggered it.
+type LineJob struct {
+	ID            uint          `json:"id" gorm:"primaryKey"`
+	Event         string        `json:"event" gorm:"size:50;not null;index"`
+	UserID        uint          `json:"user_id" gorm:"not null;index"`
+	LineUserID    string        `json:"-" gorm:"size:64;not null"`
+	Text          string        `json:"-" gorm:"type:text;not null"`
+	RequestID     *uint         `json:"request_id" gorm:"index"`
+	Status        LineJobStatus `json:"status" gorm:"type:varchar(20);not null;default:'PENDING';index:idx_line_jobs_due"`
+	Attempts      int           `json:"attempts" gorm:"not null;default:0"`
+	NextAttemptAt time.Time     `json:"next_attempt_at" gorm:"not null;index:idx_line_jobs_due"`
+	LastError     string        `json:"last_error" gorm:"type:text"`
+	SentAt        *time.Time    `json:"sent_at"`
+	CreatedAt     time.Time     `json:"created_at"`
+	UpdatedAt     time.Time     `json:"updated_at"`
+}
+
+// TableName specifies the table name for LineJob model
+func (LineJob) TableName() string {
+	return "line_jobs"
+}
@@ -0,0 +1,36 @@
+package models
+
+import (
+	"time"
+)
+
+// LoginResult describes the outcome of a login attempt
+type LoginResult string
+
+const (
+	LoginResultSuccess         LoginResult = "SUCCESS"
+	LoginResultMFAChallenge    LoginResult = "MFA_CHALLENGE"
+	LoginResultInvalidPassword LoginResult = "INVALID_PASSWORD"
+	LoginResultUnknownEmail    LoginResult = "UNKNOWN_EMAIL"
+	LoginResultInvalidMFACode  LoginResult = "INVALID_MFA_CODE"
+	LoginResultLocked          LoginResult = "LOCKED"
+	LoginResultInactive        LoginResult = "INACTIVE"
+	LoginResultIPThrottled     LoginResult = "IP_THROTTLED"
+)
+
+// LoginAttempt is an audit record of a single login attempt
+type LoginAttempt struct {
+	ID        uint        `json:"id" gorm:"primaryKey"`
+	UserID    *uint       `json:"user_id" gorm:"index"`
+	Email     string      `json:"email" gorm:"type:varchar(255);index"`
+	IPAddress string      `json:"ip_address" gorm:"type:varchar(45);index"`
+	UserAgent string      `json:"user_agent" gorm:"type:text"`
+	Success   bool        `json:"success"`
+	Result    LoginResult `json:"result" gorm:"type:varchar(30)"`
+	CreatedAt time.Time   `json:"created_at" gorm:"index"`
+}
+
+// TableName specifies the table name for LoginAttempt model
+func (LoginAttempt) TableName() string {
+	return "login_attempts"
+}
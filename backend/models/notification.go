@@ -0,0 +1,40 @@
+package models
+
+import (
+	"time"
+)
+
+// Notification is an in-app message shown in a user's notification center
+type Notification struct {
+	ID        uint       `json:"id" gorm:"primaryKey"`
+	UserID    uint       `json:"user_id" gorm:"not null;index:idx_notifications_user_read"`
+	Event     string     `json:"event" gorm:"size:50;not null"`
+	Title     string     `json:"title" gorm:"size:255;not null"`
+	Body      string     `json:"body" gorm:"type:text"`
+	Link      string     `json:"link" gorm:"size:500"`
+	RequestID *uint      `json:"request_id"`
+	ProductID *uint      `json:"product_id"`
+	ReadAt    *time.Time `json:"read_at" gorm:"index:idx_notifications_user_read"`
+	CreatedAt time.Time  `json:"created_at" gorm:"index"`
+}
+
+// TableName specifies the table name for Notification model
+func (Notification) TableName() string {
+	return "notifications"
+}
+
+// NotificationPreference stores which channels a user wants for one event.
+// Events without a row use every channel.
+type NotificationPreference struct {
+	UserID    uint      `json:"user_id" gorm:"primaryKey"`
+	Event     string    `json:"event" gorm:"primaryKey;size:50"`
+	InApp     bool      `json:"in_app" gorm:"not null;default:true"`
+	Email     bool      `json:"email" gorm:"not null;default:true"`
+	Line      bool      `json:"line" gorm:"not null;default:true"` // ใช้เมื่อผูกบัญชี LINE แล้วเท่านั้น
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// TableName specifies the table name for NotificationPreference model
+func (NotificationPreference) TableName() string {
+	return "notification_preferences"
+}
@@ -0,0 +1,92 @@
+package models
+
+import (
+	"time"
+)
+
+// Permission represents a single capability that can be granted through a role
+type Permission struct {
+	ID          uint      `json:"id" gorm:"primaryKey"`
+	Code        string    `json:"code" gorm:"uniqueIndex;size:100;not null"`
+	Description string    `json:"description" gorm:"type:text"`
+	CreatedAt   time.Time `json:"created_at"`
+	UpdatedAt   time.Time `json:"updated_at"`
+}
+
+// Permission codes ที่ใช้ตรวจสอบสิทธิ์ในระบบ
+const (
+	PermRequestView      = "request.view"
+	PermRequestApprove   = "request.approve"
+	PermRequestIssue     = "request.issue"
+	PermStockAdjust      = "stock.adjust"
+	PermProductManage    = "product.manage"
+	PermUserManage       = "user.manage"
+	PermRoleManage       = "role.manage"
+	PermDepartmentManage = "department.manage"
+	PermReportView       = "report.view"
+
+	PermServiceAccountManage = "service_account.manage"
+	PermAuditView            = "audit.view"
+	PermWebhookManage        = "webhook.manage"
+)
+
+// ScopedPermissions are enforced per department by their handlers (see auth.Scope), so they may be
+// granted for a single department. Any other permission only counts when granted university-wide.
+var ScopedPermissions = []string{
+	PermRequestView,
+	PermRequestApprove,
+	PermRequestIssue,
+	PermUserManage,
+	PermRoleManage,
+	PermReportView,
+}
+
+// PermissionCatalog lists every permission known to the system with its description
+var PermissionCatalog = []Permission{
+	{Code: PermRequestView, Description: "ดูคำขอเบิกทั้งหมด"},
+	{Code: PermRequestApprove, Description: "อนุมัติหรือปฏิเสธคำขอเบิก"},
+	{Code: PermRequestIssue, Description: "จ่ายของและปิดคำขอเบิก"},
+	{Code: PermStockAdjust, Description: "ปรับจำนวนสต็อกครุภัณฑ์"},
+	{Code: PermProductManage, Description: "เพิ่ม แก้ไข และลบครุภัณฑ์"},
+	{Code: PermUserManage, Description: "จัดการผู้ใช้งาน"},
+	{Code: PermRoleManage, Description: "กำหนดบทบาทและสิทธิ์"},
+	{Code: PermDepartmentManage, Description: "จัดการหน่วยงาน"},
+	{Code: PermReportView, Description: "ดูรายงานและแดชบอร์ด"},
+	{Code: PermServiceAccountManage, Description: "จัดการ service account และ API key"},
+	{Code: PermAuditView, Description: "ดูและตรวจสอบ audit log"},
+	{Code: PermWebhookManage, Description: "จัดการ webhook และดูประวัติการส่ง"},
+}
+
+// RoleDefinition is a named set of permissions that can be assigned to users
+type RoleDefinition struct {
+	ID          uint         `json:"id" gorm:"primaryKey"`
+	Name        string       `json:"name" gorm:"uniqueIndex;size:50;not null"`
+	DisplayName string       `json:"display_name" gorm:"size:255"`
+	Description string       `json:"description" gorm:"type:text"`
+	IsSystem    bool         `json:"is_system" gorm:"default:false"` // บทบาทตั้งต้น ห้ามลบ
+	Permissions []Permission `json:"permissions,omitempty" gorm:"many2many:role_permissions;joinForeignKey:RoleID;joinReferences:PermissionID"`
+	CreatedAt   time.Time    `json:"created_at"`
+	UpdatedAt   time.Time    `json:"updated_at"`
+}
+
+// TableName specifies the table name for RoleDefinition model
+func (RoleDefinition) TableName() string {
+	return "roles"
+}
+
+// UserRole assigns a role to a user. When DepartmentID is set the role only applies
+// within that department and its child departments; nil means university-wide.
+type UserRole struct {
+	ID           uint           `json:"id" gorm:"primaryKey"`
+	UserID       uint           `json:"user_id" gorm:"not null;index"`
+	RoleID       uint           `json:"role_id" gorm:"not null;index"`
+	Role         RoleDefinition `json:"role" gorm:"foreignKey:RoleID"`
+	DepartmentID *uint          `json:"department_id" gorm:"index"`
+	Department   *Department    `json:"department,omitempty" gorm:"foreignKey:DepartmentID"`
+	CreatedAt    time.Time      `json:"created_at"`
+}
+
+// TableName specifies the table name for UserRole model
+func (UserRole) TableName() string {
+	return "user_roles"
+}
@@ -1,6 +1,9 @@
 package models
 
 import (
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
 	"time"
 
 	"gorm.io/gorm"
@@ -10,7 +13,9 @@ type Product struct {
 	gorm.Model // ID, CreatedAt, UpdatedAt, DeletedAt
 
 	// ⭐ เพิ่ม ImageURL field
-	ImageURL *string `json:"image_url" gorm:"type:varchar(255)"` // URL ของรูปภาพ
+	ImageURL      *string        `json:"image_url" gorm:"type:varchar(255)"`          // URL ของรูปภาพ (ขนาด full)
+	ImageVariants ImageVariants  `json:"image_variants" gorm:"type:jsonb"`            // 🆕 URL แยกตามขนาด เช่น thumb, card, full
+	Media         []ProductMedia `json:"media,omitempty" gorm:"foreignKey:ProductID"` // 🆕 แกลเลอรีรูปและเอกสารแนบ
 
 	// ข้อมูลพื้นฐาน
 	Code         string   `json:"code" gorm:"unique;not null"`
@@ -27,11 +32,45 @@ type Product struct {
 	Unit     string        `json:"unit" gorm:"size:20;default:'ชิ้น'"`
 	Status   ProductStatus `json:"status" gorm:"default:'ACTIVE'"`
 
+	// ⭐ สำหรับแจ้งเตือนสต็อกต่ำและคำนวณการสั่งซื้อ
+	LeadTimeDays      *int       `json:"lead_time_days"`       // nil = ใช้ค่า REORDER_DEFAULT_LEAD_TIME_DAYS
+	LowStockAlertedAt *time.Time `json:"low_stock_alerted_at"` // แจ้งแล้วในรอบนี้ ล้างเมื่อเติมของเกินขั้นต่ำ
+
 	CreatedAt time.Time      `json:"created_at"`
 	UpdatedAt time.Time      `json:"updated_at"`
 	DeletedAt gorm.DeletedAt `json:"deleted_at" gorm:"index"`
 }
 
+// ImageVariants maps a variant name (thumb, card, full) to the URL of that rendition
+type ImageVariants map[string]string
+
+func (v ImageVariants) Value() (driver.Value, error) {
+	if len(v) == 0 {
+		return nil, nil
+	}
+	b, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	return string(b), nil
+}
+
+func (v *ImageVariants) Scan(src interface{}) error {
+	var data []byte
+	switch s := src.(type) {
+	case nil:
+		*v = nil
+		return nil
+	case []byte:
+		data = s
+	case string:
+		data = []byte(s)
+	default:
+		return fmt.Errorf("cannot scan %T into ImageVariants", src)
+	}
+	return json.Unmarshal(data, v)
+}
+
 // Product Status Enum
 type ProductStatus string
 
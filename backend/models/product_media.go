@@ -0,0 +1,31 @@
+package models
+
+import "time"
+
+// ProductMediaType distinguishes gallery images from document attachments
+type ProductMediaType string
+
+const (
+	ProductMediaImage    ProductMediaType = "IMAGE"
+	ProductMediaDocument ProductMediaType = "DOCUMENT" // เช่น spec sheet, คู่มือ (PDF)
+)
+
+// ProductMedia is one entry of a product's gallery or attachments, ordered by Position.
+// The primary image is mirrored to Product.ImageURL/ImageVariants for existing clients.
+type ProductMedia struct {
+	ID        uint             `json:"id" gorm:"primaryKey"`
+	ProductID uint             `json:"product_id" gorm:"not null;index"`
+	UploadID  uint             `json:"upload_id" gorm:"not null;index"`
+	Upload    Upload           `json:"upload" gorm:"foreignKey:UploadID;constraint:OnDelete:CASCADE"`
+	Type      ProductMediaType `json:"type" gorm:"size:20;not null"`
+	Caption   string           `json:"caption" gorm:"size:255"`
+	Position  int              `json:"position" gorm:"not null;default:0"`
+	IsPrimary bool             `json:"is_primary" gorm:"not null;default:false"`
+	CreatedAt time.Time        `json:"created_at"`
+	UpdatedAt time.Time        `json:"updated_at"`
+}
+
+// TableName specifies the table name for ProductMedia model
+func (ProductMedia) TableName() string {
+	return "product_media"
+}
@@ -0,0 +1,21 @@
+package models
+
+import (
+	"time"
+)
+
+// RecoveryCode is a single-use backup code for users who lose their authenticator device.
+// Only the SHA-256 hash of the code is stored.
+type RecoveryCode struct {
+	ID        uint       `json:"id" gorm:"primaryKey"`
+	UserID    uint       `json:"user_id" gorm:"not null;index"`
+	User      User       `json:"-" gorm:"foreignKey:UserID"`
+	CodeHash  string     `json:"-" gorm:"size:64;not null;index"`
+	UsedAt    *time.Time `json:"used_at"`
+	CreatedAt time.Time  `json:"created_at"`
+}
+
+// TableName specifies the table name for RecoveryCode model
+func (RecoveryCode) TableName() string {
+	return "user_recovery_codes"
+}
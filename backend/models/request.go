@@ -15,6 +15,7 @@ const (
 	RequestStatusRejected  RequestStatus = "REJECTED"
 	RequestStatusIssued    RequestStatus = "ISSUED"
 	RequestStatusCompleted RequestStatus = "COMPLETED"
+	RequestStatusCancelled RequestStatus = "CANCELLED"
 )
 
 type Request struct {
@@ -32,16 +33,19 @@ type Request struct {
 	IssuedDate    *time.Time
 	CompletedDate *time.Time
 
+	OverdueNotifiedAt *time.Time // ส่งอีเมลเตือนเรื่องยังไม่มารับของแล้วเมื่อไร
+
 	// Foreign Keys
 	UserID       uint
 	ApprovedByID *uint
 	IssuedByID   *uint
 
 	// Relationships
-	User       User
-	ApprovedBy *User `gorm:"foreignKey:ApprovedByID"`
-	IssuedBy   *User `gorm:"foreignKey:IssuedByID"`
-	Items      []RequestItem
+	User        User
+	ApprovedBy  *User `gorm:"foreignKey:ApprovedByID"`
+	IssuedBy    *User `gorm:"foreignKey:IssuedByID"`
+	Items       []RequestItem
+	Attachments []RequestAttachment // 🆕 บันทึกข้อความ/ใบเสนอราคาประกอบคำขอ
 }
 
 type RequestItem struct {
@@ -57,6 +61,16 @@ type RequestItem struct {
 	Product Product
 }
 
+// RequestComment is a message between the requester and staff on a request
+type RequestComment struct {
+	ID        uint      `json:"id" gorm:"primaryKey"`
+	RequestID uint      `json:"request_id" gorm:"not null;index"`
+	UserID    uint      `json:"user_id" gorm:"not null"`
+	User      User      `json:"user"`
+	Body      string    `json:"body" gorm:"type:text;not null"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
 // TableName specifies the table name for Request model
 func (Request) TableName() string {
 	return "requests"
@@ -72,3 +86,8 @@ func (r *Request) BeforeCreate(tx *gorm.DB) error {
 	// r.RequestNumber = generateRequestNumber() // You can implement this helper if needed
 	return nil
 }
+
+// TableName specifies the table name for RequestComment model
+func (RequestComment) TableName() string {
+	return "request_comments"
+}
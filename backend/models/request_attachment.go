@@ -0,0 +1,33 @@
+package models
+
+import "time"
+
+// RequestAttachmentType describes what a file attached to a request is
+type RequestAttachmentType string
+
+const (
+	RequestAttachmentMemo      RequestAttachmentType = "MEMO"      // บันทึกข้อความ
+	RequestAttachmentQuotation RequestAttachmentType = "QUOTATION" // ใบเสนอราคา
+	RequestAttachmentApproval  RequestAttachmentType = "APPROVAL"  // เอกสารอนุมัติ
+	RequestAttachmentOther     RequestAttachmentType = "OTHER"
+)
+
+// RequestAttachment is a supporting file (PDF or image) on a request. The file is kept
+// under the private storage prefix and is only downloadable by the requester and staff
+// who can view the request.
+type RequestAttachment struct {
+	ID           uint                  `json:"id" gorm:"primaryKey"`
+	RequestID    uint                  `json:"request_id" gorm:"not null;index"`
+	UploadID     uint                  `json:"upload_id" gorm:"not null;index"`
+	Upload       Upload                `json:"-" gorm:"foreignKey:UploadID;constraint:OnDelete:CASCADE"`
+	Type         RequestAttachmentType `json:"type" gorm:"size:20;not null"`
+	Caption      string                `json:"caption" gorm:"size:255"`
+	UploadedByID uint                  `json:"uploaded_by_id" gorm:"not null"`
+	UploadedBy   User                  `json:"uploaded_by" gorm:"foreignKey:UploadedByID"`
+	CreatedAt    time.Time             `json:"created_at"`
+}
+
+// TableName specifies the table name for RequestAttachment model
+func (RequestAttachment) TableName() string {
+	return "request_attachments"
+}
@@ -0,0 +1,111 @@
+package models
+
+import (
+	"net"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// ServiceAccount is a non-human identity used by integrations (procurement scripts, finance).
+// Each service account is backed by a User row with Provider "service" so existing handlers
+// that read the current user keep working; that user can never log in with a password.
+type ServiceAccount struct {
+	ID          uint           `json:"id" gorm:"primaryKey"`
+	Name        string         `json:"name" gorm:"uniqueIndex;size:100;not null"`
+	Description string         `json:"description" gorm:"type:text"`
+	UserID      uint           `json:"user_id" gorm:"not null;uniqueIndex"`
+	User        User           `json:"-" gorm:"foreignKey:UserID"`
+	CreatedByID *uint          `json:"created_by_id"`
+	IsActive    bool           `json:"is_active" gorm:"default:true"`
+	APIKeys     []APIKey       `json:"api_keys,omitempty" gorm:"foreignKey:ServiceAccountID"`
+	CreatedAt   time.Time      `json:"created_at"`
+	UpdatedAt   time.Time      `json:"updated_at"`
+	DeletedAt   gorm.DeletedAt `json:"-" gorm:"index"`
+}
+
+// TableName specifies the table name for ServiceAccount model
+func (ServiceAccount) TableName() string {
+	return "service_accounts"
+}
+
+// ProviderService marks users that back a service account
+const ProviderService = "service"
+
+// APIKey is a hashed credential of a service account, restricted to a declared permission set
+// and optionally to a list of source IPs/CIDRs.
+type APIKey struct {
+	ID               uint           `json:"id" gorm:"primaryKey"`
+	ServiceAccountID uint           `json:"service_account_id" gorm:"not null;index"`
+	ServiceAccount   ServiceAccount `json:"-" gorm:"foreignKey:ServiceAccountID"`
+	Name             string         `json:"name" gorm:"size:100"`
+	Prefix           string         `json:"prefix" gorm:"size:16;index"` // ส่วนต้นของ key ไว้แสดงให้ผู้ใช้จำได้
+	KeyHash          string         `json:"-" gorm:"uniqueIndex;size:64;not null"`
+	Permissions      []Permission   `json:"permissions,omitempty" gorm:"many2many:api_key_permissions;joinForeignKey:APIKeyID;joinReferences:PermissionID"`
+	AllowedIPs       string         `json:"allowed_ips" gorm:"type:text"` // คั่นด้วย comma, ว่าง = ทุก IP
+	ExpiresAt        *time.Time     `json:"expires_at"`
+	LastUsedAt       *time.Time     `json:"last_used_at"`
+	LastUsedIP       string         `json:"last_used_ip" gorm:"size:45"`
+	RevokedAt        *time.Time     `json:"revoked_at"`
+	CreatedAt        time.Time      `json:"created_at"`
+}
+
+// TableName specifies the table name for APIKey model
+func (APIKey) TableName() string {
+	return "api_keys"
+}
+
+// IsUsable reports whether the key is neither revoked nor expired
+func (k *APIKey) IsUsable(now time.Time) bool {
+	return k.RevokedAt == nil && (k.ExpiresAt == nil || now.Before(*k.ExpiresAt))
+}
+
+// AllowedIPList returns the configured IP allowlist entries
+func (k *APIKey) AllowedIPList() []string {
+	if strings.TrimSpace(k.AllowedIPs) == "" {
+		return nil
+	}
+	entries := strings.Split(k.AllowedIPs, ",")
+	result := make([]string, 0, len(entries))
+	for _, e := range entries {
+		if e = strings.TrimSpace(e); e != "" {
+			result = append(result, e)
+		}
+	}
+	return result
+}
+
+// AllowsIP reports whether the client IP matches the allowlist (an empty list allows all)
+func (k *APIKey) AllowsIP(clientIP string) bool {
+	entries := k.AllowedIPList()
+	if len(entries) == 0 {
+		return true
+	}
+
+	ip := net.ParseIP(clientIP)
+	if ip == nil {
+		return false
+	}
+	for _, entry := range entries {
+		if strings.Contains(entry, "/") {
+			if _, network, err := net.ParseCIDR(entry); err == nil && network.Contains(ip) {
+				return true
+			}
+			continue
+		}
+		if allowed := net.ParseIP(entry); allowed != nil && allowed.Equal(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+// PermissionCodes returns the permission codes granted to the key
+func (k *APIKey) PermissionCodes() []string {
+	codes := make([]string, 0, len(k.Permissions))
+	for _, p := range k.Permissions {
+		codes = append(codes, p.Code)
+	}
+	return codes
+}
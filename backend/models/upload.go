@@ -0,0 +1,44 @@
+package models
+
+import "time"
+
+// Entities that can reference an uploaded file
+const (
+	UploadRefProduct = "product"
+	UploadRefRequest = "request"
+	UploadRefAsset   = "asset"
+)
+
+// Upload records a file kept in object storage. Images are stored as a group of variants
+// below Key (e.g. products/product_1a2b3c4d_1700000000/thumb.jpg); other files are a single
+// object at Key. Identical uploads share one row, found by SHA256 of the original bytes.
+type Upload struct {
+	ID           uint          `json:"id" gorm:"primaryKey"`
+	Key          string        `json:"key" gorm:"size:512;not null;uniqueIndex"`
+	URL          string        `json:"url" gorm:"size:1024;not null;index"` // URL หลัก (รูปขนาด full)
+	Variants     ImageVariants `json:"variants,omitempty" gorm:"type:jsonb"`
+	SHA256       string        `json:"sha256" gorm:"size:64;not null;index"`
+	Size         int64         `json:"size"`
+	MimeType     string        `json:"mime_type" gorm:"size:100"`
+	OriginalName string        `json:"original_name" gorm:"size:255"`
+	Width        int           `json:"width,omitempty"`
+	Height       int           `json:"height,omitempty"`
+	OwnerID      *uint         `json:"owner_id" gorm:"index"`
+	Owner        *User         `json:"-" gorm:"foreignKey:OwnerID"`
+
+	// UnreferencedAt is when the last reference went away (or the upload time for files
+	// never attached); garbage collection waits a grace period from here
+	UnreferencedAt *time.Time `json:"unreferenced_at" gorm:"index"`
+
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// UploadReference marks an upload as used by one entity (product image, request attachment, ...)
+type UploadReference struct {
+	ID         uint      `json:"id" gorm:"primaryKey"`
+	UploadID   uint      `json:"upload_id" gorm:"not null;uniqueIndex:idx_upload_ref"`
+	EntityType string    `json:"entity_type" gorm:"size:30;not null;uniqueIndex:idx_upload_ref;index:idx_upload_ref_entity"`
+	EntityID   uint      `json:"entity_id" gorm:"not null;uniqueIndex:idx_upload_ref;index:idx_upload_ref_entity"`
+	CreatedAt  time.Time `json:"created_at"`
+}
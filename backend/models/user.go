@@ -9,22 +9,32 @@ import (
 
 // User represents a user in the system
 type User struct {
-	ID           uint           `json:"id" gorm:"primaryKey"`
-	Email        string         `json:"email" gorm:"uniqueIndex;not null"`
-	Name         string         `json:"name" gorm:"not null"`
-	Password     *string        `json:"-" gorm:"type:varchar(255)"` // Nullable for OAuth
-	Avatar       *string        `json:"avatar" gorm:"type:text"`
-	Role         Role           `json:"role" gorm:"type:varchar(20);default:'USER'"`
-	Provider     string         `json:"provider" gorm:"type:varchar(50);default:'local'"`
-	ProviderID   *string        `json:"provider_id" gorm:"type:varchar(255)"`
-	DepartmentID *uint          `json:"department_id" gorm:"index"`
-	Department   *Department    `json:"department,omitempty" gorm:"foreignKey:DepartmentID"`
-	Phone        *string        `json:"phone" gorm:"type:varchar(20)"`
-	IsActive     bool           `json:"is_active" gorm:"default:true"`
-	LastLoginAt  *time.Time     `json:"last_login_at"`
-	CreatedAt    time.Time      `json:"created_at"`
-	UpdatedAt    time.Time      `json:"updated_at"`
-	DeletedAt    gorm.DeletedAt `json:"-" gorm:"index"`
+	ID                  uint           `json:"id" gorm:"primaryKey"`
+	Email               string         `json:"email" gorm:"uniqueIndex;not null"`
+	Name                string         `json:"name" gorm:"not null"`
+	Password            *string        `json:"-" gorm:"type:varchar(255)"` // Nullable for OAuth
+	Avatar              *string        `json:"avatar" gorm:"type:text"`
+	Role                Role           `json:"role" gorm:"type:varchar(20);default:'USER'"`
+	Provider            string         `json:"provider" gorm:"type:varchar(50);default:'local'"`
+	ProviderID          *string        `json:"provider_id" gorm:"type:varchar(255)"`
+	DepartmentID        *uint          `json:"department_id" gorm:"index"`
+	Department          *Department    `json:"department,omitempty" gorm:"foreignKey:DepartmentID"`
+	Phone               *string        `json:"phone" gorm:"type:varchar(20)"`
+	Locale              string         `json:"locale" gorm:"type:varchar(5);default:'th'"` // ภาษาของอีเมลแจ้งเตือน
+	LineUserID          *string        `json:"-" gorm:"type:varchar(64);uniqueIndex"`      // ผูกผ่านรหัสจาก /profile
+	LineLinkedAt        *time.Time     `json:"line_linked_at"`
+	IsActive            bool           `json:"is_active" gorm:"default:true"`
+	LastLoginAt         *time.Time     `json:"last_login_at"`
+	EmailVerifiedAt     *time.Time     `json:"email_verified_at"`
+	TwoFactorEnabled    bool           `json:"two_factor_enabled" gorm:"default:false"`
+	TOTPSecret          *string        `json:"-" gorm:"type:varchar(64)"` // ตั้งไว้ตั้งแต่ setup แต่ใช้จริงเมื่อ TwoFactorEnabled
+	TwoFactorEnabledAt  *time.Time     `json:"two_factor_enabled_at"`
+	TOTPLastStep        *int64         `json:"-"` // time step ของรหัส TOTP ล่าสุดที่ใช้ไปแล้ว กันใช้รหัสซ้ำ
+	FailedLoginAttempts int            `json:"failed_login_attempts" gorm:"default:0"`
+	LockedUntil         *time.Time     `json:"locked_until"`
+	CreatedAt           time.Time      `json:"created_at"`
+	UpdatedAt           time.Time      `json:"updated_at"`
+	DeletedAt           gorm.DeletedAt `json:"-" gorm:"index"`
 }
 
 // Role enum for user roles
@@ -35,6 +45,12 @@ const (
 	RoleUser  Role = "USER"
 )
 
+// Supported values of User.Locale
+const (
+	LocaleThai    = "th"
+	LocaleEnglish = "en"
+)
+
 // TableName specifies the table name for User model
 func (User) TableName() string {
 	return "users"
@@ -75,6 +91,21 @@ func (u *User) IsOAuthUser() bool {
 	return u.Provider != "local"
 }
 
+// IsEmailVerified checks if the user has verified their email address
+func (u *User) IsEmailVerified() bool {
+	return u.EmailVerifiedAt != nil
+}
+
+// HasTwoFactor checks if the user has completed TOTP enrolment
+func (u *User) HasTwoFactor() bool {
+	return u.TwoFactorEnabled && u.TOTPSecret != nil
+}
+
+// IsLocked checks if the account is temporarily locked after repeated failed logins
+func (u *User) IsLocked(now time.Time) bool {
+	return u.LockedUntil != nil && now.Before(*u.LockedUntil)
+}
+
 // HasPassword checks if the user has a password set
 func (u *User) HasPassword() bool {
 	return u.Password != nil && *u.Password != ""
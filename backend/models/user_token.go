@@ -0,0 +1,37 @@
+package models
+
+import (
+	"time"
+)
+
+// TokenPurpose identifies what a one-time user token may be used for
+type TokenPurpose string
+
+const (
+	TokenPurposePasswordReset TokenPurpose = "PASSWORD_RESET"
+	TokenPurposeEmailVerify   TokenPurpose = "EMAIL_VERIFY"
+	TokenPurposeLineLink      TokenPurpose = "LINE_LINK" // รหัสสั้นที่ผู้ใช้พิมพ์ในแชท LINE
+)
+
+// UserToken is a single-use, time-limited token sent to a user (e.g. by e-mail).
+// Only the SHA-256 hash of the token is stored.
+type UserToken struct {
+	ID        uint         `json:"id" gorm:"primaryKey"`
+	UserID    uint         `json:"user_id" gorm:"not null;index"`
+	User      User         `json:"-" gorm:"foreignKey:UserID"`
+	Purpose   TokenPurpose `json:"purpose" gorm:"type:varchar(30);not null;index"`
+	TokenHash string       `json:"-" gorm:"uniqueIndex;size:64;not null"`
+	ExpiresAt time.Time    `json:"expires_at" gorm:"not null"`
+	UsedAt    *time.Time   `json:"used_at"`
+	CreatedAt time.Time    `json:"created_at"`
+}
+
+// TableName specifies the table name for UserToken model
+func (UserToken) TableName() string {
+	return "user_tokens"
+}
+
+// IsUsable reports whether the token has neither been used nor expired
+func (t *UserToken) IsUsable(now time.Time) bool {
+	return t.UsedAt == nil && now.Before(t.ExpiresAt)
+}
@@ -0,0 +1,109 @@
+package models
+
+import (
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Webhook event types sent to subscribers
+const (
+	WebhookEventRequestCreated       = "request.created"
+	WebhookEventRequestStatusChanged = "request.status_changed"
+	WebhookEventRequestApproved      = "request.approved"
+	WebhookEventStockChanged         = "stock.changed"
+	WebhookEventStockLow             = "stock.low"
+	WebhookEventPing                 = "ping" // ส่งจากปุ่มทดสอบเท่านั้น
+
+	WebhookEventAll = "*"
+)
+
+// WebhookEvents lists the events a subscription can choose with their descriptions
+var WebhookEvents = []struct {
+	Event       string `json:"event"`
+	Description string `json:"description"`
+}{
+	{WebhookEventRequestCreated, "มีคำขอเบิกใหม่"},
+	{WebhookEventRequestStatusChanged, "สถานะคำขอเบิกเปลี่ยน"},
+	{WebhookEventRequestApproved, "คำขอเบิกได้รับการอนุมัติ"},
+	{WebhookEventStockChanged, "จำนวนสต็อกเปลี่ยน"},
+	{WebhookEventStockLow, "สต็อกลดลงต่ำกว่าขั้นต่ำ"},
+}
+
+// WebhookSubscription is an external endpoint that receives signed event payloads.
+// The secret is kept in plain text because it is needed to compute each signature.
+type WebhookSubscription struct {
+	ID          uint           `json:"id" gorm:"primaryKey"`
+	Name        string         `json:"name" gorm:"size:100;not null"`
+	URL         string         `json:"url" gorm:"size:2048;not null"`
+	Secret      string         `json:"-" gorm:"size:255;not null"`
+	Events      string         `json:"events" gorm:"type:text;not null"` // คั่นด้วย comma, "*" = ทุก event
+	IsActive    bool           `json:"is_active" gorm:"default:true"`
+	CreatedByID *uint          `json:"created_by_id"`
+	CreatedAt   time.Time      `json:"created_at"`
+	UpdatedAt   time.Time      `json:"updated_at"`
+	DeletedAt   gorm.DeletedAt `json:"-" gorm:"index"`
+}
+
+// TableName specifies the table name for WebhookSubscription model
+func (WebhookSubscription) TableName() string {
+	return "webhook_subscriptions"
+}
+
+// EventList returns the subscribed event types
+func (w *WebhookSubscription) EventList() []string {
+	var events []string
+	for _, e := range strings.Split(w.Events, ",") {
+		if e = strings.TrimSpace(e); e != "" {
+			events = append(events, e)
+		}
+	}
+	return events
+}
+
+// Wants reports whether the subscription receives the given event type
+func (w *WebhookSubscription) Wants(event string) bool {
+	for _, e := range w.EventList() {
+		if e == WebhookEventAll || e == event {
+			return true
+		}
+	}
+	return false
+}
+
+// WebhookDeliveryStatus is the state of one queued webhook delivery
+type WebhookDeliveryStatus string
+
+const (
+	WebhookDeliveryPending   WebhookDeliveryStatus = "PENDING"
+	WebhookDeliverySucceeded WebhookDeliveryStatus = "SUCCEEDED"
+	WebhookDeliveryFailed    WebhookDeliveryStatus = "FAILED" // เกินจำนวนครั้งที่ลองส่งแล้ว
+)
+
+// WebhookDelivery is one event payload queued for one subscription. It doubles as the
+// delivery log: the last response code, body excerpt and error are kept on the row.
+type WebhookDelivery struct {
+	ID             uint                  `json:"id" gorm:"primaryKey"`
+	SubscriptionID uint                  `json:"subscription_id" gorm:"not null;index"`
+	EventID        string                `json:"event_id" gorm:"size:36;not null;index"` // เหมือนกันทุก subscription ของ event เดียวกัน
+	Event          string                `json:"event" gorm:"size:50;not null"`
+	Payload        string                `json:"payload" gorm:"type:text;not null"`
+	Status         WebhookDeliveryStatus `json:"status" gorm:"type:varchar(20);not null;default:'PENDING';index:idx_webhook_deliveries_due"`
+	Attempts       int                   `json:"attempts" gorm:"not null;default:0"`
+	NextAttemptAt  time.Time             `json:"next_attempt_at" gorm:"not null;index:idx_webhook_deliveries_due"`
+	LastAttemptAt  *time.Time            `json:"last_attempt_at"`
+	ResponseCode   *int                  `json:"response_code"`
+	ResponseBody   string                `json:"response_body" gorm:"type:text"`
+	DurationMs     int64                 `json:"duration_ms"`
+	LastError      string                `json:"last_error" gorm:"type:text"`
+	DeliveredAt    *time.Time            `json:"delivered_at"`
+	RedeliveryOfID *uint                 `json:"redelivery_of_id"`
+	CreatedAt      time.Time             `json:"created_at"`
+	UpdatedAt      time.Time             `json:"updated_at"`
+}
+
+// TableName specifies the table name for WebhookDelivery model
+func (WebhookDelivery) TableName() string {
+	return "webhook_deliveries"
+}
@@ -0,0 +1,23 @@
+package realtime
+
+import "ku-asset/auth"
+
+// Audience describes one connected client: who they are and where their permissions apply
+type Audience struct {
+	UserID uint
+	Scopes map[string]*auth.Scope // permission code -> department scope
+}
+
+// Accepts reports whether the client may receive ev. Events addressed to a user go to that
+// user; events with a permission go to holders whose scope covers the event's department;
+// events with neither are broadcast to everyone.
+func (a *Audience) Accepts(ev Event) bool {
+	if ev.UserID != nil && *ev.UserID == a.UserID {
+		return true
+	}
+	if ev.Permission == "" {
+		return ev.UserID == nil
+	}
+	scope := a.Scopes[ev.Permission]
+	return scope != nil && scope.Allows(ev.DepartmentID)
+}
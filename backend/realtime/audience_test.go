@@ -0,0 +1,44 @@
+package realtime
+
+import (
+	"testing"
+
+	"ku-asset/auth"
+)
+
+func TestAudienceAccepts(t *testing.T) {
+	owner, other := uint(1), uint(2)
+	dept, otherDept := uint(5), uint(9)
+
+	staff := &Audience{UserID: 3, Scopes: map[string]*auth.Scope{
+		"request.view": {DepartmentIDs: []uint{dept}},
+	}}
+	manager := &Audience{UserID: 4, Scopes: map[string]*auth.Scope{
+		"product.manage": auth.GlobalScope(),
+	}}
+	user := &Audience{UserID: owner, Scopes: map[string]*auth.Scope{}}
+
+	tests := []struct {
+		name     string
+		audience *Audience
+		event    Event
+		want     bool
+	}{
+		{"own event", user, Event{UserID: &owner}, true},
+		{"someone else's event", user, Event{UserID: &other}, false},
+		{"broadcast", user, Event{}, true},
+		{"staff event without permission", user, Event{Permission: "product.manage"}, false},
+		{"staff event in scope", staff, Event{Permission: "request.view", DepartmentID: &dept}, true},
+		{"staff event out of scope", staff, Event{Permission: "request.view", DepartmentID: &otherDept}, false},
+		{"staff event without department needs a global grant", staff, Event{Permission: "request.view"}, false},
+		{"global holder", manager, Event{Permission: "product.manage"}, true},
+		{"owner always receives their own staff event", user, Event{UserID: &owner, Permission: "request.view", DepartmentID: &otherDept}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.audience.Accepts(tt.event); got != tt.want {
+				t.Errorf("Accepts() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
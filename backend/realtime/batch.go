@@ -0,0 +1,46 @@
+package realtime
+
+import (
+	"context"
+	"sync"
+)
+
+// Batch holds events raised inside a database transaction until it commits,
+// so clients never hear about changes that were rolled back
+type Batch struct {
+	mu     sync.Mutex
+	events []Event
+}
+
+type batchKey struct{}
+
+// WithBatch returns a context that collects events passed to Emit
+func WithBatch(ctx context.Context) (context.Context, *Batch) {
+	b := &Batch{}
+	return context.WithValue(ctx, batchKey{}, b), b
+}
+
+// Emit queues ev on the batch in ctx, or publishes it right away when there is none
+func Emit(ctx context.Context, hub Hub, ev Event) {
+	if ctx != nil {
+		if b, ok := ctx.Value(batchKey{}).(*Batch); ok {
+			b.mu.Lock()
+			b.events = append(b.events, ev)
+			b.mu.Unlock()
+			return
+		}
+	}
+	hub.Publish(ev)
+}
+
+// Flush publishes the collected events; call it after the transaction commits
+func (b *Batch) Flush(hub Hub) {
+	b.mu.Lock()
+	events := b.events
+	b.events = nil
+	b.mu.Unlock()
+
+	for _, ev := range events {
+		hub.Publish(ev)
+	}
+}
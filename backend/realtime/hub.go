@@ -0,0 +1,110 @@
+// Package realtime fans out server events (new requests, status and stock changes,
+// notifications) to connected clients. The in-process hub serves a single instance;
+// the Postgres hub relays events through LISTEN/NOTIFY so every instance sees them.
+package realtime
+
+import (
+	"log"
+	"os"
+	"strings"
+	"sync"
+
+	"gorm.io/gorm"
+)
+
+// Event types pushed to clients
+const (
+	EventRequestCreated       = "request.created"
+	EventRequestStatusChanged = "request.status_changed"
+	EventStockChanged         = "stock.changed"
+	EventNotification         = "notification"
+)
+
+// Event is one message for subscribers. UserID, Permission and DepartmentID decide who
+// receives it (see Audience); only Type and Data are sent to the client.
+type Event struct {
+	Type         string      `json:"type"`
+	Data         interface{} `json:"data"`
+	UserID       *uint       `json:"user_id,omitempty"`
+	Permission   string      `json:"permission,omitempty"`
+	DepartmentID *uint       `json:"department_id,omitempty"`
+}
+
+// Hub publishes events to subscribers. Implementations must be safe for concurrent use.
+type Hub interface {
+	Publish(ev Event)
+	Subscribe(accept func(Event) bool) *Subscription
+}
+
+// Subscription receives the events its filter accepts on C until Close is called
+type Subscription struct {
+	C <-chan Event
+
+	ch     chan Event
+	accept func(Event) bool
+	hub    *memoryHub
+	once   sync.Once
+}
+
+// Close stops delivery and releases the subscription
+func (s *Subscription) Close() {
+	s.once.Do(func() {
+		s.hub.mu.Lock()
+		delete(s.hub.subs, s)
+		s.hub.mu.Unlock()
+	})
+}
+
+const subscriberBuffer = 32
+
+// memoryHub delivers events to subscribers of this process only
+type memoryHub struct {
+	mu   sync.RWMutex
+	subs map[*Subscription]struct{}
+}
+
+func NewMemoryHub() Hub {
+	return newMemoryHub()
+}
+
+func newMemoryHub() *memoryHub {
+	return &memoryHub{subs: make(map[*Subscription]struct{})}
+}
+
+func (h *memoryHub) Subscribe(accept func(Event) bool) *Subscription {
+	ch := make(chan Event, subscriberBuffer)
+	sub := &Subscription{C: ch, ch: ch, accept: accept, hub: h}
+
+	h.mu.Lock()
+	h.subs[sub] = struct{}{}
+	h.mu.Unlock()
+	return sub
+}
+
+func (h *memoryHub) Publish(ev Event) {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	for sub := range h.subs {
+		if sub.accept != nil && !sub.accept(ev) {
+			continue
+		}
+		// ⭐ client ที่อ่านช้าจะไม่ทำให้ hub ค้าง - ทิ้ง event แทน (client โหลดข้อมูลใหม่ได้เอง)
+		select {
+		case sub.ch <- ev:
+		default:
+			log.Printf("⚠️ Realtime: dropped %s event for a slow subscriber", ev.Type)
+		}
+	}
+}
+
+// NewFromEnv builds the hub selected by REALTIME_BROKER (memory or postgres; default memory)
+func NewFromEnv(db *gorm.DB, dsn string) Hub {
+	switch strings.ToLower(os.Getenv("REALTIME_BROKER")) {
+	case "postgres":
+		log.Println("📡 Realtime: Postgres LISTEN/NOTIFY")
+		return NewPostgresHub(db, dsn)
+	default:
+		log.Println("📡 Realtime: in-process hub (set REALTIME_BROKER=postgres for multiple instances)")
+		return NewMemoryHub()
+	}
+}
@@ -0,0 +1,92 @@
+package realtime
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"gorm.io/gorm"
+)
+
+// notifyChannel is the Postgres channel shared by all backend instances
+const notifyChannel = "ku_asset_events"
+
+// maxNotifyPayload stays under Postgres' 8000 byte NOTIFY limit
+const maxNotifyPayload = 7900
+
+// postgresHub publishes with pg_notify and delivers what it hears on LISTEN to local subscribers
+type postgresHub struct {
+	*memoryHub
+	db  *gorm.DB
+	dsn string
+}
+
+// NewPostgresHub starts listening on notifyChannel with a dedicated connection
+func NewPostgresHub(db *gorm.DB, dsn string) Hub {
+	h := &postgresHub{memoryHub: newMemoryHub(), db: db, dsn: dsn}
+	go h.listen(context.Background())
+	return h
+}
+
+func (h *postgresHub) Publish(ev Event) {
+	payload, err := json.Marshal(ev)
+	if err != nil {
+		log.Printf("❌ Realtime: cannot encode %s event: %v", ev.Type, err)
+		return
+	}
+	if len(payload) > maxNotifyPayload {
+		log.Printf("⚠️ Realtime: %s event too large for NOTIFY (%d bytes), delivering locally only", ev.Type, len(payload))
+		h.memoryHub.Publish(ev)
+		return
+	}
+	if err := h.db.Exec("SELECT pg_notify(?, ?)", notifyChannel, string(payload)).Error; err != nil {
+		log.Printf("❌ Realtime: pg_notify failed, delivering locally only: %v", err)
+		h.memoryHub.Publish(ev)
+	}
+}
+
+// listen keeps a LISTEN connection open, reconnecting with backoff
+func (h *postgresHub) listen(ctx context.Context) {
+	backoff := time.Second
+	for ctx.Err() == nil {
+		if err := h.listenOnce(ctx); err != nil {
+			log.Printf("❌ Realtime: LISTEN connection lost, retrying in %s: %v", backoff, err)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(backoff):
+		}
+		if backoff < 30*time.Second {
+			backoff *= 2
+		}
+	}
+}
+
+func (h *postgresHub) listenOnce(ctx context.Context) error {
+	conn, err := pgx.Connect(ctx, h.dsn)
+	if err != nil {
+		return err
+	}
+	defer conn.Close(context.Background())
+
+	if _, err := conn.Exec(ctx, "LISTEN "+notifyChannel); err != nil {
+		return err
+	}
+	log.Printf("📡 Realtime: listening on %s", notifyChannel)
+
+	for {
+		n, err := conn.WaitForNotification(ctx)
+		if err != nil {
+			return err
+		}
+		var ev Event
+		if err := json.Unmarshal([]byte(n.Payload), &ev); err != nil {
+			log.Printf("⚠️ Realtime: ignoring malformed event: %v", err)
+			continue
+		}
+		h.memoryHub.Publish(ev)
+	}
+}
@@ -3,6 +3,7 @@ package routes
 import (
 	"ku-asset/controllers"
 	"ku-asset/middleware"
+	"ku-asset/models"
 	"net/http"
 
 	"github.com/gin-gonic/gin"
@@ -22,54 +23,141 @@ func SetupRoutes(r *gin.Engine, controllers *controllers.Controllers) {
 	api := r.Group("/api/v1")
 	setupAPIRoutes(api, controllers)
 
+	// 🆕 ตรวจสอบใบเบิกจาก QR code บนเอกสาร (สาธารณะ ใช้ลายเซ็นใน ?sig=)
+	r.GET("/verify/requests/:number", middleware.VerifyRateLimiter.Middleware(), controllers.Request.VerifyRequest)
+
 	// ตั้งค่า web routes (สำหรับ serve static files)
-	setupWebRoutes(r)
+	setupWebRoutes(r, controllers)
 }
 
 // setupAPIRoutes เป็นตัวประสานงาน เรียกฟังก์ชันย่อยเพื่อตั้งค่า Route แต่ละกลุ่ม
 func setupAPIRoutes(api *gin.RouterGroup, c *controllers.Controllers) {
-	setupAuthRoutes(api.Group("/auth"), c.Auth)
+	setupAuthRoutes(api.Group("/auth"), c.Auth, c.Provisioning)
 	setupAdminRoutes(api.Group("/admin"), c)
 	setupProtectedRoutes(api.Group(""), c)
+
+	// 🆕 LINE Messaging API webhook - ตรวจ X-Line-Signature แทนการล็อกอิน
+	api.POST("/line/webhook", c.Line.Webhook)
+
+	// 🆕 Server-Sent Events - EventSource ตั้ง header ไม่ได้ จึงรับ token ผ่าน ?access_token= ได้ด้วย
+	api.GET("/stream", middleware.TokenFromQuery(), middleware.AuthMiddleware(), c.Stream.Stream)
 }
 
 // setupAuthRoutes จัดการ Route ที่ไม่ต้องมีการยืนยันตัวตน
-func setupAuthRoutes(group *gin.RouterGroup, authController *controllers.AuthController) {
-	group.POST("/login", authController.Login)
+func setupAuthRoutes(group *gin.RouterGroup, authController *controllers.AuthController, provisioningController *controllers.ProvisioningController) {
 	group.POST("/register", authController.Register)
 	group.POST("/refresh", authController.RefreshToken)
 	group.POST("/oauth/google", authController.GoogleOAuth)
+	group.POST("/verify-email", authController.VerifyEmail)
+	group.GET("/invitations/:token", provisioningController.GetInvitation)
+
+	// ⭐ จำกัดจำนวนครั้งต่อ IP สำหรับ endpoint ที่ถูกเดารหัสได้
+	limited := group.Group("", middleware.AuthRateLimiter.Middleware())
+	limited.POST("/login", authController.Login)
+	limited.POST("/2fa/verify", authController.VerifyMFA)
+	limited.POST("/forgot-password", authController.ForgotPassword)
+	limited.POST("/reset-password", authController.ResetPassword)
+	limited.POST("/resend-verification", authController.ResendVerification)
+	limited.POST("/invitations/accept", provisioningController.AcceptInvitation)
 }
 
-// setupAdminRoutes จัดการ Route ที่ต้องใช้สิทธิ์ "ADMIN" เท่านั้น
+// setupAdminRoutes จัดการ Route ฝั่งผู้ดูแล โดยตรวจสิทธิ์ราย permission
 func setupAdminRoutes(group *gin.RouterGroup, c *controllers.Controllers) {
-	// ⭐ ตรวจสอบลำดับ middleware - AuthMiddleware ต้องมาก่อน
+	// ⭐ ตรวจสอบลำดับ middleware - AuthMiddleware ต้องมาก่อน RequirePermission
 	protected := group.Group("")
-	protected.Use(middleware.AuthMiddleware())  // ❶ ใส่ auth ก่อน
-	protected.Use(middleware.AdminMiddleware()) // ❷ แล้วค่อย admin
+	protected.Use(middleware.AuthMiddleware())
+	protected.Use(middleware.RequireTwoFactorEnrollment()) // ⭐ REQUIRE_ADMIN_2FA policy
 	{
 		// Dashboard Routes
-		protected.GET("/stats", c.Dashboard.GetAdminStats)
-		protected.GET("/activity", c.Dashboard.GetRecentActivity)
-		protected.GET("/system-stats", c.Dashboard.GetSystemStats)
+		reports := protected.Group("", middleware.RequirePermission(models.PermReportView))
+		reports.GET("/stats", c.Dashboard.GetAdminStats)
+		reports.GET("/activity", c.Dashboard.GetRecentActivity)
+		reports.GET("/system-stats", c.Dashboard.GetSystemStats)
 
 		// Admin User Management
-		protected.GET("/users", c.User.GetUsers)
-		protected.GET("/users/:id", c.User.GetUser)
-		protected.PUT("/users/:id", c.User.UpdateUser)
-		protected.DELETE("/users/:id", c.User.DeleteUser)
+		users := protected.Group("/users", middleware.RequirePermission(models.PermUserManage))
+		users.GET("", c.User.GetUsers)
+		users.POST("/import", c.Provisioning.ImportUsers)
+		users.GET("/:id", c.User.GetUser)
+		users.PUT("/:id", c.User.UpdateUser)
+		users.DELETE("/:id", c.User.DeleteUser)
+		users.POST("/:id/unlock", c.User.UnlockUser)
+		users.GET("/:id/login-attempts", c.User.GetLoginAttempts)
+		users.POST("/:id/impersonate", c.Impersonation.Impersonate)
+
+		// User Invitations
+		invitations := protected.Group("/invitations", middleware.RequirePermission(models.PermUserManage))
+		invitations.GET("", c.Provisioning.GetInvitations)
+		invitations.POST("", c.Provisioning.CreateInvitation)
+		invitations.DELETE("/:id", c.Provisioning.RevokeInvitation)
+
+		// Impersonation audit trail
+		impersonations := protected.Group("/impersonations", middleware.RequirePermission(models.PermUserManage))
+		impersonations.GET("", c.Impersonation.GetSessions)
+		impersonations.GET("/:id", c.Impersonation.GetSession)
+		impersonations.POST("/:id/end", c.Impersonation.EndSession)
+
+		// User Role Assignment
+		userRoles := protected.Group("/users/:id/roles", middleware.RequirePermission(models.PermRoleManage))
+		userRoles.GET("", c.Role.GetUserRoles)
+		userRoles.POST("", c.Role.AssignRole)
+		userRoles.DELETE("/:roleId", c.Role.RemoveRole)
+
+		// Audit trail
+		auditLogs := protected.Group("/audit", middleware.RequirePermission(models.PermAuditView))
+		auditLogs.GET("", c.Audit.GetLogs)
+		auditLogs.GET("/verify", c.Audit.VerifyChain)
+
+		// Outgoing Webhooks
+		webhooks := protected.Group("/webhooks", middleware.RequirePermission(models.PermWebhookManage))
+		webhooks.GET("", c.Webhook.GetWebhooks)
+		webhooks.GET("/events", c.Webhook.GetWebhookEvents)
+		webhooks.POST("", c.Webhook.CreateWebhook)
+		webhooks.GET("/:id", c.Webhook.GetWebhook)
+		webhooks.PUT("/:id", c.Webhook.UpdateWebhook)
+		webhooks.DELETE("/:id", c.Webhook.DeleteWebhook)
+		webhooks.POST("/:id/ping", c.Webhook.Ping)
+		webhooks.GET("/:id/deliveries", c.Webhook.GetDeliveries)
+		webhooks.POST("/:id/deliveries/:deliveryId/redeliver", c.Webhook.Redeliver)
+
+		// Low stock & reorder suggestions
+		stock := protected.Group("/stock", middleware.RequirePermission(models.PermStockAdjust))
+		stock.GET("/low-stock", c.Stock.GetLowStock)
+		stock.GET("/reorder-suggestions", c.Stock.GetReorderSuggestions)
+		stock.GET("/reorder-suggestions/export", c.Stock.ExportPurchaseDraft)
 
-		// Admin Request Management
-		protected.GET("/requests", c.Request.GetAllRequests)
-		protected.PUT("/requests/:id/status", c.Request.UpdateRequestStatus)
-		protected.GET("/requests/:id/pdf", c.Request.DownloadRequestPDF) // ⭐ เพิ่มบรรทัดนี้
+		// Role & Permission Management
+		roles := protected.Group("", middleware.RequirePermission(models.PermRoleManage))
+		roles.GET("/permissions", c.Role.GetPermissions)
+		roles.GET("/roles", c.Role.GetRoles)
+		roles.GET("/roles/:id", c.Role.GetRole)
+		// ⭐ แก้ไขนิยาม role มีผลทั้งระบบ - role.manage ระดับหน่วยงานมอบ role ได้อย่างเดียว
+		roles.POST("/roles", middleware.RequireGlobalPermission(models.PermRoleManage), c.Role.CreateRole)
+		roles.PUT("/roles/:id", middleware.RequireGlobalPermission(models.PermRoleManage), c.Role.UpdateRole)
+		roles.DELETE("/roles/:id", middleware.RequireGlobalPermission(models.PermRoleManage), c.Role.DeleteRole)
+
+		// Service Accounts & API Keys
+		serviceAccounts := protected.Group("/service-accounts", middleware.RequirePermission(models.PermServiceAccountManage))
+		serviceAccounts.GET("", c.ServiceAccount.GetServiceAccounts)
+		serviceAccounts.GET("/:id", c.ServiceAccount.GetServiceAccount)
+		serviceAccounts.POST("", c.ServiceAccount.CreateServiceAccount)
+		serviceAccounts.DELETE("/:id", c.ServiceAccount.DeleteServiceAccount)
+		serviceAccounts.POST("/:id/keys", c.ServiceAccount.CreateAPIKey)
+		serviceAccounts.DELETE("/:id/keys/:keyId", c.ServiceAccount.RevokeAPIKey)
+
+		// Admin Request Management (สิทธิ์อนุมัติ/จ่ายของตรวจใน controller ตามสถานะ)
+		requests := protected.Group("/requests", middleware.RequirePermission(models.PermRequestView))
+		requests.GET("", c.Request.GetAllRequests)
+		requests.PUT("/:id/status", c.Request.UpdateRequestStatus)
+		requests.GET("/:id/pdf", c.Request.DownloadRequestPDF)
 
 		// Department Management
-		protected.GET("/departments", c.Department.GetDepartments)
-		protected.GET("/departments/:id", c.Department.GetDepartment)
-		protected.POST("/departments", c.Department.CreateDepartment) // เพิ่มเส้นทางนี้
-		protected.PATCH("/departments/:id", c.Department.UpdateDepartment)
-		protected.DELETE("/departments/:id", c.Department.DeleteDepartment)
+		departments := protected.Group("/departments")
+		departments.GET("", c.Department.GetDepartments)
+		departments.GET("/:id", c.Department.GetDepartment)
+		departments.POST("", middleware.RequirePermission(models.PermDepartmentManage), c.Department.CreateDepartment)
+		departments.PATCH("/:id", middleware.RequirePermission(models.PermDepartmentManage), c.Department.UpdateDepartment)
+		departments.DELETE("/:id", middleware.RequirePermission(models.PermDepartmentManage), c.Department.DeleteDepartment)
 	}
 }
 
@@ -85,6 +173,17 @@ func setupProtectedRoutes(group *gin.RouterGroup, c *controllers.Controllers) {
 			profile.PATCH("", c.User.UpdateProfile) // เพิ่ม PATCH method
 			profile.POST("/change-password", c.User.ChangePassword)
 			profile.GET("/stats", c.User.GetUserStats) // New stats endpoint
+
+			// Two-factor authentication (TOTP)
+			profile.GET("/2fa", c.TwoFactor.GetStatus)
+			profile.POST("/2fa/setup", c.TwoFactor.Setup)
+			profile.POST("/2fa/confirm", c.TwoFactor.Confirm)
+			profile.POST("/2fa/disable", c.TwoFactor.Disable)
+			profile.POST("/2fa/recovery-codes", c.TwoFactor.RegenerateRecoveryCodes)
+
+			// LINE account link (ส่งรหัสให้ bot ภายในเวลาที่กำหนด)
+			profile.POST("/line/link-code", c.Line.CreateLinkCode)
+			profile.DELETE("/line", c.Line.Unlink)
 		}
 
 		// --- Product Routes ---
@@ -93,15 +192,25 @@ func setupProtectedRoutes(group *gin.RouterGroup, c *controllers.Controllers) {
 			products.GET("", c.Product.GetProducts)
 			products.GET("/:id", c.Product.GetProduct)
 
-			// Admin only routes
-			products.POST("", middleware.AuthorizeRole("ADMIN"), c.Product.CreateProduct)
-			products.PUT("/:id", middleware.AuthorizeRole("ADMIN"), c.Product.UpdateProduct)
-			products.DELETE("/:id", middleware.AuthorizeRole("ADMIN"), c.Product.DeleteProduct)
+			// Product management routes
+			products.POST("", middleware.RequirePermission(models.PermProductManage), c.Product.CreateProduct)
+			products.PUT("/:id", middleware.RequirePermission(models.PermProductManage), c.Product.UpdateProduct)
+			products.DELETE("/:id", middleware.RequirePermission(models.PermProductManage), c.Product.DeleteProduct)
+			products.POST("/:id/stock", middleware.RequirePermission(models.PermStockAdjust), c.Product.AdjustStock)
+
+			// 🆕 Product gallery & document attachments
+			products.GET("/:id/media", c.ProductMedia.GetMedia)
+			media := products.Group("/:id/media", middleware.RequirePermission(models.PermProductManage))
+			media.POST("/images", middleware.UploadRateLimiter.Middleware(), middleware.UploadHourlyLimiter.Middleware(), c.ProductMedia.UploadImage)
+			media.POST("/documents", middleware.UploadRateLimiter.Middleware(), middleware.UploadHourlyLimiter.Middleware(), c.ProductMedia.UploadDocument)
+			media.POST("/reorder", c.ProductMedia.ReorderMedia)
+			media.PUT("/:mediaId", c.ProductMedia.UpdateMedia)
+			media.DELETE("/:mediaId", c.ProductMedia.DeleteMedia)
 		}
 
-		// ⭐ Upload Routes (Admin only with rate limiting)
+		// ⭐ Upload Routes (product managers only with rate limiting)
 		upload := group.Group("/upload")
-		upload.Use(middleware.AuthorizeRole("ADMIN"))
+		upload.Use(middleware.RequirePermission(models.PermProductManage))
 		upload.Use(middleware.UploadRateLimiter.Middleware())   // 10 uploads per minute
 		upload.Use(middleware.UploadHourlyLimiter.Middleware()) // 50 uploads per hour
 		{
@@ -115,6 +224,26 @@ func setupProtectedRoutes(group *gin.RouterGroup, c *controllers.Controllers) {
 			requests.POST("", c.Request.CreateRequest)   // ✅ User สร้างคำขอ
 			requests.GET("/my", c.Request.GetMyRequests) // ✅ User ดูคำขอของตัวเอง
 			requests.GET("/:id", c.Request.GetRequest)   // ✅ User ดูรายละเอียดคำขอของตัวเอง
+			requests.GET("/:id/comments", c.Request.GetComments)
+			requests.POST("/:id/comments", c.Request.AddComment)
+
+			// 🆕 เอกสารแนบ (บันทึกข้อความ/ใบเสนอราคา) - ผู้ขอและผู้มีสิทธิ์ดูคำขอเท่านั้น
+			requests.POST("/attachments", middleware.UploadRateLimiter.Middleware(), middleware.UploadHourlyLimiter.Middleware(), c.RequestAttachment.StageAttachment)
+			requests.GET("/:id/attachments", c.RequestAttachment.GetAttachments)
+			requests.POST("/:id/attachments", middleware.UploadRateLimiter.Middleware(), middleware.UploadHourlyLimiter.Middleware(), c.RequestAttachment.AddAttachment)
+			requests.GET("/:id/attachments/:attachmentId", c.RequestAttachment.DownloadAttachment)
+			requests.DELETE("/:id/attachments/:attachmentId", c.RequestAttachment.DeleteAttachment)
+		}
+
+		// --- Notification Center ---
+		notifications := group.Group("/notifications")
+		{
+			notifications.GET("", c.Notification.GetNotifications)
+			notifications.GET("/unread-count", c.Notification.GetUnreadCount)
+			notifications.POST("/read-all", c.Notification.MarkAllRead)
+			notifications.POST("/:id/read", c.Notification.MarkRead)
+			notifications.GET("/preferences", c.Notification.GetPreferences)
+			notifications.PUT("/preferences", c.Notification.UpdatePreferences)
 		}
 
 		// --- Category & Department Routes ---
@@ -127,7 +256,9 @@ func setupProtectedRoutes(group *gin.RouterGroup, c *controllers.Controllers) {
 }
 
 // setupWebRoutes จัดการ Route สำหรับการ serve static files
-func setupWebRoutes(r *gin.Engine) {
+func setupWebRoutes(r *gin.Engine, c *controllers.Controllers) {
 	r.Static("/static", "./static")
-	r.Static("/uploads", "./uploads")
+	// ⭐ ไฟล์อัปโหลดอ่านผ่าน storage backend (local disk หรือ S3) แทน r.Static
+	r.GET("/uploads/*key", c.Upload.ServeFile)
+	r.HEAD("/uploads/*key", c.Upload.ServeFile)
 }
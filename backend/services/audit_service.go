@@ -0,0 +1,111 @@
+package services
+
+import (
+	"encoding/json"
+	"ku-asset/audit"
+	"ku-asset/dto"
+	"ku-asset/models"
+	"math"
+
+	"gorm.io/gorm"
+)
+
+type AuditService interface {
+	GetLogs(query *dto.AuditLogQuery) (*dto.PaginatedAuditLogResponse, error)
+	VerifyChain() (*audit.VerifyResult, error)
+}
+
+type auditService struct {
+	db *gorm.DB
+}
+
+func NewAuditService(db *gorm.DB) AuditService {
+	return &auditService{db: db}
+}
+
+func (s *auditService) GetLogs(query *dto.AuditLogQuery) (*dto.PaginatedAuditLogResponse, error) {
+	q := s.db.Model(&models.AuditLog{})
+	if query.ActorID != nil {
+		q = q.Where("actor_id = ? OR impersonator_id = ?", *query.ActorID, *query.ActorID)
+	}
+	if query.Action != "" {
+		q = q.Where("action = ?", query.Action)
+	}
+	if query.EntityType != "" {
+		q = q.Where("entity_type = ?", query.EntityType)
+	}
+	if query.EntityID != "" {
+		q = q.Where("entity_id = ?", query.EntityID)
+	}
+	if query.RequestID != "" {
+		q = q.Where("request_id = ?", query.RequestID)
+	}
+	if query.From != nil {
+		q = q.Where("created_at >= ?", *query.From)
+	}
+	if query.To != nil {
+		q = q.Where("created_at < ?", *query.To)
+	}
+
+	var total int64
+	if err := q.Count(&total).Error; err != nil {
+		return nil, err
+	}
+
+	limit := query.Limit
+	if limit <= 0 || limit > 200 {
+		limit = 50
+	}
+	page := query.Page
+	if page < 1 {
+		page = 1
+	}
+
+	var logs []models.AuditLog
+	if err := q.Order("id DESC").Offset((page - 1) * limit).Limit(limit).Find(&logs).Error; err != nil {
+		return nil, err
+	}
+
+	response := make([]dto.AuditLogResponse, 0, len(logs))
+	for i := range logs {
+		response = append(response, mapAuditLogToResponse(&logs[i]))
+	}
+	return &dto.PaginatedAuditLogResponse{
+		Logs: response,
+		Pagination: dto.PaginationResponse{
+			CurrentPage: page,
+			PerPage:     limit,
+			Total:       total,
+			TotalPages:  int64(math.Ceil(float64(total) / float64(limit))),
+		},
+	}, nil
+}
+
+// VerifyChain recomputes the hash chain and reports the first tampered entry
+func (s *auditService) VerifyChain() (*audit.VerifyResult, error) {
+	return audit.Verify(s.db)
+}
+
+func mapAuditLogToResponse(l *models.AuditLog) dto.AuditLogResponse {
+	changes := json.RawMessage(l.Changes)
+	if !json.Valid(changes) {
+		changes = json.RawMessage("null")
+	}
+	return dto.AuditLogResponse{
+		ID:             l.ID,
+		ActorID:        l.ActorID,
+		ActorEmail:     l.ActorEmail,
+		ImpersonatorID: l.ImpersonatorID,
+		APIKeyID:       l.APIKeyID,
+		Action:         l.Action,
+		EntityType:     l.EntityType,
+		EntityID:       l.EntityID,
+		Changes:        changes,
+		IPAddress:      l.IPAddress,
+		UserAgent:      l.UserAgent,
+		RequestID:      l.RequestID,
+		PrevHash:       l.PrevHash,
+		Hash:           l.Hash,
+		CreatedAt:      l.CreatedAt,
+	}
+}
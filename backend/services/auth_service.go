@@ -2,8 +2,12 @@ package services
 
 import (
 	"errors"
+	"fmt"
+	"ku-asset/auth"
 	"ku-asset/dto"
+	"ku-asset/mailer"
 	"ku-asset/models"
+	"log"
 	"os"
 	"time"
 
@@ -14,19 +18,36 @@ import (
 
 // AuthService defines the interface for authentication services.
 type AuthService interface {
-	Login(req *dto.LoginRequest) (*dto.AuthResponse, error)
+	Login(req *dto.LoginRequest, client *dto.ClientInfo) (*dto.AuthResponse, error)
 	Register(req *dto.RegisterRequest) (*dto.UserResponse, error)
 	RefreshToken(tokenString string) (string, error)
-	FindOrCreateUserByGoogle(req *dto.GoogleOAuthRequest) (*dto.AuthResponse, error)
+	FindOrCreateUserByGoogle(req *dto.GoogleOAuthRequest, client *dto.ClientInfo) (*dto.AuthResponse, error)
+
+	// Password reset & email verification
+	ForgotPassword(req *dto.ForgotPasswordRequest) error
+	ResetPassword(req *dto.ResetPasswordRequest) error
+	VerifyEmail(req *dto.VerifyEmailRequest) error
+	ResendVerification(req *dto.ResendVerificationRequest) error
+
+	// Two-factor login challenge
+	VerifyMFA(req *dto.MFAVerifyRequest, client *dto.ClientInfo) (*dto.AuthResponse, error)
 }
 
+var (
+	ErrEmailNotVerified = errors.New("email address has not been verified")
+	ErrInvalidMFAToken  = errors.New("invalid or expired MFA token")
+)
+
+const mfaChallengePurpose = "mfa_challenge"
+
 type authService struct {
-	db *gorm.DB
+	db     *gorm.DB
+	mailer mailer.Mailer
 }
 
 // NewAuthService is the constructor for authService.
-func NewAuthService(db *gorm.DB) AuthService { // 👈 return เป็น interface
-	return &authService{db: db}
+func NewAuthService(db *gorm.DB, m mailer.Mailer) AuthService { // 👈 return เป็น interface
+	return &authService{db: db, mailer: m}
 }
 
 // RefreshToken validates the refresh token and issues a new access token.
@@ -45,18 +66,24 @@ func (s *authService) RefreshToken(tokenString string) (string, error) {
 		return "", errors.New("invalid token claims")
 	}
 
-	userID := uint(claims["user_id"].(float64))
+	userIDFloat, ok := claims["user_id"].(float64)
+	if !ok {
+		return "", errors.New("invalid token claims")
+	}
 	var user models.User
-	if err := s.db.First(&user, userID).Error; err != nil {
+	if err := s.db.First(&user, uint(userIDFloat)).Error; err != nil {
 		return "", errors.New("user not found")
 	}
+	if !user.IsActive {
+		return "", ErrAccountDisabled
+	}
 
 	// Generate new access token
 	return s.generateAccessToken(user)
 }
 
 // FindOrCreateUserByGoogle handles logic for Google OAuth.
-func (s *authService) FindOrCreateUserByGoogle(req *dto.GoogleOAuthRequest) (*dto.AuthResponse, error) {
+func (s *authService) FindOrCreateUserByGoogle(req *dto.GoogleOAuthRequest, client *dto.ClientInfo) (*dto.AuthResponse, error) {
 	var user models.User
 	err := s.db.Where("email = ?", req.Email).First(&user).Error
 
@@ -64,11 +91,13 @@ func (s *authService) FindOrCreateUserByGoogle(req *dto.GoogleOAuthRequest) (*dt
 	if errors.Is(err, gorm.ErrRecordNotFound) {
 		// For OAuth users, we can store a placeholder or no password
 		// Or handle it based on your security policy
+		verifiedAt := time.Now() // Google ยืนยันอีเมลให้แล้ว
 		newUser := models.User{
-			Email:  req.Email,
-			Name:   req.Name,
-			Role:   models.RoleUser, // Assign a default role
-			Avatar: req.Avatar,
+			Email:           req.Email,
+			Name:            req.Name,
+			Role:            models.RoleUser, // Assign a default role
+			Avatar:          req.Avatar,
+			EmailVerifiedAt: &verifiedAt,
 		}
 
 		if createErr := s.db.Create(&newUser).Error; createErr != nil {
@@ -79,47 +108,152 @@ func (s *authService) FindOrCreateUserByGoogle(req *dto.GoogleOAuthRequest) (*dt
 		return nil, errors.New("database error") // Handle other potential DB errors
 	}
 
-	// User exists or was just created, now generate tokens
-	accessToken, err := s.generateAccessToken(user)
-	if err != nil {
-		return nil, err
-	}
-	refreshToken, err := s.generateRefreshToken(user)
-	if err != nil {
-		return nil, err
+	if !user.IsActive {
+		recordLoginAttempt(s.db, &user.ID, user.Email, client, models.LoginResultInactive)
+		return nil, ErrAccountDisabled
 	}
 
-	authResponse := &dto.AuthResponse{
-		User: dto.UserResponse{
-			ID:           user.ID,
-			Email:        user.Email,
-			Name:         user.Name,
-			Role:         string(user.Role),
-			DepartmentID: user.DepartmentID,
-			Avatar:       user.Avatar,
-		},
-		AccessToken:  accessToken,
-		RefreshToken: refreshToken,
-	}
-
-	return authResponse, nil
+	// User exists or was just created, now generate tokens (or a 2FA challenge)
+	return s.completeLogin(user, client)
 }
 
 // Login handles the user login logic.
-func (s *authService) Login(req *dto.LoginRequest) (*dto.AuthResponse, error) {
+func (s *authService) Login(req *dto.LoginRequest, client *dto.ClientInfo) (*dto.AuthResponse, error) {
+	policy := loadLoginPolicy()
+
+	// ⭐ ตรวจจำนวนครั้งที่ผิดจาก IP นี้ก่อน เพื่อกันการเดารหัสผ่านหลายบัญชี
+	if err := checkIPThrottle(s.db, client, policy); err != nil {
+		recordLoginAttempt(s.db, nil, req.Email, client, models.LoginResultIPThrottled)
+		return nil, err
+	}
+
 	var user models.User
 	if err := s.db.Where("email = ?", req.Email).First(&user).Error; err != nil {
+		recordLoginAttempt(s.db, nil, req.Email, client, models.LoginResultUnknownEmail)
 		return nil, errors.New("invalid email or password")
 	}
 
+	now := time.Now()
+	if user.IsLocked(now) {
+		recordLoginAttempt(s.db, &user.ID, user.Email, client, models.LoginResultLocked)
+		return nil, &AccountLockedError{RetryAfter: user.LockedUntil.Sub(now)}
+	}
+
 	if user.Password == nil {
+		recordLoginAttempt(s.db, &user.ID, user.Email, client, models.LoginResultInvalidPassword)
 		return nil, errors.New("invalid email or password") // Handle case for OAuth users with no password
 	}
 
 	if err := bcrypt.CompareHashAndPassword([]byte(*user.Password), []byte(req.Password)); err != nil {
+		recordLoginAttempt(s.db, &user.ID, user.Email, client, models.LoginResultInvalidPassword)
+		if lockout, lockErr := registerLoginFailure(s.db, &user, policy); lockErr == nil && lockout > 0 {
+			return nil, &AccountLockedError{RetryAfter: lockout}
+		}
 		return nil, errors.New("invalid email or password")
 	}
 
+	if !user.IsActive {
+		recordLoginAttempt(s.db, &user.ID, user.Email, client, models.LoginResultInactive)
+		return nil, ErrAccountDisabled
+	}
+
+	if getEnvBool("REQUIRE_EMAIL_VERIFICATION", false) && !user.IsEmailVerified() {
+		return nil, ErrEmailNotVerified
+	}
+
+	return s.completeLogin(user, client)
+}
+
+// VerifyMFA exchanges an MFA challenge token plus a TOTP/recovery code for real tokens.
+func (s *authService) VerifyMFA(req *dto.MFAVerifyRequest, client *dto.ClientInfo) (*dto.AuthResponse, error) {
+	policy := loadLoginPolicy()
+	if err := checkIPThrottle(s.db, client, policy); err != nil {
+		return nil, err
+	}
+
+	token, err := jwt.Parse(req.MFAToken, func(token *jwt.Token) (interface{}, error) {
+		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
+			return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
+		}
+		return mfaSigningKey(), nil
+	})
+	if err != nil || !token.Valid {
+		return nil, ErrInvalidMFAToken
+	}
+
+	claims, ok := token.Claims.(jwt.MapClaims)
+	if !ok || claims["purpose"] != mfaChallengePurpose {
+		return nil, ErrInvalidMFAToken
+	}
+	userIDFloat, ok := claims["user_id"].(float64)
+	if !ok {
+		return nil, ErrInvalidMFAToken
+	}
+
+	var user models.User
+	if err := s.db.First(&user, uint(userIDFloat)).Error; err != nil {
+		return nil, ErrInvalidMFAToken
+	}
+
+	now := time.Now()
+	if user.IsLocked(now) {
+		recordLoginAttempt(s.db, &user.ID, user.Email, client, models.LoginResultLocked)
+		return nil, &AccountLockedError{RetryAfter: user.LockedUntil.Sub(now)}
+	}
+	if !user.IsActive {
+		recordLoginAttempt(s.db, &user.ID, user.Email, client, models.LoginResultInactive)
+		return nil, ErrAccountDisabled
+	}
+
+	// ⭐ รหัส 2FA ที่ผิดนับรวมกับรหัสผ่านที่ผิด เพื่อกันการเดา TOTP
+	if err := verifySecondFactor(s.db, &user, req.Code); err != nil {
+		if !errors.Is(err, ErrInvalidTwoFactorCode) {
+			return nil, err
+		}
+		recordLoginAttempt(s.db, &user.ID, user.Email, client, models.LoginResultInvalidMFACode)
+		if lockout, lockErr := registerLoginFailure(s.db, &user, policy); lockErr == nil && lockout > 0 {
+			return nil, &AccountLockedError{RetryAfter: lockout}
+		}
+		return nil, err
+	}
+
+	recordLoginAttempt(s.db, &user.ID, user.Email, client, models.LoginResultSuccess)
+	if err := resetLoginFailures(s.db, &user); err != nil {
+		log.Printf("❌ Failed to reset login counters for %s: %v", user.Email, err)
+	}
+	return s.issueTokens(user)
+}
+
+// completeLogin returns an MFA challenge for users with 2FA, otherwise a full token pair
+func (s *authService) completeLogin(user models.User, client *dto.ClientInfo) (*dto.AuthResponse, error) {
+	if user.HasTwoFactor() {
+		mfaToken, err := s.generateMFAChallengeToken(user)
+		if err != nil {
+			return nil, err
+		}
+		recordLoginAttempt(s.db, &user.ID, user.Email, client, models.LoginResultMFAChallenge)
+		return &dto.AuthResponse{MFARequired: true, MFAToken: mfaToken}, nil
+	}
+
+	recordLoginAttempt(s.db, &user.ID, user.Email, client, models.LoginResultSuccess)
+	if err := resetLoginFailures(s.db, &user); err != nil {
+		log.Printf("❌ Failed to reset login counters for %s: %v", user.Email, err)
+	}
+
+	authResponse, err := s.issueTokens(user)
+	if err != nil {
+		return nil, err
+	}
+	// ⭐ Policy: แจ้งให้ผู้ดูแลลงทะเบียน 2FA (เส้นทางที่ต้องใช้ permission จะถูกปิดจนกว่าจะลงทะเบียน)
+	required, err := auth.UserTwoFactorRequired(s.db, &user)
+	if err != nil {
+		return nil, err
+	}
+	authResponse.MFAEnrollmentRequired = required
+	return authResponse, nil
+}
+
+func (s *authService) issueTokens(user models.User) (*dto.AuthResponse, error) {
 	accessToken, err := s.generateAccessToken(user)
 	if err != nil {
 		return nil, err
@@ -131,14 +265,7 @@ func (s *authService) Login(req *dto.LoginRequest) (*dto.AuthResponse, error) {
 	}
 
 	authResponse := &dto.AuthResponse{
-		User: dto.UserResponse{
-			ID:           user.ID,
-			Email:        user.Email,
-			Name:         user.Name,
-			Role:         string(user.Role),
-			DepartmentID: user.DepartmentID,
-			Avatar:       user.Avatar,
-		},
+		User:         mapUserToAuthUser(&user),
 		AccessToken:  accessToken,
 		RefreshToken: refreshToken,
 	}
@@ -150,7 +277,7 @@ func (s *authService) Login(req *dto.LoginRequest) (*dto.AuthResponse, error) {
 func (s *authService) Register(req *dto.RegisterRequest) (*dto.UserResponse, error) {
 	var existingUser models.User
 	if err := s.db.Where("email = ?", req.Email).First(&existingUser).Error; err == nil {
-		return nil, errors.New("user already exists")
+		return nil, ErrUserExists
 	}
 
 	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(req.Password), bcrypt.DefaultCost)
@@ -176,6 +303,11 @@ func (s *authService) Register(req *dto.RegisterRequest) (*dto.UserResponse, err
 		return nil, errors.New("failed to create user")
 	}
 
+	// ⭐ ส่งอีเมลยืนยัน (ถ้าส่งไม่สำเร็จ ผู้ใช้ขอส่งใหม่ได้ภายหลัง)
+	if err := s.sendVerificationEmail(&user); err != nil {
+		log.Printf("❌ Failed to send verification email to %s: %v", user.Email, err)
+	}
+
 	userResponse := &dto.UserResponse{
 		ID:           user.ID,
 		Email:        user.Email,
@@ -187,6 +319,107 @@ func (s *authService) Register(req *dto.RegisterRequest) (*dto.UserResponse, err
 	return userResponse, nil
 }
 
+// ForgotPassword sends a password reset link. It never reveals whether the email exists.
+func (s *authService) ForgotPassword(req *dto.ForgotPasswordRequest) error {
+	var user models.User
+	if err := s.db.Where("email = ?", req.Email).First(&user).Error; err != nil {
+		log.Printf("⚠️ Password reset requested for unknown email: %s", req.Email)
+		return nil
+	}
+	if !user.IsActive {
+		return nil
+	}
+
+	ttl := getEnvDuration("PASSWORD_RESET_TOKEN_TTL_MINUTES", time.Minute, time.Hour)
+	token, err := issueUserToken(s.db, user.ID, models.TokenPurposePasswordReset, ttl)
+	if err != nil {
+		return errors.New("failed to create reset token")
+	}
+
+	link := fmt.Sprintf("%s/reset-password?token=%s", frontendURL(), token)
+	return s.mailer.Send(mailer.Message{
+		To:      []string{user.Email},
+		Subject: "รีเซ็ตรหัสผ่าน KU Asset / Reset your KU Asset password",
+		TextBody: fmt.Sprintf(
+			"สวัสดี %s\n\nมีการขอรีเซ็ตรหัสผ่านสำหรับบัญชีของคุณ คลิกลิงก์ด้านล่างภายใน %d นาทีเพื่อตั้งรหัสผ่านใหม่\n%s\n\n"+
+				"Someone requested a password reset for your account. Open the link above within %d minutes to choose a new password.\n"+
+				"หากคุณไม่ได้เป็นผู้ขอ สามารถเพิกเฉยอีเมลนี้ได้ / If this wasn't you, you can ignore this email.\n",
+			user.Name, int(ttl.Minutes()), link, int(ttl.Minutes())),
+	})
+}
+
+// ResetPassword sets a new password using a single-use reset token.
+func (s *authService) ResetPassword(req *dto.ResetPasswordRequest) error {
+	return s.db.Transaction(func(tx *gorm.DB) error {
+		token, err := consumeUserToken(tx, req.Token, models.TokenPurposePasswordReset)
+		if err != nil {
+			return err
+		}
+
+		var user models.User
+		if err := tx.First(&user, token.UserID).Error; err != nil {
+			return ErrInvalidToken
+		}
+		// ⭐ บัญชีที่ถูกปิดใช้งานรีเซ็ตรหัสผ่านไม่ได้ (token ไม่ถูกใช้เพราะ transaction ย้อนกลับ)
+		if !user.IsActive {
+			return ErrAccountDisabled
+		}
+
+		if err := user.SetPassword(req.NewPassword); err != nil {
+			return errors.New("could not hash new password")
+		}
+		// รหัสผ่านใหม่ปลดล็อกบัญชีที่ถูกล็อกจากการใส่รหัสผิด ใน transaction เดียวกัน
+		user.FailedLoginAttempts = 0
+		user.LockedUntil = nil
+		// การรีเซ็ตผ่านลิงก์ในอีเมลถือเป็นการยืนยันอีเมลไปในตัว
+		if user.EmailVerifiedAt == nil {
+			now := time.Now()
+			user.EmailVerifiedAt = &now
+		}
+		return tx.Save(&user).Error
+	})
+}
+
+// VerifyEmail marks the user's email as verified using a verification token.
+func (s *authService) VerifyEmail(req *dto.VerifyEmailRequest) error {
+	return s.db.Transaction(func(tx *gorm.DB) error {
+		token, err := consumeUserToken(tx, req.Token, models.TokenPurposeEmailVerify)
+		if err != nil {
+			return err
+		}
+		return tx.Model(&models.User{}).
+			Where("id = ? AND email_verified_at IS NULL", token.UserID).
+			Update("email_verified_at", time.Now()).Error
+	})
+}
+
+// ResendVerification issues a fresh verification email for an unverified account.
+func (s *authService) ResendVerification(req *dto.ResendVerificationRequest) error {
+	var user models.User
+	if err := s.db.Where("email = ?", req.Email).First(&user).Error; err != nil || user.IsEmailVerified() {
+		return nil // ไม่บอกว่ามีบัญชีนี้หรือไม่
+	}
+	return s.sendVerificationEmail(&user)
+}
+
+func (s *authService) sendVerificationEmail(user *models.User) error {
+	ttl := getEnvDuration("EMAIL_VERIFICATION_TOKEN_TTL_HOURS", time.Hour, 48*time.Hour)
+	token, err := issueUserToken(s.db, user.ID, models.TokenPurposeEmailVerify, ttl)
+	if err != nil {
+		return err
+	}
+
+	link := fmt.Sprintf("%s/verify-email?token=%s", frontendURL(), token)
+	return s.mailer.Send(mailer.Message{
+		To:      []string{user.Email},
+		Subject: "ยืนยันอีเมล KU Asset / Verify your KU Asset email",
+		TextBody: fmt.Sprintf(
+			"สวัสดี %s\n\nกรุณายืนยันอีเมลของคุณโดยคลิกลิงก์ด้านล่างภายใน %d ชั่วโมง\n%s\n\n"+
+				"Please confirm your email address by opening the link above within %d hours.\n",
+			user.Name, int(ttl.Hours()), link, int(ttl.Hours())),
+	})
+}
+
 // --- Helper methods for token generation ---
 func (s *authService) generateAccessToken(user models.User) (string, error) {
 	claims := jwt.MapClaims{
@@ -209,3 +442,20 @@ func (s *authService) generateRefreshToken(user models.User) (string, error) {
 	secret := os.Getenv("JWT_REFRESH_SECRET")
 	return token.SignedString([]byte(secret))
 }
+
+// generateMFAChallengeToken issues a short-lived token that only /auth/2fa/verify accepts.
+// It is signed with a separate key so it can never be used as an access token.
+func (s *authService) generateMFAChallengeToken(user models.User) (string, error) {
+	ttl := getEnvDuration("MFA_CHALLENGE_TTL_MINUTES", time.Minute, 5*time.Minute)
+	claims := jwt.MapClaims{
+		"user_id": user.ID,
+		"purpose": mfaChallengePurpose,
+		"exp":     time.Now().Add(ttl).Unix(),
+	}
+	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
+	return token.SignedString(mfaSigningKey())
+}
+
+func mfaSigningKey() []byte {
+	return auth.DerivedSecret("JWT_MFA_SECRET", "mfa")
+}
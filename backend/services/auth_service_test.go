@@ -0,0 +1,69 @@
+package services
+
+import (
+	"database/sql/driver"
+	"errors"
+	"testing"
+	"time"
+
+	"ku-asset/dto"
+
+	"github.com/DATA-DOG/go-sqlmock"
+)
+
+// expectResetToken expects a usable password-reset token for user 9 to be consumed
+func expectResetToken(mock sqlmock.Sqlmock) {
+	mock.ExpectQuery(`SELECT \* FROM "user_tokens" WHERE token_hash = \$1 AND purpose = \$2`).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "user_id", "purpose", "expires_at"}).
+			AddRow(3, 9, "PASSWORD_RESET", time.Now().Add(time.Hour)))
+	mock.ExpectExec(`UPDATE "user_tokens" SET "used_at"=\$1 WHERE id = \$2 AND used_at IS NULL`).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+}
+
+func TestResetPassword(t *testing.T) {
+	req := &dto.ResetPasswordRequest{Token: "raw", NewPassword: "N3w-passw0rd!"}
+
+	t.Run("clears the lockout in the same transaction", func(t *testing.T) {
+		db, mock := newMockDB(t)
+		mock.ExpectBegin()
+		expectResetToken(mock)
+		mock.ExpectQuery(`SELECT \* FROM "users"`).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "email", "is_active", "failed_login_attempts", "locked_until"}).
+				AddRow(9, "a@ku.ac.th", true, 7, time.Now().Add(time.Hour)))
+		// Save เขียนทุกคอลัมน์: failed_login_attempts=$20, locked_until=$21
+		args := make([]driver.Value, 25)
+		for i := range args {
+			args[i] = sqlmock.AnyArg()
+		}
+		args[19], args[20] = 0, nil
+		mock.ExpectExec(`UPDATE "users" SET .*"failed_login_attempts"=\$20,"locked_until"=\$21`).
+			WithArgs(args...).WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectCommit()
+
+		s := &authService{db: db}
+		if err := s.ResetPassword(req); err != nil {
+			t.Fatal(err)
+		}
+		if err := mock.ExpectationsWereMet(); err != nil {
+			t.Error(err)
+		}
+	})
+
+	t.Run("refuses a disabled account", func(t *testing.T) {
+		db, mock := newMockDB(t)
+		mock.ExpectBegin()
+		expectResetToken(mock)
+		mock.ExpectQuery(`SELECT \* FROM "users"`).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "email", "is_active"}).AddRow(9, "a@ku.ac.th", false))
+		// token ต้องไม่ถูกใช้ไป
+		mock.ExpectRollback()
+
+		s := &authService{db: db}
+		if err := s.ResetPassword(req); !errors.Is(err, ErrAccountDisabled) {
+			t.Fatalf("err = %v, want ErrAccountDisabled", err)
+		}
+		if err := mock.ExpectationsWereMet(); err != nil {
+			t.Error(err)
+		}
+	})
+}
@@ -2,6 +2,7 @@
 package services
 
 import (
+	"context"
 	"ku-asset/dto"
 	"ku-asset/models"
 
@@ -11,9 +12,9 @@ import (
 type CategoryService interface {
 	GetAll() ([]dto.CategoryResponse, error)
 	GetByID(id string) (*dto.CategoryResponse, error)
-	Create(req *dto.CreateCategoryRequest) (*dto.CategoryResponse, error)
-	Update(id string, req *dto.UpdateCategoryRequest) (*dto.CategoryResponse, error)
-	Delete(id string) error
+	Create(ctx context.Context, req *dto.CreateCategoryRequest) (*dto.CategoryResponse, error)
+	Update(ctx context.Context, id string, req *dto.UpdateCategoryRequest) (*dto.CategoryResponse, error)
+	Delete(ctx context.Context, id string) error
 }
 
 type categoryService struct {
@@ -44,21 +45,23 @@ func (s *categoryService) GetByID(id string) (*dto.CategoryResponse, error) {
 	return mapCategoryToResponse(&category), nil
 }
 
-func (s *categoryService) Create(req *dto.CreateCategoryRequest) (*dto.CategoryResponse, error) {
+func (s *categoryService) Create(ctx context.Context, req *dto.CreateCategoryRequest) (*dto.CategoryResponse, error) {
+	db := s.db.WithContext(ctx)
 	category := models.Category{
 		Name:        req.Name,
 		Description: req.Description,
 		IsActive:    true,
 	}
-	if err := s.db.Create(&category).Error; err != nil {
+	if err := db.Create(&category).Error; err != nil {
 		return nil, err
 	}
 	return mapCategoryToResponse(&category), nil
 }
 
-func (s *categoryService) Update(id string, req *dto.UpdateCategoryRequest) (*dto.CategoryResponse, error) {
+func (s *categoryService) Update(ctx context.Context, id string, req *dto.UpdateCategoryRequest) (*dto.CategoryResponse, error) {
+	db := s.db.WithContext(ctx)
 	var category models.Category
-	if err := s.db.First(&category, "id = ?", id).Error; err != nil {
+	if err := db.First(&category, "id = ?", id).Error; err != nil {
 		return nil, err
 	}
 
@@ -72,14 +75,15 @@ func (s *categoryService) Update(id string, req *dto.UpdateCategoryRequest) (*dt
 		category.IsActive = *req.IsActive
 	}
 
-	if err := s.db.Save(&category).Error; err != nil {
+	if err := db.Save(&category).Error; err != nil {
 		return nil, err
 	}
 	return mapCategoryToResponse(&category), nil
 }
 
-func (s *categoryService) Delete(id string) error {
-	return s.db.Delete(&models.Category{}, "id = ?", id).Error
+func (s *categoryService) Delete(ctx context.Context, id string) error {
+	db := s.db.WithContext(ctx)
+	return db.Delete(&models.Category{}, "id = ?", id).Error
 }
 
 // Helper
@@ -1,6 +1,7 @@
 package services
 
 import (
+	"ku-asset/auth"
 	"ku-asset/dto"
 	"ku-asset/models"
 
@@ -9,10 +10,11 @@ import (
 	"gorm.io/gorm"
 )
 
+// DashboardService ทุก method รับ scope เพื่อจำกัดสถิติตามหน่วยงานของผู้ดูแล (nil = ทั้งหมด)
 type DashboardService interface {
-	GetAdminStats() (*dto.AdminStatsResponse, error)
-	GetRecentActivity(limit int) ([]dto.RecentActivityResponse, error)
-	GetSystemStats() (*dto.SystemStatsResponse, error)
+	GetAdminStats(scope *auth.Scope) (*dto.AdminStatsResponse, error)
+	GetRecentActivity(limit int, scope *auth.Scope) ([]dto.RecentActivityResponse, error)
+	GetSystemStats(scope *auth.Scope) (*dto.SystemStatsResponse, error)
 }
 
 type dashboardService struct{ db *gorm.DB }
@@ -21,7 +23,7 @@ func NewDashboardService(db *gorm.DB) DashboardService {
 	return &dashboardService{db: db}
 }
 
-func (s *dashboardService) GetAdminStats() (*dto.AdminStatsResponse, error) {
+func (s *dashboardService) GetAdminStats(scope *auth.Scope) (*dto.AdminStatsResponse, error) {
 	var (
 		totalUsers, totalProducts, totalDepartments                            int64
 		pendingRequests, approvedRequests, rejectedRequests, completedRequests int64
@@ -32,7 +34,7 @@ func (s *dashboardService) GetAdminStats() (*dto.AdminStatsResponse, error) {
 	log.Println("🔍 Starting GetAdminStats...")
 
 	// Basic counts
-	if err := s.db.Model(&models.User{}).Count(&totalUsers).Error; err != nil {
+	if err := scopeUsers(s.db.Model(&models.User{}), scope).Count(&totalUsers).Error; err != nil {
 		log.Printf("❌ Error counting users: %v", err)
 		return nil, err
 	}
@@ -44,7 +46,11 @@ func (s *dashboardService) GetAdminStats() (*dto.AdminStatsResponse, error) {
 	}
 	log.Printf("✅ Total products: %d", totalProducts)
 
-	if err := s.db.Model(&models.Department{}).Count(&totalDepartments).Error; err != nil {
+	departmentQuery := s.db.Model(&models.Department{})
+	if scope != nil && !scope.All {
+		departmentQuery = departmentQuery.Where("id IN ?", nonEmptyIDs(scope.DepartmentIDs))
+	}
+	if err := departmentQuery.Count(&totalDepartments).Error; err != nil {
 		log.Printf("❌ Error counting departments: %v", err)
 		return nil, err
 	}
@@ -58,7 +64,7 @@ func (s *dashboardService) GetAdminStats() (*dto.AdminStatsResponse, error) {
 
 	// Debug: ดูข้อมูลใน requests table ทั้งหมด
 	var allRequests []models.Request
-	if err := s.db.Find(&allRequests).Error; err != nil {
+	if err := scopeRequests(s.db, scope).Find(&allRequests).Error; err != nil {
 		log.Printf("❌ Error getting all requests: %v", err)
 	} else {
 		log.Printf("🔍 Found %d total requests:", len(allRequests))
@@ -68,21 +74,21 @@ func (s *dashboardService) GetAdminStats() (*dto.AdminStatsResponse, error) {
 	}
 
 	// Request status counts
-	if err := s.db.Model(&models.Request{}).
+	if err := scopeRequests(s.db.Model(&models.Request{}), scope).
 		Where("status = ?", models.RequestStatusPending).Count(&pendingRequests).Error; err != nil {
 		log.Printf("❌ Error counting pending requests: %v", err)
 		return nil, err
 	}
 	log.Printf("✅ Pending requests: %d", pendingRequests)
 
-	if err := s.db.Model(&models.Request{}).
+	if err := scopeRequests(s.db.Model(&models.Request{}), scope).
 		Where("status = ?", models.RequestStatusApproved).Count(&approvedRequests).Error; err != nil {
 		log.Printf("❌ Error counting approved requests: %v", err)
 		return nil, err
 	}
 	log.Printf("✅ Approved requests: %d", approvedRequests)
 
-	if err := s.db.Model(&models.Request{}).
+	if err := scopeRequests(s.db.Model(&models.Request{}), scope).
 		Where("status = ?", models.RequestStatusRejected).Count(&rejectedRequests).Error; err != nil {
 		log.Printf("❌ Error counting rejected requests: %v", err)
 		return nil, err
@@ -90,7 +96,7 @@ func (s *dashboardService) GetAdminStats() (*dto.AdminStatsResponse, error) {
 	log.Printf("✅ Rejected requests: %d", rejectedRequests)
 
 	// Monthly requests (this month)
-	if err := s.db.Model(&models.Request{}).
+	if err := scopeRequests(s.db.Model(&models.Request{}), scope).
 		Where("created_at >= date_trunc('month', current_date)").Count(&monthlyRequests).Error; err != nil {
 		log.Printf("❌ Error counting monthly requests: %v", err)
 		return nil, err
@@ -98,16 +104,16 @@ func (s *dashboardService) GetAdminStats() (*dto.AdminStatsResponse, error) {
 	log.Printf("✅ Monthly requests: %d", monthlyRequests)
 
 	// Active users (logged in last 30 days)
-	if err := s.db.Model(&models.User{}).
+	if err := scopeUsers(s.db.Model(&models.User{}), scope).
 		Where("last_login_at >= current_date - interval '30 days'").Count(&activeUsers).Error; err != nil {
 		log.Printf("❌ Error counting active users: %v", err)
 		// ไม่ return error เพราะ last_login_at อาจจะยังไม่มี
 	}
 	log.Printf("✅ Active users: %d", activeUsers)
 
-	// Low stock products (stock <= 5) ⭐ ใช้ stock แทน quantity
+	// Low stock products ⭐ เทียบกับ min_stock ของแต่ละรายการ (นิยามเดียวกับ isLowStock)
 	if err := s.db.Model(&models.Product{}).
-		Where("stock <= ?", 5).Count(&lowStockProducts).Error; err != nil {
+		Where("stock <= min_stock AND status <> ?", models.ProductStatusDiscontinued).Count(&lowStockProducts).Error; err != nil {
 		log.Printf("❌ Error counting low stock products: %v", err)
 		return nil, err
 	}
@@ -130,9 +136,9 @@ func (s *dashboardService) GetAdminStats() (*dto.AdminStatsResponse, error) {
 	return result, nil
 }
 
-func (s *dashboardService) GetRecentActivity(limit int) ([]dto.RecentActivityResponse, error) {
+func (s *dashboardService) GetRecentActivity(limit int, scope *auth.Scope) ([]dto.RecentActivityResponse, error) {
 	var recentRequests []models.Request
-	err := s.db.Preload("User").Order("created_at desc").Limit(limit).Find(&recentRequests).Error
+	err := scopeRequests(s.db, scope).Preload("User").Order("created_at desc").Limit(limit).Find(&recentRequests).Error
 	if err != nil {
 		return nil, err
 	}
@@ -154,9 +160,9 @@ func (s *dashboardService) GetRecentActivity(limit int) ([]dto.RecentActivityRes
 	return activities, nil
 }
 
-func (s *dashboardService) GetSystemStats() (*dto.SystemStatsResponse, error) {
+func (s *dashboardService) GetSystemStats(scope *auth.Scope) (*dto.SystemStatsResponse, error) {
 	requestsByMonth := make([]dto.MonthCount, 0)
-	s.db.Model(&models.Request{}).
+	scopeRequests(s.db.Model(&models.Request{}), scope).
 		Select("to_char(created_at, 'YYYY-MM') as month, count(*) as count").
 		Group("month").
 		Order("month asc").
@@ -164,16 +170,17 @@ func (s *dashboardService) GetSystemStats() (*dto.SystemStatsResponse, error) {
 		Find(&requestsByMonth)
 
 	topRequestedItems := make([]dto.ProductCount, 0)
-	s.db.Model(&models.RequestItem{}).
+	scopeRequests(s.db.Model(&models.RequestItem{}), scope).
 		Select("products.name, COUNT(request_items.id) as count").
 		Joins("left join products on products.id = request_items.product_id").
+		Joins("join requests on requests.id = request_items.request_id").
 		Group("products.name").
 		Order("count desc").
 		Limit(10). // ⭐ เพิ่มเป็น 10 รายการ
 		Find(&topRequestedItems)
 
 	departmentUsage := make([]dto.DeptCount, 0)
-	s.db.Model(&models.Request{}).
+	scopeRequests(s.db.Model(&models.Request{}), scope).
 		Select("departments.name_th as department, COUNT(requests.id) as count").
 		Joins("left join users on users.id = requests.user_id").
 		Joins("left join departments on departments.id = users.department_id").
@@ -185,7 +192,7 @@ func (s *dashboardService) GetSystemStats() (*dto.SystemStatsResponse, error) {
 
 	// ⭐ เพิ่ม Request breakdown by status
 	requestsByStatus := make([]dto.StatusCount, 0)
-	s.db.Model(&models.Request{}).
+	scopeRequests(s.db.Model(&models.Request{}), scope).
 		Select("status, COUNT(*) as count").
 		Group("status").
 		Find(&requestsByStatus)
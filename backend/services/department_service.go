@@ -2,6 +2,7 @@
 package services
 
 import (
+	"context"
 	"errors"
 	"ku-asset/dto"
 	"ku-asset/models"
@@ -15,9 +16,9 @@ type DepartmentService interface {
 	GetDepartments(query *dto.DepartmentQuery) ([]dto.DepartmentResponse, error)
 	GetDepartmentByID(id uint) (*dto.DepartmentResponse, error)
 	GetFaculties() ([]dto.DepartmentResponse, error)
-	CreateDepartment(req *dto.CreateDepartmentRequest) (*dto.DepartmentResponse, error)
-	UpdateDepartment(id uint, req *dto.UpdateDepartmentRequest) (*dto.DepartmentResponse, error)
-	DeleteDepartment(id uint) error
+	CreateDepartment(ctx context.Context, req *dto.CreateDepartmentRequest) (*dto.DepartmentResponse, error)
+	UpdateDepartment(ctx context.Context, id uint, req *dto.UpdateDepartmentRequest) (*dto.DepartmentResponse, error)
+	DeleteDepartment(ctx context.Context, id uint) error
 }
 
 // 2. สร้าง struct ที่เป็น implementation
@@ -88,7 +89,8 @@ func (s *departmentService) GetFaculties() ([]dto.DepartmentResponse, error) {
 	return response, nil
 }
 
-func (s *departmentService) CreateDepartment(req *dto.CreateDepartmentRequest) (*dto.DepartmentResponse, error) {
+func (s *departmentService) CreateDepartment(ctx context.Context, req *dto.CreateDepartmentRequest) (*dto.DepartmentResponse, error) {
+	db := s.db.WithContext(ctx)
 	department := models.Department{
 		Code:     req.Code,
 		NameTH:   req.NameTh,
@@ -97,15 +99,16 @@ func (s *departmentService) CreateDepartment(req *dto.CreateDepartmentRequest) (
 		ParentID: req.ParentID,
 		IsActive: req.IsActive,
 	}
-	if err := s.db.Create(&department).Error; err != nil {
+	if err := db.Create(&department).Error; err != nil {
 		return nil, err
 	}
 	return mapDepartmentToResponse(&department), nil
 }
 
-func (s *departmentService) UpdateDepartment(id uint, req *dto.UpdateDepartmentRequest) (*dto.DepartmentResponse, error) {
+func (s *departmentService) UpdateDepartment(ctx context.Context, id uint, req *dto.UpdateDepartmentRequest) (*dto.DepartmentResponse, error) {
+	db := s.db.WithContext(ctx)
 	var department models.Department
-	if err := s.db.First(&department, id).Error; err != nil {
+	if err := db.First(&department, id).Error; err != nil {
 		return nil, err
 	}
 	if req.Code != "" {
@@ -126,14 +129,15 @@ func (s *departmentService) UpdateDepartment(id uint, req *dto.UpdateDepartmentR
 	if req.IsActive != nil {
 		department.IsActive = *req.IsActive
 	}
-	if err := s.db.Save(&department).Error; err != nil {
+	if err := db.Save(&department).Error; err != nil {
 		return nil, err
 	}
 	return mapDepartmentToResponse(&department), nil
 }
 
-func (s *departmentService) DeleteDepartment(id uint) error {
-	if err := s.db.Delete(&models.Department{}, id).Error; err != nil {
+func (s *departmentService) DeleteDepartment(ctx context.Context, id uint) error {
+	db := s.db.WithContext(ctx)
+	if err := db.Delete(&models.Department{}, id).Error; err != nil {
 		return err
 	}
 	return nil
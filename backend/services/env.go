@@ -0,0 +1,50 @@
+// services/env.go
+package services
+
+import (
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// getEnv returns the environment variable or defaultValue when it is unset
+func getEnv(key, defaultValue string) string {
+	if value := os.Getenv(key); value != "" {
+		return value
+	}
+	return defaultValue
+}
+
+// getEnvBool parses a boolean environment variable ("true", "1", "yes")
+func getEnvBool(key string, defaultValue bool) bool {
+	value := strings.ToLower(os.Getenv(key))
+	switch value {
+	case "true", "1", "yes":
+		return true
+	case "false", "0", "no":
+		return false
+	}
+	return defaultValue
+}
+
+// getEnvInt parses an integer environment variable
+func getEnvInt(key string, defaultValue int) int {
+	if value, err := strconv.Atoi(os.Getenv(key)); err == nil {
+		return value
+	}
+	return defaultValue
+}
+
+// getEnvDuration reads an integer environment variable expressed in the given unit
+func getEnvDuration(key string, unit time.Duration, defaultValue time.Duration) time.Duration {
+	if value, err := strconv.Atoi(os.Getenv(key)); err == nil && value > 0 {
+		return time.Duration(value) * unit
+	}
+	return defaultValue
+}
+
+// frontendURL returns the base URL used for links sent to users
+func frontendURL() string {
+	return strings.TrimRight(getEnv("FRONTEND_URL", "http://localhost:3000"), "/")
+}
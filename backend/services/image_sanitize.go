@@ -0,0 +1,219 @@
+// services/image_sanitize.go
+package services
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"image"
+	"image/draw"
+)
+
+var ErrImageTooLarge = errors.New("image dimensions exceed the allowed maximum")
+
+// maxImagePixels caps width x height of uploaded images. Decoding allocates 4+ bytes per
+// pixel up front, so a few-KB PNG declaring 50000x50000 would otherwise need ~10GB.
+func maxImagePixels() int {
+	return getEnvInt("IMAGE_MAX_PIXELS", 40_000_000)
+}
+
+// checkImageConfig reads only the image header and rejects dimensions that are empty or
+// larger than maxPixels, before any pixel buffer is allocated
+func checkImageConfig(data []byte, maxPixels int) (image.Config, string, error) {
+	cfg, format, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return cfg, "", fmt.Errorf("invalid image format: %w", err)
+	}
+	if cfg.Width <= 0 || cfg.Height <= 0 {
+		return cfg, "", errors.New("invalid image dimensions")
+	}
+	if int64(cfg.Width)*int64(cfg.Height) > int64(maxPixels) {
+		return cfg, "", fmt.Errorf("%w: %dx%d is more than %d pixels", ErrImageTooLarge, cfg.Width, cfg.Height, maxPixels)
+	}
+	return cfg, format, nil
+}
+
+// decodeUploadedImage decodes an uploaded image after checking its dimensions and turns it
+// upright according to its EXIF orientation. Only pixels are kept, so re-encoding the
+// result never writes EXIF (GPS position, camera serial, ...) back out.
+func decodeUploadedImage(data []byte, maxPixels int) (image.Image, string, error) {
+	if _, _, err := checkImageConfig(data, maxPixels); err != nil {
+		return nil, "", err
+	}
+	img, format, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, "", fmt.Errorf("invalid image format: %w", err)
+	}
+	// ⭐ กันกรณี header กับข้อมูลจริงไม่ตรงกัน
+	if b := img.Bounds(); b.Empty() || int64(b.Dx())*int64(b.Dy()) > int64(maxPixels) {
+		return nil, "", ErrImageTooLarge
+	}
+	return applyOrientation(img, exifOrientation(data, format)), format, nil
+}
+
+// exifOrientation returns the EXIF orientation (1-8) stored in a JPEG APP1 segment, a PNG
+// eXIf chunk or a WebP EXIF chunk, or 1 when there is none
+func exifOrientation(data []byte, format string) int {
+	switch format {
+	case "jpeg":
+		return tiffOrientation(jpegExif(data))
+	case "png":
+		return tiffOrientation(pngExif(data))
+	case "webp":
+		return tiffOrientation(webpExif(data))
+	}
+	return 1
+}
+
+var exifHeader = []byte("Exif\x00\x00")
+
+func jpegExif(data []byte) []byte {
+	i := 2 // after SOI
+	for i+4 <= len(data) {
+		if data[i] != 0xFF {
+			return nil
+		}
+		marker := data[i+1]
+		switch {
+		case marker == 0xFF: // fill byte
+			i++
+			continue
+		case marker == 0x01 || (marker >= 0xD0 && marker <= 0xD8):
+			i += 2
+			continue
+		case marker == 0xDA || marker == 0xD9: // image data starts, no more metadata
+			return nil
+		}
+		segLen := int(binary.BigEndian.Uint16(data[i+2:]))
+		if segLen < 2 || i+2+segLen > len(data) {
+			return nil
+		}
+		segment := data[i+4 : i+2+segLen]
+		if marker == 0xE1 && bytes.HasPrefix(segment, exifHeader) {
+			return segment[len(exifHeader):]
+		}
+		i += 2 + segLen
+	}
+	return nil
+}
+
+func pngExif(data []byte) []byte {
+	i := 8 // after signature
+	for i+8 <= len(data) {
+		length := int64(binary.BigEndian.Uint32(data[i:]))
+		chunkType := string(data[i+4 : i+8])
+		end := int64(i) + 8 + length
+		if end > int64(len(data)) {
+			return nil
+		}
+		switch chunkType {
+		case "eXIf":
+			return data[i+8 : end]
+		case "IDAT", "IEND":
+			return nil
+		}
+		i = int(end) + 4 // CRC
+	}
+	return nil
+}
+
+func webpExif(data []byte) []byte {
+	i := 12 // after RIFF header
+	for i+8 <= len(data) {
+		size := int64(binary.LittleEndian.Uint32(data[i+4:]))
+		end := int64(i) + 8 + size
+		if end > int64(len(data)) {
+			return nil
+		}
+		if string(data[i:i+4]) == "EXIF" {
+			// บาง encoder ใส่ "Exif\0\0" นำหน้าเหมือน JPEG
+			return bytes.TrimPrefix(data[i+8:end], exifHeader)
+		}
+		i = int(end + end%2) // chunks are padded to an even size
+	}
+	return nil
+}
+
+// tiffOrientation reads the Orientation tag (0x0112) from IFD0 of TIFF-formatted EXIF data
+func tiffOrientation(tiff []byte) int {
+	if len(tiff) < 8 {
+		return 1
+	}
+	var order binary.ByteOrder
+	switch string(tiff[:2]) {
+	case "II":
+		order = binary.LittleEndian
+	case "MM":
+		order = binary.BigEndian
+	default:
+		return 1
+	}
+	if order.Uint16(tiff[2:]) != 42 {
+		return 1
+	}
+	ifd := int64(order.Uint32(tiff[4:]))
+	if ifd+2 > int64(len(tiff)) {
+		return 1
+	}
+	count := int64(order.Uint16(tiff[ifd:]))
+	for n := int64(0); n < count; n++ {
+		entry := ifd + 2 + n*12
+		if entry+12 > int64(len(tiff)) {
+			return 1
+		}
+		if order.Uint16(tiff[entry:]) != 0x0112 {
+			continue
+		}
+		if order.Uint16(tiff[entry+2:]) != 3 { // SHORT
+			return 1
+		}
+		if v := int(order.Uint16(tiff[entry+8:])); v >= 1 && v <= 8 {
+			return v
+		}
+		return 1
+	}
+	return 1
+}
+
+// applyOrientation transforms img so that it displays upright for an EXIF orientation:
+// 2 mirror, 3 rotate 180, 4 flip, 5 transpose, 6 rotate 90 CW, 7 transverse, 8 rotate 90 CCW
+func applyOrientation(img image.Image, orientation int) image.Image {
+	if orientation < 2 || orientation > 8 {
+		return img
+	}
+	b := img.Bounds()
+	w, h := b.Dx(), b.Dy()
+	src := image.NewRGBA(image.Rect(0, 0, w, h))
+	draw.Draw(src, src.Bounds(), img, b.Min, draw.Src)
+
+	dw, dh := w, h
+	if orientation >= 5 {
+		dw, dh = h, w
+	}
+	dst := image.NewRGBA(image.Rect(0, 0, dw, dh))
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			var dx, dy int
+			switch orientation {
+			case 2:
+				dx, dy = w-1-x, y
+			case 3:
+				dx, dy = w-1-x, h-1-y
+			case 4:
+				dx, dy = x, h-1-y
+			case 5:
+				dx, dy = y, x
+			case 6:
+				dx, dy = h-1-y, x
+			case 7:
+				dx, dy = h-1-y, w-1-x
+			case 8:
+				dx, dy = y, w-1-x
+			}
+			s, d := src.PixOffset(x, y), dst.PixOffset(dx, dy)
+			copy(dst.Pix[d:d+4], src.Pix[s:s+4])
+		}
+	}
+	return dst
+}
@@ -0,0 +1,187 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"errors"
+	"hash/crc32"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"image/png"
+	"testing"
+
+	"ku-asset/storage"
+
+	"github.com/HugoSmits86/nativewebp"
+)
+
+const testMaxPixels = 1 << 20
+
+func testImage(w, h int) *image.RGBA {
+	img := image.NewRGBA(image.Rect(0, 0, w, h))
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			img.Set(x, y, color.RGBA{uint8(x * 40), uint8(y * 40), 0, 255})
+		}
+	}
+	return img
+}
+
+func encodePNG(t testing.TB, img image.Image) []byte {
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+// jpegWithOrientation encodes img as JPEG with an EXIF APP1 segment holding orientation
+// and a GPS IFD pointer
+func jpegWithOrientation(t testing.TB, img image.Image, orientation uint16) []byte {
+	var buf bytes.Buffer
+	if err := jpeg.Encode(&buf, img, nil); err != nil {
+		t.Fatal(err)
+	}
+	tiff := []byte("MM\x00\x2a\x00\x00\x00\x08")
+	tiff = binary.BigEndian.AppendUint16(tiff, 2)
+	tiff = append(tiff, 0x01, 0x12, 0x00, 0x03, 0x00, 0x00, 0x00, 0x01)
+	tiff = binary.BigEndian.AppendUint16(tiff, orientation)
+	tiff = append(tiff, 0x00, 0x00)
+	tiff = append(tiff, 0x88, 0x25, 0x00, 0x04, 0x00, 0x00, 0x00, 0x01, 0x00, 0x00, 0x00, 0x00) // GPSInfo
+	tiff = append(tiff, 0x00, 0x00, 0x00, 0x00)
+	segment := append([]byte("Exif\x00\x00"), tiff...)
+
+	out := []byte{0xFF, 0xD8, 0xFF, 0xE1}
+	out = binary.BigEndian.AppendUint16(out, uint16(len(segment)+2))
+	out = append(out, segment...)
+	return append(out, buf.Bytes()[2:]...)
+}
+
+// pngBomb is a valid 1x1 PNG whose header claims width x height pixels
+func pngBomb(t testing.TB, width, height uint32) []byte {
+	data := encodePNG(t, testImage(1, 1))
+	ihdr := data[8+8 : 8+8+13]
+	binary.BigEndian.PutUint32(ihdr[0:], width)
+	binary.BigEndian.PutUint32(ihdr[4:], height)
+	binary.BigEndian.PutUint32(data[8+8+13:], crc32.ChecksumIEEE(data[8+4:8+8+13]))
+	return data
+}
+
+func TestDecodeUploadedImageRejectsDecompressionBomb(t *testing.T) {
+	_, _, err := decodeUploadedImage(pngBomb(t, 50000, 50000), testMaxPixels)
+	if !errors.Is(err, ErrImageTooLarge) {
+		t.Fatalf("expected ErrImageTooLarge, got %v", err)
+	}
+}
+
+func TestDecodeUploadedImageAppliesOrientation(t *testing.T) {
+	src := testImage(4, 2)
+	for orientation, want := range map[uint16]image.Point{1: {4, 2}, 3: {4, 2}, 6: {2, 4}, 8: {2, 4}} {
+		img, format, err := decodeUploadedImage(jpegWithOrientation(t, src, orientation), testMaxPixels)
+		if err != nil {
+			t.Fatalf("orientation %d: %v", orientation, err)
+		}
+		if format != "jpeg" || img.Bounds().Size() != want {
+			t.Errorf("orientation %d: got %s %v, want jpeg %v", orientation, format, img.Bounds().Size(), want)
+		}
+	}
+}
+
+func TestApplyOrientationMovesPixels(t *testing.T) {
+	src := image.NewRGBA(image.Rect(0, 0, 2, 1))
+	red := color.RGBA{255, 0, 0, 255}
+	src.Set(0, 0, red) // top-left
+	// rotate 90 CW: top-left moves to top-right of a 1x2 image
+	if got := applyOrientation(src, 6).At(0, 0); got != red {
+		t.Errorf("orientation 6: got %v at (0,0)", got)
+	}
+	// rotate 90 CCW: top-left moves to bottom-left
+	if got := applyOrientation(src, 8).At(0, 1); got != red {
+		t.Errorf("orientation 8: got %v at (0,1)", got)
+	}
+}
+
+func TestSavedVariantsHaveNoExif(t *testing.T) {
+	img, format, err := decodeUploadedImage(jpegWithOrientation(t, testImage(8, 8), 6), testMaxPixels)
+	if err != nil {
+		t.Fatal(err)
+	}
+	store := storage.NewMemory("http://localhost/uploads")
+	us := NewUploadService(nil, store)
+	config := &UploadConfig{Variants: []ImageVariant{{Name: fullVariant, MaxWidth: 4, MaxHeight: 4}}, Quality: 80}
+	result, err := us.saveVariants(context.Background(), img, format, "test/a", config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rc, _, err := store.Open(context.Background(), "test/a/full.jpg")
+	if err != nil {
+		t.Fatalf("open %s: %v", result.URL, err)
+	}
+	defer rc.Close()
+	var buf bytes.Buffer
+	buf.ReadFrom(rc)
+	if bytes.Contains(buf.Bytes(), exifHeader) {
+		t.Error("stored variant still contains EXIF data")
+	}
+}
+
+// FuzzImagePipeline feeds arbitrary bytes through decoding, orientation and variant
+// encoding: it must never panic, and decoded images always respect the pixel limit
+func FuzzImagePipeline(f *testing.F) {
+	f.Add(encodePNG(f, testImage(3, 2)))
+	f.Add(jpegWithOrientation(f, testImage(3, 2), 6))
+	f.Add(jpegWithOrientation(f, testImage(2, 3), 7))
+	f.Add(pngBomb(f, 50000, 50000))
+	f.Add(pngBomb(f, 1, 1<<30))
+	var webp bytes.Buffer
+	if err := nativewebp.Encode(&webp, testImage(2, 2), nil); err != nil {
+		f.Fatal(err)
+	}
+	f.Add(webp.Bytes())
+	f.Add([]byte("GIF89a\x01\x00\x01\x00\x00\x00\x00;"))
+
+	store := storage.NewMemory("http://localhost/uploads")
+	us := NewUploadService(nil, store)
+	config := &UploadConfig{
+		Variants: []ImageVariant{
+			{Name: "thumb", MaxWidth: 4, MaxHeight: 4},
+			{Name: fullVariant, MaxWidth: 16, MaxHeight: 16},
+		},
+		Quality:      80,
+		OutputFormat: "jpeg",
+	}
+
+	f.Fuzz(func(t *testing.T, data []byte) {
+		img, format, err := decodeUploadedImage(data, testMaxPixels)
+		if err != nil {
+			return
+		}
+		b := img.Bounds()
+		if b.Empty() || b.Dx()*b.Dy() > testMaxPixels {
+			t.Fatalf("decoded %s image with bounds %v", format, b)
+		}
+		if _, err := us.saveVariants(context.Background(), img, format, "fuzz/x", config); err != nil {
+			t.Fatalf("saving a decoded %s image failed: %v", format, err)
+		}
+	})
+}
+
+// FuzzExifOrientation checks that malformed EXIF data never panics or yields an
+// orientation outside 1-8
+func FuzzExifOrientation(f *testing.F) {
+	f.Add(jpegWithOrientation(f, testImage(1, 1), 6), "jpeg")
+	f.Add([]byte("II*\x00\x08\x00\x00\x00\x01\x00\x12\x01\x03\x00\x01\x00\x00\x00\x03\x00"), "tiff")
+	f.Add([]byte("RIFF\x00\x00\x00\x00WEBPEXIF\x08\x00\x00\x00II*\x00\x08\x00\x00\x00"), "webp")
+	f.Add([]byte("\x89PNG\r\n\x1a\n\x00\x00\x00\x08eXIfMM\x00*\x00\x00\x00\x08"), "png")
+
+	f.Fuzz(func(t *testing.T, data []byte, format string) {
+		if o := exifOrientation(data, format); o < 1 || o > 8 {
+			t.Fatalf("orientation %d", o)
+		}
+		if o := tiffOrientation(data); o < 1 || o > 8 {
+			t.Fatalf("tiff orientation %d", o)
+		}
+	})
+}
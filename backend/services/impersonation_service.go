@@ -0,0 +1,188 @@
+// services/impersonation_service.go
+package services
+
+import (
+	"errors"
+	"ku-asset/auth"
+	"ku-asset/dto"
+	"ku-asset/models"
+	"log"
+	"os"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"gorm.io/gorm"
+)
+
+var (
+	ErrCannotImpersonate       = errors.New("this user cannot be impersonated")
+	ErrImpersonationNotFound   = errors.New("impersonation session not found")
+	ErrImpersonationNotAllowed = errors.New("you can only end your own impersonation sessions")
+)
+
+// ImpersonationService lets administrators view the system as another user.
+type ImpersonationService interface {
+	// scope คือขอบเขต user.manage ของผู้ดูแล (nil = ทั้งหมด)
+	Impersonate(adminID, userID uint, req *dto.ImpersonateRequest, client *dto.ClientInfo, scope *auth.Scope) (*dto.ImpersonationTokenResponse, error)
+	EndSession(sessionID, adminID uint) error
+	GetSessions(req *dto.PaginationRequest, userID *uint) ([]dto.ImpersonationSessionResponse, error)
+	GetSession(sessionID uint) (*dto.ImpersonationSessionResponse, error)
+}
+
+type impersonationService struct {
+	db *gorm.DB
+}
+
+func NewImpersonationService(db *gorm.DB) ImpersonationService {
+	return &impersonationService{db: db}
+}
+
+func (s *impersonationService) Impersonate(adminID, userID uint, req *dto.ImpersonateRequest, client *dto.ClientInfo, scope *auth.Scope) (*dto.ImpersonationTokenResponse, error) {
+	var target models.User
+	if err := scopeUsers(s.db, scope).First(&target, userID).Error; err != nil {
+		return nil, errors.New("user not found")
+	}
+
+	// ⭐ ห้ามสวมรอยตัวเอง ผู้ดูแลคนอื่น service account หรือบัญชีที่ถูกปิด
+	if target.ID == adminID || target.Role == models.RoleAdmin ||
+		target.Provider == models.ProviderService || !target.IsActive {
+		return nil, ErrCannotImpersonate
+	}
+	// ⭐ สวมรอยได้เฉพาะผู้ใช้ที่สิทธิ์ไม่เกินผู้ดูแล ไม่งั้นจะใช้ session อ่านข้อมูลนอกขอบเขตตัวเองได้
+	if covered, err := auth.PermissionsCovered(s.db, adminID, target.ID); err != nil {
+		return nil, err
+	} else if !covered {
+		return nil, ErrCannotImpersonate
+	}
+
+	var admin models.User
+	if err := s.db.First(&admin, adminID).Error; err != nil {
+		return nil, errors.New("user not found")
+	}
+
+	ttl := getEnvDuration("IMPERSONATION_TTL_MINUTES", time.Minute, 30*time.Minute)
+	session := models.ImpersonationSession{
+		AdminID:   adminID,
+		UserID:    target.ID,
+		Reason:    req.Reason,
+		ExpiresAt: time.Now().Add(ttl),
+	}
+	if client != nil {
+		session.IPAddress = client.IPAddress
+		session.UserAgent = client.UserAgent
+	}
+	if err := s.db.Create(&session).Error; err != nil {
+		return nil, err
+	}
+
+	claims := jwt.MapClaims{
+		"user_id":          target.ID,
+		"email":            target.Email,
+		"role":             string(target.Role),
+		"impersonator_id":  adminID,
+		"impersonation_id": session.ID,
+		"exp":              session.ExpiresAt.Unix(),
+	}
+	token, err := jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString([]byte(os.Getenv("JWT_SECRET")))
+	if err != nil {
+		return nil, err
+	}
+
+	log.Printf("🕵️ Admin %s started impersonating %s (session %d): %s", admin.Email, target.Email, session.ID, req.Reason)
+
+	session.Admin = admin
+	session.User = target
+	return &dto.ImpersonationTokenResponse{
+		AccessToken: token,
+		ExpiresAt:   session.ExpiresAt,
+		Session:     *mapImpersonationSessionToResponse(&session),
+	}, nil
+}
+
+// EndSession revokes an impersonation token before it expires
+func (s *impersonationService) EndSession(sessionID, adminID uint) error {
+	var session models.ImpersonationSession
+	if err := s.db.First(&session, sessionID).Error; err != nil {
+		return ErrImpersonationNotFound
+	}
+	if session.AdminID != adminID {
+		return ErrImpersonationNotAllowed
+	}
+	if session.EndedAt != nil {
+		return nil
+	}
+	return s.db.Model(&session).Update("ended_at", time.Now()).Error
+}
+
+func (s *impersonationService) GetSessions(req *dto.PaginationRequest, userID *uint) ([]dto.ImpersonationSessionResponse, error) {
+	query := s.db.Preload("Admin").Preload("User").Order("created_at DESC")
+	if userID != nil {
+		query = query.Where("user_id = ? OR admin_id = ?", *userID, *userID)
+	}
+
+	limit := req.Limit
+	if limit <= 0 || limit > 100 {
+		limit = 20
+	}
+	page := req.Page
+	if page < 1 {
+		page = 1
+	}
+
+	var sessions []models.ImpersonationSession
+	if err := query.Offset((page - 1) * limit).Limit(limit).Find(&sessions).Error; err != nil {
+		return nil, err
+	}
+
+	response := make([]dto.ImpersonationSessionResponse, 0, len(sessions))
+	for _, session := range sessions {
+		response = append(response, *mapImpersonationSessionToResponse(&session))
+	}
+	return response, nil
+}
+
+// GetSession returns a session with every call made under it
+func (s *impersonationService) GetSession(sessionID uint) (*dto.ImpersonationSessionResponse, error) {
+	var session models.ImpersonationSession
+	if err := s.db.Preload("Admin").Preload("User").
+		Preload("Calls", func(db *gorm.DB) *gorm.DB { return db.Order("created_at ASC") }).
+		First(&session, sessionID).Error; err != nil {
+		return nil, ErrImpersonationNotFound
+	}
+	return mapImpersonationSessionToResponse(&session), nil
+}
+
+// Helper to map model to DTO
+func mapImpersonationSessionToResponse(session *models.ImpersonationSession) *dto.ImpersonationSessionResponse {
+	res := &dto.ImpersonationSessionResponse{
+		ID:        session.ID,
+		Admin:     mapUserToAuthUser(&session.Admin),
+		User:      mapUserToAuthUser(&session.User),
+		Reason:    session.Reason,
+		IPAddress: session.IPAddress,
+		ExpiresAt: session.ExpiresAt,
+		EndedAt:   session.EndedAt,
+		CreatedAt: session.CreatedAt,
+	}
+
+	for _, call := range session.Calls {
+		res.Calls = append(res.Calls, dto.ImpersonationCallEntry{
+			Method:     call.Method,
+			Path:       call.Path,
+			StatusCode: call.StatusCode,
+			CreatedAt:  call.CreatedAt,
+		})
+	}
+	return res
+}
+
+func mapUserToAuthUser(user *models.User) dto.UserResponse {
+	return dto.UserResponse{
+		ID:           user.ID,
+		Email:        user.Email,
+		Name:         user.Name,
+		Role:         string(user.Role),
+		DepartmentID: user.DepartmentID,
+		Avatar:       user.Avatar,
+	}
+}
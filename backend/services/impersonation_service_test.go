@@ -0,0 +1,37 @@
+package services
+
+import (
+	"errors"
+	"testing"
+
+	"ku-asset/auth"
+	"ku-asset/dto"
+	"ku-asset/models"
+
+	"github.com/DATA-DOG/go-sqlmock"
+)
+
+func TestImpersonateRefusesTargetWithWiderPermissions(t *testing.T) {
+	db, mock := newMockDB(t)
+	// ผู้ใช้ในหน่วยงานเดียวกัน แต่ถือ report.view ทั้งมหาวิทยาลัย
+	mock.ExpectQuery(`SELECT \* FROM "users" WHERE users.department_id IN \(\$1\)`).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "email", "role", "provider", "is_active", "department_id"}).
+			AddRow(2, "somsri@ku.ac.th", "USER", "local", true, 5))
+	mock.ExpectQuery(`SELECT count\(\*\) FROM "user_roles"`).WithArgs(uint(2)).
+		WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(1))
+	mock.ExpectQuery(`SELECT DISTINCT permissions.code`).
+		WillReturnRows(sqlmock.NewRows([]string{"code"}).AddRow(models.PermReportView))
+	mock.ExpectQuery(`SELECT count\(\*\) FROM "user_roles"`).WithArgs(uint(1)).
+		WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(1))
+	mock.ExpectQuery(`SELECT DISTINCT permissions.code`).
+		WillReturnRows(sqlmock.NewRows([]string{"code"}).AddRow(models.PermUserManage))
+
+	s := &impersonationService{db: db}
+	_, err := s.Impersonate(1, 2, &dto.ImpersonateRequest{Reason: "support"}, nil, &auth.Scope{DepartmentIDs: []uint{5}})
+	if !errors.Is(err, ErrCannotImpersonate) {
+		t.Fatalf("err = %v, want ErrCannotImpersonate", err)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Error(err)
+	}
+}
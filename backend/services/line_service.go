@@ -0,0 +1,391 @@
+// services/line_service.go
+package services
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"ku-asset/auth"
+	"ku-asset/dto"
+	"ku-asset/line"
+	"ku-asset/models"
+	"log"
+	"math/big"
+	"os"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+var (
+	ErrLineNotConfigured    = errors.New("LINE integration is not configured")
+	ErrInvalidLineSignature = errors.New("invalid LINE signature")
+)
+
+// lineLinkCodeAlphabet omits look-alike characters (0/O, 1/I) because the code is typed by hand
+const lineLinkCodeAlphabet = "ABCDEFGHJKLMNPQRSTUVWXYZ23456789"
+
+const lineLinkCodeLength = 8
+
+// LineService links user accounts to LINE and answers chatbot commands.
+type LineService interface {
+	// CreateLinkCode issues a one-time code the user sends to the bot as "link <code>"
+	CreateLinkCode(ctx context.Context, userID uint) (*dto.LineLinkCodeResponse, error)
+	Unlink(ctx context.Context, userID uint) error
+
+	// HandleWebhook verifies and processes a call from the LINE platform
+	HandleWebhook(ctx context.Context, body []byte, signature string) error
+}
+
+type lineService struct {
+	db     *gorm.DB
+	client line.Client
+}
+
+func NewLineService(db *gorm.DB, client line.Client) LineService {
+	return &lineService{db: db, client: client}
+}
+
+func (s *lineService) CreateLinkCode(ctx context.Context, userID uint) (*dto.LineLinkCodeResponse, error) {
+	code, err := generateLineLinkCode()
+	if err != nil {
+		return nil, err
+	}
+	expiresAt := time.Now().Add(getEnvDuration("LINE_LINK_CODE_TTL_MINUTES", time.Minute, 10*time.Minute))
+
+	err = s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		// รหัสเก่าที่ยังไม่ใช้ถือว่าหมดอายุ
+		if err := tx.Model(&models.UserToken{}).
+			Where("user_id = ? AND purpose = ? AND used_at IS NULL", userID, models.TokenPurposeLineLink).
+			Update("used_at", time.Now()).Error; err != nil {
+			return err
+		}
+		return tx.Create(&models.UserToken{
+			UserID:    userID,
+			Purpose:   models.TokenPurposeLineLink,
+			TokenHash: hashSecret(code),
+			ExpiresAt: expiresAt,
+		}).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &dto.LineLinkCodeResponse{
+		Code:      code,
+		Command:   "link " + code,
+		ExpiresAt: expiresAt,
+	}, nil
+}
+
+func (s *lineService) Unlink(ctx context.Context, userID uint) error {
+	return s.db.WithContext(ctx).Model(&models.User{}).Where("id = ?", userID).
+		Updates(map[string]interface{}{"line_user_id": nil, "line_linked_at": nil}).Error
+}
+
+func (s *lineService) HandleWebhook(ctx context.Context, body []byte, signature string) error {
+	secret := os.Getenv("LINE_CHANNEL_SECRET")
+	if secret == "" {
+		return ErrLineNotConfigured
+	}
+	if !line.VerifySignature(secret, body, signature) {
+		return ErrInvalidLineSignature
+	}
+
+	var payload line.WebhookPayload
+	if err := json.Unmarshal(body, &payload); err != nil {
+		return fmt.Errorf("invalid LINE webhook payload: %w", err)
+	}
+
+	// ⭐ LINE ไม่ส่งซ้ำถ้าตอบไม่ใช่ 2xx จึง log ข้อผิดพลาดของแต่ละ event แทนการคืน error
+	for _, event := range payload.Events {
+		if err := s.handleEvent(ctx, &event); err != nil {
+			log.Printf("❌ LINE %s event from %s: %v", event.Type, event.Source.UserID, err)
+		}
+	}
+	return nil
+}
+
+func (s *lineService) handleEvent(ctx context.Context, event *line.Event) error {
+	lineUserID := event.Source.UserID
+	if lineUserID == "" {
+		return nil
+	}
+
+	switch event.Type {
+	case line.EventTypeFollow:
+		user, err := s.findLinkedUser(lineUserID)
+		if err != nil {
+			return err
+		}
+		return s.reply(event, botText(localeOf(user), "welcome")+"\n\n"+botText(localeOf(user), "help"))
+
+	case line.EventTypeUnfollow:
+		// ผู้ใช้ block bot - เลิกผูกเพื่อไม่ให้ push ค้างในคิว
+		return s.db.WithContext(ctx).Model(&models.User{}).Where("line_user_id = ?", lineUserID).
+			Updates(map[string]interface{}{"line_user_id": nil, "line_linked_at": nil}).Error
+
+	case line.EventTypeMessage:
+		if event.Message == nil || event.Message.Type != line.MessageTypeText {
+			return nil
+		}
+		text, err := s.runCommand(ctx, lineUserID, event.Message.Text)
+		if err != nil {
+			return err
+		}
+		return s.reply(event, text)
+	}
+	return nil
+}
+
+// runCommand executes one chat command and returns the reply text
+func (s *lineService) runCommand(ctx context.Context, lineUserID, text string) (string, error) {
+	fields := strings.Fields(text)
+	if len(fields) == 0 {
+		return "", nil
+	}
+	command := strings.ToLower(fields[0])
+	arg := ""
+	if len(fields) > 1 {
+		arg = fields[1]
+	}
+
+	user, err := s.findLinkedUser(lineUserID)
+	if err != nil {
+		return "", err
+	}
+	locale := localeOf(user)
+
+	switch command {
+	case "link", "ผูก", "ผูกบัญชี":
+		if arg == "" {
+			return botText(locale, "link_usage"), nil
+		}
+		return s.link(ctx, lineUserID, arg)
+	case "help", "ช่วยเหลือ", "?":
+		return botText(locale, "help"), nil
+	}
+
+	// คำสั่งที่เหลือต้องผูกบัญชีก่อน
+	if user == nil {
+		return botText(locale, "not_linked"), nil
+	}
+
+	switch command {
+	case "status", "สถานะ":
+		if arg == "" {
+			return botText(locale, "status_usage"), nil
+		}
+		return s.requestStatus(user, arg)
+	case "stock", "สต็อก", "สต๊อก":
+		if arg == "" {
+			return botText(locale, "stock_usage"), nil
+		}
+		return s.productStock(user, arg)
+	case "unlink", "เลิกผูก":
+		if err := s.Unlink(ctx, user.ID); err != nil {
+			return "", err
+		}
+		return botText(locale, "unlinked"), nil
+	}
+	return botText(locale, "help"), nil
+}
+
+// link consumes a code from /profile and attaches the LINE user ID to its owner. A LINE
+// account belongs to one user at a time, so any previous link of the same account is removed.
+func (s *lineService) link(ctx context.Context, lineUserID, code string) (string, error) {
+	var user models.User
+	err := s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		token, err := consumeUserToken(tx, strings.ToUpper(strings.TrimSpace(code)), models.TokenPurposeLineLink)
+		if err != nil {
+			return err
+		}
+		if err := tx.First(&user, token.UserID).Error; err != nil {
+			return err
+		}
+		if err := tx.Model(&models.User{}).Where("line_user_id = ? AND id <> ?", lineUserID, user.ID).
+			Updates(map[string]interface{}{"line_user_id": nil, "line_linked_at": nil}).Error; err != nil {
+			return err
+		}
+		return tx.Model(&models.User{}).Where("id = ?", user.ID).
+			Updates(map[string]interface{}{"line_user_id": lineUserID, "line_linked_at": time.Now()}).Error
+	})
+	if errors.Is(err, ErrInvalidToken) {
+		return botText(models.LocaleThai, "link_invalid"), nil
+	}
+	if err != nil {
+		return "", err
+	}
+	log.Printf("💬 LINE account linked to user %s", user.Email)
+	return fmt.Sprintf(botText(localeOf(&user), "linked"), user.Name), nil
+}
+
+// requestStatus shows a request to its requester or to holders of request.view for the requester's department
+func (s *lineService) requestStatus(user *models.User, requestNumber string) (string, error) {
+	locale := localeOf(user)
+
+	var request models.Request
+	err := s.db.Preload("User").Preload("Items.Product").
+		Where("request_number = ?", strings.ToUpper(requestNumber)).First(&request).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return fmt.Sprintf(botText(locale, "request_not_found"), requestNumber), nil
+	}
+	if err != nil {
+		return "", err
+	}
+
+	if request.UserID != user.ID {
+		scope, err := auth.PermissionScope(s.db, user.ID, models.PermRequestView)
+		if err != nil {
+			return "", err
+		}
+		if !scope.Allows(request.User.DepartmentID) {
+			// ⭐ ไม่บอกว่ามีคำขอนี้อยู่ถ้าไม่มีสิทธิ์ดู
+			return fmt.Sprintf(botText(locale, "request_not_found"), requestNumber), nil
+		}
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, botText(locale, "request_status"),
+		request.RequestNumber, requestStatusLabel(locale, request.Status),
+		request.RequestDate.In(bangkok).Format("02/01/2006"), request.Purpose)
+	for _, item := range request.Items {
+		fmt.Fprintf(&b, "\n• %s × %d %s", item.Product.Name, item.Quantity, item.Product.Unit)
+	}
+	if request.AdminNote != "" {
+		fmt.Fprintf(&b, "\n\n%s: %s", emailLayoutLabels[locale].Note, request.AdminNote)
+	}
+	return b.String(), nil
+}
+
+func (s *lineService) productStock(user *models.User, code string) (string, error) {
+	locale := localeOf(user)
+
+	var product models.Product
+	err := s.db.Where("UPPER(code) = ?", strings.ToUpper(code)).First(&product).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return fmt.Sprintf(botText(locale, "product_not_found"), code), nil
+	}
+	if err != nil {
+		return "", err
+	}
+
+	text := fmt.Sprintf(botText(locale, "product_stock"),
+		product.Name, product.Code, product.Stock, product.Unit, product.MinStock, product.Unit)
+	if product.Stock <= product.MinStock {
+		text += "\n" + botText(locale, "low_stock")
+	}
+	return text, nil
+}
+
+func (s *lineService) reply(event *line.Event, text string) error {
+	if text == "" || event.ReplyToken == "" {
+		return nil
+	}
+	return s.client.Reply(event.ReplyToken, line.Text(text))
+}
+
+// findLinkedUser returns the active user linked to a LINE account, or nil when there is none
+func (s *lineService) findLinkedUser(lineUserID string) (*models.User, error) {
+	var user models.User
+	err := s.db.Where("line_user_id = ? AND is_active = ?", lineUserID, true).First(&user).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &user, nil
+}
+
+// --- Helpers ---
+
+func generateLineLinkCode() (string, error) {
+	max := big.NewInt(int64(len(lineLinkCodeAlphabet)))
+	code := make([]byte, lineLinkCodeLength)
+	for i := range code {
+		n, err := rand.Int(rand.Reader, max)
+		if err != nil {
+			return "", err
+		}
+		code[i] = lineLinkCodeAlphabet[n.Int64()]
+	}
+	return string(code), nil
+}
+
+var bangkok = time.FixedZone("Asia/Bangkok", 7*60*60)
+
+func localeOf(user *models.User) string {
+	if user != nil && user.Locale == models.LocaleEnglish {
+		return models.LocaleEnglish
+	}
+	return models.LocaleThai
+}
+
+var lineBotTexts = map[string]map[string]string{
+	models.LocaleThai: {
+		"welcome":           "ยินดีต้อนรับสู่ KU Asset 📦",
+		"help":              "คำสั่งที่ใช้ได้\n• link <รหัส> - ผูกบัญชี (ขอรหัสได้ที่หน้าโปรไฟล์)\n• status <เลขที่คำขอ> - ดูสถานะคำขอเบิก\n• stock <รหัสครุภัณฑ์> - ดูจำนวนคงเหลือ\n• unlink - เลิกผูกบัญชี",
+		"link_usage":        "พิมพ์ link ตามด้วยรหัสจากหน้าโปรไฟล์ เช่น link ABCD2345",
+		"link_invalid":      "รหัสไม่ถูกต้องหรือหมดอายุแล้ว กรุณาขอรหัสใหม่ที่หน้าโปรไฟล์",
+		"linked":            "ผูกบัญชี LINE กับ %s เรียบร้อยแล้ว ✅ ระบบจะแจ้งเตือนคำขอเบิกผ่าน LINE",
+		"not_linked":        "กรุณาผูกบัญชีก่อน: ขอรหัสที่หน้าโปรไฟล์ในระบบ KU Asset แล้วพิมพ์ link <รหัส>",
+		"unlinked":          "เลิกผูกบัญชีแล้ว จะไม่มีการแจ้งเตือนผ่าน LINE อีก",
+		"status_usage":      "พิมพ์ status ตามด้วยเลขที่คำขอ เช่น status REQ20261019001",
+		"stock_usage":       "พิมพ์ stock ตามด้วยรหัสครุภัณฑ์ เช่น stock PRD0001",
+		"request_not_found": "ไม่พบคำขอเบิกเลขที่ %s",
+		"request_status":    "คำขอเบิก %s\nสถานะ: %s\nวันที่ขอ: %s\nวัตถุประสงค์: %s",
+		"product_not_found": "ไม่พบครุภัณฑ์รหัส %s",
+		"product_stock":     "%s (%s)\nคงเหลือ: %d %s\nขั้นต่ำ: %d %s",
+		"low_stock":         "⚠️ ใกล้หมดสต็อก",
+	},
+	models.LocaleEnglish: {
+		"welcome":           "Welcome to KU Asset 📦",
+		"help":              "Commands\n• link <code> - link your account (get a code on your profile page)\n• status <request number> - check a request\n• stock <product code> - check remaining stock\n• unlink - unlink your account",
+		"link_usage":        "Send link followed by the code from your profile page, e.g. link ABCD2345",
+		"link_invalid":      "The code is invalid or has expired. Please get a new one from your profile page.",
+		"linked":            "LINE is now linked to %s ✅ You will receive request notifications here.",
+		"not_linked":        "Please link your account first: get a code on your KU Asset profile page and send link <code>.",
+		"unlinked":          "Your account has been unlinked. You will no longer receive LINE notifications.",
+		"status_usage":      "Send status followed by a request number, e.g. status REQ20261019001",
+		"stock_usage":       "Send stock followed by a product code, e.g. stock PRD0001",
+		"request_not_found": "Request %s was not found",
+		"request_status":    "Request %s\nStatus: %s\nRequested on: %s\nPurpose: %s",
+		"product_not_found": "Product %s was not found",
+		"product_stock":     "%s (%s)\nIn stock: %d %s\nMinimum: %d %s",
+		"low_stock":         "⚠️ Low stock",
+	},
+}
+
+func botText(locale, key string) string {
+	return lineBotTexts[locale][key]
+}
+
+var requestStatusLabels = map[string]map[models.RequestStatus]string{
+	models.LocaleThai: {
+		models.RequestStatusPending:   "รออนุมัติ",
+		models.RequestStatusApproved:  "อนุมัติแล้ว",
+		models.RequestStatusRejected:  "ไม่อนุมัติ",
+		models.RequestStatusIssued:    "พร้อมให้มารับ",
+		models.RequestStatusCompleted: "เสร็จสิ้น",
+		models.RequestStatusCancelled: "ยกเลิก",
+	},
+	models.LocaleEnglish: {
+		models.RequestStatusPending:   "Pending approval",
+		models.RequestStatusApproved:  "Approved",
+		models.RequestStatusRejected:  "Rejected",
+		models.RequestStatusIssued:    "Ready for pickup",
+		models.RequestStatusCompleted: "Completed",
+		models.RequestStatusCancelled: "Cancelled",
+	},
+}
+
+func requestStatusLabel(locale string, status models.RequestStatus) string {
+	if label, ok := requestStatusLabels[locale][status]; ok {
+		return label
+	}
+	return string(status)
+}
@@ -0,0 +1,136 @@
+// services/login_guard.go
+package services
+
+import (
+	"errors"
+	"fmt"
+	"ku-asset/dto"
+	"ku-asset/models"
+	"log"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+var ErrAccountDisabled = errors.New("account is disabled")
+
+// AccountLockedError is returned while an account or client IP is temporarily blocked
+type AccountLockedError struct {
+	RetryAfter time.Duration
+}
+
+func (e *AccountLockedError) Error() string {
+	return fmt.Sprintf("too many failed login attempts, try again in %d seconds", int(e.RetryAfter.Seconds()))
+}
+
+// loginPolicy holds the brute-force protection settings (ปรับได้ผ่าน ENV)
+type loginPolicy struct {
+	maxFailures   int           // จำนวนครั้งที่ผิดติดกันก่อนเริ่มล็อกบัญชี
+	baseLockout   time.Duration // ระยะเวลาล็อกครั้งแรก จากนั้นเพิ่มเป็นสองเท่า
+	maxLockout    time.Duration
+	ipMaxFailures int // จำนวนครั้งที่ผิดจาก IP เดียวภายใน ipWindow
+	ipWindow      time.Duration
+}
+
+func loadLoginPolicy() loginPolicy {
+	return loginPolicy{
+		maxFailures:   getEnvInt("LOGIN_MAX_FAILURES", 5),
+		baseLockout:   getEnvDuration("LOGIN_LOCKOUT_BASE_MINUTES", time.Minute, time.Minute),
+		maxLockout:    getEnvDuration("LOGIN_LOCKOUT_MAX_MINUTES", time.Minute, time.Hour),
+		ipMaxFailures: getEnvInt("LOGIN_IP_MAX_FAILURES", 20),
+		ipWindow:      getEnvDuration("LOGIN_IP_WINDOW_MINUTES", time.Minute, 15*time.Minute),
+	}
+}
+
+// lockoutDuration doubles the lockout for every failure past the threshold, up to maxLockout
+func (p loginPolicy) lockoutDuration(failures int) time.Duration {
+	if failures < p.maxFailures {
+		return 0
+	}
+	d := p.baseLockout
+	for i := p.maxFailures; i < failures; i++ {
+		d *= 2
+		if d >= p.maxLockout {
+			return p.maxLockout
+		}
+	}
+	return d
+}
+
+// credentialFailures are the login results that count towards the per-IP limit. Rejections
+// because of a lockout or the throttle itself do not, otherwise retries would keep an IP
+// (e.g. a campus NAT) blocked indefinitely.
+var credentialFailures = []models.LoginResult{
+	models.LoginResultInvalidPassword,
+	models.LoginResultUnknownEmail,
+	models.LoginResultInvalidMFACode,
+}
+
+// checkIPThrottle blocks an IP that produced too many failed logins within the window
+func checkIPThrottle(db *gorm.DB, client *dto.ClientInfo, policy loginPolicy) error {
+	if client == nil || client.IPAddress == "" || policy.ipMaxFailures <= 0 {
+		return nil
+	}
+
+	var failures int64
+	if err := db.Model(&models.LoginAttempt{}).
+		Where("ip_address = ? AND result IN ? AND created_at > ?", client.IPAddress, credentialFailures, time.Now().Add(-policy.ipWindow)).
+		Count(&failures).Error; err != nil {
+		return err
+	}
+	if failures >= int64(policy.ipMaxFailures) {
+		return &AccountLockedError{RetryAfter: policy.ipWindow}
+	}
+	return nil
+}
+
+// registerLoginFailure increments the user's consecutive failure counter and locks the account when needed.
+// It returns the lockout duration applied (0 when the account is not locked).
+func registerLoginFailure(db *gorm.DB, user *models.User, policy loginPolicy) (time.Duration, error) {
+	if err := db.Model(user).
+		UpdateColumn("failed_login_attempts", gorm.Expr("failed_login_attempts + 1")).Error; err != nil {
+		return 0, err
+	}
+	if err := db.Model(user).Select("failed_login_attempts").First(user).Error; err != nil {
+		return 0, err
+	}
+
+	lockout := policy.lockoutDuration(user.FailedLoginAttempts)
+	if lockout == 0 {
+		return 0, nil
+	}
+
+	lockedUntil := time.Now().Add(lockout)
+	if err := db.Model(user).UpdateColumn("locked_until", lockedUntil).Error; err != nil {
+		return 0, err
+	}
+	log.Printf("🔒 Account %s locked for %s after %d failed attempts", user.Email, lockout, user.FailedLoginAttempts)
+	return lockout, nil
+}
+
+// resetLoginFailures clears the failure counter after a successful login
+func resetLoginFailures(db *gorm.DB, user *models.User) error {
+	now := time.Now()
+	return db.Model(user).UpdateColumns(map[string]interface{}{
+		"failed_login_attempts": 0,
+		"locked_until":          nil,
+		"last_login_at":         now,
+	}).Error
+}
+
+// recordLoginAttempt writes an audit row; failures to write are logged but never block the login flow
+func recordLoginAttempt(db *gorm.DB, userID *uint, email string, client *dto.ClientInfo, result models.LoginResult) {
+	attempt := models.LoginAttempt{
+		UserID:  userID,
+		Email:   email,
+		Success: result == models.LoginResultSuccess || result == models.LoginResultMFAChallenge,
+		Result:  result,
+	}
+	if client != nil {
+		attempt.IPAddress = client.IPAddress
+		attempt.UserAgent = client.UserAgent
+	}
+	if err := db.Create(&attempt).Error; err != nil {
+		log.Printf("❌ Failed to record login attempt for %s: %v", email, err)
+	}
+}
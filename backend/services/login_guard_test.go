@@ -0,0 +1,80 @@
+package services
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"ku-asset/dto"
+	"ku-asset/models"
+
+	"github.com/DATA-DOG/go-sqlmock"
+)
+
+func TestCheckIPThrottle(t *testing.T) {
+	policy := loginPolicy{ipMaxFailures: 3, ipWindow: 15 * time.Minute}
+	client := &dto.ClientInfo{IPAddress: "10.0.0.1"}
+
+	tests := []struct {
+		name     string
+		client   *dto.ClientInfo
+		policy   loginPolicy
+		failures int64 // -1 = ไม่ควร query
+		locked   bool
+	}{
+		{"below limit", client, policy, 2, false},
+		{"at limit", client, policy, 3, true},
+		{"above limit", client, policy, 10, true},
+		{"no client", nil, policy, -1, false},
+		{"no ip address", &dto.ClientInfo{}, policy, -1, false},
+		{"throttle disabled", client, loginPolicy{ipWindow: time.Minute}, -1, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db, mock := newMockDB(t)
+			if tt.failures >= 0 {
+				// นับเฉพาะรหัสผิด/อีเมลไม่มี/2FA ผิด ไม่นับ LOCKED และ IP_THROTTLED
+				mock.ExpectQuery(`SELECT count\(\*\) FROM "login_attempts" WHERE ip_address = \$1 AND result IN \(\$2,\$3,\$4\) AND created_at > \$5`).
+					WithArgs(client.IPAddress,
+						string(models.LoginResultInvalidPassword),
+						string(models.LoginResultUnknownEmail),
+						string(models.LoginResultInvalidMFACode),
+						sqlmock.AnyArg()).
+					WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(tt.failures))
+			}
+
+			err := checkIPThrottle(db, tt.client, tt.policy)
+
+			var locked *AccountLockedError
+			if got := errors.As(err, &locked); got != tt.locked {
+				t.Fatalf("locked = %v, want %v (err: %v)", got, tt.locked, err)
+			}
+			if tt.locked && locked.RetryAfter != tt.policy.ipWindow {
+				t.Errorf("RetryAfter = %s, want %s", locked.RetryAfter, tt.policy.ipWindow)
+			}
+			if err := mock.ExpectationsWereMet(); err != nil {
+				t.Error(err)
+			}
+		})
+	}
+}
+
+func TestLockoutDuration(t *testing.T) {
+	policy := loginPolicy{maxFailures: 5, baseLockout: time.Minute, maxLockout: 10 * time.Minute}
+	tests := []struct {
+		failures int
+		want     time.Duration
+	}{
+		{4, 0},
+		{5, time.Minute},
+		{6, 2 * time.Minute},
+		{8, 8 * time.Minute},
+		{9, 10 * time.Minute},
+		{50, 10 * time.Minute},
+	}
+	for _, tt := range tests {
+		if got := policy.lockoutDuration(tt.failures); got != tt.want {
+			t.Errorf("lockoutDuration(%d) = %s, want %s", tt.failures, got, tt.want)
+		}
+	}
+}
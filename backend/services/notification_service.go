@@ -0,0 +1,673 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"ku-asset/dto"
+	"ku-asset/line"
+	"ku-asset/mailer"
+	"ku-asset/models"
+	"ku-asset/realtime"
+	"log"
+	"math"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// NotificationService delivers events in-app and by e-mail according to each user's
+// preferences. Enqueue methods take the transaction that caused the event so
+// notifications are stored only if it commits.
+type NotificationService interface {
+	EnqueueRequestCreated(tx *gorm.DB, requestID uint) error
+	EnqueueRequestStatusChanged(tx *gorm.DB, requestID uint) error
+	EnqueueRequestComment(tx *gorm.DB, commentID uint) error
+	EnqueueLowStock(tx *gorm.DB, productID uint, requestID *uint) error
+
+	// Notification center
+	GetNotifications(userID uint, query *dto.NotificationQuery) (*dto.PaginatedNotificationResponse, error)
+	GetUnreadCount(userID uint) (int64, error)
+	MarkRead(userID, notificationID uint) error
+	MarkAllRead(userID uint) (int64, error)
+	GetPreferences(userID uint) ([]dto.NotificationPreferenceResponse, error)
+	UpdatePreferences(userID uint, req *dto.UpdateNotificationPreferencesRequest) ([]dto.NotificationPreferenceResponse, error)
+
+	// Start runs the outbox worker and the overdue pickup scanner until ctx is cancelled
+	Start(ctx context.Context)
+}
+
+var ErrNotificationNotFound = errors.New("notification not found")
+
+type notificationService struct {
+	db     *gorm.DB
+	mailer mailer.Mailer
+	line   line.Client
+	hub    realtime.Hub
+}
+
+func NewNotificationService(db *gorm.DB, m mailer.Mailer, lineClient line.Client, hub realtime.Hub) NotificationService {
+	return &notificationService{db: db, mailer: m, line: lineClient, hub: hub}
+}
+
+// --- Enqueue ---
+
+func (s *notificationService) EnqueueRequestCreated(tx *gorm.DB, requestID uint) error {
+	request, err := loadRequestForNotification(tx, requestID)
+	if err != nil {
+		return err
+	}
+
+	approvers, err := usersWithPermission(tx, models.PermRequestApprove, request.User.DepartmentID)
+	if err != nil {
+		return err
+	}
+	for i := range approvers {
+		if approvers[i].ID == request.UserID {
+			continue
+		}
+		data := requestNotificationData(request, &approvers[i], "/dashboard/admin")
+		if err := s.dispatch(tx, EventRequestCreated, &approvers[i], data, &request.ID, nil); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *notificationService) EnqueueRequestStatusChanged(tx *gorm.DB, requestID uint) error {
+	request, err := loadRequestForNotification(tx, requestID)
+	if err != nil {
+		return err
+	}
+
+	var event string
+	switch request.Status {
+	case models.RequestStatusApproved:
+		event = EventRequestApproved
+	case models.RequestStatusRejected:
+		event = EventRequestRejected
+	case models.RequestStatusIssued:
+		event = EventRequestReady
+	default:
+		return nil // COMPLETED / PENDING ไม่ต้องแจ้ง
+	}
+	data := requestNotificationData(request, &request.User, "/requests")
+	return s.dispatch(tx, event, &request.User, data, &request.ID, nil)
+}
+
+// EnqueueRequestComment notifies the requester of staff comments, and the approvers of the requester's comments
+func (s *notificationService) EnqueueRequestComment(tx *gorm.DB, commentID uint) error {
+	var comment models.RequestComment
+	if err := tx.Preload("User").First(&comment, commentID).Error; err != nil {
+		return err
+	}
+	request, err := loadRequestForNotification(tx, comment.RequestID)
+	if err != nil {
+		return err
+	}
+
+	recipients := []models.User{request.User}
+	link := "/requests"
+	if comment.UserID == request.UserID {
+		if recipients, err = usersWithPermission(tx, models.PermRequestView, request.User.DepartmentID); err != nil {
+			return err
+		}
+		link = "/dashboard/admin"
+	}
+
+	for i := range recipients {
+		if recipients[i].ID == comment.UserID {
+			continue
+		}
+		data := requestNotificationData(request, &recipients[i], link)
+		data.Items = nil
+		data.CommenterName = comment.User.Name
+		data.Comment = comment.Body
+		if err := s.dispatch(tx, EventRequestComment, &recipients[i], data, &request.ID, nil); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// EnqueueLowStock alerts stock managers that a product is at or below its minimum stock
+func (s *notificationService) EnqueueLowStock(tx *gorm.DB, productID uint, requestID *uint) error {
+	var product models.Product
+	if err := tx.First(&product, productID).Error; err != nil {
+		return err
+	}
+
+	var requestNumber string
+	if requestID != nil {
+		if err := tx.Model(&models.Request{}).Where("id = ?", *requestID).Pluck("request_number", &requestNumber).Error; err != nil {
+			return err
+		}
+	}
+
+	managers, err := usersWithPermission(tx, models.PermStockAdjust, nil)
+	if err != nil {
+		return err
+	}
+	for i := range managers {
+		data := &notificationData{
+			RecipientName: managers[i].Name,
+			RequestNumber: requestNumber,
+			ProductName:   product.Name,
+			Stock:         product.Stock,
+			MinStock:      product.MinStock,
+			Unit:          product.Unit,
+			Link:          frontendURL() + "/dashboard/admin",
+		}
+		if err := s.dispatch(tx, EventProductLowStock, &managers[i], data, requestID, &product.ID); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// dispatch renders an event in the recipient's language and stores it for every channel they enabled
+func (s *notificationService) dispatch(tx *gorm.DB, event string, recipient *models.User, data *notificationData, requestID, productID *uint) error {
+	if !recipient.IsActive {
+		return nil
+	}
+
+	pref, err := preferenceFor(tx, recipient.ID, event)
+	if err != nil {
+		return err
+	}
+	sendLine := pref.Line && recipient.LineUserID != nil
+	if !pref.InApp && !pref.Email && !sendLine {
+		return nil
+	}
+
+	rendered, err := renderNotification(event, recipient.Locale, data)
+	if err != nil {
+		return fmt.Errorf("failed to render %s notification: %w", event, err)
+	}
+
+	if pref.InApp {
+		notification := models.Notification{
+			UserID:    recipient.ID,
+			Event:     event,
+			Title:     rendered.Subject,
+			Body:      rendered.Intro,
+			Link:      strings.TrimPrefix(data.Link, frontendURL()),
+			RequestID: requestID,
+			ProductID: productID,
+		}
+		if err := tx.Create(&notification).Error; err != nil {
+			return err
+		}
+
+		// 🆕 push เข้า stream ของผู้รับ (ถ้า tx มี batch จะรอส่งหลัง commit)
+		realtime.Emit(tx.Statement.Context, s.hub, realtime.Event{
+			Type:   realtime.EventNotification,
+			Data:   mapNotificationToResponse(&notification),
+			UserID: &notification.UserID,
+		})
+	}
+
+	if pref.Email && recipient.Email != "" {
+		if err := tx.Create(&models.EmailJob{
+			Event:         event,
+			Recipient:     recipient.Email,
+			Subject:       rendered.Subject,
+			TextBody:      rendered.Text,
+			HTMLBody:      rendered.HTML,
+			RequestID:     requestID,
+			Status:        models.EmailJobPending,
+			NextAttemptAt: time.Now(),
+		}).Error; err != nil {
+			return err
+		}
+	}
+
+	if sendLine {
+		if err := tx.Create(&models.LineJob{
+			Event:         event,
+			UserID:        recipient.ID,
+			LineUserID:    *recipient.LineUserID,
+			Text:          lineNotificationText(rendered, data),
+			RequestID:     requestID,
+			Status:        models.LineJobPending,
+			NextAttemptAt: time.Now(),
+		}).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// --- Notification center ---
+
+func (s *notificationService) GetNotifications(userID uint, query *dto.NotificationQuery) (*dto.PaginatedNotificationResponse, error) {
+	q := s.db.Model(&models.Notification{}).Where("user_id = ?", userID)
+	if query.UnreadOnly {
+		q = q.Where("read_at IS NULL")
+	}
+
+	var total int64
+	if err := q.Count(&total).Error; err != nil {
+		return nil, err
+	}
+	unread, err := s.GetUnreadCount(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	limit := query.Limit
+	if limit <= 0 || limit > 100 {
+		limit = 20
+	}
+	page := query.Page
+	if page < 1 {
+		page = 1
+	}
+
+	var notifications []models.Notification
+	if err := q.Order("created_at DESC, id DESC").Offset((page - 1) * limit).Limit(limit).Find(&notifications).Error; err != nil {
+		return nil, err
+	}
+
+	response := make([]dto.NotificationResponse, 0, len(notifications))
+	for i := range notifications {
+		response = append(response, mapNotificationToResponse(&notifications[i]))
+	}
+	return &dto.PaginatedNotificationResponse{
+		Notifications: response,
+		UnreadCount:   unread,
+		Pagination: dto.PaginationResponse{
+			CurrentPage: page,
+			PerPage:     limit,
+			Total:       total,
+			TotalPages:  int64(math.Ceil(float64(total) / float64(limit))),
+		},
+	}, nil
+}
+
+func (s *notificationService) GetUnreadCount(userID uint) (int64, error) {
+	var count int64
+	err := s.db.Model(&models.Notification{}).Where("user_id = ? AND read_at IS NULL", userID).Count(&count).Error
+	return count, err
+}
+
+func (s *notificationService) MarkRead(userID, notificationID uint) error {
+	result := s.db.Model(&models.Notification{}).
+		Where("id = ? AND user_id = ?", notificationID, userID).
+		Update("read_at", gorm.Expr("COALESCE(read_at, ?)", time.Now()))
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrNotificationNotFound
+	}
+	return nil
+}
+
+func (s *notificationService) MarkAllRead(userID uint) (int64, error) {
+	result := s.db.Model(&models.Notification{}).
+		Where("user_id = ? AND read_at IS NULL", userID).
+		Update("read_at", time.Now())
+	return result.RowsAffected, result.Error
+}
+
+func (s *notificationService) GetPreferences(userID uint) ([]dto.NotificationPreferenceResponse, error) {
+	var stored []models.NotificationPreference
+	if err := s.db.Where("user_id = ?", userID).Find(&stored).Error; err != nil {
+		return nil, err
+	}
+	byEvent := make(map[string]models.NotificationPreference, len(stored))
+	for _, p := range stored {
+		byEvent[p.Event] = p
+	}
+
+	response := make([]dto.NotificationPreferenceResponse, 0, len(NotificationEvents))
+	for _, e := range NotificationEvents {
+		pref, ok := byEvent[e.Event]
+		if !ok {
+			pref = models.NotificationPreference{InApp: true, Email: true, Line: true}
+		}
+		response = append(response, dto.NotificationPreferenceResponse{
+			Event:       e.Event,
+			Description: e.Description,
+			InApp:       pref.InApp,
+			Email:       pref.Email,
+			Line:        pref.Line,
+		})
+	}
+	return response, nil
+}
+
+func (s *notificationService) UpdatePreferences(userID uint, req *dto.UpdateNotificationPreferencesRequest) ([]dto.NotificationPreferenceResponse, error) {
+	known := make(map[string]bool, len(NotificationEvents))
+	for _, e := range NotificationEvents {
+		known[e.Event] = true
+	}
+
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		for _, p := range req.Preferences {
+			if !known[p.Event] {
+				return fmt.Errorf("unknown notification event: %s", p.Event)
+			}
+			current, err := preferenceFor(tx, userID, p.Event)
+			if err != nil {
+				return err
+			}
+			// ⭐ client เก่าที่ไม่ส่ง line มาจะไม่ไปปิดช่องทาง LINE ที่ตั้งไว้
+			lineEnabled := current.Line
+			if p.Line != nil {
+				lineEnabled = *p.Line
+			}
+			pref := models.NotificationPreference{UserID: userID, Event: p.Event, InApp: p.InApp, Email: p.Email, Line: lineEnabled}
+			if err := tx.Clauses(clause.OnConflict{
+				Columns:   []clause.Column{{Name: "user_id"}, {Name: "event"}},
+				DoUpdates: clause.AssignmentColumns([]string{"in_app", "email", "line", "updated_at"}),
+			}).Create(&pref).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return s.GetPreferences(userID)
+}
+
+// --- Worker ---
+
+func (s *notificationService) Start(ctx context.Context) {
+	go s.runEvery(ctx, getEnvDuration("MAIL_QUEUE_POLL_SECONDS", time.Second, 5*time.Second), s.deliverDue)
+	go s.runEvery(ctx, getEnvDuration("MAIL_QUEUE_POLL_SECONDS", time.Second, 5*time.Second), s.deliverLineDue)
+	go s.runEvery(ctx, getEnvDuration("REQUEST_OVERDUE_CHECK_MINUTES", time.Minute, time.Hour), s.enqueueOverdue)
+}
+
+func (s *notificationService) runEvery(ctx context.Context, interval time.Duration, fn func() error) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		if err := fn(); err != nil {
+			log.Printf("❌ Notification worker: %v", err)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// outboxLease is how long a claimed batch stays hidden from other workers. If a worker dies
+// mid-batch, the jobs it did not finish become due again after the lease.
+const outboxLease = 5 * time.Minute
+
+// claimOutboxBatch locks up to 20 due jobs of an outbox table with SKIP LOCKED, pushes their
+// next_attempt_at past the lease and commits. Sending happens outside the transaction, so a
+// failed status update only affects its own job, which is re-sent once the lease expires.
+// jobs is a pointer to a slice of EmailJob or LineJob.
+func claimOutboxBatch(db *gorm.DB, jobs interface{}, pending interface{}) error {
+	return db.Transaction(func(tx *gorm.DB) error {
+		result := tx.Clauses(clause.Locking{Strength: "UPDATE", Options: "SKIP LOCKED"}).
+			Where("status = ? AND next_attempt_at <= ?", pending, time.Now()).
+			Order("next_attempt_at").Limit(20).Find(jobs)
+		if result.Error != nil || result.RowsAffected == 0 {
+			return result.Error
+		}
+		return tx.Model(jobs).Update("next_attempt_at", time.Now().Add(outboxLease)).Error
+	})
+}
+
+// deliverDue sends one batch of due e-mails. Several server instances can share the queue:
+// each claims its own batch before sending. Delivery is at-least-once: when the status of a
+// sent job cannot be recorded the job is logged and skipped, and it is sent again once its
+// lease expires.
+func (s *notificationService) deliverDue() error {
+	var jobs []models.EmailJob
+	if err := claimOutboxBatch(s.db, &jobs, models.EmailJobPending); err != nil {
+		return err
+	}
+	for i := range jobs {
+		if err := s.sendEmailJob(&jobs[i]); err != nil {
+			log.Printf("❌ Failed to record e-mail %d: %v", jobs[i].ID, err)
+		}
+	}
+	return nil
+}
+
+// sendEmailJob sends one claimed e-mail and records the outcome
+func (s *notificationService) sendEmailJob(job *models.EmailJob) error {
+	maxAttempts := getEnvInt("MAIL_QUEUE_MAX_ATTEMPTS", 6)
+	retryBase := getEnvDuration("MAIL_QUEUE_RETRY_BASE_SECONDS", time.Second, 30*time.Second)
+
+	job.Attempts++
+	err := s.mailer.Send(mailer.Message{
+		To:       []string{job.Recipient},
+		Subject:  job.Subject,
+		TextBody: job.TextBody,
+		HTMLBody: job.HTMLBody,
+	})
+
+	now := time.Now()
+	updates := map[string]interface{}{"attempts": job.Attempts}
+	switch {
+	case err == nil:
+		updates["status"] = models.EmailJobSent
+		updates["sent_at"] = now
+		updates["last_error"] = ""
+	case job.Attempts >= maxAttempts:
+		log.Printf("❌ Giving up e-mail %d (%s) to %s: %v", job.ID, job.Event, job.Recipient, err)
+		updates["status"] = models.EmailJobFailed
+		updates["last_error"] = err.Error()
+	default:
+		delay := outboxRetryDelay(retryBase, job.Attempts)
+		log.Printf("⚠️ E-mail %d to %s failed (attempt %d), retrying in %s: %v", job.ID, job.Recipient, job.Attempts, delay, err)
+		updates["next_attempt_at"] = now.Add(delay)
+		updates["last_error"] = err.Error()
+	}
+	return s.db.Model(job).Updates(updates).Error
+}
+
+// deliverLineDue pushes one batch of due LINE messages, with the same claiming and
+// retry policy as e-mail
+func (s *notificationService) deliverLineDue() error {
+	var jobs []models.LineJob
+	if err := claimOutboxBatch(s.db, &jobs, models.LineJobPending); err != nil {
+		return err
+	}
+	for i := range jobs {
+		if err := s.sendLineJob(&jobs[i]); err != nil {
+			log.Printf("❌ Failed to record LINE message %d: %v", jobs[i].ID, err)
+		}
+	}
+	return nil
+}
+
+// sendLineJob pushes one claimed LINE message and records the outcome
+func (s *notificationService) sendLineJob(job *models.LineJob) error {
+	maxAttempts := getEnvInt("MAIL_QUEUE_MAX_ATTEMPTS", 6)
+	retryBase := getEnvDuration("MAIL_QUEUE_RETRY_BASE_SECONDS", time.Second, 30*time.Second)
+
+	job.Attempts++
+	err := s.line.Push(job.LineUserID, line.Text(job.Text))
+
+	// ⭐ 4xx จาก LINE (เช่นผู้ใช้ block bot) ลองใหม่ก็ไม่สำเร็จ
+	var apiErr *line.APIError
+	permanent := errors.As(err, &apiErr) && apiErr.StatusCode >= 400 && apiErr.StatusCode < 500 && apiErr.StatusCode != 429
+
+	now := time.Now()
+	updates := map[string]interface{}{"attempts": job.Attempts}
+	switch {
+	case err == nil:
+		updates["status"] = models.LineJobSent
+		updates["sent_at"] = now
+		updates["last_error"] = ""
+	case permanent || job.Attempts >= maxAttempts:
+		log.Printf("❌ Giving up LINE message %d (%s) to user %d: %v", job.ID, job.Event, job.UserID, err)
+		updates["status"] = models.LineJobFailed
+		updates["last_error"] = err.Error()
+	default:
+		delay := outboxRetryDelay(retryBase, job.Attempts)
+		log.Printf("⚠️ LINE message %d to user %d failed (attempt %d), retrying in %s: %v", job.ID, job.UserID, job.Attempts, delay, err)
+		updates["next_attempt_at"] = now.Add(delay)
+		updates["last_error"] = err.Error()
+	}
+	return s.db.Model(job).Updates(updates).Error
+}
+
+// outboxRetryDelay is the exponential backoff of the e-mail and LINE outboxes:
+// 30s, 1m, 2m, ... สูงสุด 1 ชั่วโมง
+func outboxRetryDelay(base time.Duration, attempts int) time.Duration {
+	delay := base << (attempts - 1)
+	if delay > time.Hour || delay <= 0 {
+		delay = time.Hour
+	}
+	return delay
+}
+
+// enqueueOverdue reminds requesters whose items have been ready for pickup longer than REQUEST_PICKUP_DAYS
+func (s *notificationService) enqueueOverdue() error {
+	cutoff := time.Now().Add(-pickupWindow())
+
+	var ids []uint
+	if err := s.db.Model(&models.Request{}).
+		Where("status = ? AND issued_date < ? AND overdue_notified_at IS NULL", models.RequestStatusIssued, cutoff).
+		Pluck("id", &ids).Error; err != nil {
+		return err
+	}
+
+	for _, id := range ids {
+		err := s.db.Transaction(func(tx *gorm.DB) error {
+			request, err := loadRequestForNotification(tx, id)
+			if err != nil {
+				return err
+			}
+			data := requestNotificationData(request, &request.User, "/requests")
+			if err := s.dispatch(tx, EventRequestOverdue, &request.User, data, &request.ID, nil); err != nil {
+				return err
+			}
+			return tx.Model(&models.Request{}).Where("id = ?", id).Update("overdue_notified_at", time.Now()).Error
+		})
+		if err != nil {
+			return fmt.Errorf("overdue reminder for request %d: %w", id, err)
+		}
+	}
+	return nil
+}
+
+// --- Helpers ---
+
+func pickupWindow() time.Duration {
+	return getEnvDuration("REQUEST_PICKUP_DAYS", 24*time.Hour, 7*24*time.Hour)
+}
+
+func loadRequestForNotification(tx *gorm.DB, requestID uint) (*models.Request, error) {
+	var request models.Request
+	if err := tx.Preload("User.Department").Preload("Items.Product").First(&request, requestID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("request not found")
+		}
+		return nil, err
+	}
+	return &request, nil
+}
+
+// requestNotificationData fills the template data of a request event for one recipient
+func requestNotificationData(request *models.Request, recipient *models.User, path string) *notificationData {
+	data := &notificationData{
+		RecipientName: recipient.Name,
+		RequestNumber: request.RequestNumber,
+		Purpose:       request.Purpose,
+		RequesterName: request.User.Name,
+		AdminNote:     request.AdminNote,
+		Link:          frontendURL() + path,
+	}
+	if dept := request.User.Department; dept != nil {
+		data.Department = dept.NameTH
+		if recipient.Locale == models.LocaleEnglish && dept.NameEN != "" {
+			data.Department = dept.NameEN
+		}
+	}
+	if request.IssuedDate != nil {
+		data.DueDate = request.IssuedDate.Add(pickupWindow()).Format("02/01/2006")
+	}
+	for _, item := range request.Items {
+		data.Items = append(data.Items, notificationItem{
+			Name:     item.Product.Name,
+			Quantity: item.Quantity,
+			Unit:     item.Product.Unit,
+		})
+	}
+	return data
+}
+
+// preferenceFor returns a user's channels for an event; both are on unless the user opted out
+func preferenceFor(tx *gorm.DB, userID uint, event string) (*models.NotificationPreference, error) {
+	pref := models.NotificationPreference{UserID: userID, Event: event, InApp: true, Email: true, Line: true}
+	err := tx.Where("user_id = ? AND event = ?", userID, event).Take(&pref).Error
+	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, err
+	}
+	return &pref, nil
+}
+
+func mapNotificationToResponse(n *models.Notification) dto.NotificationResponse {
+	return dto.NotificationResponse{
+		ID:        n.ID,
+		Event:     n.Event,
+		Title:     n.Title,
+		Body:      n.Body,
+		Link:      n.Link,
+		RequestID: n.RequestID,
+		ProductID: n.ProductID,
+		ReadAt:    n.ReadAt,
+		CreatedAt: n.CreatedAt,
+	}
+}
+
+// usersWithPermission returns active users holding a permission for a department: global grants,
+// grants on the department or any of its ancestors, and legacy role holders.
+// With a nil department only global grants count.
+func usersWithPermission(tx *gorm.DB, permission string, departmentID *uint) ([]models.User, error) {
+	var ancestors []uint
+	for id := departmentID; id != nil; {
+		ancestors = append(ancestors, *id)
+		var dept models.Department
+		if err := tx.Select("id", "parent_id").First(&dept, *id).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				break
+			}
+			return nil, err
+		}
+		if len(ancestors) > 20 { // กัน parent วนซ้ำ
+			break
+		}
+		id = dept.ParentID
+	}
+
+	grants := tx.Table("user_roles").
+		Select("user_roles.user_id").
+		Joins("JOIN role_permissions ON role_permissions.role_id = user_roles.role_id").
+		Joins("JOIN permissions ON permissions.id = role_permissions.permission_id").
+		Where("permissions.code = ?", permission)
+	if len(ancestors) > 0 {
+		grants = grants.Where("user_roles.department_id IS NULL OR user_roles.department_id IN ?", ancestors)
+	} else {
+		grants = grants.Where("user_roles.department_id IS NULL")
+	}
+
+	legacy := tx.Table("users").
+		Select("users.id").
+		Joins("JOIN roles ON roles.name = users.role").
+		Joins("JOIN role_permissions ON role_permissions.role_id = roles.id").
+		Joins("JOIN permissions ON permissions.id = role_permissions.permission_id").
+		Where("permissions.code = ?", permission).
+		Where("NOT EXISTS (SELECT 1 FROM user_roles WHERE user_roles.user_id = users.id)")
+
+	var users []models.User
+	err := tx.Where("is_active = ? AND provider <> ?", true, models.ProviderService).
+		Where("id IN (?) OR id IN (?)", grants, legacy).
+		Find(&users).Error
+	return users, err
+}
@@ -0,0 +1,93 @@
+package services
+
+import (
+	"errors"
+	"testing"
+
+	"ku-asset/line"
+	"ku-asset/mailer"
+	"ku-asset/models"
+
+	"github.com/DATA-DOG/go-sqlmock"
+)
+
+func TestDeliverDueSendsOutsideTheClaim(t *testing.T) {
+	db, mock := newMockDB(t)
+	mock.ExpectBegin()
+	mock.ExpectQuery(`SELECT \* FROM "email_jobs" WHERE status = \$1 AND next_attempt_at <= \$2 ORDER BY next_attempt_at LIMIT \$3 FOR UPDATE SKIP LOCKED`).
+		WithArgs(models.EmailJobPending, sqlmock.AnyArg(), 20).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "recipient", "subject", "text_body", "status", "attempts"}).
+			AddRow(1, "a@ku.ac.th", "A", "a", "PENDING", 0).
+			AddRow(2, "b@ku.ac.th", "B", "b", "PENDING", 0))
+	mock.ExpectExec(`UPDATE "email_jobs" SET "next_attempt_at"=\$1,"updated_at"=\$2 WHERE "id" IN \(\$3,\$4\)`).
+		WillReturnResult(sqlmock.NewResult(0, 2))
+	mock.ExpectCommit()
+
+	// สถานะของแต่ละงานบันทึกแยกกัน งานที่ 1 บันทึกไม่สำเร็จต้องไม่หยุดงานที่เหลือในชุด
+	mock.ExpectBegin()
+	mock.ExpectExec(`UPDATE "email_jobs" SET .*"status"=.* WHERE "id" = \$\d+`).WillReturnError(errors.New("connection reset"))
+	mock.ExpectRollback()
+	mock.ExpectBegin()
+	mock.ExpectExec(`UPDATE "email_jobs" SET .*"status"=.* WHERE "id" = \$\d+`).WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+
+	mail := mailer.NewMemoryMailer()
+	s := &notificationService{db: db, mailer: mail}
+	if err := s.deliverDue(); err != nil {
+		t.Fatal(err)
+	}
+	if got := len(mail.Messages()); got != 2 {
+		t.Errorf("sent %d e-mails, want 2", got)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestDeliverDueEmptyQueue(t *testing.T) {
+	db, mock := newMockDB(t)
+	mock.ExpectBegin()
+	mock.ExpectQuery(`FROM "email_jobs"`).WillReturnRows(sqlmock.NewRows([]string{"id"}))
+	mock.ExpectCommit()
+
+	mail := mailer.NewMemoryMailer()
+	s := &notificationService{db: db, mailer: mail}
+	if err := s.deliverDue(); err != nil {
+		t.Fatal(err)
+	}
+	if len(mail.Messages()) != 0 {
+		t.Error("sent e-mails from an empty queue")
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestDeliverLineDue(t *testing.T) {
+	db, mock := newMockDB(t)
+	mock.ExpectBegin()
+	mock.ExpectQuery(`SELECT \* FROM "line_jobs" .* FOR UPDATE SKIP LOCKED`).
+		WithArgs(models.LineJobPending, sqlmock.AnyArg(), 20).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "user_id", "line_user_id", "text", "status", "attempts"}).
+			AddRow(4, 7, "U123", "hello", "PENDING", 0))
+	mock.ExpectExec(`UPDATE "line_jobs" SET "next_attempt_at"=\$1,"updated_at"=\$2 WHERE "id" = \$3`).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+	mock.ExpectBegin()
+	mock.ExpectExec(`UPDATE "line_jobs" SET .*"status"=.* WHERE "id" = \$\d+`).
+		WithArgs(1, sqlmock.AnyArg(), sqlmock.AnyArg(), models.LineJobSent, sqlmock.AnyArg(), 4).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+
+	client := line.NewMemoryClient()
+	s := &notificationService{db: db, line: client}
+	if err := s.deliverLineDue(); err != nil {
+		t.Fatal(err)
+	}
+	if sent := client.Sent(); len(sent) != 1 || sent[0].To != "U123" {
+		t.Errorf("sent = %+v", sent)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Error(err)
+	}
+}
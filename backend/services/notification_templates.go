@@ -0,0 +1,269 @@
+package services
+
+import (
+	"bytes"
+	htmltemplate "html/template"
+	"ku-asset/models"
+	"text/template"
+)
+
+// Notification events
+const (
+	EventRequestCreated  = "request.created"
+	EventRequestApproved = "request.approved"
+	EventRequestRejected = "request.rejected"
+	EventRequestReady    = "request.ready_for_pickup"
+	EventRequestOverdue  = "request.overdue"
+	EventRequestComment  = "request.comment"
+	EventProductLowStock = "product.low_stock"
+)
+
+// NotificationEvents lists every event a user can set preferences for
+var NotificationEvents = []struct {
+	Event       string
+	Description string
+}{
+	{EventRequestCreated, "มีคำขอเบิกใหม่รอการอนุมัติ"},
+	{EventRequestApproved, "คำขอเบิกของฉันได้รับการอนุมัติ"},
+	{EventRequestRejected, "คำขอเบิกของฉันไม่ได้รับการอนุมัติ"},
+	{EventRequestReady, "ของพร้อมให้มารับ"},
+	{EventRequestOverdue, "เตือนเมื่อยังไม่ได้มารับของ"},
+	{EventRequestComment, "มีความคิดเห็นใหม่ในคำขอเบิก"},
+	{EventProductLowStock, "ครุภัณฑ์ใกล้หมดสต็อก"},
+}
+
+// notificationData is what every notification template can use
+type notificationData struct {
+	RecipientName string
+	RequestNumber string
+	Purpose       string
+	RequesterName string
+	Department    string
+	AdminNote     string
+	Items         []notificationItem
+	Link          string
+	DueDate       string
+
+	ProductName   string
+	Stock         int
+	MinStock      int
+	Unit          string
+	CommenterName string
+	Comment       string
+}
+
+type notificationItem struct {
+	Name     string
+	Quantity int
+	Unit     string
+}
+
+// emailTemplate holds the event-specific parts; the layout (greeting, items, note, link) is shared.
+// Subject and Intro double as the title and body of the in-app notification.
+type emailTemplate struct {
+	Subject string
+	Intro   string
+	Action  string
+}
+
+type emailLabels struct {
+	Greeting string
+	Items    string
+	Note     string
+	Footer   string
+}
+
+var emailLayoutLabels = map[string]emailLabels{
+	models.LocaleThai: {
+		Greeting: "เรียน",
+		Items:    "รายการที่ขอเบิก",
+		Note:     "หมายเหตุจากผู้ดูแล",
+		Footer:   "อีเมลนี้ส่งอัตโนมัติจากระบบเบิกจ่ายครุภัณฑ์ KU Asset กรุณาอย่าตอบกลับ",
+	},
+	models.LocaleEnglish: {
+		Greeting: "Dear",
+		Items:    "Requested items",
+		Note:     "Note from the administrator",
+		Footer:   "This is an automated message from KU Asset. Please do not reply.",
+	},
+}
+
+var notificationTemplates = map[string]map[string]emailTemplate{
+	EventRequestCreated: {
+		models.LocaleThai: {
+			Subject: "คำขอเบิกใหม่ {{.RequestNumber}} จาก {{.RequesterName}}",
+			Intro:   "{{.RequesterName}}{{if .Department}} ({{.Department}}){{end}} ได้ส่งคำขอเบิกเลขที่ {{.RequestNumber}} เพื่อ {{.Purpose}} และกำลังรอการอนุมัติ",
+			Action:  "ตรวจสอบคำขอ",
+		},
+		models.LocaleEnglish: {
+			Subject: "New request {{.RequestNumber}} from {{.RequesterName}}",
+			Intro:   "{{.RequesterName}}{{if .Department}} ({{.Department}}){{end}} submitted request {{.RequestNumber}} for \"{{.Purpose}}\". It is waiting for your approval.",
+			Action:  "Review request",
+		},
+	},
+	EventRequestApproved: {
+		models.LocaleThai: {
+			Subject: "คำขอเบิก {{.RequestNumber}} ได้รับการอนุมัติแล้ว",
+			Intro:   "คำขอเบิกเลขที่ {{.RequestNumber}} ของคุณได้รับการอนุมัติแล้ว เจ้าหน้าที่กำลังจัดเตรียมของ ระบบจะแจ้งอีกครั้งเมื่อพร้อมให้มารับ",
+			Action:  "ดูคำขอของฉัน",
+		},
+		models.LocaleEnglish: {
+			Subject: "Request {{.RequestNumber}} approved",
+			Intro:   "Your request {{.RequestNumber}} has been approved. Staff are preparing the items and we will let you know when they are ready for pickup.",
+			Action:  "View my requests",
+		},
+	},
+	EventRequestRejected: {
+		models.LocaleThai: {
+			Subject: "คำขอเบิก {{.RequestNumber}} ไม่ได้รับการอนุมัติ",
+			Intro:   "ขออภัย คำขอเบิกเลขที่ {{.RequestNumber}} ของคุณไม่ได้รับการอนุมัติ",
+			Action:  "ดูคำขอของฉัน",
+		},
+		models.LocaleEnglish: {
+			Subject: "Request {{.RequestNumber}} was rejected",
+			Intro:   "Sorry, your request {{.RequestNumber}} was not approved.",
+			Action:  "View my requests",
+		},
+	},
+	EventRequestReady: {
+		models.LocaleThai: {
+			Subject: "ของตามคำขอเบิก {{.RequestNumber}} พร้อมให้มารับแล้ว",
+			Intro:   "ของตามคำขอเบิกเลขที่ {{.RequestNumber}} พร้อมให้มารับแล้ว กรุณามารับภายในวันที่ {{.DueDate}}",
+			Action:  "ดูคำขอของฉัน",
+		},
+		models.LocaleEnglish: {
+			Subject: "Request {{.RequestNumber}} is ready for pickup",
+			Intro:   "The items for request {{.RequestNumber}} are ready for pickup. Please collect them by {{.DueDate}}.",
+			Action:  "View my requests",
+		},
+	},
+	EventRequestOverdue: {
+		models.LocaleThai: {
+			Subject: "เตือน: ยังไม่ได้มารับของตามคำขอเบิก {{.RequestNumber}}",
+			Intro:   "ของตามคำขอเบิกเลขที่ {{.RequestNumber}} พร้อมให้มารับตั้งแต่ก่อนวันที่ {{.DueDate}} แต่ยังไม่มีการรับของ กรุณามารับโดยเร็ว หรือติดต่อเจ้าหน้าที่หากไม่ต้องการแล้ว",
+			Action:  "ดูคำขอของฉัน",
+		},
+		models.LocaleEnglish: {
+			Subject: "Reminder: request {{.RequestNumber}} has not been picked up",
+			Intro:   "The items for request {{.RequestNumber}} were due for pickup by {{.DueDate}} but have not been collected. Please pick them up soon or contact staff if you no longer need them.",
+			Action:  "View my requests",
+		},
+	},
+	EventRequestComment: {
+		models.LocaleThai: {
+			Subject: "ความคิดเห็นใหม่ในคำขอเบิก {{.RequestNumber}}",
+			Intro:   "{{.CommenterName}} แสดงความคิดเห็นในคำขอเบิกเลขที่ {{.RequestNumber}}: \"{{.Comment}}\"",
+			Action:  "ดูคำขอ",
+		},
+		models.LocaleEnglish: {
+			Subject: "New comment on request {{.RequestNumber}}",
+			Intro:   "{{.CommenterName}} commented on request {{.RequestNumber}}: \"{{.Comment}}\"",
+			Action:  "View request",
+		},
+	},
+	EventProductLowStock: {
+		models.LocaleThai: {
+			Subject: "ครุภัณฑ์ใกล้หมด: {{.ProductName}}",
+			Intro:   "{{.ProductName}} เหลือ {{.Stock}} {{.Unit}} ซึ่งไม่เกินจำนวนขั้นต่ำ {{.MinStock}} {{.Unit}}{{if .RequestNumber}} หลังอนุมัติคำขอเบิก {{.RequestNumber}}{{end}}",
+			Action:  "จัดการสต็อก",
+		},
+		models.LocaleEnglish: {
+			Subject: "Low stock: {{.ProductName}}",
+			Intro:   "Only {{.Stock}} {{.Unit}} of {{.ProductName}} left, at or below the minimum of {{.MinStock}} {{.Unit}}{{if .RequestNumber}} after approving request {{.RequestNumber}}{{end}}.",
+			Action:  "Manage stock",
+		},
+	},
+}
+
+var textLayout = template.Must(template.New("text").Parse(`{{.Labels.Greeting}} {{.Data.RecipientName}}
+
+{{.Intro}}
+{{if .Data.Items}}
+{{.Labels.Items}}:
+{{range .Data.Items}}- {{.Name}} x {{.Quantity}} {{.Unit}}
+{{end}}{{end}}{{if .Data.AdminNote}}
+{{.Labels.Note}}: {{.Data.AdminNote}}
+{{end}}
+{{.Action}}: {{.Data.Link}}
+
+--
+{{.Labels.Footer}}
+`))
+
+var htmlLayout = htmltemplate.Must(htmltemplate.New("html").Parse(`<!DOCTYPE html>
+<html><body style="font-family: 'Sarabun', Tahoma, sans-serif; color: #1f2937;">
+<p>{{.Labels.Greeting}} {{.Data.RecipientName}}</p>
+<p>{{.Intro}}</p>
+{{if .Data.Items}}<p><strong>{{.Labels.Items}}</strong></p>
+<ul>{{range .Data.Items}}<li>{{.Name}} &times; {{.Quantity}} {{.Unit}}</li>{{end}}</ul>{{end}}
+{{if .Data.AdminNote}}<p><strong>{{.Labels.Note}}:</strong> {{.Data.AdminNote}}</p>{{end}}
+<p><a href="{{.Data.Link}}" style="background: #006664; color: #ffffff; padding: 10px 16px; border-radius: 6px; text-decoration: none;">{{.Action}}</a></p>
+<hr><p style="font-size: 12px; color: #6b7280;">{{.Labels.Footer}}</p>
+</body></html>`))
+
+// renderedNotification is one event rendered for in-app (Subject + Intro) and e-mail (all fields)
+type renderedNotification struct {
+	Subject string
+	Intro   string
+	Text    string
+	HTML    string
+}
+
+// lineNotificationText is the chat version of a notification: title, summary and link
+func lineNotificationText(r *renderedNotification, data *notificationData) string {
+	text := r.Subject + "\n\n" + r.Intro
+	if data.Link != "" {
+		text += "\n\n" + data.Link
+	}
+	return text
+}
+
+// renderNotification renders an event in the recipient's locale (Thai when unsupported)
+func renderNotification(event, locale string, data *notificationData) (*renderedNotification, error) {
+	templates := notificationTemplates[event]
+	tmpl, ok := templates[locale]
+	if !ok {
+		locale = models.LocaleThai
+		tmpl = templates[locale]
+	}
+
+	render := func(src string) (string, error) {
+		t, err := template.New(event).Parse(src)
+		if err != nil {
+			return "", err
+		}
+		var b bytes.Buffer
+		if err := t.Execute(&b, data); err != nil {
+			return "", err
+		}
+		return b.String(), nil
+	}
+
+	subject, err := render(tmpl.Subject)
+	if err != nil {
+		return nil, err
+	}
+	intro, err := render(tmpl.Intro)
+	if err != nil {
+		return nil, err
+	}
+	action, err := render(tmpl.Action)
+	if err != nil {
+		return nil, err
+	}
+
+	layout := map[string]interface{}{
+		"Labels": emailLayoutLabels[locale],
+		"Data":   data,
+		"Intro":  intro,
+		"Action": action,
+	}
+	var textBody, htmlBody bytes.Buffer
+	if err := textLayout.Execute(&textBody, layout); err != nil {
+		return nil, err
+	}
+	if err := htmlLayout.Execute(&htmlBody, layout); err != nil {
+		return nil, err
+	}
+	return &renderedNotification{Subject: subject, Intro: intro, Text: textBody.String(), HTML: htmlBody.String()}, nil
+}
@@ -0,0 +1,334 @@
+// services/product_media_service.go
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"ku-asset/dto"
+	"ku-asset/models"
+	"mime/multipart"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+var (
+	ErrProductMediaNotFound = errors.New("product media not found")
+	ErrInvalidMediaFile     = errors.New("invalid media file")
+	ErrDuplicateMedia       = errors.New("this file is already attached to the product")
+	ErrInvalidMediaOrder    = errors.New("media_ids must list every media item of the product exactly once")
+	ErrPrimaryNotImage      = errors.New("only images can be the primary image")
+)
+
+// ProductMediaService manages a product's ordered image gallery and document attachments.
+// Files go through UploadService; the primary image is mirrored to Product.ImageURL.
+type ProductMediaService interface {
+	GetMedia(productID uint) ([]dto.ProductMediaResponse, error)
+	AddImage(ctx context.Context, productID uint, file multipart.File, header *multipart.FileHeader, caption string, ownerID *uint) (*dto.ProductMediaResponse, error)
+	AddDocument(ctx context.Context, productID uint, file multipart.File, header *multipart.FileHeader, caption string, ownerID *uint) (*dto.ProductMediaResponse, error)
+	UpdateMedia(ctx context.Context, productID, mediaID uint, req *dto.UpdateProductMediaRequest) (*dto.ProductMediaResponse, error)
+	ReorderMedia(ctx context.Context, productID uint, req *dto.ReorderProductMediaRequest) ([]dto.ProductMediaResponse, error)
+	DeleteMedia(ctx context.Context, productID, mediaID uint) error
+}
+
+type productMediaService struct {
+	db      *gorm.DB
+	uploads *UploadService
+}
+
+func NewProductMediaService(db *gorm.DB, uploads *UploadService) ProductMediaService {
+	return &productMediaService{db: db, uploads: uploads}
+}
+
+func (s *productMediaService) GetMedia(productID uint) ([]dto.ProductMediaResponse, error) {
+	if err := s.db.Select("id").First(&models.Product{}, productID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrProductNotFound
+		}
+		return nil, err
+	}
+	return s.listMedia(s.db, productID)
+}
+
+func (s *productMediaService) listMedia(db *gorm.DB, productID uint) ([]dto.ProductMediaResponse, error) {
+	var media []models.ProductMedia
+	if err := db.Preload("Upload").Where("product_id = ?", productID).
+		Order("position, id").Find(&media).Error; err != nil {
+		return nil, err
+	}
+	response := make([]dto.ProductMediaResponse, 0, len(media))
+	for i := range media {
+		response = append(response, *mapProductMediaToResponse(&media[i]))
+	}
+	return response, nil
+}
+
+func (s *productMediaService) AddImage(ctx context.Context, productID uint, file multipart.File, header *multipart.FileHeader, caption string, ownerID *uint) (*dto.ProductMediaResponse, error) {
+	config := s.uploads.GetProductImageConfig()
+	if err := s.uploads.ValidateFile(file, header, config); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidMediaFile, err)
+	}
+	if err := s.ensureProduct(ctx, productID); err != nil {
+		return nil, err
+	}
+	result, err := s.uploads.ProcessAndSaveImage(ctx, file, header, config, ownerID)
+	if err != nil {
+		return nil, err
+	}
+	return s.attach(ctx, productID, models.ProductMediaImage, result, caption)
+}
+
+func (s *productMediaService) AddDocument(ctx context.Context, productID uint, file multipart.File, header *multipart.FileHeader, caption string, ownerID *uint) (*dto.ProductMediaResponse, error) {
+	config := s.uploads.GetProductDocumentConfig()
+	if err := s.uploads.ValidateFile(file, header, config); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidMediaFile, err)
+	}
+	if err := s.ensureProduct(ctx, productID); err != nil {
+		return nil, err
+	}
+	result, err := s.uploads.ProcessAndSaveFile(ctx, file, header, config, ownerID)
+	if err != nil {
+		return nil, err
+	}
+	return s.attach(ctx, productID, models.ProductMediaDocument, result, caption)
+}
+
+func (s *productMediaService) ensureProduct(ctx context.Context, productID uint) error {
+	err := s.db.WithContext(ctx).Select("id").First(&models.Product{}, productID).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return ErrProductNotFound
+	}
+	return err
+}
+
+// attach adds an uploaded file to the end of the product's media. The first image
+// becomes the primary image.
+func (s *productMediaService) attach(ctx context.Context, productID uint, mediaType models.ProductMediaType, result *UploadResult, caption string) (*dto.ProductMediaResponse, error) {
+	db := s.db.WithContext(ctx)
+	var media models.ProductMedia
+	err := db.Transaction(func(tx *gorm.DB) error {
+		// ⭐ ล็อกสินค้าไว้ให้การเพิ่ม/จัดลำดับพร้อมกันได้ position ไม่ชนกัน
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Select("id").First(&models.Product{}, productID).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return ErrProductNotFound
+			}
+			return err
+		}
+
+		var upload models.Upload
+		if err := tx.Where("url = ?", result.URL).First(&upload).Error; err != nil {
+			return err
+		}
+		var count int64
+		if err := tx.Model(&models.ProductMedia{}).Where("product_id = ? AND upload_id = ?", productID, upload.ID).
+			Count(&count).Error; err != nil {
+			return err
+		}
+		if count > 0 {
+			return ErrDuplicateMedia
+		}
+
+		var maxPosition *int
+		if err := tx.Model(&models.ProductMedia{}).Where("product_id = ?", productID).
+			Select("MAX(position)").Scan(&maxPosition).Error; err != nil {
+			return err
+		}
+		var primaryCount int64
+		if err := tx.Model(&models.ProductMedia{}).Where("product_id = ? AND is_primary", productID).
+			Count(&primaryCount).Error; err != nil {
+			return err
+		}
+
+		media = models.ProductMedia{
+			ProductID: productID,
+			UploadID:  upload.ID,
+			Type:      mediaType,
+			Caption:   caption,
+			IsPrimary: mediaType == models.ProductMediaImage && primaryCount == 0,
+		}
+		if maxPosition != nil {
+			media.Position = *maxPosition + 1
+		}
+		if err := tx.Create(&media).Error; err != nil {
+			return err
+		}
+		media.Upload = upload
+
+		if media.IsPrimary {
+			if err := mirrorPrimaryImage(tx, productID, &media); err != nil {
+				return err
+			}
+		}
+		return syncProductUploads(tx, productID)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return mapProductMediaToResponse(&media), nil
+}
+
+func (s *productMediaService) UpdateMedia(ctx context.Context, productID, mediaID uint, req *dto.UpdateProductMediaRequest) (*dto.ProductMediaResponse, error) {
+	db := s.db.WithContext(ctx)
+	var media models.ProductMedia
+	err := db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Preload("Upload").
+			Where("id = ? AND product_id = ?", mediaID, productID).First(&media).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return ErrProductMediaNotFound
+			}
+			return err
+		}
+
+		if req.Caption != nil {
+			media.Caption = *req.Caption
+		}
+		if req.IsPrimary != nil && *req.IsPrimary && !media.IsPrimary {
+			if media.Type != models.ProductMediaImage {
+				return ErrPrimaryNotImage
+			}
+			if err := tx.Model(&models.ProductMedia{}).Where("product_id = ? AND is_primary", productID).
+				Update("is_primary", false).Error; err != nil {
+				return err
+			}
+			media.IsPrimary = true
+			if err := mirrorPrimaryImage(tx, productID, &media); err != nil {
+				return err
+			}
+		}
+		return tx.Omit("Upload").Save(&media).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	return mapProductMediaToResponse(&media), nil
+}
+
+func (s *productMediaService) ReorderMedia(ctx context.Context, productID uint, req *dto.ReorderProductMediaRequest) ([]dto.ProductMediaResponse, error) {
+	db := s.db.WithContext(ctx)
+	err := db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Select("id").First(&models.Product{}, productID).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return ErrProductNotFound
+			}
+			return err
+		}
+
+		var ids []uint
+		if err := tx.Model(&models.ProductMedia{}).Where("product_id = ?", productID).Pluck("id", &ids).Error; err != nil {
+			return err
+		}
+		if len(ids) != len(req.MediaIDs) {
+			return ErrInvalidMediaOrder
+		}
+		seen := make(map[uint]bool, len(req.MediaIDs))
+		for _, id := range req.MediaIDs {
+			if seen[id] || !containsUint(ids, id) {
+				return ErrInvalidMediaOrder
+			}
+			seen[id] = true
+		}
+
+		for position, id := range req.MediaIDs {
+			if err := tx.Model(&models.ProductMedia{}).Where("id = ?", id).
+				Update("position", position).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return s.listMedia(db, productID)
+}
+
+func (s *productMediaService) DeleteMedia(ctx context.Context, productID, mediaID uint) error {
+	db := s.db.WithContext(ctx)
+	return db.Transaction(func(tx *gorm.DB) error {
+		var media models.ProductMedia
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("id = ? AND product_id = ?", mediaID, productID).First(&media).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return ErrProductMediaNotFound
+			}
+			return err
+		}
+		if err := tx.Delete(&media).Error; err != nil {
+			return err
+		}
+
+		if media.IsPrimary {
+			// ⭐ ลบรูปหลัก - เลื่อนรูปถัดไปขึ้นมาแทน ถ้าไม่มีรูปเหลือก็ล้างรูปของสินค้า
+			var next models.ProductMedia
+			err := tx.Preload("Upload").Where("product_id = ? AND type = ?", productID, models.ProductMediaImage).
+				Order("position, id").First(&next).Error
+			switch {
+			case errors.Is(err, gorm.ErrRecordNotFound):
+				if err := mirrorPrimaryImage(tx, productID, nil); err != nil {
+					return err
+				}
+			case err != nil:
+				return err
+			default:
+				if err := tx.Model(&next).Update("is_primary", true).Error; err != nil {
+					return err
+				}
+				if err := mirrorPrimaryImage(tx, productID, &next); err != nil {
+					return err
+				}
+			}
+		}
+		// ไฟล์ที่ไม่มีใครใช้แล้วจะถูก GC ลบหลังพ้นระยะผ่อนผัน
+		return syncProductUploads(tx, productID)
+	})
+}
+
+// mirrorPrimaryImage copies the primary gallery image (Upload preloaded) to the product's
+// ImageURL/ImageVariants, or clears them when media is nil
+func mirrorPrimaryImage(tx *gorm.DB, productID uint, media *models.ProductMedia) error {
+	updates := map[string]interface{}{"image_url": nil, "image_variants": nil}
+	if media != nil {
+		updates["image_url"] = media.Upload.URL
+		updates["image_variants"] = media.Upload.Variants
+	}
+	return tx.Model(&models.Product{}).Where("id = ?", productID).Updates(updates).Error
+}
+
+// syncProductUploads records every upload a product uses: its image and all gallery
+// images and documents
+func syncProductUploads(tx *gorm.DB, productID uint) error {
+	var product models.Product
+	if err := tx.Select("id", "image_url").First(&product, productID).Error; err != nil {
+		return err
+	}
+	var urls []string
+	if err := tx.Model(&models.ProductMedia{}).
+		Joins("JOIN uploads ON uploads.id = product_media.upload_id").
+		Where("product_media.product_id = ?", productID).
+		Pluck("uploads.url", &urls).Error; err != nil {
+		return err
+	}
+	if product.ImageURL != nil && *product.ImageURL != "" {
+		urls = append(urls, *product.ImageURL)
+	}
+	return SyncUploadReferences(tx, models.UploadRefProduct, productID, urls...)
+}
+
+func mapProductMediaToResponse(m *models.ProductMedia) *dto.ProductMediaResponse {
+	return &dto.ProductMediaResponse{
+		ID:           m.ID,
+		Type:         string(m.Type),
+		URL:          m.Upload.URL,
+		Variants:     m.Upload.Variants,
+		Caption:      m.Caption,
+		Position:     m.Position,
+		IsPrimary:    m.IsPrimary,
+		MimeType:     m.Upload.MimeType,
+		Size:         m.Upload.Size,
+		OriginalName: m.Upload.OriginalName,
+		Width:        m.Upload.Width,
+		Height:       m.Upload.Height,
+		CreatedAt:    m.CreatedAt,
+	}
+}
@@ -1,32 +1,44 @@
 package services
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"ku-asset/dto"
 	"ku-asset/models"
+	"ku-asset/realtime"
+	"log"
 	"math"
 
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
+var ErrProductNotFound = errors.New("product not found")
+
 type ProductService interface {
 	GetProducts(query *dto.ProductQuery) (*dto.PaginatedProductResponse, error)
 	GetProductByID(id uint) (*dto.ProductResponse, error)
-	CreateProduct(req *dto.CreateProductRequest) (*dto.ProductResponse, error)
-	UpdateProduct(id uint, req *dto.UpdateProductRequest) (*dto.ProductResponse, error)
-	DeleteProduct(id uint) error
+	CreateProduct(ctx context.Context, req *dto.CreateProductRequest) (*dto.ProductResponse, error)
+	UpdateProduct(ctx context.Context, id uint, req *dto.UpdateProductRequest) (*dto.ProductResponse, error)
+	DeleteProduct(ctx context.Context, id uint) error
 	UpdateStock(tx *gorm.DB, productID uint, quantityChange int) error
+	AdjustStock(ctx context.Context, id uint, req *dto.StockAdjustmentRequest) (*dto.ProductResponse, error)
 }
 
 type productService struct {
-	db *gorm.DB
+	db           *gorm.DB
+	hub          realtime.Hub
+	webhooks     WebhookService
+	stockMonitor StockMonitorService
 }
 
-func NewProductService(db *gorm.DB) ProductService {
-	return &productService{db: db}
+func NewProductService(db *gorm.DB, hub realtime.Hub, webhooks WebhookService, stockMonitor StockMonitorService) ProductService {
+	return &productService{db: db, hub: hub, webhooks: webhooks, stockMonitor: stockMonitor}
 }
 
-func (s *productService) CreateProduct(req *dto.CreateProductRequest) (*dto.ProductResponse, error) {
+func (s *productService) CreateProduct(ctx context.Context, req *dto.CreateProductRequest) (*dto.ProductResponse, error) {
+	db := s.db.WithContext(ctx)
 	// สร้าง Code อัตโนมัติ
 	code, err := s.generateProductCode()
 	if err != nil {
@@ -42,13 +54,15 @@ func (s *productService) CreateProduct(req *dto.CreateProductRequest) (*dto.Prod
 		ProductModel: req.ProductModel,
 
 		// Stock fields
-		Stock:    req.Stock,
-		MinStock: req.MinStock,
-		Unit:     req.Unit,
-		Status:   models.ProductStatusActive,
+		Stock:        req.Stock,
+		MinStock:     req.MinStock,
+		Unit:         req.Unit,
+		Status:       models.ProductStatusActive,
+		LeadTimeDays: req.LeadTimeDays,
 
 		// ⭐ เพิ่ม ImageURL
-		ImageURL: req.ImageURL,
+		ImageURL:      req.ImageURL,
+		ImageVariants: req.ImageVariants,
 	}
 
 	// ตั้งค่า default unit
@@ -56,12 +70,18 @@ func (s *productService) CreateProduct(req *dto.CreateProductRequest) (*dto.Prod
 		product.Unit = "ชิ้น"
 	}
 
-	if err := s.db.Create(&product).Error; err != nil {
+	err = db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(&product).Error; err != nil {
+			return err
+		}
+		return syncProductUploads(tx, product.ID)
+	})
+	if err != nil {
 		return nil, err
 	}
 
 	// โหลดข้อมูลใหม่พร้อม relations
-	if err := s.db.Preload("Category").First(&product, product.ID).Error; err != nil {
+	if err := db.Preload("Category").First(&product, product.ID).Error; err != nil {
 		return nil, err
 	}
 
@@ -70,7 +90,10 @@ func (s *productService) CreateProduct(req *dto.CreateProductRequest) (*dto.Prod
 
 func (s *productService) GetProductByID(id uint) (*dto.ProductResponse, error) {
 	var product models.Product
-	if err := s.db.Preload("Category").First(&product, id).Error; err != nil {
+	if err := s.db.Preload("Category").
+		Preload("Media", func(db *gorm.DB) *gorm.DB { return db.Order("position, id") }).
+		Preload("Media.Upload").
+		First(&product, id).Error; err != nil {
 		return nil, err
 	}
 	return mapProductToResponse(&product), nil
@@ -119,12 +142,16 @@ func (s *productService) GetProducts(query *dto.ProductQuery) (*dto.PaginatedPro
 	}, nil
 }
 
-func (s *productService) UpdateProduct(id uint, req *dto.UpdateProductRequest) (*dto.ProductResponse, error) {
+func (s *productService) UpdateProduct(ctx context.Context, id uint, req *dto.UpdateProductRequest) (*dto.ProductResponse, error) {
+	db := s.db.WithContext(ctx)
 	var product models.Product
-	if err := s.db.First(&product, id).Error; err != nil {
+	if err := db.First(&product, id).Error; err != nil {
 		return nil, err
 	}
 
+	previousStock := product.Stock
+	previousMinStock := product.MinStock
+
 	// อัปเดตเฉพาะ field ที่มีค่า
 	if req.Name != "" {
 		product.Name = req.Name
@@ -147,6 +174,9 @@ func (s *productService) UpdateProduct(id uint, req *dto.UpdateProductRequest) (
 	if req.Unit != "" {
 		product.Unit = req.Unit
 	}
+	if req.LeadTimeDays != nil {
+		product.LeadTimeDays = req.LeadTimeDays
+	}
 	if req.CategoryID > 0 {
 		product.CategoryID = req.CategoryID
 	}
@@ -154,22 +184,54 @@ func (s *productService) UpdateProduct(id uint, req *dto.UpdateProductRequest) (
 	// ⭐ อัปเดต ImageURL (รวมถึงการลบรูป)
 	if req.ImageURL != nil {
 		product.ImageURL = req.ImageURL
+		// ⭐ variants ของรูปเดิมใช้กับรูปใหม่ไม่ได้ จึงแทนที่ทั้งชุดเสมอ
+		product.ImageVariants = req.ImageVariants
 	}
 
-	if err := s.db.Save(&product).Error; err != nil {
+	err := db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Save(&product).Error; err != nil {
+			return err
+		}
+		if req.ImageURL != nil {
+			if err := syncProductUploads(tx, product.ID); err != nil {
+				return err
+			}
+		}
+		if product.Stock != previousStock {
+			if err := s.webhooks.EnqueueStockChanged(tx, &product, previousStock, "product_update", nil); err != nil {
+				return err
+			}
+		}
+		if product.Stock != previousStock || product.MinStock != previousMinStock {
+			return s.stockMonitor.Evaluate(tx, product.ID, nil)
+		}
+		return nil
+	})
+	if err != nil {
 		return nil, err
 	}
 
+	if product.Stock != previousStock {
+		s.hub.Publish(stockChangedEvent(&product))
+	}
+
 	// โหลดข้อมูลใหม่
-	if err := s.db.Preload("Category").First(&product, product.ID).Error; err != nil {
+	if err := db.Preload("Category").First(&product, product.ID).Error; err != nil {
 		return nil, err
 	}
 
 	return mapProductToResponse(&product), nil
 }
 
-func (s *productService) DeleteProduct(id uint) error {
-	return s.db.Delete(&models.Product{}, id).Error
+func (s *productService) DeleteProduct(ctx context.Context, id uint) error {
+	db := s.db.WithContext(ctx)
+	return db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Delete(&models.Product{}, id).Error; err != nil {
+			return err
+		}
+		// ⭐ ปล่อยรูปให้ GC เก็บหลังพ้นระยะผ่อนผัน
+		return SyncUploadReferences(tx, models.UploadRefProduct, id)
+	})
 }
 
 func (s *productService) UpdateStock(tx *gorm.DB, productID uint, quantityChange int) error {
@@ -180,6 +242,91 @@ func (s *productService) UpdateStock(tx *gorm.DB, productID uint, quantityChange
 		Update("quantity", gorm.Expr("quantity + ?", quantityChange)).Error
 }
 
+// AdjustStock เพิ่ม ลด หรือกำหนดจำนวนสต็อกของครุภัณฑ์โดยตรง
+func (s *productService) AdjustStock(ctx context.Context, id uint, req *dto.StockAdjustmentRequest) (*dto.ProductResponse, error) {
+	db := s.db.WithContext(ctx)
+	err := db.Transaction(func(tx *gorm.DB) error {
+		var product models.Product
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&product, id).Error; err != nil {
+			return err
+		}
+
+		newStock := product.Stock
+		switch req.AdjustmentType {
+		case "ADD":
+			newStock += req.Quantity
+		case "REMOVE":
+			newStock -= req.Quantity
+		case "SET":
+			newStock = req.Quantity
+		}
+		if newStock < 0 {
+			return fmt.Errorf("insufficient stock for product %s: available %d, requested %d",
+				product.Name, product.Stock, req.Quantity)
+		}
+
+		updates := map[string]interface{}{"stock": newStock}
+		if newStock == 0 {
+			updates["status"] = models.ProductStatusOutOfStock
+		} else if product.Status == models.ProductStatusOutOfStock {
+			updates["status"] = models.ProductStatusActive
+		}
+		previousStock := product.Stock
+		if err := tx.Model(&product).Updates(updates).Error; err != nil {
+			return err
+		}
+		product.Stock = newStock
+		if status, ok := updates["status"].(models.ProductStatus); ok {
+			product.Status = status
+		}
+		if err := s.webhooks.EnqueueStockChanged(tx, &product, previousStock, "adjustment", nil); err != nil {
+			return err
+		}
+		if err := s.stockMonitor.Evaluate(tx, product.ID, nil); err != nil {
+			return err
+		}
+
+		log.Printf("✅ Adjusted stock for %s (%s %d): %d -> %d, notes: %s",
+			product.Name, req.AdjustmentType, req.Quantity, previousStock, newStock, req.Notes)
+		return nil
+	})
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrProductNotFound
+		}
+		return nil, err
+	}
+
+	product, err := s.GetProductByID(id)
+	if err != nil {
+		return nil, err
+	}
+	s.hub.Publish(realtime.Event{
+		Type:       realtime.EventStockChanged,
+		Permission: models.PermProductManage,
+		Data:       stockEventData(product.ID, product.Stock, product.MinStock, string(product.Status)),
+	})
+	return product, nil
+}
+
+// 🆕 stockChangedEvent แจ้งเจ้าหน้าที่ที่จัดการครุภัณฑ์ว่าสต็อกสินค้าเปลี่ยน
+func stockChangedEvent(product *models.Product) realtime.Event {
+	return realtime.Event{
+		Type:       realtime.EventStockChanged,
+		Permission: models.PermProductManage,
+		Data:       stockEventData(product.ID, product.Stock, product.MinStock, string(product.Status)),
+	}
+}
+
+func stockEventData(productID uint, stock, minStock int, status string) map[string]interface{} {
+	return map[string]interface{}{
+		"product_id": productID,
+		"stock":      stock,
+		"min_stock":  minStock,
+		"status":     status,
+	}
+}
+
 // ⭐ สร้าง Product Code อัตโนมัติ
 func (s *productService) generateProductCode() (string, error) {
 	var count int64
@@ -205,14 +352,21 @@ func mapProductToResponse(p *models.Product) *dto.ProductResponse {
 		MinStock:     p.MinStock,
 		Unit:         p.Unit,
 		Status:       string(p.Status),
+		LeadTimeDays: p.LeadTimeDays,
+		IsLowStock:   isLowStock(p),
 
 		// ⭐ เพิ่ม ImageURL
-		ImageURL: p.ImageURL,
+		ImageURL:      p.ImageURL,
+		ImageVariants: p.ImageVariants,
 
 		CreatedAt: p.CreatedAt,
 		UpdatedAt: p.UpdatedAt,
 	}
 
+	for i := range p.Media {
+		res.Media = append(res.Media, *mapProductMediaToResponse(&p.Media[i]))
+	}
+
 	if p.Category.ID != 0 {
 		res.Category = &dto.CategoryResponse{
 			ID:   p.Category.ID,
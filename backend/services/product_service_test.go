@@ -0,0 +1,22 @@
+package services
+
+import (
+	"testing"
+
+	"ku-asset/auth"
+	"ku-asset/models"
+	"ku-asset/realtime"
+)
+
+func TestStockChangedEventGoesToProductManagersOnly(t *testing.T) {
+	ev := stockChangedEvent(&models.Product{Stock: 3})
+
+	user := &realtime.Audience{UserID: 1, Scopes: map[string]*auth.Scope{}}
+	if user.Accepts(ev) {
+		t.Error("stock.changed was delivered to a user without product.manage")
+	}
+	manager := &realtime.Audience{UserID: 2, Scopes: map[string]*auth.Scope{models.PermProductManage: auth.GlobalScope()}}
+	if !manager.Accepts(ev) {
+		t.Error("stock.changed was not delivered to a product manager")
+	}
+}
@@ -0,0 +1,346 @@
+// services/request_attachment_service.go
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"ku-asset/auth"
+	"ku-asset/dto"
+	"ku-asset/models"
+	"ku-asset/storage"
+	"mime/multipart"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+var (
+	ErrRequestAttachmentNotFound = errors.New("attachment not found")
+	ErrInvalidAttachment         = errors.New("invalid attachment")
+	ErrDuplicateAttachment       = errors.New("this file is already attached to the request")
+	ErrTooManyAttachments        = errors.New("the request already has the maximum number of attachments")
+	ErrAttachmentsLocked         = errors.New("attachments of this request can no longer be changed")
+)
+
+// RequestAttachmentService manages supporting files (memos, quotations, approvals) on
+// requests. Files are stored privately and only the requester and staff who can view the
+// request (viewScope of request.view) can list or download them.
+type RequestAttachmentService interface {
+	// StageAttachment stores a file before its request exists; the returned upload ID is
+	// listed in CreateRequestInput.Attachments. Unused files are garbage-collected.
+	StageAttachment(ctx context.Context, userID uint, file multipart.File, header *multipart.FileHeader) (*dto.StagedAttachmentResponse, error)
+	GetAttachments(requestID, userID uint, viewScope *auth.Scope) ([]dto.RequestAttachmentResponse, error)
+	AddAttachment(ctx context.Context, requestID, userID uint, viewScope *auth.Scope, file multipart.File, header *multipart.FileHeader, attachmentType, caption string) (*dto.RequestAttachmentResponse, error)
+	OpenAttachment(ctx context.Context, requestID, attachmentID, userID uint, viewScope *auth.Scope) (*dto.RequestAttachmentResponse, io.ReadCloser, *storage.Object, error)
+	DeleteAttachment(ctx context.Context, requestID, attachmentID, userID uint, viewScope *auth.Scope) error
+}
+
+type requestAttachmentService struct {
+	db      *gorm.DB
+	uploads *UploadService
+}
+
+func NewRequestAttachmentService(db *gorm.DB, uploads *UploadService) RequestAttachmentService {
+	return &requestAttachmentService{db: db, uploads: uploads}
+}
+
+func (s *requestAttachmentService) StageAttachment(ctx context.Context, userID uint, file multipart.File, header *multipart.FileHeader) (*dto.StagedAttachmentResponse, error) {
+	upload, err := s.saveFile(ctx, userID, file, header)
+	if err != nil {
+		return nil, err
+	}
+	return &dto.StagedAttachmentResponse{
+		UploadID:     upload.ID,
+		OriginalName: upload.OriginalName,
+		MimeType:     upload.MimeType,
+		Size:         upload.Size,
+	}, nil
+}
+
+func (s *requestAttachmentService) GetAttachments(requestID, userID uint, viewScope *auth.Scope) ([]dto.RequestAttachmentResponse, error) {
+	if _, err := findAccessibleRequest(s.db, requestID, userID, viewScope); err != nil {
+		return nil, err
+	}
+	var attachments []models.RequestAttachment
+	if err := s.db.Preload("Upload").Preload("UploadedBy").Where("request_id = ?", requestID).
+		Order("id").Find(&attachments).Error; err != nil {
+		return nil, err
+	}
+	response := make([]dto.RequestAttachmentResponse, 0, len(attachments))
+	for i := range attachments {
+		response = append(response, mapRequestAttachmentToResponse(&attachments[i]))
+	}
+	return response, nil
+}
+
+func (s *requestAttachmentService) AddAttachment(ctx context.Context, requestID, userID uint, viewScope *auth.Scope, file multipart.File, header *multipart.FileHeader, attachmentType, caption string) (*dto.RequestAttachmentResponse, error) {
+	kind, err := parseRequestAttachmentType(attachmentType)
+	if err != nil {
+		return nil, err
+	}
+	if len(caption) > 255 {
+		return nil, fmt.Errorf("%w: caption is longer than 255 characters", ErrInvalidAttachment)
+	}
+
+	// ตรวจสิทธิ์ก่อนเก็บไฟล์ แล้วตรวจซ้ำใน transaction
+	db := s.db.WithContext(ctx)
+	request, err := findAccessibleRequest(db, requestID, userID, viewScope)
+	if err != nil {
+		return nil, err
+	}
+	if !canEditAttachments(request, userID, viewScope) {
+		return nil, ErrAttachmentsLocked
+	}
+
+	upload, err := s.saveFile(ctx, userID, file, header)
+	if err != nil {
+		return nil, err
+	}
+
+	attachment := models.RequestAttachment{
+		RequestID:    requestID,
+		UploadID:     upload.ID,
+		Type:         kind,
+		Caption:      caption,
+		UploadedByID: userID,
+	}
+	err = db.Transaction(func(tx *gorm.DB) error {
+		// ⭐ ล็อกคำขอไว้ การแนบพร้อมกันจะนับจำนวนไฟล์ได้ถูกต้อง
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Select("id").First(&models.Request{}, requestID).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return ErrRequestNotFound
+			}
+			return err
+		}
+		request, err := findAccessibleRequest(tx, requestID, userID, viewScope)
+		if err != nil {
+			return err
+		}
+		if !canEditAttachments(request, userID, viewScope) {
+			return ErrAttachmentsLocked
+		}
+
+		var existing []models.RequestAttachment
+		if err := tx.Select("id", "upload_id").Where("request_id = ?", requestID).Find(&existing).Error; err != nil {
+			return err
+		}
+		if len(existing) >= requestAttachmentLimit() {
+			return ErrTooManyAttachments
+		}
+		for _, a := range existing {
+			if a.UploadID == upload.ID {
+				return ErrDuplicateAttachment
+			}
+		}
+
+		if err := tx.Create(&attachment).Error; err != nil {
+			return err
+		}
+		return syncRequestUploads(tx, requestID)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if err := db.Preload("Upload").Preload("UploadedBy").First(&attachment, attachment.ID).Error; err != nil {
+		return nil, err
+	}
+	response := mapRequestAttachmentToResponse(&attachment)
+	return &response, nil
+}
+
+func (s *requestAttachmentService) OpenAttachment(ctx context.Context, requestID, attachmentID, userID uint, viewScope *auth.Scope) (*dto.RequestAttachmentResponse, io.ReadCloser, *storage.Object, error) {
+	db := s.db.WithContext(ctx)
+	if _, err := findAccessibleRequest(db, requestID, userID, viewScope); err != nil {
+		return nil, nil, nil, err
+	}
+	attachment, err := s.findAttachment(db, requestID, attachmentID)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	rc, obj, err := s.uploads.openObject(ctx, attachment.Upload.Key)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	response := mapRequestAttachmentToResponse(attachment)
+	return &response, rc, obj, nil
+}
+
+// DeleteAttachment removes an attachment added by the user. The file itself is left to
+// upload garbage collection.
+func (s *requestAttachmentService) DeleteAttachment(ctx context.Context, requestID, attachmentID, userID uint, viewScope *auth.Scope) error {
+	return s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Select("id").First(&models.Request{}, requestID).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return ErrRequestNotFound
+			}
+			return err
+		}
+		request, err := findAccessibleRequest(tx, requestID, userID, viewScope)
+		if err != nil {
+			return err
+		}
+		attachment, err := s.findAttachment(tx, requestID, attachmentID)
+		if err != nil {
+			return err
+		}
+		if attachment.UploadedByID != userID || !canEditAttachments(request, userID, viewScope) {
+			return ErrAttachmentsLocked
+		}
+
+		if err := tx.Delete(&models.RequestAttachment{}, attachment.ID).Error; err != nil {
+			return err
+		}
+		return syncRequestUploads(tx, requestID)
+	})
+}
+
+func (s *requestAttachmentService) findAttachment(db *gorm.DB, requestID, attachmentID uint) (*models.RequestAttachment, error) {
+	var attachment models.RequestAttachment
+	err := db.Preload("Upload").Preload("UploadedBy").
+		Where("id = ? AND request_id = ?", attachmentID, requestID).First(&attachment).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrRequestAttachmentNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &attachment, nil
+}
+
+// saveFile validates and stores an attachment below the user's own private prefix, so a
+// re-upload of the same file is only deduplicated against the user's own files
+func (s *requestAttachmentService) saveFile(ctx context.Context, userID uint, file multipart.File, header *multipart.FileHeader) (*models.Upload, error) {
+	config := s.uploads.GetRequestAttachmentConfig()
+	if err := s.uploads.ValidateFile(file, header, config); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidAttachment, err)
+	}
+	config.KeyPrefix = requestAttachmentPrefix(userID)
+
+	result, err := s.uploads.ProcessAndSaveFile(ctx, file, header, config, &userID)
+	if err != nil {
+		return nil, err
+	}
+	var upload models.Upload
+	if err := s.db.WithContext(ctx).Where("url = ?", result.URL).First(&upload).Error; err != nil {
+		return nil, err
+	}
+	return &upload, nil
+}
+
+// attachStagedUploads attaches files the requester staged before creating the request,
+// inside the request's creation transaction
+func attachStagedUploads(tx *gorm.DB, requestID, userID uint, inputs []dto.CreateRequestAttachmentInput) error {
+	if len(inputs) == 0 {
+		return nil
+	}
+	if len(inputs) > requestAttachmentLimit() {
+		return ErrTooManyAttachments
+	}
+
+	seen := make(map[uint]bool, len(inputs))
+	for _, input := range inputs {
+		if seen[input.UploadID] {
+			return ErrDuplicateAttachment
+		}
+		seen[input.UploadID] = true
+
+		kind, err := parseRequestAttachmentType(input.Type)
+		if err != nil {
+			return err
+		}
+		// ⭐ แนบได้เฉพาะไฟล์ที่ผู้ขออัปโหลดเองผ่าน POST /requests/attachments
+		var upload models.Upload
+		err = tx.Select("id").Where("id = ? AND owner_id = ? AND key LIKE ?",
+			input.UploadID, userID, requestAttachmentPrefix(userID)+"/%").First(&upload).Error
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return fmt.Errorf("%w: upload %d not found", ErrInvalidAttachment, input.UploadID)
+		}
+		if err != nil {
+			return err
+		}
+
+		attachment := models.RequestAttachment{
+			RequestID:    requestID,
+			UploadID:     upload.ID,
+			Type:         kind,
+			Caption:      input.Caption,
+			UploadedByID: userID,
+		}
+		if err := tx.Create(&attachment).Error; err != nil {
+			return err
+		}
+	}
+	return syncRequestUploads(tx, requestID)
+}
+
+// syncRequestUploads records every file attached to the request in the upload registry
+func syncRequestUploads(tx *gorm.DB, requestID uint) error {
+	var urls []string
+	if err := tx.Model(&models.RequestAttachment{}).
+		Joins("JOIN uploads ON uploads.id = request_attachments.upload_id").
+		Where("request_attachments.request_id = ?", requestID).
+		Pluck("uploads.url", &urls).Error; err != nil {
+		return err
+	}
+	return SyncUploadReferences(tx, models.UploadRefRequest, requestID, urls...)
+}
+
+// canEditAttachments: staff who can view the request may attach files at any stage (e.g.
+// approval documents); the requester only while the request is pending
+func canEditAttachments(request *models.Request, userID uint, viewScope *auth.Scope) bool {
+	if viewScope.Allows(request.User.DepartmentID) {
+		return true
+	}
+	return request.UserID == userID && request.Status == models.RequestStatusPending
+}
+
+func parseRequestAttachmentType(value string) (models.RequestAttachmentType, error) {
+	switch kind := models.RequestAttachmentType(value); kind {
+	case "":
+		return models.RequestAttachmentOther, nil
+	case models.RequestAttachmentMemo, models.RequestAttachmentQuotation,
+		models.RequestAttachmentApproval, models.RequestAttachmentOther:
+		return kind, nil
+	}
+	return "", fmt.Errorf("%w: unknown attachment type %q", ErrInvalidAttachment, value)
+}
+
+func requestAttachmentPrefix(userID uint) string {
+	return fmt.Sprintf("%srequests/user_%d", privateKeyPrefix, userID)
+}
+
+// requestAttachmentLimit is the maximum number of files on one request
+func requestAttachmentLimit() int {
+	return getEnvInt("REQUEST_ATTACHMENT_MAX_FILES", 10)
+}
+
+// preloadRequestAttachments loads a request's attachments for the detail view and PDF
+func preloadRequestAttachments(db *gorm.DB) *gorm.DB {
+	return db.Preload("Attachments", func(db *gorm.DB) *gorm.DB {
+		return db.Order("request_attachments.id")
+	}).Preload("Attachments.Upload").Preload("Attachments.UploadedBy")
+}
+
+func mapRequestAttachmentToResponse(a *models.RequestAttachment) dto.RequestAttachmentResponse {
+	response := dto.RequestAttachmentResponse{
+		ID:           a.ID,
+		Type:         string(a.Type),
+		Caption:      a.Caption,
+		OriginalName: a.Upload.OriginalName,
+		MimeType:     a.Upload.MimeType,
+		Size:         a.Upload.Size,
+		DownloadURL:  fmt.Sprintf("/api/v1/requests/%d/attachments/%d", a.RequestID, a.ID),
+		CreatedAt:    a.CreatedAt,
+	}
+	if a.Upload.Key != "" {
+		response.URL, response.URLExpiresAt = SignedFileURL(a.Upload.Key, privateFileURLTTL())
+	}
+	if a.UploadedBy.ID != 0 {
+		response.UploadedBy = &dto.UserProfileResponse{ID: a.UploadedBy.ID, Name: a.UploadedBy.Name}
+	}
+	return response
+}
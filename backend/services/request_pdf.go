@@ -0,0 +1,408 @@
+// services/request_pdf.go
+package services
+
+import (
+	"bytes"
+	_ "embed"
+	"fmt"
+	"image"
+	"image/draw"
+	"image/png"
+	"ku-asset/dto"
+	"ku-asset/models"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/boombuler/barcode"
+	"github.com/boombuler/barcode/qr"
+	"github.com/jung-kurt/gofpdf/v2"
+)
+
+// ⭐ ฟอนต์ TH Sarabun New ฝังในไฟล์ binary - ฟอนต์มาตรฐานของ gofpdf แสดงภาษาไทยไม่ได้
+var (
+	//go:embed fonts/THSarabunNew.ttf
+	sarabunRegular []byte
+	//go:embed fonts/THSarabunNew-Bold.ttf
+	sarabunBold []byte
+)
+
+const (
+	pdfFont        = "THSarabunNew"
+	pdfMarginLeft  = 20.0
+	pdfMarginRight = 15.0
+	pdfMarginTop   = 15.0
+	pdfContentW    = 210 - pdfMarginLeft - pdfMarginRight
+	pdfBottomLimit = 297 - 25.0 // เว้นที่ให้ footer
+)
+
+var thaiMonths = [...]string{
+	"มกราคม", "กุมภาพันธ์", "มีนาคม", "เมษายน", "พฤษภาคม", "มิถุนายน",
+	"กรกฎาคม", "สิงหาคม", "กันยายน", "ตุลาคม", "พฤศจิกายน", "ธันวาคม",
+}
+
+// formatThaiDate formats a date the way Thai official documents do, e.g. 19 ตุลาคม 2569
+func formatThaiDate(t time.Time) string {
+	t = t.In(bangkok)
+	return fmt.Sprintf("%d %s %d", t.Day(), thaiMonths[t.Month()-1], t.Year()+543)
+}
+
+var requestAttachmentLabels = map[string]string{
+	string(models.RequestAttachmentMemo):      "บันทึกข้อความ",
+	string(models.RequestAttachmentQuotation): "ใบเสนอราคา",
+	string(models.RequestAttachmentApproval):  "เอกสารอนุมัติ",
+	string(models.RequestAttachmentOther):     "อื่น ๆ",
+}
+
+// GenerateRequestPDF renders the requisition form (ใบเบิกวัสดุ/ครุภัณฑ์): organization and
+// department header, item table, Buddhist-era dates, signature blocks for the requester,
+// approver and issuer, a list of attachments and a QR code linking to the verification page
+func (s *requestService) GenerateRequestPDF(req *dto.RequestResponse) ([]byte, error) {
+	pdf := gofpdf.New("P", "mm", "A4", "")
+	pdf.AddUTF8FontFromBytes(pdfFont, "", sarabunRegular)
+	pdf.AddUTF8FontFromBytes(pdfFont, "B", sarabunBold)
+	pdf.SetMargins(pdfMarginLeft, pdfMarginTop, pdfMarginRight)
+	pdf.SetAutoPageBreak(true, 297-pdfBottomLimit)
+	pdf.AliasNbPages("{nb}")
+	printedAt := time.Now()
+	pdf.SetFooterFunc(func() {
+		pdf.SetY(-15)
+		pdf.SetFont(pdfFont, "", 11)
+		pdf.CellFormat(pdfContentW/2, 6, pdfText(fmt.Sprintf("พิมพ์จากระบบ KU Asset เมื่อ %s %s น.",
+			formatThaiDate(printedAt), printedAt.In(bangkok).Format("15:04"))), "", 0, "L", false, 0, "")
+		pdf.CellFormat(pdfContentW/2, 6, fmt.Sprintf("หน้า %d/{nb}", pdf.PageNo()), "", 0, "R", false, 0, "")
+	})
+	pdf.AddPage()
+
+	if err := writeVerificationQR(pdf, req.RequestNumber); err != nil {
+		return nil, err
+	}
+	writeRequestHeader(pdf, req)
+	writeRequestItems(pdf, req)
+	writeRequestSignatures(pdf, req)
+	writeRequestAttachments(pdf, req)
+
+	if err := pdf.Error(); err != nil {
+		return nil, err
+	}
+	var buf bytes.Buffer
+	if err := pdf.Output(&buf); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// writeVerificationQR puts a QR code of the signed verification URL in the top-right
+// corner, so a hand-altered printout can be checked against the system
+func writeVerificationQR(pdf *gofpdf.Fpdf, requestNumber string) error {
+	const size = 24.0
+	code, err := qr.Encode(RequestVerificationURL(requestNumber), qr.M, qr.Auto)
+	if err != nil {
+		return fmt.Errorf("failed to create QR code: %w", err)
+	}
+	code, err = barcode.Scale(code, 240, 240)
+	if err != nil {
+		return fmt.Errorf("failed to create QR code: %w", err)
+	}
+	// barcode.Scale ให้ภาพ Gray16 แต่ gofpdf อ่าน PNG ได้แค่ 8-bit
+	gray := image.NewGray(code.Bounds())
+	draw.Draw(gray, gray.Bounds(), code, code.Bounds().Min, draw.Src)
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, gray); err != nil {
+		return fmt.Errorf("failed to create QR code: %w", err)
+	}
+
+	options := gofpdf.ImageOptions{ImageType: "PNG"}
+	pdf.RegisterImageOptionsReader("verify-qr", options, &buf)
+	x, y := 210-pdfMarginRight-size, pdfMarginTop-5
+	pdf.ImageOptions("verify-qr", x, y, size, size, false, options, 0, "")
+	pdf.SetFont(pdfFont, "", 10)
+	pdf.SetXY(x-3, y+size)
+	pdf.CellFormat(size+6, 4, "สแกนเพื่อตรวจสอบเอกสาร", "", 0, "C", false, 0, "")
+	pdf.SetXY(pdfMarginLeft, pdfMarginTop)
+	return nil
+}
+
+func writeRequestHeader(pdf *gofpdf.Fpdf, req *dto.RequestResponse) {
+	var department, faculty string
+	if req.User != nil && req.User.Department != nil {
+		department = req.User.Department.Name
+		if req.User.Department.Faculty != nil {
+			faculty = *req.User.Department.Faculty
+		} else if req.User.Department.Type == string(models.DepartmentTypeFaculty) {
+			faculty, department = department, ""
+		}
+	}
+
+	pdf.SetFont(pdfFont, "B", 22)
+	pdf.CellFormat(pdfContentW, 10, "ใบเบิกวัสดุ/ครุภัณฑ์", "", 1, "C", false, 0, "")
+	pdf.SetFont(pdfFont, "", 16)
+	pdf.CellFormat(pdfContentW, 7, pdfText(getEnv("ORGANIZATION_NAME", "มหาวิทยาลัยเกษตรศาสตร์")), "", 1, "C", false, 0, "")
+	if faculty != "" {
+		pdf.CellFormat(pdfContentW, 7, pdfText(faculty), "", 1, "C", false, 0, "")
+	}
+	pdf.Ln(4)
+
+	half := pdfContentW / 2
+	labelValue(pdf, half, "เลขที่ใบเบิก", req.RequestNumber)
+	labelValue(pdf, half, "วันที่", formatThaiDate(req.RequestDate))
+	pdf.Ln(7)
+	requester := ""
+	if req.User != nil {
+		requester = req.User.Name
+	}
+	labelValue(pdf, half, "ผู้ขอเบิก", requester)
+	labelValue(pdf, half, "สถานะ", requestStatusLabel(models.LocaleThai, models.RequestStatus(req.Status)))
+	pdf.Ln(7)
+	if department != "" {
+		labelValue(pdf, pdfContentW, "ส่วนงาน/ภาควิชา", department)
+		pdf.Ln(7)
+	}
+	labelParagraph(pdf, "วัตถุประสงค์", req.Purpose)
+	if req.Notes != "" {
+		labelParagraph(pdf, "หมายเหตุ", req.Notes)
+	}
+	pdf.Ln(3)
+}
+
+// labelValue writes "label: value" in a cell of width w on the current line
+func labelValue(pdf *gofpdf.Fpdf, w float64, label, value string) {
+	pdf.SetFont(pdfFont, "B", 16)
+	labelW := pdf.GetStringWidth(label+": ") + 1
+	pdf.CellFormat(labelW, 7, label+":", "", 0, "L", false, 0, "")
+	pdf.SetFont(pdfFont, "", 16)
+	pdf.CellFormat(w-labelW, 7, pdfText(value), "", 0, "L", false, 0, "")
+}
+
+// labelParagraph writes "label: text" with text wrapped below the label's indent
+func labelParagraph(pdf *gofpdf.Fpdf, label, text string) {
+	pdf.SetFont(pdfFont, "B", 16)
+	labelW := pdf.GetStringWidth(label+": ") + 1
+	pdf.CellFormat(labelW, 7, label+":", "", 0, "L", false, 0, "")
+	pdf.SetFont(pdfFont, "", 16)
+	for i, line := range splitPDFText(pdf, pdfText(text), pdfContentW-labelW) {
+		if i > 0 {
+			pdf.SetX(pdfMarginLeft + labelW)
+		}
+		pdf.CellFormat(pdfContentW-labelW, 7, line, "", 1, "L", false, 0, "")
+	}
+}
+
+var (
+	itemColumnWidths = []float64{12, 26, 61, 17, 20, 20, 19}
+	itemColumnAligns = []string{"C", "L", "L", "C", "R", "R", "L"}
+	itemHeaders      = []string{"ลำดับ", "รหัส", "รายการ", "หน่วย", "จำนวนขอเบิก", "จำนวนอนุมัติ", "หมายเหตุ"}
+)
+
+func writeRequestItems(pdf *gofpdf.Fpdf, req *dto.RequestResponse) {
+	header := func() {
+		pdf.SetFont(pdfFont, "B", 14)
+		pdf.SetFillColor(235, 235, 235)
+		for i, h := range itemHeaders {
+			pdf.CellFormat(itemColumnWidths[i], 8, h, "1", 0, "C", true, 0, "")
+		}
+		pdf.Ln(-1)
+		pdf.SetFont(pdfFont, "", 14)
+	}
+	header()
+
+	// จำนวนอนุมัติ = จำนวนที่ขอ เมื่อคำขอผ่านการอนุมัติแล้ว (ระบบอนุมัติทั้งคำขอ)
+	approved := func(qty int) string {
+		switch models.RequestStatus(req.Status) {
+		case models.RequestStatusApproved, models.RequestStatusIssued, models.RequestStatusCompleted:
+			return fmt.Sprintf("%d", qty)
+		case models.RequestStatusRejected:
+			return "-"
+		}
+		return ""
+	}
+
+	total := 0
+	for i, item := range req.Items {
+		tableRow(pdf, header, []string{
+			fmt.Sprintf("%d", i+1),
+			item.Product.Code,
+			item.Product.Name,
+			item.Product.Unit,
+			fmt.Sprintf("%d", item.Quantity),
+			approved(item.Quantity),
+			"",
+		})
+		total += item.Quantity
+	}
+	pdf.SetFont(pdfFont, "B", 14)
+	pdf.CellFormat(pdfContentW, 8, fmt.Sprintf("รวม %d รายการ จำนวน %d หน่วย", len(req.Items), total), "", 1, "R", false, 0, "")
+
+	if req.AdminNote != "" {
+		labelParagraph(pdf, "ความเห็นผู้อนุมัติ", req.AdminNote)
+	}
+}
+
+// tableRow draws one item row, wrapping long text and starting a new page (with the
+// table header again) when the row does not fit
+func tableRow(pdf *gofpdf.Fpdf, header func(), cells []string) {
+	const lineHeight = 6.5
+	lines := make([][]string, len(cells))
+	rowLines := 1
+	for i, text := range cells {
+		lines[i] = splitPDFText(pdf, pdfText(text), itemColumnWidths[i])
+		rowLines = max(rowLines, len(lines[i]))
+	}
+	height := float64(rowLines)*lineHeight + 1.5
+	if pdf.GetY()+height > pdfBottomLimit {
+		pdf.AddPage()
+		header()
+	}
+
+	x, y := pdf.GetXY()
+	for i := range cells {
+		pdf.Rect(x, y, itemColumnWidths[i], height, "D")
+		for j, line := range lines[i] {
+			pdf.SetXY(x, y+0.75+float64(j)*lineHeight)
+			pdf.CellFormat(itemColumnWidths[i], lineHeight, line, "", 0, itemColumnAligns[i], false, 0, "")
+		}
+		x += itemColumnWidths[i]
+	}
+	pdf.SetXY(pdfMarginLeft, y+height)
+}
+
+func writeRequestSignatures(pdf *gofpdf.Fpdf, req *dto.RequestResponse) {
+	type signer struct {
+		role string
+		name string
+		date *time.Time
+	}
+	requestDate := req.RequestDate
+	signers := []signer{{role: "ผู้ขอเบิก", date: &requestDate}, {role: "ผู้อนุมัติ", date: req.ApprovedDate}, {role: "ผู้จ่ายของ", date: req.IssuedDate}}
+	if req.User != nil {
+		signers[0].name = req.User.Name
+	}
+	if req.ApprovedBy != nil {
+		signers[1].name = req.ApprovedBy.Name
+	}
+	if req.IssuedBy != nil {
+		signers[2].name = req.IssuedBy.Name
+	}
+
+	const blockHeight = 36
+	if pdf.GetY()+blockHeight+10 > pdfBottomLimit {
+		pdf.AddPage()
+	}
+	pdf.Ln(10)
+	w := pdfContentW / float64(len(signers))
+	y := pdf.GetY()
+	pdf.SetFont(pdfFont, "", 15)
+	for i, sg := range signers {
+		name := "......................................................"
+		if sg.name != "" {
+			name = pdfText(sg.name)
+		}
+		date := "........../........../.........."
+		if sg.date != nil {
+			date = formatThaiDate(*sg.date)
+		}
+		x := pdfMarginLeft + float64(i)*w
+		for j, line := range []string{"ลงชื่อ ..........................................", "(" + name + ")", sg.role, "วันที่ " + date} {
+			pdf.SetXY(x, y+float64(j)*8)
+			pdf.CellFormat(w, 8, line, "", 0, "C", false, 0, "")
+		}
+	}
+	pdf.SetXY(pdfMarginLeft, y+blockHeight)
+}
+
+// writeRequestAttachments lists attached documents; the files are downloaded from the system
+func writeRequestAttachments(pdf *gofpdf.Fpdf, req *dto.RequestResponse) {
+	if len(req.Attachments) == 0 {
+		return
+	}
+	pdf.Ln(4)
+	pdf.SetFont(pdfFont, "B", 16)
+	pdf.CellFormat(pdfContentW, 8, fmt.Sprintf("เอกสารแนบ (%d รายการ)", len(req.Attachments)), "", 1, "L", false, 0, "")
+	pdf.SetFont(pdfFont, "", 14)
+	for i, a := range req.Attachments {
+		label := requestAttachmentLabels[a.Type]
+		if label == "" {
+			label = a.Type
+		}
+		line := fmt.Sprintf("%d. [%s] %s (%s)", i+1, label, a.OriginalName, formatFileSize(a.Size))
+		if a.Caption != "" {
+			line += " - " + a.Caption
+		}
+		for _, l := range splitPDFText(pdf, pdfText(line), pdfContentW) {
+			pdf.CellFormat(pdfContentW, 6.5, l, "", 1, "L", false, 0, "")
+		}
+	}
+}
+
+// formatFileSize formats a byte count for display, e.g. 1.5 MB
+func formatFileSize(size int64) string {
+	switch {
+	case size >= 1024*1024:
+		return fmt.Sprintf("%.1f MB", float64(size)/(1024*1024))
+	case size >= 1024:
+		return fmt.Sprintf("%.0f KB", float64(size)/1024)
+	}
+	return fmt.Sprintf("%d B", size)
+}
+
+// pdfText replaces characters outside the Basic Multilingual Plane (emoji, ...), which
+// gofpdf's UTF-8 fonts cannot measure, and flattens tabs
+func pdfText(s string) string {
+	return strings.Map(func(r rune) rune {
+		switch {
+		case r > 0xFFFF:
+			return '?'
+		case r == '\t':
+			return ' '
+		}
+		return r
+	}, s)
+}
+
+// splitPDFText wraps text to fit a cell of width w. Thai has no spaces between words, so
+// lines break at the last space or else between letters, keeping vowels and tone marks
+// with their consonant. (gofpdf's SplitText breaks after every Thai combining mark.)
+func splitPDFText(pdf *gofpdf.Fpdf, text string, w float64) []string {
+	w -= 2 * pdf.GetCellMargin()
+	lines := make([]string, 0, 1)
+	for _, paragraph := range strings.Split(text, "\n") {
+		var line []string
+		for _, c := range textClusters(paragraph) {
+			if len(line) > 0 && pdf.GetStringWidth(strings.Join(line, "")+c) > w {
+				cut := len(line)
+				for j := len(line) - 1; j > 0; j-- {
+					if line[j] == " " {
+						cut = j
+						break
+					}
+				}
+				lines = append(lines, strings.TrimRight(strings.Join(line[:cut], ""), " "))
+				line = append([]string(nil), line[cut:]...)
+				for len(line) > 0 && line[0] == " " {
+					line = line[1:]
+				}
+			}
+			line = append(line, c)
+		}
+		lines = append(lines, strings.Join(line, ""))
+	}
+	return lines
+}
+
+// textClusters splits text into units that must stay on one line: a letter with its
+// combining marks, a Thai leading vowel (เ แ โ ใ ไ) with the next letter, and following
+// vowels (ะ า ำ) and ๆ with the letter before them
+func textClusters(text string) []string {
+	var clusters []string
+	joinNext := false
+	for _, r := range text {
+		attach := unicode.Is(unicode.Mn, r) || strings.ContainsRune("ะาำๆ", r)
+		if len(clusters) > 0 && (attach || joinNext) {
+			clusters[len(clusters)-1] += string(r)
+		} else {
+			clusters = append(clusters, string(r))
+		}
+		joinNext = strings.ContainsRune("เแโใไ", r)
+	}
+	return clusters
+}
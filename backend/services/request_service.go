@@ -1,36 +1,77 @@
 package services
 
 import (
-	"bytes"
+	"context"
 	"errors"
 	"fmt"
+	"ku-asset/auth"
 	"ku-asset/dto"
 	"ku-asset/models"
+	"ku-asset/realtime"
 	"log"
+	"strings"
 	"time"
 
-	"github.com/jung-kurt/gofpdf/v2"
-
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+var (
+	ErrRequestNotFound         = errors.New("request not found")
+	ErrInvalidStatusTransition = errors.New("invalid status transition")
 )
 
+// requestTransitions คือสถานะถัดไปที่คำขอแต่ละสถานะเปลี่ยนได้ (อนุมัติก่อนจ่ายของเสมอ)
+var requestTransitions = map[models.RequestStatus][]models.RequestStatus{
+	models.RequestStatusPending:  {models.RequestStatusApproved, models.RequestStatusRejected, models.RequestStatusCancelled},
+	models.RequestStatusApproved: {models.RequestStatusIssued},
+	models.RequestStatusIssued:   {models.RequestStatusCompleted},
+}
+
+// canTransitionRequest reports whether a request may move from one status to another
+func canTransitionRequest(from, to models.RequestStatus) bool {
+	for _, next := range requestTransitions[from] {
+		if next == to {
+			return true
+		}
+	}
+	return false
+}
+
 // ⭐ อัปเดต Interface ให้ตรงกับที่ Controller เรียกใช้
 type RequestService interface {
-	CreateRequest(userID uint, input *dto.CreateRequestInput) (*dto.RequestResponse, error)
+	CreateRequest(ctx context.Context, userID uint, input *dto.CreateRequestInput) (*dto.RequestResponse, error)
 	GetRequestsByUserID(userID uint) ([]dto.RequestResponse, error)
 	GetRequestByID(requestID uint) (*dto.RequestResponse, error)
-	GetAllRequests() ([]dto.RequestResponse, error)
-	UpdateRequestStatus(requestID uint, status string, notes string) (*dto.RequestResponse, error)
+	GetAllRequests(scope *auth.Scope) ([]dto.RequestResponse, error)
+	UpdateRequestStatus(ctx context.Context, requestID uint, status string, notes string, actorID *uint, scope *auth.Scope) (*dto.RequestResponse, error)
 	GenerateRequestPDF(req *dto.RequestResponse) ([]byte, error) // ⭐ เพิ่ม method นี้
+	// 🆕 ตรวจสอบใบเบิกกระดาษจาก QR code (ไม่ต้องล็อกอิน ใช้ลายเซ็นใน URL แทน)
+	VerifyRequest(requestNumber, sig string) (*dto.RequestVerificationResponse, error)
+
+	// Comments - ผู้ขอเบิกหรือผู้มีสิทธิ์ request.view ในหน่วยงานของผู้ขอ (viewScope)
+	GetComments(requestID, userID uint, viewScope *auth.Scope) ([]dto.RequestCommentResponse, error)
+	AddComment(ctx context.Context, requestID, userID uint, input *dto.CreateRequestCommentInput, viewScope *auth.Scope) (*dto.RequestCommentResponse, error)
 }
 
 type requestService struct {
 	db             *gorm.DB
 	productService ProductService
+	notifications  NotificationService
+	webhooks       WebhookService
+	stockMonitor   StockMonitorService
+	hub            realtime.Hub
 }
 
-func NewRequestService(db *gorm.DB, productService ProductService) RequestService {
-	return &requestService{db: db, productService: productService}
+func NewRequestService(db *gorm.DB, productService ProductService, notifications NotificationService, webhooks WebhookService, stockMonitor StockMonitorService, hub realtime.Hub) RequestService {
+	return &requestService{
+		db:             db,
+		productService: productService,
+		notifications:  notifications,
+		webhooks:       webhooks,
+		stockMonitor:   stockMonitor,
+		hub:            hub,
+	}
 }
 
 // ApproveRequest คือ Flow ที่ Admin ทำ
@@ -100,8 +141,11 @@ func (s *requestService) generateRequestNumber() (string, error) {
 }
 
 // ⭐ อัปเดต CreateRequest ให้สร้าง Request Number
-func (s *requestService) CreateRequest(userID uint, req *dto.CreateRequestInput) (*dto.RequestResponse, error) {
-	tx := s.db.Begin()
+func (s *requestService) CreateRequest(ctx context.Context, userID uint, req *dto.CreateRequestInput) (*dto.RequestResponse, error) {
+	// 🆕 event ที่เกิดใน transaction (เช่น notification) จะถูกส่งเข้า stream หลัง commit เท่านั้น
+	ctx, batch := realtime.WithBatch(ctx)
+	db := s.db.WithContext(ctx)
+	tx := db.Begin()
 	if tx.Error != nil {
 		return nil, tx.Error
 	}
@@ -145,16 +189,34 @@ func (s *requestService) CreateRequest(userID uint, req *dto.CreateRequestInput)
 		}
 	}
 
+	// 🆕 แนบไฟล์ที่ผู้ขออัปโหลดไว้ก่อน (บันทึกข้อความ, ใบเสนอราคา)
+	if err := attachStagedUploads(tx, request.ID, userID, req.Attachments); err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+
+	// ⭐ แจ้งผู้อนุมัติทางอีเมล (เข้าคิวใน transaction เดียวกัน)
+	if err := s.notifications.EnqueueRequestCreated(tx, request.ID); err != nil {
+		tx.Rollback()
+		return nil, fmt.Errorf("failed to queue notifications: %v", err)
+	}
+	if err := s.webhooks.EnqueueRequestCreated(tx, request.ID); err != nil {
+		tx.Rollback()
+		return nil, fmt.Errorf("failed to queue webhooks: %v", err)
+	}
+
 	if err := tx.Commit().Error; err != nil {
 		return nil, err
 	}
+	batch.Flush(s.hub)
 
 	// โหลดข้อมูลใหม่พร้อม relations
 	var createdRequest models.Request
 	// ✅ FIXED: เพิ่ม Preload("User.Department")
-	if err := s.db.Preload("User.Department").Preload("Items.Product.Category").First(&createdRequest, request.ID).Error; err != nil {
+	if err := preloadRequestAttachments(db).Preload("User.Department").Preload("Items.Product.Category").First(&createdRequest, request.ID).Error; err != nil {
 		return nil, err
 	}
+	s.hub.Publish(requestEvent(realtime.EventRequestCreated, &createdRequest))
 
 	return mapRequestToResponse(&createdRequest), nil
 }
@@ -182,19 +244,20 @@ func (s *requestService) GetRequestsByUserID(userID uint) ([]dto.RequestResponse
 func (s *requestService) GetRequestByID(requestID uint) (*dto.RequestResponse, error) {
 	var request models.Request
 	// ✅ FIXED: เพิ่ม Preload("User.Department")
-	if err := s.db.Preload("User.Department").Preload("Items.Product.Category").First(&request, requestID).Error; err != nil {
+	if err := preloadRequestAttachments(s.db).Preload("User.Department.Parent").Preload("ApprovedBy").Preload("IssuedBy").
+		Preload("Items.Product.Category").First(&request, requestID).Error; err != nil {
 		return nil, errors.New("request not found")
 	}
 	return mapRequestToResponse(&request), nil
 }
 
-// ⭐ เพิ่ม GetAllRequests
-func (s *requestService) GetAllRequests() ([]dto.RequestResponse, error) {
+// ⭐ เพิ่ม GetAllRequests (จำกัดตามหน่วยงานของผู้ดูแลด้วย scope)
+func (s *requestService) GetAllRequests(scope *auth.Scope) ([]dto.RequestResponse, error) {
 	var requests []models.Request
 
 	// --- จุดที่สำคัญที่สุดอยู่ตรงนี้ ---
 	// ต้องมี .Preload("User.Department") และ .Preload("User.Department.Parent") เพื่อดึงข้อมูลคณะ
-	err := s.db.
+	err := scopeRequests(s.db, scope).
 		Preload("User.Department.Parent"). // <--- เพิ่มบรรทัดนี้เพื่อดึงข้อมูลคณะ
 		Preload("User.Department").        // <--- บรรทัดนี้ "ต้องมี" และ "ต้องเป็นแบบนี้"
 		Preload("Items.Product").          // Preload ส่วนอื่นที่จำเป็น
@@ -215,9 +278,11 @@ func (s *requestService) GetAllRequests() ([]dto.RequestResponse, error) {
 	return responses, nil
 }
 
-// ⭐ แก้ไข UpdateRequestStatus
-func (s *requestService) UpdateRequestStatus(requestID uint, status string, notes string) (*dto.RequestResponse, error) {
-	tx := s.db.Begin()
+// ⭐ แก้ไข UpdateRequestStatus (คำขอนอก scope จะถือว่าไม่พบ)
+func (s *requestService) UpdateRequestStatus(ctx context.Context, requestID uint, status string, notes string, actorID *uint, scope *auth.Scope) (*dto.RequestResponse, error) {
+	ctx, batch := realtime.WithBatch(ctx)
+	db := s.db.WithContext(ctx)
+	tx := db.Begin()
 	if tx.Error != nil {
 		return nil, tx.Error
 	}
@@ -227,12 +292,19 @@ func (s *requestService) UpdateRequestStatus(requestID uint, status string, note
 		}
 	}()
 
-	// อัปเดตสถานะคำขอ
+	// อัปเดตสถานะคำขอ (ล็อกแถวไว้ กันอนุมัติซ้ำพร้อมกันจนตัดสต็อกสองครั้ง)
 	var request models.Request
-	if err := tx.First(&request, requestID).Error; err != nil {
+	if err := scopeRequests(tx, scope).Clauses(clause.Locking{Strength: "UPDATE"}).First(&request, requestID).Error; err != nil {
 		tx.Rollback()
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("request not found")
+		}
 		return nil, err
 	}
+	if !canTransitionRequest(request.Status, models.RequestStatus(status)) {
+		tx.Rollback()
+		return nil, fmt.Errorf("%w: %s -> %s", ErrInvalidStatusTransition, request.Status, status)
+	}
 
 	request.Status = models.RequestStatus(status)
 	request.AdminNote = notes
@@ -242,6 +314,7 @@ func (s *requestService) UpdateRequestStatus(requestID uint, status string, note
 	switch status {
 	case "APPROVED":
 		request.ApprovedDate = &now
+		request.ApprovedByID = actorID // 🆕 ชื่อผู้อนุมัติในใบเบิก
 		// ⭐ ลดสินค้าในคลังเมื่ออนุมัติ
 		if err := s.reduceProductStock(tx, requestID); err != nil {
 			tx.Rollback()
@@ -249,6 +322,7 @@ func (s *requestService) UpdateRequestStatus(requestID uint, status string, note
 		}
 	case "ISSUED":
 		request.IssuedDate = &now
+		request.IssuedByID = actorID
 	case "COMPLETED":
 		request.CompletedDate = &now
 	}
@@ -258,16 +332,28 @@ func (s *requestService) UpdateRequestStatus(requestID uint, status string, note
 		return nil, err
 	}
 
+	// ⭐ แจ้งผู้ขอเบิกทางอีเมล (อนุมัติ / ไม่อนุมัติ / พร้อมให้มารับ)
+	if err := s.notifications.EnqueueRequestStatusChanged(tx, requestID); err != nil {
+		tx.Rollback()
+		return nil, fmt.Errorf("failed to queue notifications: %v", err)
+	}
+	if err := s.webhooks.EnqueueRequestStatusChanged(tx, requestID); err != nil {
+		tx.Rollback()
+		return nil, fmt.Errorf("failed to queue webhooks: %v", err)
+	}
+
 	if err := tx.Commit().Error; err != nil {
 		return nil, err
 	}
+	batch.Flush(s.hub)
 
 	// โหลดข้อมูลใหม่
 	var updatedRequest models.Request
 	// ✅ FIXED: เพิ่ม Preload("User.Department")
-	if err := s.db.Preload("User.Department").Preload("Items.Product.Category").First(&updatedRequest, requestID).Error; err != nil {
+	if err := db.Preload("User.Department").Preload("Items.Product.Category").First(&updatedRequest, requestID).Error; err != nil {
 		return nil, err
 	}
+	s.hub.Publish(requestEvent(realtime.EventRequestStatusChanged, &updatedRequest))
 
 	return mapRequestToResponse(&updatedRequest), nil
 }
@@ -298,9 +384,14 @@ func (s *requestService) reduceProductStock(tx *gorm.DB, requestID uint) error {
 		log.Printf("✅ Reduced stock for %s: %d -> %d (requested: %d)",
 			product.Name, product.Stock, newStock, item.Quantity)
 
-		if newStock <= product.MinStock {
-			log.Printf("⚠️ Low stock alert: %s (remaining: %d, min: %d)",
-				product.Name, newStock, product.MinStock)
+		updated := product
+		updated.Stock = newStock
+		if newStock == 0 {
+			updated.Status = models.ProductStatusOutOfStock
+		}
+		realtime.Emit(tx.Statement.Context, s.hub, stockChangedEvent(&updated))
+		if err := s.webhooks.EnqueueStockChanged(tx, &updated, product.Stock, "request_approved", &requestID); err != nil {
+			return fmt.Errorf("failed to queue webhooks: %v", err)
 		}
 
 		if newStock == 0 {
@@ -308,11 +399,34 @@ func (s *requestService) reduceProductStock(tx *gorm.DB, requestID uint) error {
 				log.Printf("Failed to update product status: %v", err)
 			}
 		}
+
+		// ⭐ เทียบกับ MinStock ของสินค้าเอง แจ้งผู้ดูแลสต็อกครั้งเดียวต่อรอบที่ต่ำกว่าขั้นต่ำ
+		if err := s.stockMonitor.Evaluate(tx, product.ID, &requestID); err != nil {
+			return err
+		}
 	}
 
 	return nil
 }
 
+// 🆕 requestEvent ส่งถึงผู้ขอเบิก และผู้มีสิทธิ์ request.view ที่ครอบคลุมหน่วยงานของผู้ขอ
+func requestEvent(eventType string, r *models.Request) realtime.Event {
+	userID := r.UserID
+	return realtime.Event{
+		Type: eventType,
+		Data: map[string]interface{}{
+			"id":             r.ID,
+			"request_number": r.RequestNumber,
+			"status":         r.Status,
+			"user_id":        r.UserID,
+			"department_id":  r.User.DepartmentID,
+		},
+		UserID:       &userID,
+		Permission:   models.PermRequestView,
+		DepartmentID: r.User.DepartmentID,
+	}
+}
+
 // ⭐ Helper function (Correct version)
 func mapRequestToResponse(r *models.Request) *dto.RequestResponse {
 	// สร้าง User DTO
@@ -357,11 +471,18 @@ func mapRequestToResponse(r *models.Request) *dto.RequestResponse {
 				ID:   item.Product.ID,
 				Name: item.Product.Name,
 				Code: productCode,
+				Unit: item.Product.Unit,
 			},
 			Quantity: item.Quantity,
 		})
 	}
 
+	// 🆕 เอกสารแนบ (มีเฉพาะเมื่อ preload มา)
+	var attachmentResponses []dto.RequestAttachmentResponse
+	for i := range r.Attachments {
+		attachmentResponses = append(attachmentResponses, mapRequestAttachmentToResponse(&r.Attachments[i]))
+	}
+
 	// สร้าง Response สุดท้าย
 	res := &dto.RequestResponse{
 		ID:            r.ID,
@@ -373,6 +494,7 @@ func mapRequestToResponse(r *models.Request) *dto.RequestResponse {
 		AdminNote:     r.AdminNote,
 		User:          userDto,
 		Items:         itemResponses,
+		Attachments:   attachmentResponses,
 		CreatedAt:     r.CreatedAt,
 		UpdatedAt:     r.UpdatedAt,
 	}
@@ -380,6 +502,13 @@ func mapRequestToResponse(r *models.Request) *dto.RequestResponse {
 	if r.ApprovedDate != nil {
 		res.ApprovedDate = r.ApprovedDate
 	}
+	// ผู้อนุมัติ/ผู้จ่ายของ (มีเฉพาะเมื่อ preload มา เช่นในใบเบิก PDF)
+	if r.ApprovedBy != nil {
+		res.ApprovedBy = &dto.UserProfileResponse{ID: r.ApprovedBy.ID, Name: r.ApprovedBy.Name}
+	}
+	if r.IssuedBy != nil {
+		res.IssuedBy = &dto.UserProfileResponse{ID: r.IssuedBy.ID, Name: r.IssuedBy.Name}
+	}
 	if r.IssuedDate != nil {
 		res.IssuedDate = r.IssuedDate
 	}
@@ -390,33 +519,73 @@ func mapRequestToResponse(r *models.Request) *dto.RequestResponse {
 	return res
 }
 
-// ⭐ เพิ่มฟังก์ชัน GenerateRequestPDF ใน requestService
-func (s *requestService) GenerateRequestPDF(req *dto.RequestResponse) ([]byte, error) {
-	pdf := gofpdf.New("P", "mm", "A4", "")
-	pdf.AddPage()
-	pdf.SetFont("Arial", "", 16)
-
-	pdf.Cell(40, 10, "ใบเบิกครุภัณฑ์")
-	pdf.Ln(12)
-	pdf.Cell(40, 10, fmt.Sprintf("เลขที่คำขอ: %s", req.RequestNumber))
-	pdf.Ln(8)
-	pdf.Cell(40, 10, fmt.Sprintf("ชื่อผู้ขอ: %s", req.User.Name))
-	pdf.Ln(8)
-	pdf.Cell(40, 10, fmt.Sprintf("วัตถุประสงค์: %s", req.Purpose))
-	pdf.Ln(8)
-	pdf.Cell(40, 10, fmt.Sprintf("สถานะ: %s", req.Status))
-	pdf.Ln(12)
-	pdf.Cell(40, 10, "รายการครุภัณฑ์:")
-	pdf.Ln(8)
-	for _, item := range req.Items {
-		pdf.Cell(40, 10, fmt.Sprintf("- %s x%d", item.Product.Name, item.Quantity))
-		pdf.Ln(6)
+// --- Comments ---
+
+func (s *requestService) GetComments(requestID, userID uint, viewScope *auth.Scope) ([]dto.RequestCommentResponse, error) {
+	if _, err := findAccessibleRequest(s.db, requestID, userID, viewScope); err != nil {
+		return nil, err
 	}
 
-	var buf bytes.Buffer
-	err := pdf.Output(&buf)
+	var comments []models.RequestComment
+	if err := s.db.Preload("User").Where("request_id = ?", requestID).Order("created_at, id").Find(&comments).Error; err != nil {
+		return nil, err
+	}
+
+	response := make([]dto.RequestCommentResponse, 0, len(comments))
+	for i := range comments {
+		response = append(response, mapRequestCommentToResponse(&comments[i]))
+	}
+	return response, nil
+}
+
+func (s *requestService) AddComment(ctx context.Context, requestID, userID uint, input *dto.CreateRequestCommentInput, viewScope *auth.Scope) (*dto.RequestCommentResponse, error) {
+	body := strings.TrimSpace(input.Body)
+	if body == "" {
+		return nil, errors.New("comment is empty")
+	}
+
+	comment := models.RequestComment{RequestID: requestID, UserID: userID, Body: body}
+	err := s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if _, err := findAccessibleRequest(tx, requestID, userID, viewScope); err != nil {
+			return err
+		}
+		if err := tx.Create(&comment).Error; err != nil {
+			return err
+		}
+		return s.notifications.EnqueueRequestComment(tx, comment.ID)
+	})
 	if err != nil {
 		return nil, err
 	}
-	return buf.Bytes(), nil
+
+	if err := s.db.Preload("User").First(&comment, comment.ID).Error; err != nil {
+		return nil, err
+	}
+	response := mapRequestCommentToResponse(&comment)
+	return &response, nil
+}
+
+// findAccessibleRequest loads a request that the user owns or can view through viewScope
+func findAccessibleRequest(tx *gorm.DB, requestID, userID uint, viewScope *auth.Scope) (*models.Request, error) {
+	var request models.Request
+	if err := tx.Preload("User").First(&request, requestID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrRequestNotFound
+		}
+		return nil, err
+	}
+	if request.UserID != userID && !viewScope.Allows(request.User.DepartmentID) {
+		return nil, ErrRequestNotFound
+	}
+	return &request, nil
+}
+
+func mapRequestCommentToResponse(c *models.RequestComment) dto.RequestCommentResponse {
+	return dto.RequestCommentResponse{
+		ID:        c.ID,
+		RequestID: c.RequestID,
+		User:      mapUserToAuthUser(&c.User),
+		Body:      c.Body,
+		CreatedAt: c.CreatedAt,
+	}
 }
@@ -0,0 +1,71 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"ku-asset/auth"
+	"ku-asset/models"
+
+	"github.com/DATA-DOG/go-sqlmock"
+)
+
+func TestCanTransitionRequest(t *testing.T) {
+	tests := []struct {
+		from, to models.RequestStatus
+		want     bool
+	}{
+		{models.RequestStatusPending, models.RequestStatusApproved, true},
+		{models.RequestStatusPending, models.RequestStatusRejected, true},
+		{models.RequestStatusPending, models.RequestStatusCancelled, true},
+		{models.RequestStatusApproved, models.RequestStatusIssued, true},
+		{models.RequestStatusIssued, models.RequestStatusCompleted, true},
+
+		{models.RequestStatusPending, models.RequestStatusIssued, false},    // ข้ามการอนุมัติ
+		{models.RequestStatusPending, models.RequestStatusCompleted, false}, // ข้ามการอนุมัติ
+		{models.RequestStatusApproved, models.RequestStatusApproved, false}, // อนุมัติซ้ำ = ตัดสต็อกซ้ำ
+		{models.RequestStatusApproved, models.RequestStatusRejected, false},
+		{models.RequestStatusApproved, models.RequestStatusPending, false},
+		{models.RequestStatusRejected, models.RequestStatusApproved, false},
+		{models.RequestStatusCompleted, models.RequestStatusIssued, false},
+		{models.RequestStatusCancelled, models.RequestStatusPending, false},
+	}
+	for _, tt := range tests {
+		t.Run(string(tt.from)+"->"+string(tt.to), func(t *testing.T) {
+			if got := canTransitionRequest(tt.from, tt.to); got != tt.want {
+				t.Errorf("canTransitionRequest(%s, %s) = %v, want %v", tt.from, tt.to, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestUpdateRequestStatusRejectsInvalidTransition(t *testing.T) {
+	tests := []struct {
+		current models.RequestStatus
+		status  string
+	}{
+		{models.RequestStatusApproved, "APPROVED"},
+		{models.RequestStatusPending, "ISSUED"},
+		{models.RequestStatusPending, "COMPLETED"},
+	}
+	for _, tt := range tests {
+		t.Run(string(tt.current)+"->"+tt.status, func(t *testing.T) {
+			db, mock := newMockDB(t)
+			mock.ExpectBegin()
+			mock.ExpectQuery(`SELECT \* FROM "requests" .* FOR UPDATE`).
+				WillReturnRows(sqlmock.NewRows([]string{"id", "status"}).AddRow(3, string(tt.current)))
+			mock.ExpectRollback()
+
+			s := &requestService{db: db}
+			_, err := s.UpdateRequestStatus(context.Background(), 3, tt.status, "", nil, auth.GlobalScope())
+			if !errors.Is(err, ErrInvalidStatusTransition) {
+				t.Fatalf("err = %v, want ErrInvalidStatusTransition", err)
+			}
+			// ไม่มีการตัดสต็อกหรือบันทึกใด ๆ หลังตรวจสถานะ
+			if err := mock.ExpectationsWereMet(); err != nil {
+				t.Error(err)
+			}
+		})
+	}
+}
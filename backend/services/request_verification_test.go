@@ -0,0 +1,66 @@
+package services
+
+import (
+	"errors"
+	"net/url"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+)
+
+func TestVerifyRequest(t *testing.T) {
+	t.Setenv("JWT_SECRET", "test-secret")
+	t.Setenv("REQUEST_VERIFY_SECRET", "")
+
+	const number = "REQ20261019001"
+	link, err := url.Parse(RequestVerificationURL(number))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if link.Path != "/verify/requests/"+number {
+		t.Fatalf("unexpected path %s", link.Path)
+	}
+	sig := link.Query().Get("sig")
+
+	tests := []struct {
+		name    string
+		number  string
+		sig     string
+		found   bool // -> query ถูกเรียก
+		query   bool
+		wantErr error
+	}{
+		{"valid link", number, sig, true, true, nil},
+		{"valid link, request deleted", number, sig, false, true, ErrRequestNotFound},
+		{"signature of another request", "REQ20261019002", sig, false, false, ErrInvalidVerificationSignature},
+		{"missing signature", number, "", false, false, ErrInvalidVerificationSignature},
+		{"forged signature", number, "AAAAAAAAAAAAAAAAAAAAAA", false, false, ErrInvalidVerificationSignature},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db, mock := newMockDB(t)
+			mock.MatchExpectationsInOrder(false)
+			if tt.query {
+				rows := sqlmock.NewRows([]string{"id", "request_number", "status", "user_id"})
+				if tt.found {
+					rows.AddRow(3, number, "APPROVED", 7)
+					mock.ExpectQuery(`FROM "users"`).WillReturnRows(sqlmock.NewRows([]string{"id", "name", "email"}).AddRow(7, "Somchai", "somchai@ku.ac.th"))
+					mock.ExpectQuery(`FROM "request_items"`).WillReturnRows(sqlmock.NewRows([]string{"id", "request_id"}))
+				}
+				mock.ExpectQuery(`FROM "requests" WHERE request_number = \$1`).WithArgs(tt.number, 1).WillReturnRows(rows)
+			}
+
+			s := &requestService{db: db}
+			res, err := s.VerifyRequest(tt.number, tt.sig)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("err = %v, want %v", err, tt.wantErr)
+			}
+			if tt.found && (res.RequestNumber != number || res.Requester != "Somchai" || res.StatusLabel != "อนุมัติแล้ว") {
+				t.Errorf("response = %+v", res)
+			}
+			if err := mock.ExpectationsWereMet(); err != nil {
+				t.Error(err)
+			}
+		})
+	}
+}
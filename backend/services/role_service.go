@@ -0,0 +1,414 @@
+// services/role_service.go
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"ku-asset/auth"
+	"ku-asset/dto"
+	"ku-asset/models"
+	"strings"
+
+	"gorm.io/gorm"
+)
+
+var (
+	ErrRoleNotFound       = errors.New("role not found")
+	ErrRoleExists         = errors.New("role already exists")
+	ErrSystemRole         = errors.New("system roles cannot be deleted")
+	ErrUnknownPermission  = errors.New("unknown permission")
+	ErrRoleAlreadyGranted = errors.New("role already assigned to user")
+	ErrOutOfScope         = errors.New("department is outside your scope")
+	ErrRoleNotGrantable   = errors.New("you cannot grant this role")
+)
+
+// RoleService manages roles, their permissions and role assignments.
+type RoleService interface {
+	GetPermissions() ([]dto.PermissionResponse, error)
+	GetRoles() ([]dto.RoleResponse, error)
+	GetRoleByID(id uint) (*dto.RoleResponse, error)
+	CreateRole(ctx context.Context, req *dto.CreateRoleRequest) (*dto.RoleResponse, error)
+	UpdateRole(ctx context.Context, id uint, req *dto.UpdateRoleRequest) (*dto.RoleResponse, error)
+	DeleteRole(ctx context.Context, id uint) error
+
+	// scope คือขอบเขตหน่วยงานของผู้มอบสิทธิ์ (nil = ทั้งหมด)
+	GetUserRoles(userID uint) ([]dto.UserRoleResponse, error)
+	AssignRole(ctx context.Context, userID uint, req *dto.AssignRoleRequest, granterID uint, scope *auth.Scope) (*dto.UserRoleResponse, error)
+	RemoveRole(ctx context.Context, userID, roleID uint, scope *auth.Scope) error
+}
+
+type roleService struct {
+	db *gorm.DB
+}
+
+func NewRoleService(db *gorm.DB) RoleService {
+	return &roleService{db: db}
+}
+
+func (s *roleService) GetPermissions() ([]dto.PermissionResponse, error) {
+	var permissions []models.Permission
+	if err := s.db.Order("code ASC").Find(&permissions).Error; err != nil {
+		return nil, err
+	}
+
+	response := make([]dto.PermissionResponse, 0, len(permissions))
+	for _, p := range permissions {
+		response = append(response, dto.PermissionResponse{Code: p.Code, Description: p.Description})
+	}
+	return response, nil
+}
+
+func (s *roleService) GetRoles() ([]dto.RoleResponse, error) {
+	var roles []models.RoleDefinition
+	if err := s.db.Preload("Permissions").Order("name ASC").Find(&roles).Error; err != nil {
+		return nil, err
+	}
+
+	response := make([]dto.RoleResponse, 0, len(roles))
+	for _, role := range roles {
+		response = append(response, *mapRoleToResponse(&role))
+	}
+	return response, nil
+}
+
+func (s *roleService) GetRoleByID(id uint) (*dto.RoleResponse, error) {
+	role, err := s.findRole(s.db, id)
+	if err != nil {
+		return nil, err
+	}
+	return mapRoleToResponse(role), nil
+}
+
+func (s *roleService) CreateRole(ctx context.Context, req *dto.CreateRoleRequest) (*dto.RoleResponse, error) {
+	db := s.db.WithContext(ctx)
+	name := strings.ToUpper(strings.TrimSpace(req.Name))
+
+	var count int64
+	if err := db.Model(&models.RoleDefinition{}).Where("name = ?", name).Count(&count).Error; err != nil {
+		return nil, err
+	}
+	if count > 0 {
+		return nil, ErrRoleExists
+	}
+
+	permissions, err := s.resolvePermissions(req.Permissions)
+	if err != nil {
+		return nil, err
+	}
+
+	role := models.RoleDefinition{
+		Name:        name,
+		DisplayName: req.DisplayName,
+		Description: req.Description,
+		Permissions: permissions,
+	}
+	if err := db.Create(&role).Error; err != nil {
+		return nil, fmt.Errorf("failed to create role: %w", err)
+	}
+
+	return s.GetRoleByID(role.ID)
+}
+
+func (s *roleService) UpdateRole(ctx context.Context, id uint, req *dto.UpdateRoleRequest) (*dto.RoleResponse, error) {
+	db := s.db.WithContext(ctx)
+	err := db.Transaction(func(tx *gorm.DB) error {
+		role, err := s.findRole(tx, id)
+		if err != nil {
+			return err
+		}
+
+		if req.DisplayName != nil {
+			role.DisplayName = *req.DisplayName
+		}
+		if req.Description != nil {
+			role.Description = *req.Description
+		}
+		if err := tx.Omit("Permissions").Save(role).Error; err != nil {
+			return err
+		}
+
+		if req.Permissions != nil {
+			permissions, err := s.resolvePermissions(req.Permissions)
+			if err != nil {
+				return err
+			}
+			if err := tx.Model(role).Association("Permissions").Replace(permissions); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return s.GetRoleByID(id)
+}
+
+func (s *roleService) DeleteRole(ctx context.Context, id uint) error {
+	db := s.db.WithContext(ctx)
+	return db.Transaction(func(tx *gorm.DB) error {
+		role, err := s.findRole(tx, id)
+		if err != nil {
+			return err
+		}
+		if role.IsSystem {
+			return ErrSystemRole
+		}
+
+		if err := tx.Where("role_id = ?", id).Delete(&models.UserRole{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Model(role).Association("Permissions").Clear(); err != nil {
+			return err
+		}
+		return tx.Delete(role).Error
+	})
+}
+
+func (s *roleService) GetUserRoles(userID uint) ([]dto.UserRoleResponse, error) {
+	if err := s.db.First(&models.User{}, userID).Error; err != nil {
+		return nil, errors.New("user not found")
+	}
+
+	var assignments []models.UserRole
+	if err := s.db.Preload("Role.Permissions").Preload("Department").Where("user_id = ?", userID).
+		Order("created_at ASC").Find(&assignments).Error; err != nil {
+		return nil, err
+	}
+
+	response := make([]dto.UserRoleResponse, 0, len(assignments))
+	for _, a := range assignments {
+		response = append(response, *mapUserRoleToResponse(&a))
+	}
+	return response, nil
+}
+
+func (s *roleService) AssignRole(ctx context.Context, userID uint, req *dto.AssignRoleRequest, granterID uint, scope *auth.Scope) (*dto.UserRoleResponse, error) {
+	db := s.db.WithContext(ctx)
+	if !scope.Allows(req.DepartmentID) {
+		return nil, ErrOutOfScope
+	}
+	if err := db.First(&models.User{}, userID).Error; err != nil {
+		return nil, errors.New("user not found")
+	}
+	role, err := s.findRole(db, req.RoleID)
+	if err != nil {
+		return nil, err
+	}
+	// ⭐ system role (เช่น ADMIN) มอบได้เฉพาะผู้มี role.manage ทั้งระบบ
+	if !canGrantRole(role, req.DepartmentID, scope) {
+		return nil, ErrRoleNotGrantable
+	}
+	// ⭐ มอบได้เฉพาะ role ที่ผู้มอบมีทุก permission อยู่แล้วในขอบเขตนั้น
+	if ok, err := holdsRolePermissions(db, granterID, role, req.DepartmentID); err != nil {
+		return nil, err
+	} else if !ok {
+		return nil, ErrRoleNotGrantable
+	}
+	if req.DepartmentID != nil {
+		if err := db.First(&models.Department{}, *req.DepartmentID).Error; err != nil {
+			return nil, errors.New("department not found")
+		}
+	}
+
+	var count int64
+	query := db.Model(&models.UserRole{}).Where("user_id = ? AND role_id = ?", userID, req.RoleID)
+	if req.DepartmentID != nil {
+		query = query.Where("department_id = ?", *req.DepartmentID)
+	} else {
+		query = query.Where("department_id IS NULL")
+	}
+	if err := query.Count(&count).Error; err != nil {
+		return nil, err
+	}
+	if count > 0 {
+		return nil, ErrRoleAlreadyGranted
+	}
+
+	assignment := models.UserRole{UserID: userID, RoleID: req.RoleID, DepartmentID: req.DepartmentID}
+	if err := db.Create(&assignment).Error; err != nil {
+		return nil, fmt.Errorf("failed to assign role: %w", err)
+	}
+
+	if err := db.Preload("Role.Permissions").Preload("Department").First(&assignment, assignment.ID).Error; err != nil {
+		return nil, err
+	}
+	return mapUserRoleToResponse(&assignment), nil
+}
+
+// RemoveRole ลบ role ออกจากผู้ใช้ เฉพาะ assignment ที่อยู่ใน scope ของผู้ดำเนินการ
+func (s *roleService) RemoveRole(ctx context.Context, userID, roleID uint, scope *auth.Scope) error {
+	db := s.db.WithContext(ctx)
+	query := db.Where("user_id = ? AND role_id = ?", userID, roleID)
+	if scope != nil && !scope.All {
+		query = query.Where("department_id IN ?", nonEmptyIDs(scope.DepartmentIDs))
+	}
+
+	result := query.Delete(&models.UserRole{})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrRoleNotFound
+	}
+	return nil
+}
+
+func (s *roleService) findRole(tx *gorm.DB, id uint) (*models.RoleDefinition, error) {
+	var role models.RoleDefinition
+	if err := tx.Preload("Permissions").First(&role, id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrRoleNotFound
+		}
+		return nil, err
+	}
+	return &role, nil
+}
+
+// resolvePermissions loads permission rows by code and rejects codes that do not exist
+func (s *roleService) resolvePermissions(codes []string) ([]models.Permission, error) {
+	return resolvePermissions(s.db, codes)
+}
+
+func resolvePermissions(db *gorm.DB, codes []string) ([]models.Permission, error) {
+	permissions := []models.Permission{}
+	if len(codes) == 0 {
+		return permissions, nil
+	}
+
+	if err := db.Where("code IN ?", codes).Find(&permissions).Error; err != nil {
+		return nil, err
+	}
+
+	found := make(map[string]bool, len(permissions))
+	for _, p := range permissions {
+		found[p.Code] = true
+	}
+	for _, code := range codes {
+		if !found[code] {
+			return nil, fmt.Errorf("%w: %s", ErrUnknownPermission, code)
+		}
+	}
+	return permissions, nil
+}
+
+// syncSystemRole moves a user's system-role assignment from one legacy role to another,
+// keeping the user_roles table in step with the users.role column.
+func syncSystemRole(tx *gorm.DB, userID uint, from, to models.Role) error {
+	if from == to {
+		return nil
+	}
+
+	var roles []models.RoleDefinition
+	if err := tx.Where("name IN ?", []string{string(from), string(to)}).Find(&roles).Error; err != nil {
+		return err
+	}
+
+	for _, role := range roles {
+		switch role.Name {
+		case string(from):
+			if err := tx.Where("user_id = ? AND role_id = ? AND department_id IS NULL", userID, role.ID).Delete(&models.UserRole{}).Error; err != nil {
+				return err
+			}
+		case string(to):
+			var count int64
+			if err := tx.Model(&models.UserRole{}).Where("user_id = ? AND role_id = ? AND department_id IS NULL", userID, role.ID).
+				Count(&count).Error; err != nil {
+				return err
+			}
+			if count == 0 {
+				if err := tx.Create(&models.UserRole{UserID: userID, RoleID: role.ID}).Error; err != nil {
+					return err
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// Helper to map model to DTO
+func mapRoleToResponse(role *models.RoleDefinition) *dto.RoleResponse {
+	permissions := make([]string, 0, len(role.Permissions))
+	for _, p := range role.Permissions {
+		permissions = append(permissions, p.Code)
+	}
+
+	return &dto.RoleResponse{
+		ID:          role.ID,
+		Name:        role.Name,
+		DisplayName: role.DisplayName,
+		Description: role.Description,
+		IsSystem:    role.IsSystem,
+		Permissions: permissions,
+		CreatedAt:   role.CreatedAt,
+		UpdatedAt:   role.UpdatedAt,
+	}
+}
+
+func mapUserRoleToResponse(a *models.UserRole) *dto.UserRoleResponse {
+	res := &dto.UserRoleResponse{
+		ID:           a.ID,
+		UserID:       a.UserID,
+		Role:         *mapRoleToResponse(&a.Role),
+		DepartmentID: a.DepartmentID,
+		CreatedAt:    a.CreatedAt,
+	}
+
+	if a.Department != nil {
+		res.Department = &dto.DepartmentInfoResponse{
+			ID:       a.Department.ID,
+			Name:     a.Department.NameTH,
+			Code:     a.Department.Code,
+			Type:     string(a.Department.Type),
+			ParentID: a.Department.ParentID,
+		}
+	}
+	return res
+}
+
+// canGrantRole checks the granter's role.manage scope. USER needs nothing; ADMIN needs a global
+// grant; other roles are granted within departmentID.
+func canGrantRole(role *models.RoleDefinition, departmentID *uint, roleScope *auth.Scope) bool {
+	switch {
+	case role.Name == string(models.RoleUser):
+		return true
+	case role.IsSystem:
+		return roleScope.Allows(nil)
+	}
+	return roleScope.Allows(departmentID)
+}
+
+// holdsRolePermissions reports whether granterID holds every permission of role wherever the
+// grant applies (departmentID, nil = university-wide), so role.manage alone cannot hand out
+// access the granter does not have. USER carries no permissions and always passes.
+func holdsRolePermissions(db *gorm.DB, granterID uint, role *models.RoleDefinition, departmentID *uint) (bool, error) {
+	var codes []string
+	if err := db.Table("permissions").
+		Joins("JOIN role_permissions ON role_permissions.permission_id = permissions.id").
+		Where("role_permissions.role_id = ?", role.ID).
+		Pluck("permissions.code", &codes).Error; err != nil {
+		return false, err
+	}
+
+	for _, code := range codes {
+		scope, err := auth.PermissionScope(db, granterID, code)
+		if err != nil {
+			return false, err
+		}
+		if !scope.Allows(departmentID) {
+			return false, nil
+		}
+	}
+	return true, nil
+}
+
+// canChangeRole is canGrantRole for a user that already holds current; taking ADMIN away
+// from someone needs a global grant as well.
+func canChangeRole(current models.Role, role *models.RoleDefinition, departmentID *uint, roleScope *auth.Scope) bool {
+	if current == models.RoleAdmin && role.Name != string(models.RoleAdmin) && !roleScope.Allows(nil) {
+		return false
+	}
+	return canGrantRole(role, departmentID, roleScope)
+}
@@ -0,0 +1,171 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"ku-asset/auth"
+	"ku-asset/dto"
+	"ku-asset/models"
+
+	"github.com/DATA-DOG/go-sqlmock"
+)
+
+func TestCanGrantRole(t *testing.T) {
+	dept, other := uint(5), uint(9)
+	admin := &models.RoleDefinition{Name: string(models.RoleAdmin), IsSystem: true}
+	user := &models.RoleDefinition{Name: string(models.RoleUser), IsSystem: true}
+	faculty := &models.RoleDefinition{Name: "FACULTY_ADMIN"}
+	scoped := &auth.Scope{DepartmentIDs: []uint{dept}}
+
+	tests := []struct {
+		name       string
+		role       *models.RoleDefinition
+		department *uint
+		scope      *auth.Scope
+		want       bool
+	}{
+		{"USER needs no grant", user, nil, &auth.Scope{}, true},
+		{"ADMIN with global grant", admin, nil, auth.GlobalScope(), true},
+		{"ADMIN with scoped grant", admin, &dept, scoped, false},
+		{"ADMIN without grant", admin, nil, &auth.Scope{}, false},
+		{"custom role inside scope", faculty, &dept, scoped, true},
+		{"custom role outside scope", faculty, &other, scoped, false},
+		{"custom role university-wide with scoped grant", faculty, nil, scoped, false},
+		{"custom role university-wide with global grant", faculty, nil, auth.GlobalScope(), true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := canGrantRole(tt.role, tt.department, tt.scope); got != tt.want {
+				t.Errorf("canGrantRole() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCanChangeRole(t *testing.T) {
+	dept := uint(5)
+	admin := &models.RoleDefinition{Name: string(models.RoleAdmin), IsSystem: true}
+	user := &models.RoleDefinition{Name: string(models.RoleUser), IsSystem: true}
+	scoped := &auth.Scope{DepartmentIDs: []uint{dept}}
+
+	tests := []struct {
+		name    string
+		current models.Role
+		role    *models.RoleDefinition
+		scope   *auth.Scope
+		want    bool
+	}{
+		{"promote to ADMIN with scoped grant", models.RoleUser, admin, scoped, false},
+		{"promote to ADMIN with global grant", models.RoleUser, admin, auth.GlobalScope(), true},
+		{"demote ADMIN with scoped grant", models.RoleAdmin, user, scoped, false},
+		{"demote ADMIN without grant", models.RoleAdmin, user, &auth.Scope{}, false},
+		{"demote ADMIN with global grant", models.RoleAdmin, user, auth.GlobalScope(), true},
+		{"keep USER without grant", models.RoleUser, user, &auth.Scope{}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := canChangeRole(tt.current, tt.role, &dept, tt.scope); got != tt.want {
+				t.Errorf("canChangeRole() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+// expectRolePermissions mocks the permission codes of the role being granted
+func expectRolePermissions(mock sqlmock.Sqlmock, codes ...string) {
+	rows := sqlmock.NewRows([]string{"code"})
+	for _, c := range codes {
+		rows.AddRow(c)
+	}
+	mock.ExpectQuery(`SELECT "permissions"."code" FROM "permissions" JOIN role_permissions`).WillReturnRows(rows)
+}
+
+// expectGranterScope mocks auth.PermissionScope for the granter: departments holds the
+// department_id of each applicable grant (nil = university-wide)
+func expectGranterScope(mock sqlmock.Sqlmock, departments ...interface{}) {
+	mock.ExpectQuery(`SELECT count\(\*\) FROM "user_roles"`).
+		WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(1))
+	rows := sqlmock.NewRows([]string{"department_id"})
+	subtree := sqlmock.NewRows([]string{"id"})
+	global := false
+	for _, d := range departments {
+		rows.AddRow(d)
+		subtree.AddRow(d)
+		global = global || d == nil
+	}
+	mock.ExpectQuery(`SELECT "user_roles"."department_id"`).WillReturnRows(rows)
+	if !global && len(departments) > 0 {
+		mock.ExpectQuery(`WITH RECURSIVE subtree`).WillReturnRows(subtree)
+	}
+}
+
+func TestAssignRoleSystemRoleNeedsGlobalGrant(t *testing.T) {
+	dept := uint(5)
+	scoped := &auth.Scope{DepartmentIDs: []uint{dept}}
+
+	tests := []struct {
+		name     string
+		role     string
+		isSystem bool
+		scope    *auth.Scope
+		expect   func(mock sqlmock.Sqlmock) // permission ของ role และของผู้มอบ
+		denied   bool
+	}{
+		{"scoped grant cannot assign ADMIN", "ADMIN", true, scoped, nil, true},
+		{"scoped grant assigns custom role in department", "FACULTY_ADMIN", false, scoped, func(mock sqlmock.Sqlmock) {
+			expectRolePermissions(mock, models.PermRequestApprove)
+			expectGranterScope(mock, dept)
+		}, false},
+		{"role carries a permission the granter lacks", "STORE_CLERK", false, scoped, func(mock sqlmock.Sqlmock) {
+			expectRolePermissions(mock, models.PermStockAdjust)
+			expectGranterScope(mock)
+		}, true},
+		{"granter holds the permission in another department only", "FACULTY_ADMIN", false, scoped, func(mock sqlmock.Sqlmock) {
+			expectRolePermissions(mock, models.PermRequestApprove)
+			expectGranterScope(mock, 9)
+		}, true},
+		{"global grant assigns ADMIN", "ADMIN", true, auth.GlobalScope(), func(mock sqlmock.Sqlmock) {
+			expectRolePermissions(mock, models.PermAuditView)
+			expectGranterScope(mock, nil)
+		}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db, mock := newMockDB(t)
+			mock.ExpectQuery(`FROM "users"`).WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(7))
+			mock.ExpectQuery(`FROM "roles"`).
+				WillReturnRows(sqlmock.NewRows([]string{"id", "name", "is_system"}).AddRow(3, tt.role, tt.isSystem))
+			mock.ExpectQuery(`FROM "role_permissions"`).WillReturnRows(sqlmock.NewRows([]string{"role_id", "permission_id"}))
+			if tt.expect != nil {
+				tt.expect(mock)
+			}
+
+			s := &roleService{db: db}
+			_, err := s.AssignRole(context.Background(), 7, &dto.AssignRoleRequest{RoleID: 3, DepartmentID: &dept}, 1, tt.scope)
+
+			if got := errors.Is(err, ErrRoleNotGrantable); got != tt.denied {
+				t.Fatalf("denied = %v, want %v (err: %v)", got, tt.denied, err)
+			}
+			if err := mock.ExpectationsWereMet(); err != nil {
+				t.Error(err)
+			}
+		})
+	}
+}
+
+func TestSyncSystemRoleStopsOnCountError(t *testing.T) {
+	db, mock := newMockDB(t)
+	mock.ExpectQuery(`SELECT \* FROM "roles" WHERE name IN`).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "name", "is_system"}).AddRow(2, "ADMIN", true))
+	mock.ExpectQuery(`SELECT count\(\*\) FROM "user_roles"`).WillReturnError(errors.New("connection reset"))
+	// ห้าม INSERT ต่อเมื่อนับไม่สำเร็จ ไม่งั้นได้ assignment ซ้ำ
+
+	if err := syncSystemRole(db, 7, models.RoleUser, models.RoleAdmin); err == nil {
+		t.Fatal("expected the count error to be returned")
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Error(err)
+	}
+}
@@ -0,0 +1,37 @@
+// services/scope.go
+package services
+
+import (
+	"ku-asset/auth"
+	"ku-asset/models"
+
+	"gorm.io/gorm"
+)
+
+// scopeUsers limits a users query to the departments covered by scope.
+// A nil or global scope leaves the query untouched.
+func scopeUsers(db *gorm.DB, scope *auth.Scope) *gorm.DB {
+	if scope == nil || scope.All {
+		return db
+	}
+	return db.Where("users.department_id IN ?", nonEmptyIDs(scope.DepartmentIDs))
+}
+
+// scopeRequests limits a requests query to requests whose requester belongs to the scope
+func scopeRequests(db *gorm.DB, scope *auth.Scope) *gorm.DB {
+	if scope == nil || scope.All {
+		return db
+	}
+	return db.Where("requests.user_id IN (?)",
+		db.Session(&gorm.Session{NewDB: true}).Model(&models.User{}).
+			Select("id").
+			Where("department_id IN ?", nonEmptyIDs(scope.DepartmentIDs)))
+}
+
+// nonEmptyIDs keeps "IN ?" valid SQL when a scope grants no departments at all
+func nonEmptyIDs(ids []uint) []uint {
+	if len(ids) == 0 {
+		return []uint{0}
+	}
+	return ids
+}
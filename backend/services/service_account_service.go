@@ -0,0 +1,281 @@
+// services/service_account_service.go
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"ku-asset/auth"
+	"ku-asset/dto"
+	"ku-asset/models"
+	"log"
+	"net"
+	"regexp"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+var (
+	ErrServiceAccountNotFound = errors.New("service account not found")
+	ErrServiceAccountExists   = errors.New("service account already exists")
+	ErrAPIKeyNotFound         = errors.New("API key not found")
+	ErrInvalidIPAllowlist     = errors.New("invalid IP allowlist entry")
+	ErrPermissionNotHeld      = errors.New("cannot grant a permission you do not hold")
+	ErrInvalidExpiry          = errors.New("expires_at must be in the future")
+)
+
+var serviceAccountSlug = regexp.MustCompile(`[^a-z0-9]+`)
+
+// ServiceAccountService manages service accounts and their API keys.
+type ServiceAccountService interface {
+	GetServiceAccounts() ([]dto.ServiceAccountResponse, error)
+	GetServiceAccount(id uint) (*dto.ServiceAccountResponse, error)
+	CreateServiceAccount(ctx context.Context, req *dto.CreateServiceAccountRequest, createdByID uint) (*dto.ServiceAccountResponse, error)
+	DeleteServiceAccount(ctx context.Context, id uint) error
+
+	// granterPermissions คือสิทธิ์ของผู้สร้าง key - มอบสิทธิ์เกินที่ตัวเองมีไม่ได้
+	CreateAPIKey(ctx context.Context, accountID uint, req *dto.CreateAPIKeyRequest, granterPermissions []string) (*dto.CreatedAPIKeyResponse, error)
+	RevokeAPIKey(ctx context.Context, accountID, keyID uint) error
+}
+
+type serviceAccountService struct {
+	db *gorm.DB
+}
+
+func NewServiceAccountService(db *gorm.DB) ServiceAccountService {
+	return &serviceAccountService{db: db}
+}
+
+func (s *serviceAccountService) GetServiceAccounts() ([]dto.ServiceAccountResponse, error) {
+	var accounts []models.ServiceAccount
+	if err := s.db.Preload("APIKeys", func(db *gorm.DB) *gorm.DB {
+		return db.Order("created_at DESC")
+	}).Preload("APIKeys.Permissions").Order("name ASC").Find(&accounts).Error; err != nil {
+		return nil, err
+	}
+
+	response := make([]dto.ServiceAccountResponse, 0, len(accounts))
+	for _, a := range accounts {
+		response = append(response, *mapServiceAccountToResponse(&a))
+	}
+	return response, nil
+}
+
+func (s *serviceAccountService) GetServiceAccount(id uint) (*dto.ServiceAccountResponse, error) {
+	account, err := s.findAccount(s.db, id)
+	if err != nil {
+		return nil, err
+	}
+	return mapServiceAccountToResponse(account), nil
+}
+
+// CreateServiceAccount creates the account together with its backing (password-less) user
+func (s *serviceAccountService) CreateServiceAccount(ctx context.Context, req *dto.CreateServiceAccountRequest, createdByID uint) (*dto.ServiceAccountResponse, error) {
+	db := s.db.WithContext(ctx)
+	name := strings.TrimSpace(req.Name)
+
+	var count int64
+	if err := db.Unscoped().Model(&models.ServiceAccount{}).Where("name = ?", name).Count(&count).Error; err != nil {
+		return nil, err
+	}
+	if count > 0 {
+		return nil, ErrServiceAccountExists
+	}
+
+	var account models.ServiceAccount
+	err := db.Transaction(func(tx *gorm.DB) error {
+		slug := strings.Trim(serviceAccountSlug.ReplaceAllString(strings.ToLower(name), "-"), "-")
+		user := models.User{
+			Email:    fmt.Sprintf("svc-%s@service-account.local", slug),
+			Name:     name,
+			Role:     models.RoleUser,
+			Provider: models.ProviderService,
+			IsActive: true,
+		}
+		if err := tx.Create(&user).Error; err != nil {
+			return ErrServiceAccountExists
+		}
+
+		account = models.ServiceAccount{
+			Name:        name,
+			Description: req.Description,
+			UserID:      user.ID,
+			CreatedByID: &createdByID,
+			IsActive:    true,
+		}
+		return tx.Create(&account).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	log.Printf("✅ Created service account: %s", account.Name)
+	return s.GetServiceAccount(account.ID)
+}
+
+// DeleteServiceAccount revokes every key, disables the backing user and removes the account
+func (s *serviceAccountService) DeleteServiceAccount(ctx context.Context, id uint) error {
+	db := s.db.WithContext(ctx)
+	return db.Transaction(func(tx *gorm.DB) error {
+		account, err := s.findAccount(tx, id)
+		if err != nil {
+			return err
+		}
+
+		if err := tx.Model(&models.APIKey{}).
+			Where("service_account_id = ? AND revoked_at IS NULL", id).
+			Update("revoked_at", time.Now()).Error; err != nil {
+			return err
+		}
+		if err := tx.Model(&models.User{}).Where("id = ?", account.UserID).Update("is_active", false).Error; err != nil {
+			return err
+		}
+		if err := tx.Model(account).Update("is_active", false).Error; err != nil {
+			return err
+		}
+		return tx.Delete(account).Error
+	})
+}
+
+func (s *serviceAccountService) CreateAPIKey(ctx context.Context, accountID uint, req *dto.CreateAPIKeyRequest, granterPermissions []string) (*dto.CreatedAPIKeyResponse, error) {
+	db := s.db.WithContext(ctx)
+	account, err := s.findAccount(db, accountID)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, code := range req.Permissions {
+		if !auth.HasPermission(granterPermissions, code) {
+			return nil, fmt.Errorf("%w: %s", ErrPermissionNotHeld, code)
+		}
+	}
+	permissions, err := resolvePermissions(db, req.Permissions)
+	if err != nil {
+		return nil, err
+	}
+
+	allowedIPs, err := normalizeIPAllowlist(req.AllowedIPs)
+	if err != nil {
+		return nil, err
+	}
+	if req.ExpiresAt != nil && !req.ExpiresAt.After(time.Now()) {
+		return nil, ErrInvalidExpiry
+	}
+
+	raw, prefix, hash, err := auth.GenerateAPIKey()
+	if err != nil {
+		return nil, err
+	}
+
+	key := models.APIKey{
+		ServiceAccountID: account.ID,
+		Name:             req.Name,
+		Prefix:           prefix,
+		KeyHash:          hash,
+		Permissions:      permissions,
+		AllowedIPs:       strings.Join(allowedIPs, ","),
+		ExpiresAt:        req.ExpiresAt,
+	}
+	if err := db.Create(&key).Error; err != nil {
+		return nil, fmt.Errorf("failed to create API key: %w", err)
+	}
+
+	log.Printf("🔑 Created API key %s for service account %s", key.Prefix, account.Name)
+	return &dto.CreatedAPIKeyResponse{
+		APIKeyResponse: *mapAPIKeyToResponse(&key),
+		Key:            raw,
+	}, nil
+}
+
+func (s *serviceAccountService) RevokeAPIKey(ctx context.Context, accountID, keyID uint) error {
+	db := s.db.WithContext(ctx)
+	result := db.Model(&models.APIKey{}).
+		Where("id = ? AND service_account_id = ? AND revoked_at IS NULL", keyID, accountID).
+		Update("revoked_at", time.Now())
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrAPIKeyNotFound
+	}
+	return nil
+}
+
+func (s *serviceAccountService) findAccount(tx *gorm.DB, id uint) (*models.ServiceAccount, error) {
+	var account models.ServiceAccount
+	if err := tx.Preload("APIKeys", func(db *gorm.DB) *gorm.DB {
+		return db.Order("created_at DESC")
+	}).Preload("APIKeys.Permissions").First(&account, id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrServiceAccountNotFound
+		}
+		return nil, err
+	}
+	return &account, nil
+}
+
+// normalizeIPAllowlist validates IP/CIDR entries and returns them in canonical form
+func normalizeIPAllowlist(entries []string) ([]string, error) {
+	result := make([]string, 0, len(entries))
+	for _, entry := range entries {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		if strings.Contains(entry, "/") {
+			_, network, err := net.ParseCIDR(entry)
+			if err != nil {
+				return nil, fmt.Errorf("%w: %s", ErrInvalidIPAllowlist, entry)
+			}
+			result = append(result, network.String())
+			continue
+		}
+		ip := net.ParseIP(entry)
+		if ip == nil {
+			return nil, fmt.Errorf("%w: %s", ErrInvalidIPAllowlist, entry)
+		}
+		result = append(result, ip.String())
+	}
+	return result, nil
+}
+
+// Helper to map model to DTO
+func mapServiceAccountToResponse(a *models.ServiceAccount) *dto.ServiceAccountResponse {
+	keys := make([]dto.APIKeyResponse, 0, len(a.APIKeys))
+	for _, k := range a.APIKeys {
+		keys = append(keys, *mapAPIKeyToResponse(&k))
+	}
+
+	return &dto.ServiceAccountResponse{
+		ID:          a.ID,
+		Name:        a.Name,
+		Description: a.Description,
+		UserID:      a.UserID,
+		IsActive:    a.IsActive,
+		APIKeys:     keys,
+		CreatedAt:   a.CreatedAt,
+		UpdatedAt:   a.UpdatedAt,
+	}
+}
+
+func mapAPIKeyToResponse(k *models.APIKey) *dto.APIKeyResponse {
+	allowed := k.AllowedIPList()
+	if allowed == nil {
+		allowed = []string{}
+	}
+
+	return &dto.APIKeyResponse{
+		ID:          k.ID,
+		Name:        k.Name,
+		Prefix:      k.Prefix,
+		Permissions: k.PermissionCodes(),
+		AllowedIPs:  allowed,
+		ExpiresAt:   k.ExpiresAt,
+		LastUsedAt:  k.LastUsedAt,
+		LastUsedIP:  k.LastUsedIP,
+		RevokedAt:   k.RevokedAt,
+		CreatedAt:   k.CreatedAt,
+	}
+}
@@ -1,30 +1,67 @@
 package services
 
 import (
+	"ku-asset/line"
+	"ku-asset/mailer"
+	"ku-asset/realtime"
+	"ku-asset/storage"
+
 	"gorm.io/gorm"
 )
 
 type Services struct {
-	Auth       AuthService
-	User       UserService
-	Product    ProductService // 👈 RequestService จะใช้ตัวนี้
-	Category   CategoryService
-	Department DepartmentService
-	Request    RequestService
-	Dashboard  DashboardService
+	Auth              AuthService
+	User              UserService
+	Product           ProductService // 👈 RequestService จะใช้ตัวนี้
+	Category          CategoryService
+	Department        DepartmentService
+	Request           RequestService
+	Dashboard         DashboardService
+	Role              RoleService
+	TwoFactor         TwoFactorService
+	ServiceAccount    ServiceAccountService
+	Impersonation     ImpersonationService
+	Provisioning      UserProvisioningService
+	Audit             AuditService
+	Notification      NotificationService
+	Webhook           WebhookService
+	Line              LineService
+	StockMonitor      StockMonitorService
+	Upload            *UploadService
+	ProductMedia      ProductMediaService
+	RequestAttachment RequestAttachmentService
+	Realtime          realtime.Hub // 🆕 SSE stream (/api/v1/stream)
 }
 
-func NewServices(db *gorm.DB) *Services {
-	productService := NewProductService(db)
+func NewServices(db *gorm.DB, m mailer.Mailer, lineClient line.Client, hub realtime.Hub, store storage.Storage) *Services {
+	webhookService := NewWebhookService(db)
+	notificationService := NewNotificationService(db, m, lineClient, hub)
+	stockMonitorService := NewStockMonitorService(db, notificationService)
+	productService := NewProductService(db, hub, webhookService, stockMonitorService)
+	uploadService := NewUploadService(db, store)
 
 	return &Services{
-		Auth:       NewAuthService(db),
-		User:       NewUserService(db),
-		Product:    productService,
-		Category:   NewCategoryService(db),
-		Department: NewDepartmentService(db),
-		Dashboard:  NewDashboardService(db),
-		Request:    NewRequestService(db, productService), // 👈 ส่ง productService เข้าไป
+		Auth:              NewAuthService(db, m),
+		User:              NewUserService(db),
+		Product:           productService,
+		Category:          NewCategoryService(db),
+		Department:        NewDepartmentService(db),
+		Dashboard:         NewDashboardService(db),
+		Role:              NewRoleService(db),
+		TwoFactor:         NewTwoFactorService(db),
+		ServiceAccount:    NewServiceAccountService(db),
+		Impersonation:     NewImpersonationService(db),
+		Provisioning:      NewUserProvisioningService(db, m),
+		Audit:             NewAuditService(db),
+		Notification:      notificationService,
+		Webhook:           webhookService,
+		Line:              NewLineService(db, lineClient),
+		StockMonitor:      stockMonitorService,
+		Upload:            uploadService,
+		ProductMedia:      NewProductMediaService(db, uploadService),
+		RequestAttachment: NewRequestAttachmentService(db, uploadService),
+		Realtime:          hub,
+		Request:           NewRequestService(db, productService, notificationService, webhookService, stockMonitorService, hub), // 👈 ส่ง productService เข้าไป
 
 	}
 }
@@ -0,0 +1,60 @@
+package services
+
+import (
+	"errors"
+	"net/url"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestVerifyFileSignature(t *testing.T) {
+	t.Setenv("JWT_SECRET", "test-secret")
+	t.Setenv("FILE_URL_SECRET", "")
+	t.Setenv("BASE_URL", "https://asset.example")
+
+	const key = "private/requests/user_7/memo ฉบับ 1.pdf"
+	link, expiresAt := SignedFileURL(key, 10*time.Minute)
+	if !strings.HasPrefix(link, "https://asset.example/uploads/private/requests/user_7/") {
+		t.Fatalf("unexpected URL %s", link)
+	}
+	parsed, err := url.Parse(link)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := strings.TrimPrefix(parsed.Path, "/uploads/"); got != key {
+		t.Fatalf("path decodes to %q, want %q", got, key)
+	}
+	expires, sig := parsed.Query().Get("expires"), parsed.Query().Get("sig")
+	past := time.Now().Add(-time.Minute).Unix()
+	tampered := []byte(sig)
+	tampered[0] ^= 1
+
+	tests := []struct {
+		name              string
+		key, expires, sig string
+		wantErr           error
+	}{
+		{"valid link", key, expires, sig, nil},
+		{"other file", "private/requests/user_8/memo.pdf", expires, sig, ErrInvalidFileSignature},
+		{"extended expiry", key, strconv.FormatInt(expiresAt.Unix()+3600, 10), sig, ErrInvalidFileSignature},
+		{"missing signature", key, expires, "", ErrInvalidFileSignature},
+		{"malformed expiry", key, "soon", sig, ErrInvalidFileSignature},
+		{"tampered signature", key, expires, string(tampered), ErrInvalidFileSignature},
+		{"expired link", key, strconv.FormatInt(past, 10), signFileKey(key, past), ErrFileURLExpired},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := verifyFileSignature(tt.key, tt.expires, tt.sig); !errors.Is(err, tt.wantErr) {
+				t.Errorf("verifyFileSignature() = %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+
+	// ⭐ เปลี่ยน secret แล้วลิงก์เดิมต้องใช้ไม่ได้
+	t.Setenv("FILE_URL_SECRET", "rotated")
+	if err := verifyFileSignature(key, expires, sig); !errors.Is(err, ErrInvalidFileSignature) {
+		t.Errorf("link signed with the old secret: %v", err)
+	}
+}
@@ -0,0 +1,306 @@
+// services/stock_monitor_service.go
+package services
+
+import (
+	"context"
+	"fmt"
+	"ku-asset/dto"
+	"ku-asset/models"
+	"log"
+	"math"
+	"time"
+
+	"github.com/xuri/excelize/v2"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// StockMonitorService compares every product with its own MinStock, alerts store staff
+// once per low-stock episode, and suggests what to reorder from recent consumption.
+type StockMonitorService interface {
+	// Evaluate runs after a stock movement, inside the same transaction
+	Evaluate(tx *gorm.DB, productID uint, requestID *uint) error
+
+	GetLowStockProducts() ([]dto.ProductResponse, error)
+	GetReorderSuggestions(query *dto.ReorderQuery) (*dto.ReorderSuggestionsResponse, error)
+	// ExportPurchaseDraft returns the products that need reordering as an XLSX purchase request draft
+	ExportPurchaseDraft(query *dto.ReorderQuery) ([]byte, error)
+
+	// Start runs the scheduled scan until ctx is cancelled
+	Start(ctx context.Context)
+}
+
+type stockMonitorService struct {
+	db            *gorm.DB
+	notifications NotificationService
+}
+
+func NewStockMonitorService(db *gorm.DB, notifications NotificationService) StockMonitorService {
+	return &stockMonitorService{db: db, notifications: notifications}
+}
+
+// isLowStock is the single definition of "low stock": at or below the product's own minimum.
+// Discontinued products are never reported.
+func isLowStock(p *models.Product) bool {
+	return p.Status != models.ProductStatusDiscontinued && p.Stock <= p.MinStock
+}
+
+func (s *stockMonitorService) Evaluate(tx *gorm.DB, productID uint, requestID *uint) error {
+	var product models.Product
+	if err := tx.First(&product, productID).Error; err != nil {
+		return err
+	}
+
+	low := isLowStock(&product)
+	switch {
+	case low && product.LowStockAlertedAt == nil:
+		log.Printf("⚠️ Low stock alert: %s (remaining: %d, min: %d)", product.Name, product.Stock, product.MinStock)
+		if err := s.notifications.EnqueueLowStock(tx, product.ID, requestID); err != nil {
+			return fmt.Errorf("failed to queue low stock alert: %v", err)
+		}
+		return tx.Model(&product).Update("low_stock_alerted_at", time.Now()).Error
+	case !low && product.LowStockAlertedAt != nil:
+		// ⭐ เติมของเกินขั้นต่ำแล้ว รอบหน้าที่ลดลงจะแจ้งใหม่
+		return tx.Model(&product).Update("low_stock_alerted_at", nil).Error
+	}
+	return nil
+}
+
+func (s *stockMonitorService) GetLowStockProducts() ([]dto.ProductResponse, error) {
+	var products []models.Product
+	if err := s.db.Preload("Category").
+		Where("stock <= min_stock AND status <> ?", models.ProductStatusDiscontinued).
+		Order("stock - min_stock ASC, name ASC").Find(&products).Error; err != nil {
+		return nil, err
+	}
+
+	response := make([]dto.ProductResponse, 0, len(products))
+	for i := range products {
+		response = append(response, *mapProductToResponse(&products[i]))
+	}
+	return response, nil
+}
+
+// GetReorderSuggestions estimates daily consumption from approved requests in the window
+// and proposes an order that covers the lead time plus the coverage period on top of MinStock:
+//
+//	reorder point = ⌈daily × lead time⌉ + MinStock
+//	target        = ⌈daily × (lead time + coverage)⌉ + MinStock (at least reorder point + 1)
+//	suggested     = target − (stock − pending requests)
+func (s *stockMonitorService) GetReorderSuggestions(query *dto.ReorderQuery) (*dto.ReorderSuggestionsResponse, error) {
+	windowDays := query.WindowDays
+	if windowDays <= 0 {
+		windowDays = getEnvInt("REORDER_WINDOW_DAYS", 90)
+	}
+	coverageDays := query.CoverageDays
+	if coverageDays <= 0 {
+		coverageDays = getEnvInt("REORDER_COVERAGE_DAYS", 30)
+	}
+	defaultLeadTime := getEnvInt("REORDER_DEFAULT_LEAD_TIME_DAYS", 14)
+	since := time.Now().AddDate(0, 0, -windowDays)
+
+	q := s.db.Preload("Category").Where("status <> ?", models.ProductStatusDiscontinued)
+	if query.CategoryID > 0 {
+		q = q.Where("category_id = ?", query.CategoryID)
+	}
+	var products []models.Product
+	if err := q.Order("code ASC").Find(&products).Error; err != nil {
+		return nil, err
+	}
+
+	consumed, err := s.sumRequestedQuantities(func(db *gorm.DB) *gorm.DB {
+		return db.Where("requests.status IN ? AND requests.approved_date >= ?",
+			[]models.RequestStatus{models.RequestStatusApproved, models.RequestStatusIssued, models.RequestStatusCompleted}, since)
+	})
+	if err != nil {
+		return nil, err
+	}
+	pending, err := s.sumRequestedQuantities(func(db *gorm.DB) *gorm.DB {
+		return db.Where("requests.status = ?", models.RequestStatusPending)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	suggestions := make([]dto.ReorderSuggestionResponse, 0)
+	for i := range products {
+		p := &products[i]
+		leadTime := defaultLeadTime
+		if p.LeadTimeDays != nil {
+			leadTime = *p.LeadTimeDays
+		}
+
+		daily := float64(consumed[p.ID]) / float64(windowDays)
+		reorderPoint := int(math.Ceil(daily*float64(leadTime))) + p.MinStock
+		target := int(math.Ceil(daily*float64(leadTime+coverageDays))) + p.MinStock
+		if target <= reorderPoint {
+			target = reorderPoint + 1
+		}
+		available := p.Stock - pending[p.ID]
+		needsReorder := available <= reorderPoint
+
+		suggested := 0
+		if needsReorder {
+			suggested = target - available
+		}
+
+		var daysLeft *float64
+		if daily > 0 {
+			d := math.Round(float64(p.Stock)/daily*10) / 10
+			daysLeft = &d
+		}
+
+		if !needsReorder && !query.All {
+			continue
+		}
+		suggestions = append(suggestions, dto.ReorderSuggestionResponse{
+			ProductID:         p.ID,
+			Code:              p.Code,
+			Name:              p.Name,
+			Category:          p.Category.Name,
+			Unit:              p.Unit,
+			Stock:             p.Stock,
+			MinStock:          p.MinStock,
+			PendingQuantity:   pending[p.ID],
+			ConsumedQuantity:  consumed[p.ID],
+			DailyConsumption:  math.Round(daily*100) / 100,
+			LeadTimeDays:      leadTime,
+			DaysOfStockLeft:   daysLeft,
+			ReorderPoint:      reorderPoint,
+			SuggestedQuantity: suggested,
+			NeedsReorder:      needsReorder,
+			IsLowStock:        isLowStock(p),
+		})
+	}
+
+	return &dto.ReorderSuggestionsResponse{
+		WindowDays:   windowDays,
+		CoverageDays: coverageDays,
+		GeneratedAt:  time.Now(),
+		Suggestions:  suggestions,
+	}, nil
+}
+
+// sumRequestedQuantities totals request item quantities per product for requests matching filter
+func (s *stockMonitorService) sumRequestedQuantities(filter func(*gorm.DB) *gorm.DB) (map[uint]int, error) {
+	var rows []struct {
+		ProductID uint
+		Total     int
+	}
+	q := s.db.Model(&models.RequestItem{}).
+		Select("request_items.product_id, COALESCE(SUM(request_items.quantity), 0) AS total").
+		Joins("JOIN requests ON requests.id = request_items.request_id AND requests.deleted_at IS NULL")
+	if err := filter(q).Group("request_items.product_id").Scan(&rows).Error; err != nil {
+		return nil, err
+	}
+
+	totals := make(map[uint]int, len(rows))
+	for _, r := range rows {
+		totals[r.ProductID] = r.Total
+	}
+	return totals, nil
+}
+
+func (s *stockMonitorService) ExportPurchaseDraft(query *dto.ReorderQuery) ([]byte, error) {
+	draftQuery := *query
+	draftQuery.All = false
+	result, err := s.GetReorderSuggestions(&draftQuery)
+	if err != nil {
+		return nil, err
+	}
+
+	f := excelize.NewFile()
+	defer f.Close()
+	sheet := "ร่างใบขอซื้อ"
+	if err := f.SetSheetName("Sheet1", sheet); err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	f.SetCellValue(sheet, "A1", "ร่างบันทึกขอซื้อครุภัณฑ์/วัสดุ (สร้างจากระบบ KU Asset)")
+	f.SetCellValue(sheet, "A2", fmt.Sprintf("วันที่จัดทำ %s/%d", now.Format("02/01"), now.Year()+543))
+	f.SetCellValue(sheet, "A3", fmt.Sprintf("คำนวณจากปริมาณการเบิกย้อนหลัง %d วัน และสั่งให้พอใช้อีก %d วันหลังได้รับของ",
+		result.WindowDays, result.CoverageDays))
+
+	headers := []string{"ลำดับ", "รหัส", "รายการ", "หมวดหมู่", "หน่วย", "คงเหลือ", "รออนุมัติ",
+		"อัตราการใช้/วัน", "ระยะเวลารอของ (วัน)", "จำนวนที่เสนอซื้อ", "หมายเหตุ"}
+	const headerRow = 5
+	for i, h := range headers {
+		cell, _ := excelize.CoordinatesToCellName(i+1, headerRow)
+		f.SetCellValue(sheet, cell, h)
+	}
+	if bold, err := f.NewStyle(&excelize.Style{Font: &excelize.Font{Bold: true}}); err == nil {
+		f.SetCellStyle(sheet, "A1", "A1", bold)
+		lastHeader, _ := excelize.CoordinatesToCellName(len(headers), headerRow)
+		f.SetCellStyle(sheet, fmt.Sprintf("A%d", headerRow), lastHeader, bold)
+	}
+
+	for i, item := range result.Suggestions {
+		row := headerRow + 1 + i
+		note := ""
+		if item.IsLowStock {
+			note = "ต่ำกว่าขั้นต่ำ"
+		}
+		values := []interface{}{i + 1, item.Code, item.Name, item.Category, item.Unit, item.Stock,
+			item.PendingQuantity, item.DailyConsumption, item.LeadTimeDays, item.SuggestedQuantity, note}
+		cell, _ := excelize.CoordinatesToCellName(1, row)
+		if err := f.SetSheetRow(sheet, cell, &values); err != nil {
+			return nil, err
+		}
+	}
+
+	f.SetColWidth(sheet, "C", "C", 40)
+	f.SetColWidth(sheet, "D", "D", 20)
+	f.SetColWidth(sheet, "H", "J", 16)
+
+	buf, err := f.WriteToBuffer()
+	if err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// --- Scheduled scan ---
+
+func (s *stockMonitorService) Start(ctx context.Context) {
+	go func() {
+		ticker := time.NewTicker(getEnvDuration("LOW_STOCK_CHECK_MINUTES", time.Minute, time.Hour))
+		defer ticker.Stop()
+		for {
+			if err := s.scan(); err != nil {
+				log.Printf("❌ Stock monitor: %v", err)
+			}
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+}
+
+// scan catches products whose alert state is out of date, e.g. after MinStock was raised
+// or stock was changed outside the API
+func (s *stockMonitorService) scan() error {
+	var ids []uint
+	if err := s.db.Model(&models.Product{}).
+		Where("(stock <= min_stock AND status <> ? AND low_stock_alerted_at IS NULL) OR (stock > min_stock AND low_stock_alerted_at IS NOT NULL)",
+			models.ProductStatusDiscontinued).
+		Pluck("id", &ids).Error; err != nil {
+		return err
+	}
+
+	for _, id := range ids {
+		err := s.db.Transaction(func(tx *gorm.DB) error {
+			// ⭐ ล็อกแถวกันแจ้งซ้ำเมื่อมีหลาย instance สแกนพร้อมกัน
+			if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Select("id").First(&models.Product{}, id).Error; err != nil {
+				return err
+			}
+			return s.Evaluate(tx, id, nil)
+		})
+		if err != nil {
+			return fmt.Errorf("product %d: %w", id, err)
+		}
+	}
+	return nil
+}
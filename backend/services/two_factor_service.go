@@ -0,0 +1,329 @@
+// services/two_factor_service.go
+package services
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base64"
+	"errors"
+	"image/png"
+	"ku-asset/auth"
+	"ku-asset/dto"
+	"ku-asset/models"
+	"math/big"
+	"strings"
+	"time"
+
+	"github.com/pquerna/otp/totp"
+	"gorm.io/gorm"
+)
+
+var (
+	ErrTwoFactorAlreadyEnabled = errors.New("two-factor authentication is already enabled")
+	ErrTwoFactorNotEnabled     = errors.New("two-factor authentication is not enabled")
+	ErrTwoFactorSetupRequired  = errors.New("start two-factor setup first")
+	ErrTwoFactorRequired       = errors.New("two-factor authentication is required for your role")
+	ErrInvalidTwoFactorCode    = errors.New("invalid two-factor code")
+)
+
+const (
+	recoveryCodeCount    = 10
+	recoveryCodeAlphabet = "abcdefghjkmnpqrstuvwxyz23456789" // ตัด 0/o, 1/l/i ที่อ่านสับสน
+	totpPeriod           = 30                                // วินาที (ค่าเริ่มต้นของ totp.Generate)
+)
+
+// TwoFactorService manages TOTP enrolment and recovery codes for the current user.
+type TwoFactorService interface {
+	GetStatus(userID uint) (*dto.TwoFactorStatusResponse, error)
+	Setup(ctx context.Context, userID uint) (*dto.TwoFactorSetupResponse, error)
+	Confirm(ctx context.Context, userID uint, req *dto.TwoFactorCodeRequest) (*dto.RecoveryCodesResponse, error)
+	Disable(ctx context.Context, userID uint, req *dto.DisableTwoFactorRequest) error
+	RegenerateRecoveryCodes(ctx context.Context, userID uint, req *dto.TwoFactorCodeRequest) (*dto.RecoveryCodesResponse, error)
+}
+
+type twoFactorService struct {
+	db *gorm.DB
+}
+
+func NewTwoFactorService(db *gorm.DB) TwoFactorService {
+	return &twoFactorService{db: db}
+}
+
+func (s *twoFactorService) GetStatus(userID uint) (*dto.TwoFactorStatusResponse, error) {
+	user, err := s.findUser(s.db, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	var remaining int64
+	if err := s.db.Model(&models.RecoveryCode{}).
+		Where("user_id = ? AND used_at IS NULL", userID).Count(&remaining).Error; err != nil {
+		return nil, err
+	}
+
+	required, err := auth.UserTwoFactorRequired(s.db, user)
+	if err != nil {
+		return nil, err
+	}
+
+	return &dto.TwoFactorStatusResponse{
+		Enabled:                user.HasTwoFactor(),
+		Required:               required,
+		RecoveryCodesRemaining: remaining,
+	}, nil
+}
+
+// Setup generates a new TOTP secret. 2FA stays disabled until Confirm succeeds.
+func (s *twoFactorService) Setup(ctx context.Context, userID uint) (*dto.TwoFactorSetupResponse, error) {
+	db := s.db.WithContext(ctx)
+	user, err := s.findUser(db, userID)
+	if err != nil {
+		return nil, err
+	}
+	if user.HasTwoFactor() {
+		return nil, ErrTwoFactorAlreadyEnabled
+	}
+
+	key, err := totp.Generate(totp.GenerateOpts{
+		Issuer:      getEnv("TOTP_ISSUER", "KU Asset"),
+		AccountName: user.Email,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	img, err := key.Image(256, 256)
+	if err != nil {
+		return nil, err
+	}
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		return nil, err
+	}
+
+	secret := key.Secret()
+	if err := db.Model(user).Update("totp_secret", secret).Error; err != nil {
+		return nil, err
+	}
+
+	return &dto.TwoFactorSetupResponse{
+		Secret:     secret,
+		OTPAuthURI: key.URL(),
+		QRCodePNG:  "data:image/png;base64," + base64.StdEncoding.EncodeToString(buf.Bytes()),
+	}, nil
+}
+
+// Confirm verifies the first code from the authenticator app, enables 2FA and issues recovery codes.
+func (s *twoFactorService) Confirm(ctx context.Context, userID uint, req *dto.TwoFactorCodeRequest) (*dto.RecoveryCodesResponse, error) {
+	db := s.db.WithContext(ctx)
+	var codes []string
+	err := db.Transaction(func(tx *gorm.DB) error {
+		user, err := s.findUser(tx, userID)
+		if err != nil {
+			return err
+		}
+		if user.HasTwoFactor() {
+			return ErrTwoFactorAlreadyEnabled
+		}
+		if user.TOTPSecret == nil {
+			return ErrTwoFactorSetupRequired
+		}
+		if ok, err := acceptTOTP(tx, user, req.Code, time.Now()); err != nil {
+			return err
+		} else if !ok {
+			return ErrInvalidTwoFactorCode
+		}
+
+		now := time.Now()
+		if err := tx.Model(user).Updates(map[string]interface{}{
+			"two_factor_enabled":    true,
+			"two_factor_enabled_at": now,
+		}).Error; err != nil {
+			return err
+		}
+
+		codes, err = generateRecoveryCodes(tx, userID)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &dto.RecoveryCodesResponse{RecoveryCodes: codes}, nil
+}
+
+// Disable turns 2FA off after re-checking the password and a current code
+func (s *twoFactorService) Disable(ctx context.Context, userID uint, req *dto.DisableTwoFactorRequest) error {
+	db := s.db.WithContext(ctx)
+	return db.Transaction(func(tx *gorm.DB) error {
+		user, err := s.findUser(tx, userID)
+		if err != nil {
+			return err
+		}
+		if !user.HasTwoFactor() {
+			return ErrTwoFactorNotEnabled
+		}
+		if required, err := auth.UserTwoFactorRequired(tx, user); err != nil {
+			return err
+		} else if required {
+			return ErrTwoFactorRequired
+		}
+		if user.HasPassword() {
+			if err := user.CheckPassword(req.Password); err != nil {
+				return errors.New("current password is incorrect")
+			}
+		}
+		if err := verifySecondFactor(tx, user, req.Code); err != nil {
+			return err
+		}
+
+		if err := tx.Model(user).Updates(map[string]interface{}{
+			"two_factor_enabled":    false,
+			"two_factor_enabled_at": nil,
+			"totp_secret":           nil,
+		}).Error; err != nil {
+			return err
+		}
+		return tx.Where("user_id = ?", userID).Delete(&models.RecoveryCode{}).Error
+	})
+}
+
+// RegenerateRecoveryCodes replaces all recovery codes; requires a valid TOTP code
+func (s *twoFactorService) RegenerateRecoveryCodes(ctx context.Context, userID uint, req *dto.TwoFactorCodeRequest) (*dto.RecoveryCodesResponse, error) {
+	db := s.db.WithContext(ctx)
+	var codes []string
+	err := db.Transaction(func(tx *gorm.DB) error {
+		user, err := s.findUser(tx, userID)
+		if err != nil {
+			return err
+		}
+		if !user.HasTwoFactor() {
+			return ErrTwoFactorNotEnabled
+		}
+		if ok, err := acceptTOTP(tx, user, req.Code, time.Now()); err != nil {
+			return err
+		} else if !ok {
+			return ErrInvalidTwoFactorCode
+		}
+
+		codes, err = generateRecoveryCodes(tx, userID)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &dto.RecoveryCodesResponse{RecoveryCodes: codes}, nil
+}
+
+func (s *twoFactorService) findUser(tx *gorm.DB, userID uint) (*models.User, error) {
+	var user models.User
+	if err := tx.First(&user, userID).Error; err != nil {
+		return nil, errors.New("user not found")
+	}
+	return &user, nil
+}
+
+// --- Shared helpers (also used by authService for the login challenge) ---
+
+// verifySecondFactor accepts either a current TOTP code or an unused recovery code
+func verifySecondFactor(tx *gorm.DB, user *models.User, code string) error {
+	if !user.HasTwoFactor() {
+		return ErrTwoFactorNotEnabled
+	}
+
+	code = strings.TrimSpace(code)
+	if ok, err := acceptTOTP(tx, user, code, time.Now()); err != nil || ok {
+		return err
+	}
+
+	// ⭐ ใช้ WHERE used_at IS NULL ป้องกันการใช้ recovery code ซ้ำพร้อมกัน
+	result := tx.Model(&models.RecoveryCode{}).
+		Where("user_id = ? AND code_hash = ? AND used_at IS NULL", user.ID, hashSecret(normalizeRecoveryCode(code))).
+		Update("used_at", time.Now())
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrInvalidTwoFactorCode
+	}
+	return nil
+}
+
+// acceptTOTP checks a TOTP code and records its time step. A step at or before the last
+// accepted one is rejected, so a code works once even though it stays valid for ±1 step.
+func acceptTOTP(tx *gorm.DB, user *models.User, code string, now time.Time) (bool, error) {
+	step, ok := matchTOTPStep(*user.TOTPSecret, strings.TrimSpace(code), now)
+	if !ok || (user.TOTPLastStep != nil && step <= *user.TOTPLastStep) {
+		return false, nil
+	}
+
+	// ⭐ เงื่อนไขใน WHERE กันสอง request ใช้รหัสเดียวกันพร้อมกัน
+	result := tx.Model(&models.User{}).
+		Where("id = ? AND (totp_last_step IS NULL OR totp_last_step < ?)", user.ID, step).
+		Update("totp_last_step", step)
+	if result.Error != nil {
+		return false, result.Error
+	}
+	if result.RowsAffected == 0 {
+		return false, nil
+	}
+	user.TOTPLastStep = &step
+	return true, nil
+}
+
+// matchTOTPStep returns the time step whose code matches, allowing one step of clock skew
+// either way (the same window as totp.Validate)
+func matchTOTPStep(secret, code string, now time.Time) (int64, bool) {
+	current := now.Unix() / totpPeriod
+	for _, step := range []int64{current - 1, current, current + 1} {
+		expected, err := totp.GenerateCode(secret, time.Unix(step*totpPeriod, 0))
+		if err == nil && subtle.ConstantTimeCompare([]byte(expected), []byte(code)) == 1 {
+			return step, true
+		}
+	}
+	return 0, false
+}
+
+// generateRecoveryCodes replaces the user's recovery codes and returns the new plain-text codes
+func generateRecoveryCodes(tx *gorm.DB, userID uint) ([]string, error) {
+	if err := tx.Where("user_id = ?", userID).Delete(&models.RecoveryCode{}).Error; err != nil {
+		return nil, err
+	}
+
+	codes := make([]string, 0, recoveryCodeCount)
+	rows := make([]models.RecoveryCode, 0, recoveryCodeCount)
+	for i := 0; i < recoveryCodeCount; i++ {
+		code, err := randomRecoveryCode()
+		if err != nil {
+			return nil, err
+		}
+		codes = append(codes, code)
+		rows = append(rows, models.RecoveryCode{UserID: userID, CodeHash: hashSecret(normalizeRecoveryCode(code))})
+	}
+
+	if err := tx.Create(&rows).Error; err != nil {
+		return nil, err
+	}
+	return codes, nil
+}
+
+// randomRecoveryCode returns a code formatted as xxxxx-xxxxx
+func randomRecoveryCode() (string, error) {
+	b := make([]byte, 10)
+	max := big.NewInt(int64(len(recoveryCodeAlphabet)))
+	for i := range b {
+		n, err := rand.Int(rand.Reader, max)
+		if err != nil {
+			return "", err
+		}
+		b[i] = recoveryCodeAlphabet[n.Int64()]
+	}
+	return string(b[:5]) + "-" + string(b[5:]), nil
+}
+
+func normalizeRecoveryCode(code string) string {
+	code = strings.ToLower(code)
+	code = strings.ReplaceAll(code, "-", "")
+	return strings.ReplaceAll(code, " ", "")
+}
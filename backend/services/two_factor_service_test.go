@@ -0,0 +1,75 @@
+package services
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"ku-asset/models"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/pquerna/otp/totp"
+)
+
+func TestVerifySecondFactor(t *testing.T) {
+	const secret = "JBSWY3DPEHPK3PXP"
+	step := time.Now().Unix() / totpPeriod
+	codeAt := func(s int64) string {
+		code, err := totp.GenerateCode(secret, time.Unix(s*totpPeriod, 0))
+		if err != nil {
+			t.Fatal(err)
+		}
+		return code
+	}
+	last := func(s int64) *int64 { return &s }
+
+	tests := []struct {
+		name        string
+		enabled     bool
+		lastStep    *int64
+		code        string
+		totpUpdate  int64 // แถวที่ UPDATE totp_last_step กระทบ (-1 = ไม่ควร UPDATE)
+		recoveryHit int64 // แถวที่ UPDATE recovery_codes กระทบ (-1 = ไม่ควรตรวจ)
+		wantErr     error
+	}{
+		{"current code", true, nil, codeAt(step), 1, -1, nil},
+		{"previous step within skew", true, last(step - 2), codeAt(step - 1), 1, -1, nil},
+		{"replayed code", true, last(step), codeAt(step), -1, 0, ErrInvalidTwoFactorCode},
+		{"older code after a newer one", true, last(step), codeAt(step - 1), -1, 0, ErrInvalidTwoFactorCode},
+		{"code used concurrently", true, nil, codeAt(step), 0, 0, ErrInvalidTwoFactorCode},
+		{"code outside window", true, nil, codeAt(step - 3), -1, 0, ErrInvalidTwoFactorCode},
+		{"unused recovery code", true, nil, "abcde-fghjk", -1, 1, nil},
+		{"used recovery code", true, nil, "abcde-fghjk", -1, 0, ErrInvalidTwoFactorCode},
+		{"two-factor disabled", false, nil, codeAt(step), -1, -1, ErrTwoFactorNotEnabled},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db, mock := newMockDB(t)
+			if tt.totpUpdate >= 0 {
+				mock.ExpectBegin()
+				mock.ExpectExec(`UPDATE "users" SET "totp_last_step"=\$1.*totp_last_step IS NULL OR totp_last_step < \$4`).
+					WillReturnResult(sqlmock.NewResult(0, tt.totpUpdate))
+				mock.ExpectCommit()
+			}
+			if tt.recoveryHit >= 0 {
+				mock.ExpectBegin()
+				mock.ExpectExec(`UPDATE "user_recovery_codes" SET "used_at"=\$1 .*used_at IS NULL`).
+					WillReturnResult(sqlmock.NewResult(0, tt.recoveryHit))
+				mock.ExpectCommit()
+			}
+
+			s := secret
+			user := &models.User{ID: 7, TwoFactorEnabled: tt.enabled, TOTPSecret: &s, TOTPLastStep: tt.lastStep}
+			err := verifySecondFactor(db, user, " "+tt.code+" ")
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("err = %v, want %v", err, tt.wantErr)
+			}
+			if err := mock.ExpectationsWereMet(); err != nil {
+				t.Error(err)
+			}
+			if tt.wantErr == nil && tt.totpUpdate == 1 && (user.TOTPLastStep == nil || *user.TOTPLastStep < step-1) {
+				t.Errorf("TOTPLastStep = %v, want the accepted step", user.TOTPLastStep)
+			}
+		})
+	}
+}
@@ -0,0 +1,243 @@
+// services/upload_registry.go
+package services
+
+import (
+	"context"
+	"errors"
+	"ku-asset/models"
+	"ku-asset/storage"
+	"log"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+var ErrFileInUse = errors.New("file is still in use")
+
+// SyncUploadReferences makes urls the complete set of uploads referenced by one entity,
+// inside the caller's transaction. URLs that are not in the registry (external links,
+// files uploaded before the registry existed) are ignored. Uploads that lose their last
+// reference start the garbage-collection grace period.
+func SyncUploadReferences(tx *gorm.DB, entityType string, entityID uint, urls ...string) error {
+	ids := make([]uint, 0)
+	if len(urls) > 0 {
+		// ⭐ ล็อกแถวไว้ GC จะข้ามไป (SKIP LOCKED) ระหว่างที่กำลังผูกการอ้างอิง
+		var uploads []models.Upload
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Select("id").
+			Where("url IN ?", urls).Find(&uploads).Error; err != nil {
+			return err
+		}
+		for _, u := range uploads {
+			ids = append(ids, u.ID)
+		}
+	}
+
+	var previous []uint
+	if err := tx.Model(&models.UploadReference{}).
+		Where("entity_type = ? AND entity_id = ?", entityType, entityID).
+		Pluck("upload_id", &previous).Error; err != nil {
+		return err
+	}
+
+	stale := tx.Where("entity_type = ? AND entity_id = ?", entityType, entityID)
+	if len(ids) > 0 {
+		stale = stale.Where("upload_id NOT IN ?", ids)
+	}
+	if err := stale.Delete(&models.UploadReference{}).Error; err != nil {
+		return err
+	}
+
+	for _, id := range ids {
+		ref := models.UploadReference{UploadID: id, EntityType: entityType, EntityID: entityID}
+		if err := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&ref).Error; err != nil {
+			return err
+		}
+	}
+	if len(ids) > 0 {
+		if err := tx.Model(&models.Upload{}).Where("id IN ?", ids).
+			Update("unreferenced_at", nil).Error; err != nil {
+			return err
+		}
+	}
+
+	released := make([]uint, 0)
+	for _, id := range previous {
+		if !containsUint(ids, id) {
+			released = append(released, id)
+		}
+	}
+	if len(released) == 0 {
+		return nil
+	}
+	return tx.Model(&models.Upload{}).
+		Where("id IN ? AND NOT EXISTS (SELECT 1 FROM upload_references r WHERE r.upload_id = uploads.id)", released).
+		Update("unreferenced_at", time.Now()).Error
+}
+
+func containsUint(list []uint, v uint) bool {
+	for _, x := range list {
+		if x == v {
+			return true
+		}
+	}
+	return false
+}
+
+// findDuplicate returns a registered upload with the same content below keyPrefix whose
+// files still exist. A re-upload restarts the grace period of an unreferenced file.
+func (us *UploadService) findDuplicate(ctx context.Context, sha string, keyPrefix string) (*models.Upload, error) {
+	var found *models.Upload
+	err := us.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		// ⭐ ล็อกแถวไว้ GC จะข้ามไป (SKIP LOCKED) และถ้า GC ลบไปก่อน แถวจะไม่อยู่ในผลลัพธ์
+		var existing models.Upload
+		err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("sha256 = ? AND key LIKE ?", sha, keyPrefix+"/%").Order("id").First(&existing).Error
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		if key, ok := us.keyFromURL(existing.URL); !ok {
+			return nil
+		} else if _, err := us.store.Stat(ctx, key); err != nil {
+			if errors.Is(err, storage.ErrNotFound) {
+				return nil
+			}
+			return err
+		}
+
+		if existing.UnreferencedAt != nil {
+			now := time.Now()
+			existing.UnreferencedAt = &now
+			if err := tx.Model(&existing).Update("unreferenced_at", now).Error; err != nil {
+				return err
+			}
+		}
+		found = &existing
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return found, nil
+}
+
+// registerUpload records a newly stored upload as unreferenced until an entity uses it
+func (us *UploadService) registerUpload(ctx context.Context, key, sha string, ownerID *uint, result *UploadResult) error {
+	now := time.Now()
+	upload := models.Upload{
+		Key:            key,
+		URL:            result.URL,
+		Variants:       models.ImageVariants(result.Variants),
+		SHA256:         sha,
+		Size:           result.Size,
+		MimeType:       result.MimeType,
+		OriginalName:   result.OriginalName,
+		Width:          result.Width,
+		Height:         result.Height,
+		OwnerID:        ownerID,
+		UnreferencedAt: &now,
+	}
+	// ⭐ regenerate ใช้ key เดิม จึงอัปเดตแทนการสร้างใหม่
+	return us.db.WithContext(ctx).Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "key"}},
+		DoUpdates: clause.AssignmentColumns([]string{"url", "variants", "size", "mime_type", "width", "height", "updated_at"}),
+	}).Create(&upload).Error
+}
+
+// deleteObjects removes every stored object of an upload: the variants below key, or
+// the single object at key
+func (us *UploadService) deleteObjects(ctx context.Context, key string) error {
+	objects, err := us.store.List(ctx, key+"/")
+	if err != nil {
+		return err
+	}
+	if len(objects) == 0 {
+		if err := us.store.Delete(ctx, key); err != nil && !errors.Is(err, storage.ErrNotFound) {
+			return err
+		}
+		return nil
+	}
+	for _, obj := range objects {
+		if err := us.store.Delete(ctx, obj.Key); err != nil && !errors.Is(err, storage.ErrNotFound) {
+			return err
+		}
+	}
+	return nil
+}
+
+// --- Garbage collection ---
+
+// Start runs the orphaned-file collector until ctx is cancelled
+func (us *UploadService) Start(ctx context.Context) {
+	go func() {
+		ticker := time.NewTicker(getEnvDuration("UPLOAD_GC_INTERVAL_MINUTES", time.Minute, time.Hour))
+		defer ticker.Stop()
+		for {
+			if n, err := us.CollectGarbage(ctx); err != nil {
+				log.Printf("❌ Upload GC: %v", err)
+			} else if n > 0 {
+				log.Printf("🧹 Upload GC: removed %d unreferenced upload(s)", n)
+			}
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+}
+
+// CollectGarbage deletes uploads that have had no references for longer than
+// UPLOAD_GC_GRACE_HOURS, returning how many were removed
+func (us *UploadService) CollectGarbage(ctx context.Context) (int, error) {
+	cutoff := time.Now().Add(-getEnvDuration("UPLOAD_GC_GRACE_HOURS", time.Hour, 24*time.Hour))
+	removed := 0
+	for {
+		n, processed, err := us.collectBatch(ctx, cutoff)
+		removed += n
+		if err != nil || processed == 0 {
+			return removed, err
+		}
+	}
+}
+
+// collectBatch removes up to one batch of expired uploads and returns how many were removed
+// and how many were looked at. An upload whose files cannot be deleted is skipped: its grace
+// period restarts so it is retried later and does not block the rest of the queue.
+func (us *UploadService) collectBatch(ctx context.Context, cutoff time.Time) (int, int, error) {
+	removed, processed := 0, 0
+	err := us.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var uploads []models.Upload
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE", Options: "SKIP LOCKED"}).
+			Where("unreferenced_at < ?", cutoff).
+			Where("NOT EXISTS (SELECT 1 FROM upload_references r WHERE r.upload_id = uploads.id)").
+			Order("id").Limit(50).Find(&uploads).Error; err != nil {
+			return err
+		}
+
+		processed = len(uploads)
+		for _, upload := range uploads {
+			// ลบไฟล์ก่อนแถว ถ้าลบไฟล์ไม่สำเร็จเลื่อน unreferenced_at ออกไปแล้วทำรายการถัดไปต่อ
+			if err := us.deleteObjects(ctx, upload.Key); err != nil {
+				log.Printf("⚠️ Upload GC: upload %d (%s): %v - retrying after the grace period", upload.ID, upload.Key, err)
+				if err := tx.Model(&upload).Update("unreferenced_at", time.Now()).Error; err != nil {
+					return err
+				}
+				continue
+			}
+			if err := tx.Delete(&upload).Error; err != nil {
+				return err
+			}
+			removed++
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, 0, err
+	}
+	return removed, processed, nil
+}
@@ -0,0 +1,152 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"ku-asset/storage"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"gorm.io/gorm"
+)
+
+// failingStore is a storage.Memory whose deletes fail below one prefix
+type failingStore struct {
+	*storage.Memory
+	failPrefix string
+}
+
+func (s *failingStore) Delete(ctx context.Context, key string) error {
+	if strings.HasPrefix(key, s.failPrefix) {
+		return errors.New("access denied")
+	}
+	return s.Memory.Delete(ctx, key)
+}
+
+func putObject(t *testing.T, store storage.Storage, key string) {
+	t.Helper()
+	if err := store.Put(context.Background(), key, strings.NewReader("x"), 1, "image/jpeg"); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestSyncUploadReferences(t *testing.T) {
+	db, mock := newMockDB(t)
+	mock.ExpectBegin()
+	mock.ExpectQuery(`SELECT "id" FROM "uploads" WHERE url IN \(\$1\) FOR UPDATE`).
+		WithArgs("https://cdn/products/new.jpg").
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(3))
+	mock.ExpectQuery(`SELECT "upload_id" FROM "upload_references" WHERE entity_type = \$1 AND entity_id = \$2`).
+		WithArgs("product", 9).
+		WillReturnRows(sqlmock.NewRows([]string{"upload_id"}).AddRow(2).AddRow(3))
+	mock.ExpectExec(`DELETE FROM "upload_references" WHERE \(entity_type = \$1 AND entity_id = \$2\) AND upload_id NOT IN \(\$3\)`).
+		WithArgs("product", 9, 3).WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectQuery(`INSERT INTO "upload_references" .* ON CONFLICT DO NOTHING`).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}))
+	mock.ExpectExec(`UPDATE "uploads" SET "unreferenced_at"=\$1,"updated_at"=\$2 WHERE id IN \(\$3\)`).
+		WithArgs(nil, sqlmock.AnyArg(), 3).WillReturnResult(sqlmock.NewResult(0, 1))
+	// upload 2 หลุดจาก entity นี้ เริ่มนับ grace period เฉพาะเมื่อไม่มี entity อื่นอ้างถึงแล้ว
+	mock.ExpectExec(`UPDATE "uploads" SET "unreferenced_at"=\$1,"updated_at"=\$2 WHERE id IN \(\$3\) AND NOT EXISTS \(SELECT 1 FROM upload_references r WHERE r.upload_id = uploads.id\)`).
+		WithArgs(sqlmock.AnyArg(), sqlmock.AnyArg(), 2).WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+
+	err := db.Transaction(func(tx *gorm.DB) error {
+		return SyncUploadReferences(tx, "product", 9, "https://cdn/products/new.jpg")
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestCollectGarbageSkipsUploadsThatFailToDelete(t *testing.T) {
+	store := &failingStore{Memory: storage.NewMemory("https://cdn"), failPrefix: "products/a/"}
+	putObject(t, store, "products/a/full.jpg")
+	putObject(t, store, "products/b/full.jpg")
+
+	db, mock := newMockDB(t)
+	mock.ExpectBegin()
+	mock.ExpectQuery(`SELECT \* FROM "uploads" WHERE unreferenced_at < \$1 AND NOT EXISTS .* ORDER BY id LIMIT \$2 FOR UPDATE SKIP LOCKED`).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "key"}).AddRow(1, "products/a").AddRow(2, "products/b"))
+	// ลบไฟล์ของ upload 1 ไม่ได้ - เลื่อนออกไปแล้วทำ upload 2 ต่อ
+	mock.ExpectExec(`UPDATE "uploads" SET "unreferenced_at"=\$1,"updated_at"=\$2 WHERE "id" = \$3`).
+		WithArgs(sqlmock.AnyArg(), sqlmock.AnyArg(), 1).WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec(`DELETE FROM "uploads" WHERE "uploads"."id" = \$1`).
+		WithArgs(2).WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+	mock.ExpectBegin()
+	mock.ExpectQuery(`SELECT \* FROM "uploads"`).WillReturnRows(sqlmock.NewRows([]string{"id", "key"}))
+	mock.ExpectCommit()
+
+	us := &UploadService{db: db, store: store}
+	removed, err := us.CollectGarbage(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if removed != 1 {
+		t.Errorf("removed = %d, want 1", removed)
+	}
+	if _, err := store.Stat(context.Background(), "products/b/full.jpg"); !errors.Is(err, storage.ErrNotFound) {
+		t.Errorf("products/b was not deleted: %v", err)
+	}
+	if _, err := store.Stat(context.Background(), "products/a/full.jpg"); err != nil {
+		t.Errorf("products/a should remain: %v", err)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestFindDuplicate(t *testing.T) {
+	store := storage.NewMemory("https://cdn")
+	putObject(t, store, "products/abc/full.jpg")
+	unreferenced := time.Now().Add(-time.Hour)
+
+	tests := []struct {
+		name    string
+		rows    *sqlmock.Rows
+		restart bool // เริ่ม grace period ใหม่
+		want    bool
+	}{
+		{"unreferenced duplicate", sqlmock.NewRows([]string{"id", "key", "url", "unreferenced_at"}).
+			AddRow(4, "products/abc", store.URL("products/abc/full.jpg"), unreferenced), true, true},
+		{"referenced duplicate", sqlmock.NewRows([]string{"id", "key", "url", "unreferenced_at"}).
+			AddRow(4, "products/abc", store.URL("products/abc/full.jpg"), nil), false, true},
+		{"files already gone", sqlmock.NewRows([]string{"id", "key", "url", "unreferenced_at"}).
+			AddRow(5, "products/gone", store.URL("products/gone/full.jpg"), unreferenced), false, false},
+		{"removed by GC while waiting for the lock", sqlmock.NewRows([]string{"id"}), false, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db, mock := newMockDB(t)
+			mock.ExpectBegin()
+			mock.ExpectQuery(`SELECT \* FROM "uploads" WHERE sha256 = \$1 AND key LIKE \$2 ORDER BY id,"uploads"."id" LIMIT \$3 FOR UPDATE`).
+				WithArgs("sha", "products/%", 1).WillReturnRows(tt.rows)
+			if tt.restart {
+				mock.ExpectExec(`UPDATE "uploads" SET "unreferenced_at"=\$1,"updated_at"=\$2 WHERE "id" = \$3`).
+					WillReturnResult(sqlmock.NewResult(0, 1))
+			}
+			mock.ExpectCommit()
+
+			us := &UploadService{db: db, store: store}
+			got, err := us.findDuplicate(context.Background(), "sha", "products")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if (got != nil) != tt.want {
+				t.Errorf("findDuplicate() = %+v, want found=%v", got, tt.want)
+			}
+			if tt.restart && !got.UnreferencedAt.After(unreferenced) {
+				t.Error("grace period was not restarted")
+			}
+			if err := mock.ExpectationsWereMet(); err != nil {
+				t.Error(err)
+			}
+		})
+	}
+}
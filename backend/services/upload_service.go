@@ -2,47 +2,80 @@ package services
 
 import (
 	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
 	"errors"
 	"fmt"
 	"image"
 	"image/jpeg"
 	"image/png"
 	"io"
+	"ku-asset/models"
+	"ku-asset/storage"
+	"log"
 	"mime/multipart"
-	"os"
+	"net/url"
+	"path"
 	"path/filepath"
 	"strings"
 	"time"
 
+	"github.com/HugoSmits86/nativewebp"
 	"github.com/google/uuid"
 	"golang.org/x/image/draw"
+	_ "golang.org/x/image/webp" // ⭐ register WebP decoder for image.Decode
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
-type UploadService struct{}
+var ErrFileNotFound = errors.New("file not found")
+
+// UploadService ตรวจ/ย่อรูปแล้วเก็บผ่าน storage.Storage (local disk หรือ S3-compatible)
+// และบันทึกไฟล์ลงตาราง uploads เพื่อตัดไฟล์ซ้ำและเก็บกวาดไฟล์ที่ไม่มีใครใช้
+type UploadService struct {
+	db    *gorm.DB
+	store storage.Storage
+}
 
 type UploadConfig struct {
 	MaxFileSize      int64           // in bytes
 	AllowedTypes     map[string]bool // file extensions
 	AllowedMimeTypes map[string]bool // MIME types
-	MaxWidth         int             // max image width
-	MaxHeight        int             // max image height
+	Variants         []ImageVariant  // 🆕 ขนาดที่สร้างทุกครั้งที่อัปโหลด (ต้องมี "full")
 	Quality          int             // JPEG quality (1-100)
-	UploadDir        string          // upload directory
-	BaseURL          string          // base URL for file access
+	OutputFormat     string          // "jpeg", "png" or "webp"; empty keeps the uploaded format
+	KeyPrefix        string          // ⭐ prefix ของ object key ใน storage เช่น "products"
 }
 
+// ImageVariant is one rendition generated from every uploaded image, scaled down to fit
+// within MaxWidth x MaxHeight (never enlarged)
+type ImageVariant struct {
+	Name      string
+	MaxWidth  int
+	MaxHeight int
+	Format    string // empty uses UploadConfig.OutputFormat
+}
+
+// fullVariant is the largest rendition; its URL is the image URL stored on the product
+const fullVariant = "full"
+
+// defaultImageVariants is the IMAGE_VARIANTS default: name=WIDTHxHEIGHT[:format],...
+const defaultImageVariants = "thumb=200x200,card=640x480,full=1920x1080"
+
 type UploadResult struct {
-	URL          string `json:"url"`
-	Filename     string `json:"filename"`
-	Size         int64  `json:"size"`
-	OriginalName string `json:"original_name"`
-	MimeType     string `json:"mime_type"`
-	Width        int    `json:"width,omitempty"`
-	Height       int    `json:"height,omitempty"`
+	URL          string            `json:"url"` // URL ของขนาด full
+	Filename     string            `json:"filename"`
+	Size         int64             `json:"size"`
+	OriginalName string            `json:"original_name"`
+	MimeType     string            `json:"mime_type"`
+	Width        int               `json:"width,omitempty"`
+	Height       int               `json:"height,omitempty"`
+	Variants     map[string]string `json:"variants"` // 🆕 URL ของทุกขนาด เช่น thumb, card, full
 }
 
-func NewUploadService() *UploadService {
-	return &UploadService{}
+func NewUploadService(db *gorm.DB, store storage.Storage) *UploadService {
+	return &UploadService{db: db, store: store}
 }
 
 // GetProductImageConfig returns configuration for product image uploads
@@ -60,16 +93,135 @@ func (us *UploadService) GetProductImageConfig() *UploadConfig {
 			"image/png":  true,
 			"image/webp": true,
 		},
-		MaxWidth:  1920,
-		MaxHeight: 1080,
-		Quality:   85,
-		UploadDir: "uploads/products",
-		BaseURL:   os.Getenv("BASE_URL"),
+		Variants:     imageVariantsFromEnv(),
+		Quality:      85,
+		OutputFormat: getEnv("UPLOAD_IMAGE_FORMAT", ""),
+		KeyPrefix:    "products",
+	}
+}
+
+// imageVariantsFromEnv reads IMAGE_VARIANTS, falling back to the defaults when it is invalid
+func imageVariantsFromEnv() []ImageVariant {
+	variants, err := parseImageVariants(getEnv("IMAGE_VARIANTS", defaultImageVariants))
+	if err != nil {
+		log.Printf("⚠️ Invalid IMAGE_VARIANTS (%v), using %s", err, defaultImageVariants)
+		variants, _ = parseImageVariants(defaultImageVariants)
+	}
+	return variants
+}
+
+// parseImageVariants parses "thumb=200x200:webp,card=640x480,full=1920x1080"
+func parseImageVariants(spec string) ([]ImageVariant, error) {
+	variants := make([]ImageVariant, 0)
+	seen := make(map[string]bool)
+	for _, item := range strings.Split(spec, ",") {
+		name, size, ok := strings.Cut(strings.TrimSpace(item), "=")
+		if !ok || name == "" || strings.ContainsAny(name, "/.\\") {
+			return nil, fmt.Errorf("invalid variant %q", item)
+		}
+		size, format, _ := strings.Cut(size, ":")
+		if _, ok := imageFormatExt[format]; format != "" && !ok {
+			return nil, fmt.Errorf("unsupported format %q for variant %s", format, name)
+		}
+		var v ImageVariant
+		if _, err := fmt.Sscanf(size, "%dx%d", &v.MaxWidth, &v.MaxHeight); err != nil || v.MaxWidth <= 0 || v.MaxHeight <= 0 {
+			return nil, fmt.Errorf("invalid size %q for variant %s", size, name)
+		}
+		if seen[name] {
+			return nil, fmt.Errorf("duplicate variant %s", name)
+		}
+		seen[name] = true
+		v.Name, v.Format = name, format
+		variants = append(variants, v)
+	}
+	if !seen[fullVariant] {
+		return nil, errors.New(`a "full" variant is required`)
+	}
+	return variants, nil
+}
+
+// GetProductDocumentConfig returns configuration for product documents such as spec
+// sheets and manuals. Documents are stored as uploaded, without processing.
+func (us *UploadService) GetProductDocumentConfig() *UploadConfig {
+	return &UploadConfig{
+		MaxFileSize: 20 * 1024 * 1024, // 20MB
+		AllowedTypes: map[string]bool{
+			".pdf": true,
+		},
+		AllowedMimeTypes: map[string]bool{
+			"application/pdf": true,
+		},
+		KeyPrefix: "products/documents",
+	}
+}
+
+// privateKeyPrefix holds files that must not be served from the public /uploads path,
+// such as request attachments; they are streamed by handlers that check access
+const privateKeyPrefix = "private/"
+
+// isPrivateKey reports whether key is below privateKeyPrefix
+func isPrivateKey(key string) bool {
+	return strings.HasPrefix(key, privateKeyPrefix)
+}
+
+// GetRequestAttachmentConfig returns configuration for files attached to requests
+// (memos, quotations, approvals): PDFs and photos/scans, kept private and stored as is
+func (us *UploadService) GetRequestAttachmentConfig() *UploadConfig {
+	return &UploadConfig{
+		MaxFileSize: int64(getEnvInt("REQUEST_ATTACHMENT_MAX_MB", 10)) * 1024 * 1024,
+		AllowedTypes: map[string]bool{
+			".pdf":  true,
+			".jpg":  true,
+			".jpeg": true,
+			".png":  true,
+			".webp": true,
+		},
+		AllowedMimeTypes: map[string]bool{
+			"application/pdf": true,
+			"image/jpeg":      true,
+			"image/png":       true,
+			"image/webp":      true,
+		},
+		KeyPrefix: privateKeyPrefix + "requests",
 	}
 }
 
+// fileMimeTypes maps allowed extensions to the content type their bytes must have
+var fileMimeTypes = map[string]string{
+	".jpg":  "image/jpeg",
+	".jpeg": "image/jpeg",
+	".png":  "image/png",
+	".webp": "image/webp",
+	".gif":  "image/gif",
+	".pdf":  "application/pdf",
+}
+
+// imageFormatExt is the extension used for files written in each output format
+var imageFormatExt = map[string]string{
+	"jpeg": ".jpg",
+	"png":  ".png",
+	"webp": ".webp",
+}
+
+// sniffFileType detects the file type from its magic bytes, returning "" when unknown
+func sniffFileType(head []byte) string {
+	switch {
+	case bytes.HasPrefix(head, []byte{0xFF, 0xD8, 0xFF}):
+		return "image/jpeg"
+	case bytes.HasPrefix(head, []byte("\x89PNG\r\n\x1a\n")):
+		return "image/png"
+	case len(head) >= 12 && string(head[0:4]) == "RIFF" && string(head[8:12]) == "WEBP":
+		return "image/webp"
+	case bytes.HasPrefix(head, []byte("GIF87a")), bytes.HasPrefix(head, []byte("GIF89a")):
+		return "image/gif"
+	case bytes.HasPrefix(head, []byte("%PDF-")):
+		return "application/pdf"
+	}
+	return ""
+}
+
 // ValidateFile validates uploaded file against configuration
-func (us *UploadService) ValidateFile(header *multipart.FileHeader, config *UploadConfig) error {
+func (us *UploadService) ValidateFile(file multipart.File, header *multipart.FileHeader, config *UploadConfig) error {
 	// Check file size
 	if header.Size > config.MaxFileSize {
 		return fmt.Errorf("file size %d bytes exceeds maximum allowed size %d bytes", header.Size, config.MaxFileSize)
@@ -81,10 +233,36 @@ func (us *UploadService) ValidateFile(header *multipart.FileHeader, config *Uplo
 		return fmt.Errorf("file type %s not allowed", ext)
 	}
 
-	// Check MIME type
-	if len(config.AllowedMimeTypes) > 0 {
-		// This would need additional MIME type detection in a real implementation
-		// For now, we'll rely on file extension
+	// Check MIME type from the file content (magic bytes), not the client's Content-Type
+	head := make([]byte, 512)
+	n, err := io.ReadFull(file, head)
+	if err != nil && err != io.ErrUnexpectedEOF && err != io.EOF {
+		return fmt.Errorf("failed to read file: %w", err)
+	}
+	if _, err := file.Seek(0, io.SeekStart); err != nil {
+		return fmt.Errorf("failed to read file: %w", err)
+	}
+	mimeType := sniffFileType(head[:n])
+	if mimeType == "" || !config.AllowedMimeTypes[mimeType] {
+		return errors.New("file content is not an allowed file type")
+	}
+	// ⭐ กันไฟล์ที่ตั้งนามสกุลหลอก เช่น .png แต่เนื้อในเป็น webp
+	if fileMimeTypes[ext] != mimeType {
+		return fmt.Errorf("file extension %s does not match its content (%s)", ext, mimeType)
+	}
+
+	// 🆕 ตรวจขนาดภาพจาก header ก่อน decode จริง (กัน decompression bomb)
+	if strings.HasPrefix(mimeType, "image/") {
+		data, err := io.ReadAll(io.LimitReader(file, config.MaxFileSize+1))
+		if err != nil {
+			return fmt.Errorf("failed to read file: %w", err)
+		}
+		if _, err := file.Seek(0, io.SeekStart); err != nil {
+			return fmt.Errorf("failed to read file: %w", err)
+		}
+		if _, _, err := checkImageConfig(data, maxImagePixels()); err != nil {
+			return err
+		}
 	}
 
 	// Check filename for security
@@ -95,79 +273,252 @@ func (us *UploadService) ValidateFile(header *multipart.FileHeader, config *Uplo
 	return nil
 }
 
-// ProcessAndSaveImage processes and saves an uploaded image
-func (us *UploadService) ProcessAndSaveImage(file multipart.File, header *multipart.FileHeader, config *UploadConfig) (*UploadResult, error) {
-	// Create upload directory
-	if err := os.MkdirAll(config.UploadDir, 0755); err != nil {
-		return nil, fmt.Errorf("failed to create upload directory: %w", err)
+// ProcessAndSaveImage processes and saves an uploaded image. Every variant is re-encoded
+// from decoded pixels, so EXIF metadata such as GPS position is never stored.
+func (us *UploadService) ProcessAndSaveImage(ctx context.Context, file multipart.File, header *multipart.FileHeader, config *UploadConfig, ownerID *uint) (*UploadResult, error) {
+	// Read file content (ไม่เชื่อ header.Size - อ่านไม่เกินขนาดที่อนุญาต)
+	fileBytes, err := io.ReadAll(io.LimitReader(file, config.MaxFileSize+1))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read file: %w", err)
+	}
+	if int64(len(fileBytes)) > config.MaxFileSize {
+		return nil, fmt.Errorf("file exceeds maximum allowed size %d bytes", config.MaxFileSize)
 	}
 
-	// Read file content
-	fileBytes, err := io.ReadAll(file)
+	// ⭐ ไฟล์เดียวกันเคยอัปโหลดแล้ว ใช้ของเดิมแทนการเก็บซ้ำ
+	digest := sha256.Sum256(fileBytes)
+	sha := hex.EncodeToString(digest[:])
+	existing, err := us.findDuplicate(ctx, sha, config.KeyPrefix)
 	if err != nil {
-		return nil, fmt.Errorf("failed to read file: %w", err)
+		return nil, err
+	}
+	if existing != nil {
+		return &UploadResult{
+			URL:          existing.URL,
+			Filename:     path.Base(existing.Key),
+			Size:         existing.Size,
+			OriginalName: header.Filename,
+			MimeType:     existing.MimeType,
+			Width:        existing.Width,
+			Height:       existing.Height,
+			Variants:     existing.Variants,
+		}, nil
 	}
 
-	// Decode image
-	img, format, err := image.Decode(bytes.NewReader(fileBytes))
+	// Decode image (ตรวจจำนวน pixel ก่อน และหมุนตาม EXIF orientation)
+	img, format, err := decodeUploadedImage(fileBytes, maxImagePixels())
 	if err != nil {
-		return nil, fmt.Errorf("invalid image format: %w", err)
+		return nil, err
 	}
 
-	// Get original dimensions
-	bounds := img.Bounds()
-	originalWidth := bounds.Dx()
-	originalHeight := bounds.Dy()
+	// Generate filename - ทุกขนาดเก็บใต้ key เดียวกัน เช่น products/product_1a2b3c4d_1700000000/thumb.jpg
+	filename := fmt.Sprintf("product_%s_%d",
+		uuid.New().String()[:8],
+		time.Now().Unix())
 
-	// Resize if necessary
-	if originalWidth > config.MaxWidth || originalHeight > config.MaxHeight {
-		img = us.resizeImage(img, config.MaxWidth, config.MaxHeight)
+	key := config.KeyPrefix + "/" + filename
+	result, err := us.saveVariants(ctx, img, format, key, config)
+	if err != nil {
+		return nil, err
 	}
+	result.Filename = filename
+	result.OriginalName = header.Filename
 
-	// Generate filename
-	ext := strings.ToLower(filepath.Ext(header.Filename))
-	if ext == "" {
-		ext = "." + format
+	if err := us.registerUpload(ctx, key, sha, ownerID, result); err != nil {
+		us.deleteObjects(ctx, key)
+		return nil, fmt.Errorf("failed to register upload: %w", err)
 	}
+	return result, nil
+}
 
-	filename := fmt.Sprintf("product_%s_%d%s",
+// ProcessAndSaveFile stores a validated non-image file (e.g. a PDF manual) as is
+func (us *UploadService) ProcessAndSaveFile(ctx context.Context, file multipart.File, header *multipart.FileHeader, config *UploadConfig, ownerID *uint) (*UploadResult, error) {
+	fileBytes, err := io.ReadAll(file)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read file: %w", err)
+	}
+
+	digest := sha256.Sum256(fileBytes)
+	sha := hex.EncodeToString(digest[:])
+	existing, err := us.findDuplicate(ctx, sha, config.KeyPrefix)
+	if err != nil {
+		return nil, err
+	}
+	if existing != nil {
+		return &UploadResult{
+			URL:          existing.URL,
+			Filename:     path.Base(existing.Key),
+			Size:         existing.Size,
+			OriginalName: header.Filename,
+			MimeType:     existing.MimeType,
+		}, nil
+	}
+
+	ext := strings.ToLower(filepath.Ext(header.Filename))
+	mimeType := fileMimeTypes[ext]
+	filename := fmt.Sprintf("file_%s_%d%s",
 		uuid.New().String()[:8],
 		time.Now().Unix(),
 		ext)
+	key := config.KeyPrefix + "/" + filename
+	if err := us.store.Put(ctx, key, bytes.NewReader(fileBytes), int64(len(fileBytes)), mimeType); err != nil {
+		return nil, fmt.Errorf("failed to save file: %w", err)
+	}
 
-	filePath := filepath.Join(config.UploadDir, filename)
+	result := &UploadResult{
+		URL:          us.store.URL(key),
+		Filename:     filename,
+		Size:         int64(len(fileBytes)),
+		OriginalName: header.Filename,
+		MimeType:     mimeType,
+	}
+	if err := us.registerUpload(ctx, key, sha, ownerID, result); err != nil {
+		us.deleteObjects(ctx, key)
+		return nil, fmt.Errorf("failed to register upload: %w", err)
+	}
+	return result, nil
+}
+
+// saveVariants encodes and stores every configured variant of img under baseKey.
+// Already stored variants are removed again when a later one fails.
+func (us *UploadService) saveVariants(ctx context.Context, img image.Image, sourceFormat, baseKey string, config *UploadConfig) (*UploadResult, error) {
+	result := &UploadResult{Variants: make(map[string]string, len(config.Variants))}
+	stored := make([]string, 0, len(config.Variants))
+	cleanup := func() {
+		for _, key := range stored {
+			us.store.Delete(ctx, key)
+		}
+	}
+
+	bounds := img.Bounds()
+	for _, variant := range config.Variants {
+		// Resize if necessary (ย่อจากต้นฉบับทุกครั้ง ไม่ย่อต่อจากขนาดก่อนหน้า)
+		resized := img
+		if bounds.Dx() > variant.MaxWidth || bounds.Dy() > variant.MaxHeight {
+			resized = us.resizeImage(img, variant.MaxWidth, variant.MaxHeight)
+		}
+
+		// Choose output format
+		outputFormat := sourceFormat
+		if _, ok := imageFormatExt[config.OutputFormat]; ok {
+			outputFormat = config.OutputFormat
+		}
+		if variant.Format != "" {
+			outputFormat = variant.Format
+		}
+		ext, ok := imageFormatExt[outputFormat]
+		if !ok {
+			// ⭐ รูปแบบที่ไม่มี encoder - เก็บเป็น PNG แทน
+			outputFormat, ext = "png", ".png"
+		}
+
+		// Encode processed image
+		var buf bytes.Buffer
+		if err := us.encodeImage(&buf, resized, outputFormat, config.Quality); err != nil {
+			cleanup()
+			return nil, fmt.Errorf("failed to save image: %w", err)
+		}
+		size := int64(buf.Len())
+
+		// Save to storage
+		key := baseKey + "/" + variant.Name + ext
+		if err := us.store.Put(ctx, key, &buf, size, "image/"+outputFormat); err != nil {
+			cleanup()
+			return nil, fmt.Errorf("failed to save image: %w", err)
+		}
+		stored = append(stored, key)
+		result.Variants[variant.Name] = us.store.URL(key)
+
+		if variant.Name == fullVariant {
+			result.URL = us.store.URL(key)
+			result.Size = size
+			result.MimeType = "image/" + outputFormat
+			result.Width = resized.Bounds().Dx()
+			result.Height = resized.Bounds().Dy()
+		}
+	}
+	return result, nil
+}
 
-	// Save processed image
-	if err := us.saveImage(img, filePath, format, config.Quality); err != nil {
-		return nil, fmt.Errorf("failed to save image: %w", err)
+// RegenerateVariants rebuilds every configured variant of an existing image from its
+// largest stored rendition. Images saved before variants existed (a single file such as
+// products/product_1a2b3c4d_1700000000.jpg) get their variants under
+// products/product_1a2b3c4d_1700000000/. Files that are no longer used are left in
+// place until DeleteReplacedImages is called, so the image keeps working meanwhile.
+func (us *UploadService) RegenerateVariants(ctx context.Context, imageURL string, config *UploadConfig) (*UploadResult, error) {
+	key, ok := us.keyFromURL(imageURL)
+	if !ok {
+		return nil, fmt.Errorf("image %s is not in the configured storage", imageURL)
+	}
+	baseKey := path.Dir(key)
+	if baseKey == config.KeyPrefix {
+		// legacy single file
+		baseKey = strings.TrimSuffix(key, path.Ext(key))
 	}
 
-	// Get file info
-	stat, err := os.Stat(filePath)
+	rc, _, err := us.store.Open(ctx, key)
+	if err != nil {
+		if errors.Is(err, storage.ErrNotFound) {
+			return nil, ErrFileNotFound
+		}
+		return nil, err
+	}
+	source, err := io.ReadAll(rc)
+	rc.Close()
 	if err != nil {
-		return nil, fmt.Errorf("failed to get file stats: %w", err)
+		return nil, err
+	}
+	img, format, err := decodeUploadedImage(source, maxImagePixels())
+	if err != nil {
+		return nil, err
 	}
 
-	// Create URL
-	baseURL := config.BaseURL
-	if baseURL == "" {
-		baseURL = "http://localhost:8080"
+	result, err := us.saveVariants(ctx, img, format, baseKey, config)
+	if err != nil {
+		return nil, err
 	}
+	result.Filename = path.Base(baseKey)
 
-	imageURL := fmt.Sprintf("%s/%s/%s", baseURL, config.UploadDir, filename)
+	// ⭐ รูปเก่าที่ยังไม่อยู่ในตาราง uploads จะถูกลงทะเบียนตรงนี้ (hash จากไฟล์ที่ใช้สร้างใหม่)
+	digest := sha256.Sum256(source)
+	if err := us.registerUpload(ctx, baseKey, hex.EncodeToString(digest[:]), nil, result); err != nil {
+		return nil, fmt.Errorf("failed to register upload: %w", err)
+	}
+	return result, nil
+}
 
-	// Get final dimensions
-	finalBounds := img.Bounds()
+// DeleteReplacedImages removes the files behind previous image URLs that are not part
+// of current, e.g. a legacy single file or a variant whose format changed
+func (us *UploadService) DeleteReplacedImages(ctx context.Context, previous []string, current *UploadResult) {
+	keep := make(map[string]bool, len(current.Variants))
+	for _, u := range current.Variants {
+		keep[u] = true
+	}
+	for _, u := range previous {
+		if u == "" || keep[u] {
+			continue
+		}
+		key, ok := us.keyFromURL(u)
+		if !ok {
+			continue
+		}
+		if err := us.store.Delete(ctx, key); err != nil && !errors.Is(err, storage.ErrNotFound) {
+			log.Printf("⚠️ Failed to delete replaced image %s: %v", key, err)
+		}
+	}
+}
 
-	return &UploadResult{
-		URL:          imageURL,
-		Filename:     filename,
-		Size:         stat.Size(),
-		OriginalName: header.Filename,
-		MimeType:     "image/" + format,
-		Width:        finalBounds.Dx(),
-		Height:       finalBounds.Dy(),
-	}, nil
+// keyFromURL maps a URL produced by the storage back to its object key
+func (us *UploadService) keyFromURL(rawURL string) (string, bool) {
+	base := us.store.URL("")
+	if !strings.HasPrefix(rawURL, base) {
+		return "", false
+	}
+	key, err := url.PathUnescape(strings.TrimPrefix(rawURL, base))
+	if err != nil || storage.ValidateKey(key) != nil {
+		return "", false
+	}
+	return key, true
 }
 
 // resizeImage resizes an image while maintaining aspect ratio
@@ -196,6 +547,9 @@ func (us *UploadService) resizeImage(src image.Image, maxWidth, maxHeight int) i
 		}
 	}
 
+	// ภาพที่ยาว/แคบมากอาจคำนวณได้ 0 pixel
+	newWidth, newHeight = max(newWidth, 1), max(newHeight, 1)
+
 	// Create new image
 	dst := image.NewRGBA(image.Rect(0, 0, newWidth, newHeight))
 
@@ -205,36 +559,73 @@ func (us *UploadService) resizeImage(src image.Image, maxWidth, maxHeight int) i
 	return dst
 }
 
-// saveImage saves an image to disk with specified quality
-func (us *UploadService) saveImage(img image.Image, filePath, format string, quality int) error {
-	file, err := os.Create(filePath)
-	if err != nil {
-		return err
-	}
-	defer file.Close()
-
+// encodeImage encodes an image with specified quality
+func (us *UploadService) encodeImage(w io.Writer, img image.Image, format string, quality int) error {
 	switch format {
 	case "jpeg", "jpg":
-		return jpeg.Encode(file, img, &jpeg.Options{Quality: quality})
+		return jpeg.Encode(w, img, &jpeg.Options{Quality: quality})
 	case "png":
-		return png.Encode(file, img)
+		return png.Encode(w, img)
+	case "webp":
+		// ⭐ pure Go encoder รองรับเฉพาะ lossless (VP8L) จึงไม่ใช้ quality
+		return nativewebp.Encode(w, img, nil)
 	default:
 		return fmt.Errorf("unsupported format: %s", format)
 	}
 }
 
 // DeleteFile deletes an uploaded file
-func (us *UploadService) DeleteFile(filename, uploadDir string) error {
+func (us *UploadService) DeleteFile(ctx context.Context, filename, keyPrefix string) error {
 	// Security check
 	if strings.Contains(filename, "..") || strings.Contains(filename, "/") || strings.Contains(filename, "\\") {
 		return errors.New("invalid filename")
 	}
 
-	filePath := filepath.Join(uploadDir, filename)
+	key := keyPrefix + "/" + filename
+	return us.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var upload models.Upload
+		err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Where("key = ?", key).First(&upload).Error
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			// ไฟล์ก่อนมีตาราง uploads - ลบได้เลย
+			err := us.store.Delete(ctx, key)
+			if errors.Is(err, storage.ErrNotFound) {
+				return ErrFileNotFound
+			}
+			return err
+		}
+		if err != nil {
+			return err
+		}
+
+		// ⭐ ห้ามลบไฟล์ที่สินค้า/คำขออื่นยังใช้อยู่ (ไฟล์ซ้ำถูกใช้ร่วมกัน)
+		var refs int64
+		if err := tx.Model(&models.UploadReference{}).Where("upload_id = ?", upload.ID).Count(&refs).Error; err != nil {
+			return err
+		}
+		if refs > 0 {
+			return ErrFileInUse
+		}
+		if err := us.deleteObjects(ctx, upload.Key); err != nil {
+			return err
+		}
+		return tx.Delete(&upload).Error
+	})
+}
 
-	if _, err := os.Stat(filePath); os.IsNotExist(err) {
-		return errors.New("file not found")
+// OpenFile เปิดไฟล์จาก storage เพื่อส่งต่อให้ client (ใช้กับ GET /uploads/*key)
+// ไฟล์ private ถือว่าไม่พบ ต้องเปิดผ่าน handler ที่ตรวจสิทธิ์แทน
+func (us *UploadService) OpenFile(ctx context.Context, key string) (io.ReadCloser, *storage.Object, error) {
+	if isPrivateKey(key) {
+		return nil, nil, ErrFileNotFound
 	}
+	return us.openObject(ctx, key)
+}
 
-	return os.Remove(filePath)
+// openObject opens any stored object, including private ones; callers check access
+func (us *UploadService) openObject(ctx context.Context, key string) (io.ReadCloser, *storage.Object, error) {
+	rc, obj, err := us.store.Open(ctx, key)
+	if errors.Is(err, storage.ErrNotFound) || errors.Is(err, storage.ErrInvalidKey) {
+		return nil, nil, ErrFileNotFound
+	}
+	return rc, obj, err
 }
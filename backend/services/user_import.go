@@ -0,0 +1,135 @@
+// services/user_import.go
+package services
+
+import (
+	"bytes"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"ku-asset/dto"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/xuri/excelize/v2"
+)
+
+const maxImportRows = 5000
+
+var ErrUnsupportedImportFile = errors.New("unsupported file type, please upload .csv or .xlsx")
+
+// importColumns maps accepted header names (Thai and English) to row fields
+var importColumns = map[string]string{
+	"email":           "email",
+	"อีเมล":           "email",
+	"name":            "name",
+	"ชื่อ":            "name",
+	"ชื่อ-นามสกุล":    "name",
+	"department_code": "department_code",
+	"department":      "department_code",
+	"รหัสหน่วยงาน":    "department_code",
+	"role":            "role",
+	"บทบาท":           "role",
+	"phone":           "phone",
+	"เบอร์โทร":        "phone",
+	"is_active":       "is_active",
+	"active":          "is_active",
+}
+
+// ParseUserImportFile reads a CSV or XLSX file with a header row into import rows
+func ParseUserImportFile(filename string, r io.Reader) ([]dto.UserImportRow, error) {
+	var records [][]string
+	var err error
+
+	switch strings.ToLower(filepath.Ext(filename)) {
+	case ".csv":
+		records, err = readCSVRecords(r)
+	case ".xlsx":
+		records, err = readXLSXRecords(r)
+	default:
+		return nil, ErrUnsupportedImportFile
+	}
+	if err != nil {
+		return nil, err
+	}
+	if len(records) < 2 {
+		return nil, errors.New("file has no data rows")
+	}
+	if len(records)-1 > maxImportRows {
+		return nil, fmt.Errorf("file has more than %d rows", maxImportRows)
+	}
+
+	// ⭐ จับคู่ header กับ field (ไม่สนตัวพิมพ์เล็ก/ใหญ่)
+	columns := make(map[string]int)
+	for i, header := range records[0] {
+		header = strings.ToLower(strings.TrimSpace(strings.TrimPrefix(header, "\ufeff")))
+		if field, ok := importColumns[header]; ok {
+			columns[field] = i
+		}
+	}
+	if _, ok := columns["email"]; !ok {
+		return nil, errors.New("missing required column: email")
+	}
+
+	rows := make([]dto.UserImportRow, 0, len(records)-1)
+	for i, record := range records[1:] {
+		get := func(field string) string {
+			if idx, ok := columns[field]; ok && idx < len(record) {
+				return strings.TrimSpace(record[idx])
+			}
+			return ""
+		}
+
+		// ข้ามแถวว่าง
+		if strings.TrimSpace(strings.Join(record, "")) == "" {
+			continue
+		}
+
+		row := dto.UserImportRow{
+			Row:            i + 2,
+			Email:          get("email"),
+			Name:           get("name"),
+			DepartmentCode: get("department_code"),
+			Role:           get("role"),
+			Phone:          get("phone"),
+			IsActiveRaw:    get("is_active"),
+		}
+		if raw := row.IsActiveRaw; raw != "" {
+			if active, err := strconv.ParseBool(strings.ToLower(raw)); err == nil {
+				row.IsActive = &active
+			}
+		}
+		rows = append(rows, row)
+	}
+	return rows, nil
+}
+
+func readCSVRecords(r io.Reader) ([][]string, error) {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = -1
+	reader.TrimLeadingSpace = true
+	records, err := reader.ReadAll()
+	if err != nil {
+		return nil, fmt.Errorf("invalid CSV: %w", err)
+	}
+	return records, nil
+}
+
+func readXLSXRecords(r io.Reader) ([][]string, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	file, err := excelize.OpenReader(bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("invalid XLSX: %w", err)
+	}
+	defer file.Close()
+
+	sheets := file.GetSheetList()
+	if len(sheets) == 0 {
+		return nil, errors.New("workbook has no sheets")
+	}
+	return file.GetRows(sheets[0])
+}
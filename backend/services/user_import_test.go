@@ -0,0 +1,77 @@
+package services
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+)
+
+func TestParseUserImportFileIsActive(t *testing.T) {
+	tests := []struct {
+		raw        string
+		wantActive *bool
+	}{
+		{"", nil},
+		{"true", boolPtr(true)},
+		{"FALSE", boolPtr(false)},
+		{"1", boolPtr(true)},
+		{"0", boolPtr(false)},
+		{"maybe", nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.raw, func(t *testing.T) {
+			csv := "email,name,is_active\nsomchai@ku.ac.th,Somchai," + tt.raw + "\n"
+			rows, err := ParseUserImportFile("users.csv", strings.NewReader(csv))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(rows) != 1 {
+				t.Fatalf("got %d rows, want 1", len(rows))
+			}
+			row := rows[0]
+			if row.IsActiveRaw != tt.raw {
+				t.Errorf("IsActiveRaw = %q, want %q", row.IsActiveRaw, tt.raw)
+			}
+			if (row.IsActive == nil) != (tt.wantActive == nil) || (row.IsActive != nil && *row.IsActive != *tt.wantActive) {
+				t.Errorf("IsActive = %v, want %v", row.IsActive, tt.wantActive)
+			}
+		})
+	}
+}
+
+func TestImportUsersDryRunReportsInvalidIsActive(t *testing.T) {
+	db, mock := newMockDB(t)
+	mock.ExpectQuery(`SELECT \* FROM "roles"`).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "name", "is_system"}).AddRow(2, "USER", true))
+	mock.ExpectQuery(`SELECT \* FROM "users" WHERE LOWER\(email\) IN`).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "email"}))
+	expectRolePermissions(mock) // USER ไม่มี permission ตรวจครั้งเดียวแล้วใช้ซ้ำ
+
+	rows, err := ParseUserImportFile("users.csv", strings.NewReader(
+		"email,name,is_active\nsomchai@ku.ac.th,Somchai,true\nsomsri@ku.ac.th,Somsri,maybe\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	s := &userProvisioningService{db: db}
+	result, err := s.ImportUsers(context.Background(), rows, true, false, 1, nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Created != 1 || result.Failed != 1 {
+		t.Errorf("created = %d, failed = %d, want 1 and 1", result.Created, result.Failed)
+	}
+	if errs := result.Rows[0].Errors; len(errs) != 0 {
+		t.Errorf("row 2 errors = %v, want none", errs)
+	}
+	if errs := result.Rows[1].Errors; len(errs) != 1 || !strings.Contains(errs[0], `invalid is_active value "maybe"`) {
+		t.Errorf("row 3 errors = %v, want the invalid is_active value", errs)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Error(err)
+	}
+}
+
+func boolPtr(b bool) *bool { return &b }
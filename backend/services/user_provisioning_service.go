@@ -0,0 +1,564 @@
+// services/user_provisioning_service.go
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"ku-asset/auth"
+	"ku-asset/dto"
+	"ku-asset/mailer"
+	"ku-asset/models"
+	"log"
+	"net/mail"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+var (
+	ErrUserExists         = errors.New("user already exists")
+	ErrInvitationNotFound = errors.New("invitation not found")
+)
+
+// UserProvisioningService handles admin-driven onboarding: invitations and bulk import.
+type UserProvisioningService interface {
+	// userScope = ขอบเขต user.manage, roleScope = ขอบเขต role.manage ของผู้ดำเนินการ
+	CreateInvitation(ctx context.Context, req *dto.CreateInvitationRequest, inviterID uint, userScope, roleScope *auth.Scope) (*dto.InvitationResponse, error)
+	GetInvitations(status string, userScope *auth.Scope) ([]dto.InvitationResponse, error)
+	RevokeInvitation(ctx context.Context, id uint, userScope *auth.Scope) error
+
+	// Public endpoints (ผู้ได้รับเชิญ)
+	GetInvitationByToken(token string) (*dto.InvitationResponse, error)
+	AcceptInvitation(ctx context.Context, req *dto.AcceptInvitationRequest) (*dto.UserResponse, error)
+
+	ImportUsers(ctx context.Context, rows []dto.UserImportRow, dryRun, notify bool, importerID uint, userScope, roleScope *auth.Scope) (*dto.UserImportResult, error)
+}
+
+type userProvisioningService struct {
+	db     *gorm.DB
+	mailer mailer.Mailer
+}
+
+func NewUserProvisioningService(db *gorm.DB, m mailer.Mailer) UserProvisioningService {
+	return &userProvisioningService{db: db, mailer: m}
+}
+
+// --- Invitations ---
+
+func (s *userProvisioningService) CreateInvitation(ctx context.Context, req *dto.CreateInvitationRequest, inviterID uint, userScope, roleScope *auth.Scope) (*dto.InvitationResponse, error) {
+	db := s.db.WithContext(ctx)
+	email := strings.ToLower(strings.TrimSpace(req.Email))
+	roleName := normalizeRoleName(req.Role)
+
+	if !userScope.Allows(req.DepartmentID) {
+		return nil, ErrOutOfScope
+	}
+	if req.DepartmentID != nil {
+		if err := db.First(&models.Department{}, *req.DepartmentID).Error; err != nil {
+			return nil, errors.New("department not found")
+		}
+	}
+	role, err := findRoleByName(db, roleName)
+	if err != nil {
+		return nil, err
+	}
+	if !canGrantRole(role, req.DepartmentID, roleScope) {
+		return nil, ErrOutOfScope
+	}
+	if ok, err := holdsRolePermissions(db, inviterID, role, grantDepartment(role, req.DepartmentID)); err != nil {
+		return nil, err
+	} else if !ok {
+		return nil, ErrOutOfScope
+	}
+
+	var count int64
+	if err := db.Model(&models.User{}).Where("LOWER(email) = ?", email).Count(&count).Error; err != nil {
+		return nil, err
+	}
+	if count > 0 {
+		return nil, ErrUserExists
+	}
+
+	raw, err := generateSecret(32)
+	if err != nil {
+		return nil, err
+	}
+
+	ttl := getEnvDuration("INVITATION_TTL_HOURS", time.Hour, 7*24*time.Hour)
+	invitation := models.Invitation{
+		Email:        email,
+		Name:         strings.TrimSpace(req.Name),
+		RoleName:     role.Name,
+		DepartmentID: req.DepartmentID,
+		TokenHash:    hashSecret(raw),
+		InvitedByID:  inviterID,
+		ExpiresAt:    time.Now().Add(ttl),
+	}
+
+	err = db.Transaction(func(tx *gorm.DB) error {
+		// คำเชิญเก่าของอีเมลเดียวกันที่ยังไม่ถูกใช้จะถูกยกเลิก
+		if err := tx.Model(&models.Invitation{}).
+			Where("email = ? AND accepted_at IS NULL AND revoked_at IS NULL", email).
+			Update("revoked_at", time.Now()).Error; err != nil {
+			return err
+		}
+		return tx.Create(&invitation).Error
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create invitation: %w", err)
+	}
+
+	link := fmt.Sprintf("%s/accept-invitation?token=%s", frontendURL(), raw)
+	if err := s.mailer.Send(mailer.Message{
+		To:      []string{email},
+		Subject: "คำเชิญเข้าใช้งาน KU Asset / You're invited to KU Asset",
+		TextBody: fmt.Sprintf(
+			"สวัสดี %s\n\nคุณได้รับเชิญให้เข้าใช้งานระบบเบิกครุภัณฑ์ KU Asset คลิกลิงก์ด้านล่างเพื่อตั้งรหัสผ่านภายใน %d วัน\n%s\n\n"+
+				"You have been invited to KU Asset. Open the link above within %d days to set up your account.\n",
+			invitation.Name, int(ttl.Hours()/24), link, int(ttl.Hours()/24)),
+	}); err != nil {
+		log.Printf("❌ Failed to send invitation email to %s: %v", email, err)
+	}
+
+	if err := db.Preload("Department").First(&invitation, invitation.ID).Error; err != nil {
+		return nil, err
+	}
+	return mapInvitationToResponse(&invitation), nil
+}
+
+func (s *userProvisioningService) GetInvitations(status string, userScope *auth.Scope) ([]dto.InvitationResponse, error) {
+	query := s.db.Preload("Department").Order("created_at DESC")
+	if userScope != nil && !userScope.All {
+		query = query.Where("department_id IN ?", nonEmptyIDs(userScope.DepartmentIDs))
+	}
+
+	now := time.Now()
+	switch strings.ToUpper(status) {
+	case models.InvitationPending:
+		query = query.Where("accepted_at IS NULL AND revoked_at IS NULL AND expires_at > ?", now)
+	case models.InvitationAccepted:
+		query = query.Where("accepted_at IS NOT NULL")
+	case models.InvitationRevoked:
+		query = query.Where("revoked_at IS NOT NULL AND accepted_at IS NULL")
+	case models.InvitationExpired:
+		query = query.Where("accepted_at IS NULL AND revoked_at IS NULL AND expires_at <= ?", now)
+	}
+
+	var invitations []models.Invitation
+	if err := query.Find(&invitations).Error; err != nil {
+		return nil, err
+	}
+
+	response := make([]dto.InvitationResponse, 0, len(invitations))
+	for _, inv := range invitations {
+		response = append(response, *mapInvitationToResponse(&inv))
+	}
+	return response, nil
+}
+
+func (s *userProvisioningService) RevokeInvitation(ctx context.Context, id uint, userScope *auth.Scope) error {
+	db := s.db.WithContext(ctx)
+	var invitation models.Invitation
+	if err := db.First(&invitation, id).Error; err != nil || !userScope.Allows(invitation.DepartmentID) {
+		return ErrInvitationNotFound
+	}
+	if invitation.Status(time.Now()) != models.InvitationPending {
+		return errors.New("invitation is no longer pending")
+	}
+	return db.Model(&invitation).Update("revoked_at", time.Now()).Error
+}
+
+func (s *userProvisioningService) GetInvitationByToken(token string) (*dto.InvitationResponse, error) {
+	var invitation models.Invitation
+	if err := s.db.Preload("Department").Where("token_hash = ?", hashSecret(token)).First(&invitation).Error; err != nil {
+		return nil, ErrInvalidToken
+	}
+	if invitation.Status(time.Now()) != models.InvitationPending {
+		return nil, ErrInvalidToken
+	}
+	return mapInvitationToResponse(&invitation), nil
+}
+
+// AcceptInvitation creates the invited user with the pre-assigned role and department
+func (s *userProvisioningService) AcceptInvitation(ctx context.Context, req *dto.AcceptInvitationRequest) (*dto.UserResponse, error) {
+	db := s.db.WithContext(ctx)
+	var user models.User
+	err := db.Transaction(func(tx *gorm.DB) error {
+		var invitation models.Invitation
+		if err := tx.Where("token_hash = ?", hashSecret(req.Token)).First(&invitation).Error; err != nil {
+			return ErrInvalidToken
+		}
+		if invitation.Status(time.Now()) != models.InvitationPending {
+			return ErrInvalidToken
+		}
+
+		var count int64
+		if err := tx.Model(&models.User{}).Where("LOWER(email) = ?", invitation.Email).Count(&count).Error; err != nil {
+			return err
+		}
+		if count > 0 {
+			return ErrUserExists
+		}
+
+		name := strings.TrimSpace(req.Name)
+		if name == "" {
+			name = invitation.Name
+		}
+		if name == "" {
+			return errors.New("name is required")
+		}
+
+		now := time.Now()
+		user = models.User{
+			Email:           invitation.Email,
+			Name:            name,
+			Role:            models.RoleUser,
+			DepartmentID:    invitation.DepartmentID,
+			IsActive:        true,
+			EmailVerifiedAt: &now, // ได้รับลิงก์ทางอีเมลแล้ว
+		}
+		if phone := strings.TrimSpace(req.Phone); phone != "" {
+			user.Phone = &phone
+		}
+		if err := user.SetPassword(req.Password); err != nil {
+			return errors.New("could not hash password")
+		}
+		if err := tx.Create(&user).Error; err != nil {
+			return fmt.Errorf("failed to create user: %w", err)
+		}
+
+		if err := applyProvisionedRole(tx, &user, invitation.RoleName, invitation.DepartmentID); err != nil {
+			return err
+		}
+
+		// ⭐ ใช้ WHERE accepted_at IS NULL ป้องกันการใช้ลิงก์ซ้ำพร้อมกัน
+		result := tx.Model(&models.Invitation{}).
+			Where("id = ? AND accepted_at IS NULL AND revoked_at IS NULL", invitation.ID).
+			Updates(map[string]interface{}{"accepted_at": now, "accepted_user_id": user.ID})
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return ErrInvalidToken
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	log.Printf("✅ Invitation accepted: %s", user.Email)
+	response := mapUserToAuthUser(&user)
+	return &response, nil
+}
+
+// --- Bulk import ---
+
+// ImportUsers validates every row first; rows are only written when the whole file is valid
+// and dryRun is false, in a single transaction.
+func (s *userProvisioningService) ImportUsers(ctx context.Context, rows []dto.UserImportRow, dryRun, notify bool, importerID uint, userScope, roleScope *auth.Scope) (*dto.UserImportResult, error) {
+	db := s.db.WithContext(ctx)
+	result := &dto.UserImportResult{DryRun: dryRun, Total: len(rows), Rows: make([]dto.UserImportRowResult, 0, len(rows))}
+
+	departments, roles, existing, err := s.loadImportReferences(rows)
+	if err != nil {
+		return nil, err
+	}
+
+	// ผลตรวจ permission ของผู้นำเข้าต่อ role/หน่วยงาน (ไฟล์ส่วนใหญ่ใช้ role ซ้ำ ๆ)
+	granted := make(map[string]bool)
+	holdsGrant := func(role *models.RoleDefinition, departmentID *uint) (bool, error) {
+		departmentID = grantDepartment(role, departmentID)
+		key := fmt.Sprint(role.ID)
+		if departmentID != nil {
+			key = fmt.Sprintf("%d:%d", role.ID, *departmentID)
+		}
+		if ok, cached := granted[key]; cached {
+			return ok, nil
+		}
+		ok, err := holdsRolePermissions(db, importerID, role, departmentID)
+		if err == nil {
+			granted[key] = ok
+		}
+		return ok, err
+	}
+
+	seen := make(map[string]int, len(rows))
+	for _, row := range rows {
+		email := strings.ToLower(strings.TrimSpace(row.Email))
+		rowResult := dto.UserImportRowResult{Row: row.Row, Email: email, Action: "CREATE"}
+		addError := func(format string, args ...interface{}) {
+			rowResult.Errors = append(rowResult.Errors, fmt.Sprintf(format, args...))
+		}
+
+		if _, err := mail.ParseAddress(email); err != nil || email == "" {
+			addError("invalid email")
+		} else if firstRow, dup := seen[email]; dup {
+			addError("duplicate email (first seen in row %d)", firstRow)
+		} else {
+			seen[email] = row.Row
+		}
+
+		user, exists := existing[email]
+		if exists {
+			rowResult.Action = "UPDATE"
+			if user.Provider == models.ProviderService {
+				addError("service accounts cannot be imported")
+			} else if !userScope.Allows(user.DepartmentID) {
+				addError("user is outside your scope")
+			}
+		} else if strings.TrimSpace(row.Name) == "" {
+			addError("name is required for new users")
+		}
+
+		var departmentID *uint
+		if code := strings.TrimSpace(row.DepartmentCode); code != "" {
+			if dept, ok := departments[strings.ToUpper(code)]; ok {
+				departmentID = &dept.ID
+			} else {
+				addError("unknown department code %q", code)
+			}
+		} else if exists {
+			departmentID = user.DepartmentID
+		}
+		if strings.TrimSpace(row.DepartmentCode) != "" || !exists {
+			if !userScope.Allows(departmentID) {
+				addError("department is outside your scope")
+			}
+		}
+
+		if roleName := normalizeRoleName(row.Role); strings.TrimSpace(row.Role) != "" || !exists {
+			if role, ok := roles[roleName]; !ok {
+				addError("unknown role %q", row.Role)
+			} else if !canGrantRole(role, departmentID, roleScope) {
+				addError("you cannot grant role %s here", roleName)
+			} else if ok, err := holdsGrant(role, departmentID); err != nil {
+				return nil, err
+			} else if !ok {
+				addError("you cannot grant role %s here", roleName)
+			} else if exists && user.Role == models.RoleAdmin && roleName != string(models.RoleAdmin) && !roleScope.Allows(nil) {
+				addError("you cannot change the role of an administrator")
+			}
+		}
+
+		if row.IsActiveRaw != "" && row.IsActive == nil {
+			addError("invalid is_active value %q (use true or false)", row.IsActiveRaw)
+		}
+
+		if len(rowResult.Errors) > 0 {
+			result.Failed++
+		} else if exists {
+			result.Updated++
+		} else {
+			result.Created++
+		}
+		result.Rows = append(result.Rows, rowResult)
+	}
+
+	if dryRun || result.Failed > 0 {
+		return result, nil
+	}
+
+	var created []models.User
+	err = db.Transaction(func(tx *gorm.DB) error {
+		for _, row := range rows {
+			email := strings.ToLower(strings.TrimSpace(row.Email))
+			user, exists := existing[email]
+			if !exists {
+				user = &models.User{Email: email, Role: models.RoleUser, IsActive: true}
+			}
+
+			if name := strings.TrimSpace(row.Name); name != "" {
+				user.Name = name
+			}
+			if code := strings.TrimSpace(row.DepartmentCode); code != "" {
+				user.DepartmentID = &departments[strings.ToUpper(code)].ID
+			}
+			if phone := strings.TrimSpace(row.Phone); phone != "" {
+				user.Phone = &phone
+			}
+			if row.IsActive != nil {
+				user.IsActive = *row.IsActive
+			}
+
+			if err := tx.Omit("Department").Save(user).Error; err != nil {
+				return fmt.Errorf("row %d: %w", row.Row, err)
+			}
+			if strings.TrimSpace(row.Role) != "" || !exists {
+				if err := applyProvisionedRole(tx, user, normalizeRoleName(row.Role), user.DepartmentID); err != nil {
+					return fmt.Errorf("row %d: %w", row.Row, err)
+				}
+			}
+			if !exists {
+				created = append(created, *user)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	result.Applied = true
+
+	if notify {
+		for i := range created {
+			if err := s.sendAccountSetupEmail(&created[i]); err != nil {
+				log.Printf("❌ Failed to send account setup email to %s: %v", created[i].Email, err)
+			}
+		}
+	}
+
+	log.Printf("✅ User import applied: %d created, %d updated", result.Created, result.Updated)
+	return result, nil
+}
+
+// loadImportReferences fetches departments, roles and existing users referenced by the rows in bulk
+func (s *userProvisioningService) loadImportReferences(rows []dto.UserImportRow) (map[string]*models.Department, map[string]*models.RoleDefinition, map[string]*models.User, error) {
+	codes := make([]string, 0)
+	emails := make([]string, 0, len(rows))
+	for _, row := range rows {
+		if code := strings.TrimSpace(row.DepartmentCode); code != "" {
+			codes = append(codes, strings.ToUpper(code))
+		}
+		emails = append(emails, strings.ToLower(strings.TrimSpace(row.Email)))
+	}
+
+	departments := make(map[string]*models.Department)
+	if len(codes) > 0 {
+		var list []models.Department
+		if err := s.db.Where("UPPER(code) IN ?", codes).Find(&list).Error; err != nil {
+			return nil, nil, nil, err
+		}
+		for i := range list {
+			departments[strings.ToUpper(list[i].Code)] = &list[i]
+		}
+	}
+
+	var roleList []models.RoleDefinition
+	if err := s.db.Find(&roleList).Error; err != nil {
+		return nil, nil, nil, err
+	}
+	roles := make(map[string]*models.RoleDefinition, len(roleList))
+	for i := range roleList {
+		roles[roleList[i].Name] = &roleList[i]
+	}
+
+	existing := make(map[string]*models.User)
+	if len(emails) > 0 {
+		var users []models.User
+		if err := s.db.Where("LOWER(email) IN ?", emails).Find(&users).Error; err != nil {
+			return nil, nil, nil, err
+		}
+		for i := range users {
+			existing[strings.ToLower(users[i].Email)] = &users[i]
+		}
+	}
+	return departments, roles, existing, nil
+}
+
+// sendAccountSetupEmail sends imported users a link to choose their first password
+func (s *userProvisioningService) sendAccountSetupEmail(user *models.User) error {
+	ttl := getEnvDuration("INVITATION_TTL_HOURS", time.Hour, 7*24*time.Hour)
+	token, err := issueUserToken(s.db, user.ID, models.TokenPurposePasswordReset, ttl)
+	if err != nil {
+		return err
+	}
+
+	link := fmt.Sprintf("%s/reset-password?token=%s", frontendURL(), token)
+	return s.mailer.Send(mailer.Message{
+		To:      []string{user.Email},
+		Subject: "ตั้งรหัสผ่าน KU Asset / Set up your KU Asset account",
+		TextBody: fmt.Sprintf(
+			"สวัสดี %s\n\nผู้ดูแลได้สร้างบัญชี KU Asset ให้คุณแล้ว คลิกลิงก์ด้านล่างเพื่อตั้งรหัสผ่าน\n%s\n\n"+
+				"An administrator created a KU Asset account for you. Open the link above to choose your password.\n",
+			user.Name, link),
+	})
+}
+
+// --- Role helpers shared by invitations and import ---
+
+func normalizeRoleName(name string) string {
+	name = strings.ToUpper(strings.TrimSpace(name))
+	if name == "" {
+		return string(models.RoleUser)
+	}
+	return name
+}
+
+func findRoleByName(db *gorm.DB, name string) (*models.RoleDefinition, error) {
+	var role models.RoleDefinition
+	if err := db.Where("name = ?", name).First(&role).Error; err != nil {
+		return nil, fmt.Errorf("%w: %s", ErrRoleNotFound, name)
+	}
+	return &role, nil
+}
+
+// grantDepartment is where a provisioned role takes effect: system roles are university-wide
+// (users.role), other roles are bound to the user's department
+func grantDepartment(role *models.RoleDefinition, departmentID *uint) *uint {
+	if role.IsSystem {
+		return nil
+	}
+	return departmentID
+}
+
+// applyProvisionedRole gives a user the named role: system roles update users.role (and the
+// global assignment), other roles are assigned within the user's department.
+func applyProvisionedRole(tx *gorm.DB, user *models.User, roleName string, departmentID *uint) error {
+	role, err := findRoleByName(tx, roleName)
+	if err != nil {
+		return err
+	}
+
+	if role.IsSystem {
+		previous := user.Role
+		user.Role = models.Role(role.Name)
+		if err := tx.Model(user).Update("role", user.Role).Error; err != nil {
+			return err
+		}
+		return syncSystemRole(tx, user.ID, previous, user.Role)
+	}
+
+	query := tx.Model(&models.UserRole{}).Where("user_id = ? AND role_id = ?", user.ID, role.ID)
+	if departmentID != nil {
+		query = query.Where("department_id = ?", *departmentID)
+	} else {
+		query = query.Where("department_id IS NULL")
+	}
+	var count int64
+	if err := query.Count(&count).Error; err != nil {
+		return err
+	}
+	if count > 0 {
+		return nil
+	}
+	return tx.Create(&models.UserRole{UserID: user.ID, RoleID: role.ID, DepartmentID: departmentID}).Error
+}
+
+// Helper to map model to DTO
+func mapInvitationToResponse(inv *models.Invitation) *dto.InvitationResponse {
+	res := &dto.InvitationResponse{
+		ID:           inv.ID,
+		Email:        inv.Email,
+		Name:         inv.Name,
+		Role:         inv.RoleName,
+		DepartmentID: inv.DepartmentID,
+		Status:       inv.Status(time.Now()),
+		ExpiresAt:    inv.ExpiresAt,
+		AcceptedAt:   inv.AcceptedAt,
+		CreatedAt:    inv.CreatedAt,
+	}
+
+	if inv.Department != nil {
+		res.Department = &dto.DepartmentInfoResponse{
+			ID:       inv.Department.ID,
+			Name:     inv.Department.NameTH,
+			Code:     inv.Department.Code,
+			Type:     string(inv.Department.Type),
+			ParentID: inv.Department.ParentID,
+		}
+	}
+	return res
+}
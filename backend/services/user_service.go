@@ -2,10 +2,13 @@
 package services
 
 import (
+	"context"
 	"errors"
 	"fmt"
+	"ku-asset/auth"
 	"ku-asset/dto"
 	"ku-asset/models"
+	"log"
 	"math"
 
 	"golang.org/x/crypto/bcrypt"
@@ -16,14 +19,17 @@ import (
 type UserService interface {
 	// General User methods
 	GetUserByID(id uint) (*dto.UserProfileResponse, error)
-	UpdateUserProfile(id uint, req *dto.UpdateProfileRequest) (*dto.UserProfileResponse, error)
-	ChangePassword(id uint, req *dto.ChangePasswordRequest) error
+	UpdateUserProfile(ctx context.Context, id uint, req *dto.UpdateProfileRequest) (*dto.UserProfileResponse, error)
+	ChangePassword(ctx context.Context, id uint, req *dto.ChangePasswordRequest) error
 	GetUserStats(id uint) (*dto.UserStatsResponse, error)
 
-	// Admin methods
-	GetUsers(req *dto.PaginationRequest) (*dto.PaginatedUserResponse, error)
-	AdminUpdateUser(id uint, req *dto.AdminUpdateUserRequest) (*dto.UserProfileResponse, error)
-	DeleteUser(id uint) error
+	// Admin methods (scope จำกัดผลลัพธ์ตามหน่วยงานของผู้ดูแล, nil = ทั้งหมด)
+	GetUsers(req *dto.PaginationRequest, scope *auth.Scope) (*dto.PaginatedUserResponse, error)
+	// scope = ขอบเขต user.manage, roleScope = ขอบเขต role.manage ของผู้ดำเนินการ
+	AdminUpdateUser(ctx context.Context, id uint, req *dto.AdminUpdateUserRequest, actorID uint, scope, roleScope *auth.Scope) (*dto.UserProfileResponse, error)
+	DeleteUser(ctx context.Context, id uint, scope *auth.Scope) error
+	UnlockUser(ctx context.Context, id uint, scope *auth.Scope) error
+	GetLoginAttempts(id uint, limit int, scope *auth.Scope) ([]dto.LoginAttemptResponse, error)
 }
 
 type userService struct {
@@ -50,9 +56,10 @@ func (s *userService) GetUserByID(id uint) (*dto.UserProfileResponse, error) {
 	return response, nil
 }
 
-func (s *userService) UpdateUserProfile(id uint, req *dto.UpdateProfileRequest) (*dto.UserProfileResponse, error) {
+func (s *userService) UpdateUserProfile(ctx context.Context, id uint, req *dto.UpdateProfileRequest) (*dto.UserProfileResponse, error) {
+	db := s.db.WithContext(ctx)
 	var user models.User
-	if err := s.db.First(&user, id).Error; err != nil {
+	if err := db.First(&user, id).Error; err != nil {
 		return nil, errors.New("user not found")
 	}
 
@@ -74,16 +81,21 @@ func (s *userService) UpdateUserProfile(id uint, req *dto.UpdateProfileRequest)
 		user.Avatar = req.Avatar
 	}
 
-	if err := s.db.Save(&user).Error; err != nil {
+	if req.Locale != nil {
+		user.Locale = *req.Locale
+	}
+
+	if err := db.Save(&user).Error; err != nil {
 		return nil, errors.New("failed to update profile")
 	}
 
 	return s.GetUserByID(id)
 }
 
-func (s *userService) ChangePassword(id uint, req *dto.ChangePasswordRequest) error {
+func (s *userService) ChangePassword(ctx context.Context, id uint, req *dto.ChangePasswordRequest) error {
+	db := s.db.WithContext(ctx)
 	var user models.User
-	if err := s.db.First(&user, id).Error; err != nil {
+	if err := db.First(&user, id).Error; err != nil {
 		return errors.New("user not found")
 	}
 	if user.Password == nil {
@@ -98,7 +110,7 @@ func (s *userService) ChangePassword(id uint, req *dto.ChangePasswordRequest) er
 	}
 	newPasswordStr := string(newHashedPassword)
 	user.Password = &newPasswordStr
-	if err := s.db.Save(&user).Error; err != nil {
+	if err := db.Save(&user).Error; err != nil {
 		return errors.New("failed to update password in database")
 	}
 	return nil
@@ -153,16 +165,16 @@ func (s *userService) GetUserStats(id uint) (*dto.UserStatsResponse, error) {
 }
 
 // --- Admin Methods ---
-func (s *userService) GetUsers(req *dto.PaginationRequest) (*dto.PaginatedUserResponse, error) {
+func (s *userService) GetUsers(req *dto.PaginationRequest, scope *auth.Scope) (*dto.PaginatedUserResponse, error) {
 	var users []models.User
 	var total int64
 
-	if err := s.db.Model(&models.User{}).Count(&total).Error; err != nil {
+	if err := scopeUsers(s.db.Model(&models.User{}), scope).Count(&total).Error; err != nil {
 		return nil, errors.New("failed to count users")
 	}
 
 	offset := (req.Page - 1) * req.Limit
-	if err := s.db.Offset(offset).Limit(req.Limit).Find(&users).Error; err != nil {
+	if err := scopeUsers(s.db, scope).Offset(offset).Limit(req.Limit).Find(&users).Error; err != nil {
 		return nil, errors.New("failed to fetch users")
 	}
 
@@ -182,11 +194,33 @@ func (s *userService) GetUsers(req *dto.PaginationRequest) (*dto.PaginatedUserRe
 	}, nil
 }
 
-func (s *userService) AdminUpdateUser(id uint, req *dto.AdminUpdateUserRequest) (*dto.UserProfileResponse, error) {
+func (s *userService) AdminUpdateUser(ctx context.Context, id uint, req *dto.AdminUpdateUserRequest, actorID uint, scope, roleScope *auth.Scope) (*dto.UserProfileResponse, error) {
+	db := s.db.WithContext(ctx)
 	var user models.User
-	if err := s.db.First(&user, id).Error; err != nil {
+	if err := scopeUsers(db, scope).First(&user, id).Error; err != nil {
 		return nil, errors.New("user not found")
 	}
+	// ⭐ ห้ามย้ายผู้ใช้ออกไปนอกหน่วยงานที่ตนดูแล
+	if req.DepartmentID != nil && !scope.Allows(req.DepartmentID) {
+		return nil, errors.New("department is outside your scope")
+	}
+	// ⭐ เปลี่ยน role ได้เฉพาะที่ตนมีสิทธิ์มอบ - ADMIN ต้องมี role.manage ทั้งระบบ
+	if req.Role != "" && models.Role(req.Role) != user.Role {
+		role, err := findRoleByName(db, req.Role)
+		if err != nil {
+			return nil, err
+		}
+		if !canChangeRole(user.Role, role, user.DepartmentID, roleScope) {
+			return nil, ErrRoleNotGrantable
+		}
+		if ok, err := holdsRolePermissions(db, actorID, role, nil); err != nil {
+			return nil, err
+		} else if !ok {
+			return nil, ErrRoleNotGrantable
+		}
+	}
+
+	previousRole := user.Role
 
 	if req.Name != "" {
 		user.Name = req.Name
@@ -201,20 +235,83 @@ func (s *userService) AdminUpdateUser(id uint, req *dto.AdminUpdateUserRequest)
 		user.IsActive = *req.IsActive
 	}
 
-	if err := s.db.Save(&user).Error; err != nil {
+	err := db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Save(&user).Error; err != nil {
+			return err
+		}
+		// ⭐ ให้ role assignment ตรงกับ role เดิมของผู้ใช้
+		return syncSystemRole(tx, user.ID, previousRole, user.Role)
+	})
+	if err != nil {
 		return nil, errors.New("failed to update user")
 	}
 	return s.GetUserByID(id)
 }
 
-func (s *userService) DeleteUser(id uint) error {
+func (s *userService) DeleteUser(ctx context.Context, id uint, scope *auth.Scope) error {
+	db := s.db.WithContext(ctx)
+	var user models.User
+	if err := scopeUsers(db, scope).First(&user, id).Error; err != nil {
+		return errors.New("user not found")
+	}
+
 	// GORM's Delete performs a soft delete if the model has gorm.DeletedAt
-	if err := s.db.Delete(&models.User{}, id).Error; err != nil {
+	if err := db.Delete(&user).Error; err != nil {
 		return errors.New("failed to delete user")
 	}
 	return nil
 }
 
+// UnlockUser clears a temporary login lockout and the failed-attempt counter
+func (s *userService) UnlockUser(ctx context.Context, id uint, scope *auth.Scope) error {
+	db := s.db.WithContext(ctx)
+	var user models.User
+	if err := scopeUsers(db, scope).First(&user, id).Error; err != nil {
+		return errors.New("user not found")
+	}
+
+	if err := db.Model(&user).UpdateColumns(map[string]interface{}{
+		"failed_login_attempts": 0,
+		"locked_until":          nil,
+	}).Error; err != nil {
+		return errors.New("failed to unlock user")
+	}
+	log.Printf("🔓 Account %s unlocked by admin", user.Email)
+	return nil
+}
+
+// GetLoginAttempts returns the most recent login attempts of a user, newest first
+func (s *userService) GetLoginAttempts(id uint, limit int, scope *auth.Scope) ([]dto.LoginAttemptResponse, error) {
+	var user models.User
+	if err := scopeUsers(s.db, scope).First(&user, id).Error; err != nil {
+		return nil, errors.New("user not found")
+	}
+	if limit <= 0 || limit > 200 {
+		limit = 50
+	}
+
+	var attempts []models.LoginAttempt
+	if err := s.db.Where("user_id = ? OR email = ?", user.ID, user.Email).
+		Order("created_at DESC").Limit(limit).Find(&attempts).Error; err != nil {
+		return nil, err
+	}
+
+	response := make([]dto.LoginAttemptResponse, 0, len(attempts))
+	for _, a := range attempts {
+		response = append(response, dto.LoginAttemptResponse{
+			ID:        a.ID,
+			UserID:    a.UserID,
+			Email:     a.Email,
+			IPAddress: a.IPAddress,
+			UserAgent: a.UserAgent,
+			Success:   a.Success,
+			Result:    string(a.Result),
+			CreatedAt: a.CreatedAt,
+		})
+	}
+	return response, nil
+}
+
 // --- Helper function to map model to DTO ---
 func mapUserToProfileResponse(user *models.User, db *gorm.DB) *dto.UserProfileResponse {
 	fmt.Printf("🗂️ Mapping user to response - DepartmentID: %v\n", user.DepartmentID)
@@ -228,6 +325,9 @@ func mapUserToProfileResponse(user *models.User, db *gorm.DB) *dto.UserProfileRe
 		IsActive:     user.IsActive,
 		DepartmentID: user.DepartmentID,
 		Avatar:       user.Avatar,
+		Locale:       user.Locale,
+		LineLinked:   user.LineUserID != nil,
+		LineLinkedAt: user.LineLinkedAt,
 	}
 
 	// Map Department information if available
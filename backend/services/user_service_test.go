@@ -0,0 +1,66 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"ku-asset/auth"
+	"ku-asset/dto"
+	"ku-asset/models"
+
+	"github.com/DATA-DOG/go-sqlmock"
+)
+
+func TestAdminUpdateUserRoleChange(t *testing.T) {
+	dept := uint(5)
+	scoped := &auth.Scope{DepartmentIDs: []uint{dept}}
+
+	tests := []struct {
+		name      string
+		current   models.Role
+		requested string
+		roleScope *auth.Scope
+		lookup    bool     // คาดว่าจะโหลด role ที่ขอ
+		roleCodes []string // permission ของ role ที่ขอ (ตรวจเมื่อผ่าน role.manage แล้ว)
+		denied    bool
+	}{
+		{"scoped admin cannot grant ADMIN", models.RoleUser, "ADMIN", scoped, true, nil, true},
+		{"user.manage without role.manage cannot grant ADMIN", models.RoleUser, "ADMIN", &auth.Scope{}, true, nil, true},
+		{"scoped admin cannot demote ADMIN", models.RoleAdmin, "USER", scoped, true, nil, true},
+		{"global role.manage grants ADMIN", models.RoleUser, "ADMIN", auth.GlobalScope(), true, []string{}, false},
+		{"global role.manage without ADMIN's permissions", models.RoleUser, "ADMIN", auth.GlobalScope(), true, []string{models.PermAuditView}, true},
+		{"unchanged role skips the check", models.RoleAdmin, "ADMIN", scoped, false, nil, false},
+		{"no role in request", models.RoleUser, "", scoped, false, nil, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db, mock := newMockDB(t)
+			mock.ExpectQuery(`FROM "users"`).
+				WillReturnRows(sqlmock.NewRows([]string{"id", "email", "name", "role", "department_id", "is_active"}).
+					AddRow(7, "staff@ku.ac.th", "Staff", string(tt.current), dept, true))
+			if tt.lookup {
+				mock.ExpectQuery(`FROM "roles"`).
+					WillReturnRows(sqlmock.NewRows([]string{"id", "name", "is_system"}).AddRow(1, tt.requested, true))
+			}
+			if tt.roleCodes != nil {
+				expectRolePermissions(mock, tt.roleCodes...)
+				for range tt.roleCodes {
+					expectGranterScope(mock) // ผู้แก้ไขไม่มี permission นี้
+				}
+			}
+			// ส่วนที่เหลือ (บันทึกผู้ใช้) ไม่ได้ mock ไว้ จึงล้มด้วย error อื่นถ้าผ่านการตรวจ role
+
+			s := &userService{db: db}
+			req := &dto.AdminUpdateUserRequest{Role: tt.requested}
+			_, err := s.AdminUpdateUser(context.Background(), 7, req, 1, scoped, tt.roleScope)
+
+			if got := errors.Is(err, ErrRoleNotGrantable); got != tt.denied {
+				t.Fatalf("denied = %v, want %v (err: %v)", got, tt.denied, err)
+			}
+			if err := mock.ExpectationsWereMet(); err != nil {
+				t.Error(err)
+			}
+		})
+	}
+}
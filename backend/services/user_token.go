@@ -0,0 +1,86 @@
+// services/user_token.go
+package services
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"ku-asset/models"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+var ErrInvalidToken = errors.New("invalid or expired token")
+
+// generateSecret returns a random URL-safe secret with n bytes of entropy
+func generateSecret(n int) (string, error) {
+	b := make([]byte, n)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+// hashSecret returns the hex SHA-256 digest that is stored instead of the raw secret
+func hashSecret(secret string) string {
+	sum := sha256.Sum256([]byte(secret))
+	return hex.EncodeToString(sum[:])
+}
+
+// issueUserToken invalidates any outstanding token of the same purpose and creates a new one.
+// The raw token is returned to be delivered to the user; only its hash is persisted.
+func issueUserToken(tx *gorm.DB, userID uint, purpose models.TokenPurpose, ttl time.Duration) (string, error) {
+	now := time.Now()
+	if err := tx.Model(&models.UserToken{}).
+		Where("user_id = ? AND purpose = ? AND used_at IS NULL", userID, purpose).
+		Update("used_at", now).Error; err != nil {
+		return "", err
+	}
+
+	raw, err := generateSecret(32)
+	if err != nil {
+		return "", err
+	}
+
+	token := models.UserToken{
+		UserID:    userID,
+		Purpose:   purpose,
+		TokenHash: hashSecret(raw),
+		ExpiresAt: now.Add(ttl),
+	}
+	if err := tx.Create(&token).Error; err != nil {
+		return "", err
+	}
+	return raw, nil
+}
+
+// consumeUserToken validates a raw token for the given purpose and marks it as used.
+// Callers should run it inside the same transaction as the action the token authorizes.
+func consumeUserToken(tx *gorm.DB, raw string, purpose models.TokenPurpose) (*models.UserToken, error) {
+	var token models.UserToken
+	if err := tx.Where("token_hash = ? AND purpose = ?", hashSecret(raw), purpose).First(&token).Error; err != nil {
+		return nil, ErrInvalidToken
+	}
+
+	now := time.Now()
+	if !token.IsUsable(now) {
+		return nil, ErrInvalidToken
+	}
+
+	// ⭐ ใช้ WHERE used_at IS NULL ป้องกันการใช้ token ซ้ำพร้อมกัน
+	result := tx.Model(&models.UserToken{}).
+		Where("id = ? AND used_at IS NULL", token.ID).
+		Update("used_at", now)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	if result.RowsAffected == 0 {
+		return nil, ErrInvalidToken
+	}
+
+	token.UsedAt = &now
+	return &token, nil
+}
@@ -0,0 +1,754 @@
+// services/webhook_service.go
+package services
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"ku-asset/dto"
+	"ku-asset/models"
+	"log"
+	"math"
+	"net"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+var (
+	ErrWebhookNotFound         = errors.New("webhook not found")
+	ErrWebhookDeliveryNotFound = errors.New("webhook delivery not found")
+	ErrUnknownWebhookEvent     = errors.New("unknown webhook event")
+	ErrInvalidWebhookURL       = errors.New("webhook URL must be an absolute http or https URL")
+	ErrWebhookTargetNotAllowed = errors.New("webhook URL must not point to a private, loopback or link-local address")
+)
+
+// Headers sent with every webhook delivery. The signature is
+// hex(HMAC-SHA256(secret, timestamp + "." + body)) prefixed with "sha256=".
+const (
+	WebhookHeaderEvent     = "X-KU-Asset-Event"
+	WebhookHeaderEventID   = "X-KU-Asset-Event-ID"
+	WebhookHeaderDelivery  = "X-KU-Asset-Delivery"
+	WebhookHeaderTimestamp = "X-KU-Asset-Timestamp"
+	WebhookHeaderSignature = "X-KU-Asset-Signature"
+)
+
+// webhookResponseExcerpt is how much of the receiver's response body is kept in the log
+const webhookResponseExcerpt = 2048
+
+// WebhookService manages webhook subscriptions and delivers queued events to them.
+type WebhookService interface {
+	GetWebhooks() ([]dto.WebhookResponse, error)
+	GetWebhook(id uint) (*dto.WebhookResponse, error)
+	CreateWebhook(ctx context.Context, req *dto.CreateWebhookRequest, createdByID uint) (*dto.WebhookResponse, error)
+	UpdateWebhook(ctx context.Context, id uint, req *dto.UpdateWebhookRequest) (*dto.WebhookResponse, error)
+	DeleteWebhook(ctx context.Context, id uint) error
+
+	// Delivery log
+	GetDeliveries(subscriptionID uint, query *dto.WebhookDeliveryQuery) (*dto.PaginatedWebhookDeliveryResponse, error)
+	Redeliver(subscriptionID, deliveryID uint) (*dto.WebhookDeliveryResponse, error)
+	Ping(subscriptionID uint) (*dto.WebhookDeliveryResponse, error)
+
+	// Enqueue - เรียกภายใน transaction ของการเปลี่ยนแปลง เพื่อให้ส่งเฉพาะสิ่งที่ commit แล้ว
+	EnqueueRequestCreated(tx *gorm.DB, requestID uint) error
+	EnqueueRequestStatusChanged(tx *gorm.DB, requestID uint) error
+	EnqueueStockChanged(tx *gorm.DB, product *models.Product, previousStock int, source string, requestID *uint) error
+
+	// Start runs the delivery worker until ctx is cancelled
+	Start(ctx context.Context)
+}
+
+type webhookService struct {
+	db     *gorm.DB
+	client *http.Client
+}
+
+func NewWebhookService(db *gorm.DB) WebhookService {
+	return &webhookService{
+		db:     db,
+		client: newWebhookHTTPClient(getEnvDuration("WEBHOOK_TIMEOUT_SECONDS", time.Second, 10*time.Second)),
+	}
+}
+
+// newWebhookHTTPClient builds the delivery client. ⭐ ตรวจ IP ตอน dial จริง (หลัง resolve DNS แล้ว)
+// จึงกัน DNS rebinding ได้ และไม่ตาม redirect เพื่อไม่ให้ปลายทางพาไปยัง address ภายใน
+func newWebhookHTTPClient(timeout time.Duration) *http.Client {
+	dialer := &net.Dialer{
+		Timeout: timeout,
+		Control: func(network, address string, _ syscall.RawConn) error {
+			host, _, err := net.SplitHostPort(address)
+			if err != nil {
+				return err
+			}
+			if ip := net.ParseIP(host); ip == nil || !webhookTargetAllowed(ip) {
+				return fmt.Errorf("%w: %s", ErrWebhookTargetNotAllowed, host)
+			}
+			return nil
+		},
+	}
+
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.Proxy = nil // ต่อตรงเสมอ ไม่งั้น Control จะเห็นแค่ IP ของ proxy
+	transport.DialContext = dialer.DialContext
+
+	return &http.Client{
+		Timeout:   timeout,
+		Transport: transport,
+		CheckRedirect: func(*http.Request, []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+	}
+}
+
+// --- Subscriptions ---
+
+func (s *webhookService) GetWebhooks() ([]dto.WebhookResponse, error) {
+	var hooks []models.WebhookSubscription
+	if err := s.db.Order("name ASC").Find(&hooks).Error; err != nil {
+		return nil, err
+	}
+
+	response := make([]dto.WebhookResponse, 0, len(hooks))
+	for i := range hooks {
+		response = append(response, *mapWebhookToResponse(&hooks[i]))
+	}
+	return response, nil
+}
+
+func (s *webhookService) GetWebhook(id uint) (*dto.WebhookResponse, error) {
+	hook, err := s.findWebhook(s.db, id)
+	if err != nil {
+		return nil, err
+	}
+	return mapWebhookToResponse(hook), nil
+}
+
+// CreateWebhook คืน secret ให้ครั้งเดียว ผู้รับต้องเก็บไว้ตรวจลายเซ็น
+func (s *webhookService) CreateWebhook(ctx context.Context, req *dto.CreateWebhookRequest, createdByID uint) (*dto.WebhookResponse, error) {
+	events, err := normalizeWebhookEvents(req.Events)
+	if err != nil {
+		return nil, err
+	}
+	if err := validateWebhookURL(req.URL); err != nil {
+		return nil, err
+	}
+
+	secret := req.Secret
+	if secret == "" {
+		if secret, err = generateSecret(32); err != nil {
+			return nil, err
+		}
+	}
+
+	hook := models.WebhookSubscription{
+		Name:        strings.TrimSpace(req.Name),
+		URL:         req.URL,
+		Secret:      secret,
+		Events:      strings.Join(events, ","),
+		IsActive:    true,
+		CreatedByID: &createdByID,
+	}
+	if req.IsActive != nil {
+		hook.IsActive = *req.IsActive
+	}
+	if err := s.db.WithContext(ctx).Create(&hook).Error; err != nil {
+		return nil, err
+	}
+
+	response := mapWebhookToResponse(&hook)
+	response.Secret = secret
+	return response, nil
+}
+
+func (s *webhookService) UpdateWebhook(ctx context.Context, id uint, req *dto.UpdateWebhookRequest) (*dto.WebhookResponse, error) {
+	db := s.db.WithContext(ctx)
+	hook, err := s.findWebhook(db, id)
+	if err != nil {
+		return nil, err
+	}
+
+	if req.Name != nil {
+		hook.Name = strings.TrimSpace(*req.Name)
+	}
+	if req.URL != nil {
+		if err := validateWebhookURL(*req.URL); err != nil {
+			return nil, err
+		}
+		hook.URL = *req.URL
+	}
+	if req.Events != nil {
+		events, err := normalizeWebhookEvents(req.Events)
+		if err != nil {
+			return nil, err
+		}
+		hook.Events = strings.Join(events, ",")
+	}
+	if req.IsActive != nil {
+		hook.IsActive = *req.IsActive
+	}
+	if req.RotateSecret {
+		if hook.Secret, err = generateSecret(32); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := db.Save(hook).Error; err != nil {
+		return nil, err
+	}
+
+	response := mapWebhookToResponse(hook)
+	if req.RotateSecret {
+		response.Secret = hook.Secret
+	}
+	return response, nil
+}
+
+// DeleteWebhook ลบ subscription และยกเลิกรายการที่ยังรอส่ง (ประวัติการส่งยังเก็บไว้)
+func (s *webhookService) DeleteWebhook(ctx context.Context, id uint) error {
+	return s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		hook, err := s.findWebhook(tx, id)
+		if err != nil {
+			return err
+		}
+		if err := tx.Model(&models.WebhookDelivery{}).
+			Where("subscription_id = ? AND status = ?", id, models.WebhookDeliveryPending).
+			Updates(map[string]interface{}{"status": models.WebhookDeliveryFailed, "last_error": "subscription deleted"}).Error; err != nil {
+			return err
+		}
+		return tx.Delete(hook).Error
+	})
+}
+
+// --- Delivery log ---
+
+func (s *webhookService) GetDeliveries(subscriptionID uint, query *dto.WebhookDeliveryQuery) (*dto.PaginatedWebhookDeliveryResponse, error) {
+	if _, err := s.findWebhook(s.db, subscriptionID); err != nil {
+		return nil, err
+	}
+
+	q := s.db.Model(&models.WebhookDelivery{}).Where("subscription_id = ?", subscriptionID)
+	if query.Status != "" {
+		q = q.Where("status = ?", strings.ToUpper(query.Status))
+	}
+	if query.Event != "" {
+		q = q.Where("event = ?", query.Event)
+	}
+
+	var total int64
+	if err := q.Count(&total).Error; err != nil {
+		return nil, err
+	}
+
+	limit := query.Limit
+	if limit <= 0 || limit > 100 {
+		limit = 20
+	}
+	page := query.Page
+	if page < 1 {
+		page = 1
+	}
+
+	var deliveries []models.WebhookDelivery
+	if err := q.Order("created_at DESC, id DESC").Offset((page - 1) * limit).Limit(limit).Find(&deliveries).Error; err != nil {
+		return nil, err
+	}
+
+	response := make([]dto.WebhookDeliveryResponse, 0, len(deliveries))
+	for i := range deliveries {
+		response = append(response, mapWebhookDeliveryToResponse(&deliveries[i]))
+	}
+	return &dto.PaginatedWebhookDeliveryResponse{
+		Deliveries: response,
+		Pagination: dto.PaginationResponse{
+			CurrentPage: page,
+			PerPage:     limit,
+			Total:       total,
+			TotalPages:  int64(math.Ceil(float64(total) / float64(limit))),
+		},
+	}, nil
+}
+
+// Redeliver queues the same payload (and event ID) again as a new delivery, so the
+// original attempt stays in the log and receivers can de-duplicate by event ID.
+func (s *webhookService) Redeliver(subscriptionID, deliveryID uint) (*dto.WebhookDeliveryResponse, error) {
+	var original models.WebhookDelivery
+	if err := s.db.Where("id = ? AND subscription_id = ?", deliveryID, subscriptionID).First(&original).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrWebhookDeliveryNotFound
+		}
+		return nil, err
+	}
+	if _, err := s.findWebhook(s.db, subscriptionID); err != nil {
+		return nil, err
+	}
+
+	delivery := models.WebhookDelivery{
+		SubscriptionID: original.SubscriptionID,
+		EventID:        original.EventID,
+		Event:          original.Event,
+		Payload:        original.Payload,
+		Status:         models.WebhookDeliveryPending,
+		NextAttemptAt:  time.Now(),
+		RedeliveryOfID: &original.ID,
+	}
+	if err := s.db.Create(&delivery).Error; err != nil {
+		return nil, err
+	}
+	response := mapWebhookDeliveryToResponse(&delivery)
+	return &response, nil
+}
+
+// Ping queues a test event for one subscription, whatever events it is subscribed to
+func (s *webhookService) Ping(subscriptionID uint) (*dto.WebhookDeliveryResponse, error) {
+	hook, err := s.findWebhook(s.db, subscriptionID)
+	if err != nil {
+		return nil, err
+	}
+
+	eventID, payload, err := buildWebhookPayload(models.WebhookEventPing, map[string]interface{}{
+		"webhook_id": hook.ID,
+		"message":    "ทดสอบการเชื่อมต่อ webhook",
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	delivery := models.WebhookDelivery{
+		SubscriptionID: hook.ID,
+		EventID:        eventID,
+		Event:          models.WebhookEventPing,
+		Payload:        payload,
+		Status:         models.WebhookDeliveryPending,
+		NextAttemptAt:  time.Now(),
+	}
+	if err := s.db.Create(&delivery).Error; err != nil {
+		return nil, err
+	}
+	response := mapWebhookDeliveryToResponse(&delivery)
+	return &response, nil
+}
+
+// --- Enqueue ---
+
+func (s *webhookService) EnqueueRequestCreated(tx *gorm.DB, requestID uint) error {
+	request, err := loadRequestForWebhook(tx, requestID)
+	if err != nil {
+		return err
+	}
+	return s.enqueue(tx, models.WebhookEventRequestCreated, webhookRequestData(request))
+}
+
+// EnqueueRequestStatusChanged ส่ง request.status_changed ทุกครั้ง และ request.approved เพิ่มเมื่ออนุมัติ
+func (s *webhookService) EnqueueRequestStatusChanged(tx *gorm.DB, requestID uint) error {
+	request, err := loadRequestForWebhook(tx, requestID)
+	if err != nil {
+		return err
+	}
+	data := webhookRequestData(request)
+	if err := s.enqueue(tx, models.WebhookEventRequestStatusChanged, data); err != nil {
+		return err
+	}
+	if request.Status == models.RequestStatusApproved {
+		return s.enqueue(tx, models.WebhookEventRequestApproved, data)
+	}
+	return nil
+}
+
+// EnqueueStockChanged expects product to hold the new stock and status. source says what
+// changed it ("request_approved", "adjustment", "product_update").
+func (s *webhookService) EnqueueStockChanged(tx *gorm.DB, product *models.Product, previousStock int, source string, requestID *uint) error {
+	data := map[string]interface{}{
+		"product_id":     product.ID,
+		"code":           product.Code,
+		"name":           product.Name,
+		"stock":          product.Stock,
+		"previous_stock": previousStock,
+		"min_stock":      product.MinStock,
+		"unit":           product.Unit,
+		"status":         product.Status,
+		"source":         source,
+		"request_id":     requestID,
+	}
+	if err := s.enqueue(tx, models.WebhookEventStockChanged, data); err != nil {
+		return err
+	}
+	// ⭐ แจ้ง stock.low เฉพาะตอนที่เพิ่งลดลงถึงขั้นต่ำ ไม่แจ้งซ้ำทุกครั้งที่เบิก
+	if product.Stock <= product.MinStock && previousStock > product.MinStock {
+		return s.enqueue(tx, models.WebhookEventStockLow, data)
+	}
+	return nil
+}
+
+// enqueue writes one delivery per active subscription that wants the event. All
+// deliveries of one event share the event ID so receivers can de-duplicate.
+func (s *webhookService) enqueue(tx *gorm.DB, event string, data interface{}) error {
+	var hooks []models.WebhookSubscription
+	if err := tx.Where("is_active = ?", true).Find(&hooks).Error; err != nil {
+		return err
+	}
+
+	var subscriberIDs []uint
+	for i := range hooks {
+		if hooks[i].Wants(event) {
+			subscriberIDs = append(subscriberIDs, hooks[i].ID)
+		}
+	}
+	if len(subscriberIDs) == 0 {
+		return nil
+	}
+
+	eventID, payload, err := buildWebhookPayload(event, data)
+	if err != nil {
+		return err
+	}
+	deliveries := make([]models.WebhookDelivery, 0, len(subscriberIDs))
+	for _, id := range subscriberIDs {
+		deliveries = append(deliveries, models.WebhookDelivery{
+			SubscriptionID: id,
+			EventID:        eventID,
+			Event:          event,
+			Payload:        payload,
+			Status:         models.WebhookDeliveryPending,
+			NextAttemptAt:  time.Now(),
+		})
+	}
+	return tx.Create(&deliveries).Error
+}
+
+// --- Worker ---
+
+func (s *webhookService) Start(ctx context.Context) {
+	go func() {
+		ticker := time.NewTicker(getEnvDuration("WEBHOOK_QUEUE_POLL_SECONDS", time.Second, 5*time.Second))
+		defer ticker.Stop()
+		for {
+			if err := s.deliverDue(); err != nil {
+				log.Printf("❌ Webhook worker: %v", err)
+			}
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+}
+
+// webhookBatchSize is the number of deliveries claimed per poll
+const webhookBatchSize = 20
+
+// deliverDue claims a batch of due deliveries and sends them. Claiming pushes
+// next_attempt_at forward (a lease) and commits before any HTTP call, so a slow
+// receiver never holds row locks and a crashed instance's claims expire on their own.
+func (s *webhookService) deliverDue() error {
+	// ⭐ ส่งทีละรายการ lease จึงต้องครอบทั้ง batch ที่ทุกรายการใช้เวลาเต็ม timeout
+	// ไม่งั้น instance อื่นจะ claim รายการที่ยังไม่ได้ส่งไปส่งซ้ำ
+	timeout := getEnvDuration("WEBHOOK_TIMEOUT_SECONDS", time.Second, 10*time.Second)
+	lease := timeout*webhookBatchSize + time.Minute
+
+	var deliveries []models.WebhookDelivery
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE", Options: "SKIP LOCKED"}).
+			Where("status = ? AND next_attempt_at <= ?", models.WebhookDeliveryPending, time.Now()).
+			Order("next_attempt_at").Limit(webhookBatchSize).Find(&deliveries).Error; err != nil {
+			return err
+		}
+		if len(deliveries) == 0 {
+			return nil
+		}
+		ids := make([]uint, len(deliveries))
+		for i := range deliveries {
+			ids[i] = deliveries[i].ID
+		}
+		return tx.Model(&models.WebhookDelivery{}).Where("id IN ?", ids).
+			Update("next_attempt_at", time.Now().Add(lease)).Error
+	})
+	if err != nil {
+		return err
+	}
+
+	for i := range deliveries {
+		// รายการที่บันทึกผลไม่ได้จะถูกส่งใหม่เมื่อ lease หมด ไม่ให้ค้างทั้ง batch
+		if err := s.attempt(&deliveries[i]); err != nil {
+			log.Printf("⚠️ Webhook delivery %d: %v", deliveries[i].ID, err)
+		}
+	}
+	return nil
+}
+
+// attempt sends one delivery and records the outcome
+func (s *webhookService) attempt(delivery *models.WebhookDelivery) error {
+	maxAttempts := getEnvInt("WEBHOOK_MAX_ATTEMPTS", 8)
+	retryBase := getEnvDuration("WEBHOOK_RETRY_BASE_SECONDS", time.Second, 30*time.Second)
+
+	now := time.Now()
+	delivery.Attempts++
+	updates := map[string]interface{}{"attempts": delivery.Attempts, "last_attempt_at": now}
+
+	var hook models.WebhookSubscription
+	err := s.db.First(&hook, delivery.SubscriptionID).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) || (err == nil && !hook.IsActive) {
+		// subscription ถูกลบหรือปิดใช้งานหลังจากเข้าคิว - ไม่ส่งต่อ
+		updates["status"] = models.WebhookDeliveryFailed
+		updates["last_error"] = "subscription is deleted or inactive"
+		return s.db.Model(delivery).Updates(updates).Error
+	}
+	if err != nil {
+		return err
+	}
+
+	code, body, duration, sendErr := s.send(&hook, delivery)
+	updates["duration_ms"] = duration.Milliseconds()
+	updates["response_body"] = body
+	if code > 0 {
+		updates["response_code"] = code
+	}
+	if sendErr == nil && (code < 200 || code > 299) {
+		sendErr = fmt.Errorf("receiver responded with HTTP %d", code)
+	}
+
+	switch {
+	case sendErr == nil:
+		updates["status"] = models.WebhookDeliverySucceeded
+		updates["delivered_at"] = now
+		updates["last_error"] = ""
+	case delivery.Attempts >= maxAttempts:
+		log.Printf("❌ Giving up webhook delivery %d (%s) to %s: %v", delivery.ID, delivery.Event, hook.URL, sendErr)
+		updates["status"] = models.WebhookDeliveryFailed
+		updates["last_error"] = sendErr.Error()
+	default:
+		// ⭐ exponential backoff: 30s, 1m, 2m, ... สูงสุด 6 ชั่วโมง
+		delay := retryBase << (delivery.Attempts - 1)
+		if delay > 6*time.Hour || delay <= 0 {
+			delay = 6 * time.Hour
+		}
+		log.Printf("⚠️ Webhook delivery %d to %s failed (attempt %d), retrying in %s: %v", delivery.ID, hook.URL, delivery.Attempts, delay, sendErr)
+		updates["next_attempt_at"] = now.Add(delay)
+		updates["last_error"] = sendErr.Error()
+	}
+	return s.db.Model(delivery).Updates(updates).Error
+}
+
+// send POSTs the signed payload and returns the status code and a response excerpt
+func (s *webhookService) send(hook *models.WebhookSubscription, delivery *models.WebhookDelivery) (int, string, time.Duration, error) {
+	body := []byte(delivery.Payload)
+	timestamp := time.Now().Unix()
+
+	req, err := http.NewRequest(http.MethodPost, hook.URL, bytes.NewReader(body))
+	if err != nil {
+		return 0, "", 0, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "KU-Asset-Webhook/1.0")
+	req.Header.Set(WebhookHeaderEvent, delivery.Event)
+	req.Header.Set(WebhookHeaderEventID, delivery.EventID)
+	req.Header.Set(WebhookHeaderDelivery, strconv.FormatUint(uint64(delivery.ID), 10))
+	req.Header.Set(WebhookHeaderTimestamp, strconv.FormatInt(timestamp, 10))
+	req.Header.Set(WebhookHeaderSignature, "sha256="+SignWebhookPayload(hook.Secret, timestamp, body))
+
+	start := time.Now()
+	resp, err := s.client.Do(req)
+	duration := time.Since(start)
+	if err != nil {
+		return 0, "", duration, err
+	}
+	defer resp.Body.Close()
+
+	excerpt, _ := io.ReadAll(io.LimitReader(resp.Body, webhookResponseExcerpt))
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 1<<20)) // ให้ connection กลับไปใช้ซ้ำได้
+	return resp.StatusCode, strings.ToValidUTF8(string(excerpt), ""), duration, nil
+}
+
+// SignWebhookPayload returns hex(HMAC-SHA256(secret, "<timestamp>.<body>")). Receivers
+// recompute it from the X-KU-Asset-Timestamp header and the raw body.
+func SignWebhookPayload(secret string, timestamp int64, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(strconv.FormatInt(timestamp, 10)))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// --- Helpers ---
+
+func (s *webhookService) findWebhook(db *gorm.DB, id uint) (*models.WebhookSubscription, error) {
+	var hook models.WebhookSubscription
+	if err := db.First(&hook, id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrWebhookNotFound
+		}
+		return nil, err
+	}
+	return &hook, nil
+}
+
+// buildWebhookPayload wraps data in the envelope sent to every receiver
+func buildWebhookPayload(event string, data interface{}) (string, string, error) {
+	eventID := uuid.New().String()
+	payload, err := json.Marshal(map[string]interface{}{
+		"id":         eventID,
+		"event":      event,
+		"created_at": time.Now().UTC().Format(time.RFC3339),
+		"data":       data,
+	})
+	if err != nil {
+		return "", "", fmt.Errorf("failed to encode %s webhook: %w", event, err)
+	}
+	return eventID, string(payload), nil
+}
+
+func normalizeWebhookEvents(events []string) ([]string, error) {
+	known := map[string]bool{models.WebhookEventAll: true}
+	for _, e := range models.WebhookEvents {
+		known[e.Event] = true
+	}
+
+	seen := make(map[string]bool, len(events))
+	var normalized []string
+	for _, e := range events {
+		e = strings.TrimSpace(e)
+		if !known[e] {
+			return nil, fmt.Errorf("%w: %s", ErrUnknownWebhookEvent, e)
+		}
+		if !seen[e] {
+			seen[e] = true
+			normalized = append(normalized, e)
+		}
+	}
+	if len(normalized) == 0 {
+		return nil, fmt.Errorf("%w: at least one event is required", ErrUnknownWebhookEvent)
+	}
+	return normalized, nil
+}
+
+// validateWebhookURL checks the URL shape and that every address the host resolves to is public.
+// The delivery client checks again at connect time, since DNS can change after this.
+func validateWebhookURL(raw string) error {
+	u, err := url.Parse(raw)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Hostname() == "" {
+		return ErrInvalidWebhookURL
+	}
+
+	host := u.Hostname()
+	ips := []net.IP{net.ParseIP(host)}
+	if ips[0] == nil {
+		addrs, err := net.DefaultResolver.LookupIPAddr(context.Background(), host)
+		if err != nil || len(addrs) == 0 {
+			return fmt.Errorf("%w: cannot resolve %s", ErrInvalidWebhookURL, host)
+		}
+		ips = ips[:0]
+		for _, addr := range addrs {
+			ips = append(ips, addr.IP)
+		}
+	}
+	for _, ip := range ips {
+		if !webhookTargetAllowed(ip) {
+			return fmt.Errorf("%w: %s", ErrWebhookTargetNotAllowed, host)
+		}
+	}
+	return nil
+}
+
+// carrierGradeNAT (100.64.0.0/10) ไม่อยู่ใน net.IP.IsPrivate แต่ก็ไม่ใช่ address สาธารณะ
+var carrierGradeNAT = &net.IPNet{IP: net.IPv4(100, 64, 0, 0), Mask: net.CIDRMask(10, 32)}
+
+// webhookTargetAllowed reports whether deliveries may connect to ip. Set
+// WEBHOOK_ALLOW_PRIVATE_TARGETS=true to allow local receivers during development.
+func webhookTargetAllowed(ip net.IP) bool {
+	if getEnvBool("WEBHOOK_ALLOW_PRIVATE_TARGETS", false) {
+		return true
+	}
+	return !(ip.IsLoopback() || ip.IsPrivate() || ip.IsUnspecified() ||
+		ip.IsLinkLocalUnicast() || ip.IsLinkLocalMulticast() ||
+		ip.IsInterfaceLocalMulticast() || ip.IsMulticast() || carrierGradeNAT.Contains(ip))
+}
+
+func loadRequestForWebhook(tx *gorm.DB, requestID uint) (*models.Request, error) {
+	var request models.Request
+	if err := tx.Preload("User").Preload("Items.Product").First(&request, requestID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("request not found")
+		}
+		return nil, err
+	}
+	return &request, nil
+}
+
+func webhookRequestData(r *models.Request) map[string]interface{} {
+	items := make([]map[string]interface{}, 0, len(r.Items))
+	for _, item := range r.Items {
+		items = append(items, map[string]interface{}{
+			"product_id":   item.ProductID,
+			"product_code": item.Product.Code,
+			"product_name": item.Product.Name,
+			"quantity":     item.Quantity,
+			"unit":         item.Product.Unit,
+		})
+	}
+	return map[string]interface{}{
+		"id":             r.ID,
+		"request_number": r.RequestNumber,
+		"status":         r.Status,
+		"purpose":        r.Purpose,
+		"admin_note":     r.AdminNote,
+		"request_date":   r.RequestDate,
+		"approved_date":  r.ApprovedDate,
+		"requester": map[string]interface{}{
+			"id":            r.User.ID,
+			"name":          r.User.Name,
+			"email":         r.User.Email,
+			"department_id": r.User.DepartmentID,
+		},
+		"items": items,
+	}
+}
+
+func mapWebhookToResponse(w *models.WebhookSubscription) *dto.WebhookResponse {
+	events := w.EventList()
+	if events == nil {
+		events = []string{}
+	}
+	return &dto.WebhookResponse{
+		ID:        w.ID,
+		Name:      w.Name,
+		URL:       w.URL,
+		Events:    events,
+		IsActive:  w.IsActive,
+		CreatedAt: w.CreatedAt,
+		UpdatedAt: w.UpdatedAt,
+	}
+}
+
+func mapWebhookDeliveryToResponse(d *models.WebhookDelivery) dto.WebhookDeliveryResponse {
+	return dto.WebhookDeliveryResponse{
+		ID:             d.ID,
+		SubscriptionID: d.SubscriptionID,
+		EventID:        d.EventID,
+		Event:          d.Event,
+		Payload:        d.Payload,
+		Status:         string(d.Status),
+		Attempts:       d.Attempts,
+		NextAttemptAt:  d.NextAttemptAt,
+		LastAttemptAt:  d.LastAttemptAt,
+		ResponseCode:   d.ResponseCode,
+		ResponseBody:   d.ResponseBody,
+		DurationMs:     d.DurationMs,
+		LastError:      d.LastError,
+		DeliveredAt:    d.DeliveredAt,
+		RedeliveryOfID: d.RedeliveryOfID,
+		CreatedAt:      d.CreatedAt,
+	}
+}
@@ -0,0 +1,168 @@
+package services
+
+import (
+	"database/sql/driver"
+	"errors"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"ku-asset/models"
+
+	"github.com/DATA-DOG/go-sqlmock"
+)
+
+func TestSignWebhookPayload(t *testing.T) {
+	tests := []struct {
+		name      string
+		secret    string
+		timestamp int64
+		body      string
+		want      string
+	}{
+		{"ping", "whsec_test", 1760000000, `{"event":"ping"}`, "7b3dfb12b416238df73d3eb92d1702951e3ebe69a71967d039b03ccc93c8d291"},
+		{"timestamp is signed", "whsec_test", 1760000001, `{"event":"ping"}`, "03366a50060f46173b7f5694811e1688c2b0a5522ddd692c2d50c28ef530cd53"},
+		{"empty secret and body", "", 0, "", "b849d5a581847b281957065739df36df2463d1977ea8d6e1e4e6cf33fadc68c3"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := SignWebhookPayload(tt.secret, tt.timestamp, []byte(tt.body)); got != tt.want {
+				t.Errorf("SignWebhookPayload() = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestWebhookTargetAllowed(t *testing.T) {
+	t.Setenv("WEBHOOK_ALLOW_PRIVATE_TARGETS", "")
+
+	tests := []struct {
+		ip   string
+		want bool
+	}{
+		{"93.184.216.34", true},
+		{"2606:2800:220:1:248:1893:25c8:1946", true},
+		{"127.0.0.1", false},
+		{"::1", false},
+		{"10.1.2.3", false},
+		{"172.16.0.1", false},
+		{"192.168.1.10", false},
+		{"169.254.169.254", false}, // cloud metadata
+		{"fe80::1", false},
+		{"fd00::1", false},
+		{"0.0.0.0", false},
+		{"224.0.0.1", false},
+		{"100.64.0.1", false},
+		{"::ffff:127.0.0.1", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.ip, func(t *testing.T) {
+			if got := webhookTargetAllowed(net.ParseIP(tt.ip)); got != tt.want {
+				t.Errorf("webhookTargetAllowed(%s) = %v, want %v", tt.ip, got, tt.want)
+			}
+		})
+	}
+
+	t.Setenv("WEBHOOK_ALLOW_PRIVATE_TARGETS", "true")
+	if !webhookTargetAllowed(net.ParseIP("127.0.0.1")) {
+		t.Error("WEBHOOK_ALLOW_PRIVATE_TARGETS should allow loopback")
+	}
+}
+
+func TestValidateWebhookURL(t *testing.T) {
+	t.Setenv("WEBHOOK_ALLOW_PRIVATE_TARGETS", "")
+
+	tests := []struct {
+		url     string
+		wantErr error
+	}{
+		{"https://93.184.216.34/hooks", nil},
+		{"http://[2606:2800:220:1:248:1893:25c8:1946]:8080/hooks", nil},
+		{"ftp://93.184.216.34/hooks", ErrInvalidWebhookURL},
+		{"/relative/path", ErrInvalidWebhookURL},
+		{"https://", ErrInvalidWebhookURL},
+		{"http://127.0.0.1:8080/hooks", ErrWebhookTargetNotAllowed},
+		{"http://169.254.169.254/latest/meta-data", ErrWebhookTargetNotAllowed},
+		{"http://[::1]/hooks", ErrWebhookTargetNotAllowed},
+		{"http://localhost/hooks", ErrWebhookTargetNotAllowed},
+	}
+	for _, tt := range tests {
+		t.Run(tt.url, func(t *testing.T) {
+			if err := validateWebhookURL(tt.url); !errors.Is(err, tt.wantErr) {
+				t.Errorf("validateWebhookURL(%q) = %v, want %v", tt.url, err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestWebhookHTTPClient(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/redirect" {
+			http.Redirect(w, r, "/internal", http.StatusFound)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer server.Close()
+	client := newWebhookHTTPClient(5 * time.Second)
+
+	// ⭐ ตรวจตอน dial ด้วย ไม่ใช่แค่ตอนบันทึก URL (กัน DNS rebinding)
+	t.Setenv("WEBHOOK_ALLOW_PRIVATE_TARGETS", "")
+	if _, err := client.Post(server.URL, "application/json", nil); !errors.Is(err, ErrWebhookTargetNotAllowed) {
+		t.Fatalf("delivery to loopback: err = %v, want ErrWebhookTargetNotAllowed", err)
+	}
+
+	t.Setenv("WEBHOOK_ALLOW_PRIVATE_TARGETS", "true")
+	resp, err := client.Post(server.URL+"/redirect", "application/json", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusFound {
+		t.Errorf("status = %d, want the redirect itself (%d)", resp.StatusCode, http.StatusFound)
+	}
+}
+
+// leaseUntil matches a next_attempt_at at least d after the time the test started
+type leaseUntil struct {
+	from time.Time
+	d    time.Duration
+}
+
+func (l leaseUntil) Match(v driver.Value) bool {
+	t, ok := v.(time.Time)
+	return ok && !t.Before(l.from.Add(l.d))
+}
+
+func TestWebhookDeliverDue(t *testing.T) {
+	t.Setenv("WEBHOOK_TIMEOUT_SECONDS", "10")
+	db, mock := newMockDB(t)
+	mock.ExpectBegin()
+	mock.ExpectQuery(`SELECT \* FROM "webhook_deliveries" WHERE status = \$1 AND next_attempt_at <= \$2 ORDER BY next_attempt_at LIMIT \$3 FOR UPDATE SKIP LOCKED`).
+		WithArgs(models.WebhookDeliveryPending, sqlmock.AnyArg(), webhookBatchSize).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "subscription_id", "event", "status", "attempts"}).
+			AddRow(1, 5, "ping", "PENDING", 0).
+			AddRow(2, 5, "ping", "PENDING", 0))
+	// ⭐ lease ต้องครอบทั้ง batch: 20 รายการ x timeout 10 วินาที
+	mock.ExpectExec(`UPDATE "webhook_deliveries" SET "next_attempt_at"=\$1,"updated_at"=\$2 WHERE id IN \(\$3,\$4\)`).
+		WithArgs(leaseUntil{time.Now(), 200 * time.Second}, sqlmock.AnyArg(), 1, 2).
+		WillReturnResult(sqlmock.NewResult(0, 2))
+	mock.ExpectCommit()
+
+	// รายการแรกผิดพลาด รายการถัดไปต้องยังถูกส่งต่อ
+	mock.ExpectQuery(`SELECT \* FROM "webhook_subscriptions"`).WillReturnError(errors.New("connection reset"))
+	mock.ExpectQuery(`SELECT \* FROM "webhook_subscriptions"`).WillReturnRows(sqlmock.NewRows([]string{"id"}))
+	mock.ExpectBegin()
+	mock.ExpectExec(`UPDATE "webhook_deliveries" SET .*"status"=.* WHERE "id" = \$\d+`).WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+
+	s := &webhookService{db: db, client: newWebhookHTTPClient(time.Second)}
+	if err := s.deliverDue(); err != nil {
+		t.Fatal(err)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Error(err)
+	}
+}
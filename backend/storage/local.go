@@ -0,0 +1,174 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"mime"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// Local stores objects as files below a root directory. It suits a single instance with
+// a persistent volume mounted at the root.
+type Local struct {
+	root      string
+	publicURL string
+}
+
+func NewLocal(root, publicURL string) (*Local, error) {
+	if err := os.MkdirAll(root, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create storage directory: %w", err)
+	}
+	return &Local{root: root, publicURL: publicURL}, nil
+}
+
+func (l *Local) path(key string) (string, error) {
+	if err := ValidateKey(key); err != nil {
+		return "", err
+	}
+	return filepath.Join(l.root, filepath.FromSlash(key)), nil
+}
+
+func (l *Local) Put(ctx context.Context, key string, r io.Reader, size int64, contentType string) error {
+	p, err := l.path(key)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(p), 0755); err != nil {
+		return err
+	}
+
+	// ⭐ เขียนลงไฟล์ชั่วคราวก่อนแล้วค่อย rename กันคนอ่านเจอไฟล์ที่เขียนไม่เสร็จ
+	tmp, err := os.CreateTemp(filepath.Dir(p), ".upload-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := io.Copy(tmp, r); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	if err := os.Chmod(tmp.Name(), 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), p)
+}
+
+func (l *Local) Open(ctx context.Context, key string) (io.ReadCloser, *Object, error) {
+	p, err := l.path(key)
+	if err != nil {
+		return nil, nil, err
+	}
+	f, err := os.Open(p)
+	if err != nil {
+		return nil, nil, mapFSError(err)
+	}
+	obj, err := l.stat(f, key)
+	if err != nil {
+		f.Close()
+		return nil, nil, err
+	}
+	return f, obj, nil
+}
+
+func (l *Local) Stat(ctx context.Context, key string) (*Object, error) {
+	p, err := l.path(key)
+	if err != nil {
+		return nil, err
+	}
+	f, err := os.Open(p)
+	if err != nil {
+		return nil, mapFSError(err)
+	}
+	defer f.Close()
+	return l.stat(f, key)
+}
+
+func (l *Local) stat(f *os.File, key string) (*Object, error) {
+	info, err := f.Stat()
+	if err != nil {
+		return nil, err
+	}
+	if info.IsDir() {
+		return nil, ErrNotFound
+	}
+	return &Object{
+		Key:         key,
+		Size:        info.Size(),
+		ContentType: mime.TypeByExtension(path.Ext(key)),
+		ModTime:     info.ModTime(),
+		ETag:        fmt.Sprintf(`"%x-%x"`, info.ModTime().UnixNano(), info.Size()),
+	}, nil
+}
+
+func (l *Local) Delete(ctx context.Context, key string) error {
+	p, err := l.path(key)
+	if err != nil {
+		return err
+	}
+	return mapFSError(os.Remove(p))
+}
+
+func (l *Local) List(ctx context.Context, prefix string) ([]Object, error) {
+	if strings.Contains(prefix, "..") || strings.HasPrefix(prefix, "/") {
+		return nil, ErrInvalidKey
+	}
+	// ⭐ เดินเฉพาะโฟลเดอร์ที่ prefix ชี้ถึง ไม่ต้องไล่ทั้ง root
+	dir := prefix
+	if !strings.HasSuffix(dir, "/") {
+		dir = path.Dir(dir)
+	}
+	start := filepath.Join(l.root, filepath.FromSlash(strings.TrimSuffix(dir, "/")))
+
+	objects := make([]Object, 0)
+	err := filepath.WalkDir(start, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			if errors.Is(err, fs.ErrNotExist) {
+				return nil
+			}
+			return err
+		}
+		if d.IsDir() || strings.HasPrefix(d.Name(), ".upload-") {
+			return nil
+		}
+		rel, err := filepath.Rel(l.root, p)
+		if err != nil {
+			return err
+		}
+		key := filepath.ToSlash(rel)
+		if !strings.HasPrefix(key, prefix) {
+			return nil
+		}
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		objects = append(objects, Object{
+			Key:         key,
+			Size:        info.Size(),
+			ContentType: mime.TypeByExtension(path.Ext(key)),
+			ModTime:     info.ModTime(),
+		})
+		return nil
+	})
+	return objects, err
+}
+
+func (l *Local) URL(key string) string {
+	return joinURL(l.publicURL, key)
+}
+
+func mapFSError(err error) error {
+	if errors.Is(err, fs.ErrNotExist) {
+		return ErrNotFound
+	}
+	return err
+}
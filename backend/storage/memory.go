@@ -0,0 +1,103 @@
+package storage
+
+import (
+	"bytes"
+	"context"
+	"crypto/md5"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Memory keeps objects in process memory. It is a fake for development and tests and
+// does not survive a restart.
+type Memory struct {
+	mu        sync.RWMutex
+	objects   map[string]memoryObject
+	publicURL string
+}
+
+type memoryObject struct {
+	data []byte
+	info Object
+}
+
+func NewMemory(publicURL string) *Memory {
+	return &Memory{objects: make(map[string]memoryObject), publicURL: publicURL}
+}
+
+func (m *Memory) Put(ctx context.Context, key string, r io.Reader, size int64, contentType string) error {
+	if err := ValidateKey(key); err != nil {
+		return err
+	}
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return err
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.objects[key] = memoryObject{
+		data: data,
+		info: Object{
+			Key:         key,
+			Size:        int64(len(data)),
+			ContentType: contentType,
+			ModTime:     time.Now(),
+			ETag:        fmt.Sprintf(`"%x"`, md5.Sum(data)),
+		},
+	}
+	return nil
+}
+
+func (m *Memory) Open(ctx context.Context, key string) (io.ReadCloser, *Object, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	obj, ok := m.objects[key]
+	if !ok {
+		return nil, nil, ErrNotFound
+	}
+	info := obj.info
+	return io.NopCloser(bytes.NewReader(obj.data)), &info, nil
+}
+
+func (m *Memory) Stat(ctx context.Context, key string) (*Object, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	obj, ok := m.objects[key]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	info := obj.info
+	return &info, nil
+}
+
+func (m *Memory) Delete(ctx context.Context, key string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if _, ok := m.objects[key]; !ok {
+		return ErrNotFound
+	}
+	delete(m.objects, key)
+	return nil
+}
+
+func (m *Memory) List(ctx context.Context, prefix string) ([]Object, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	objects := make([]Object, 0)
+	for key, obj := range m.objects {
+		if strings.HasPrefix(key, prefix) {
+			objects = append(objects, obj.info)
+		}
+	}
+	sort.Slice(objects, func(i, j int) bool { return objects[i].Key < objects[j].Key })
+	return objects, nil
+}
+
+func (m *Memory) URL(key string) string {
+	return joinURL(m.publicURL, key)
+}
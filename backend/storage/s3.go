@@ -0,0 +1,328 @@
+package storage
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// S3Config points the S3 backend at a bucket. Endpoint is the service URL, e.g.
+// https://s3.ap-southeast-1.amazonaws.com or http://localhost:9000 for MinIO.
+type S3Config struct {
+	Endpoint        string
+	Region          string
+	Bucket          string
+	AccessKeyID     string
+	SecretAccessKey string
+	// PathStyle addresses objects as endpoint/bucket/key (required by MinIO) instead of
+	// bucket.endpoint/key
+	PathStyle bool
+	PublicURL string
+}
+
+// S3 stores objects in an S3-compatible bucket, signing requests with AWS Signature V4
+type S3 struct {
+	cfg      S3Config
+	endpoint *url.URL
+	http     *http.Client
+}
+
+// S3Error is returned when the service answers with an unexpected status
+type S3Error struct {
+	StatusCode int
+	Body       string
+}
+
+func (e *S3Error) Error() string {
+	return fmt.Sprintf("S3 responded with HTTP %d: %s", e.StatusCode, e.Body)
+}
+
+func NewS3(cfg S3Config) (*S3, error) {
+	if cfg.Endpoint == "" || cfg.Bucket == "" || cfg.AccessKeyID == "" || cfg.SecretAccessKey == "" {
+		return nil, errors.New("S3 storage requires S3_ENDPOINT, S3_BUCKET, S3_ACCESS_KEY_ID and S3_SECRET_ACCESS_KEY")
+	}
+	if cfg.Region == "" {
+		cfg.Region = "us-east-1"
+	}
+	endpoint, err := url.Parse(strings.TrimRight(cfg.Endpoint, "/"))
+	if err != nil || endpoint.Host == "" {
+		return nil, fmt.Errorf("invalid S3_ENDPOINT %q", cfg.Endpoint)
+	}
+	return &S3{cfg: cfg, endpoint: endpoint, http: &http.Client{Timeout: 60 * time.Second}}, nil
+}
+
+func (s *S3) Put(ctx context.Context, key string, r io.Reader, size int64, contentType string) error {
+	req, err := s.newRequest(ctx, http.MethodPut, key, r)
+	if err != nil {
+		return err
+	}
+	req.ContentLength = size
+	if contentType != "" {
+		req.Header.Set("Content-Type", contentType)
+	}
+	resp, err := s.do(req)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	return nil
+}
+
+func (s *S3) Open(ctx context.Context, key string) (io.ReadCloser, *Object, error) {
+	req, err := s.newRequest(ctx, http.MethodGet, key, nil)
+	if err != nil {
+		return nil, nil, err
+	}
+	resp, err := s.do(req)
+	if err != nil {
+		return nil, nil, err
+	}
+	return resp.Body, objectFromHeader(key, resp), nil
+}
+
+func (s *S3) Stat(ctx context.Context, key string) (*Object, error) {
+	req, err := s.newRequest(ctx, http.MethodHead, key, nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := s.do(req)
+	if err != nil {
+		return nil, err
+	}
+	resp.Body.Close()
+	return objectFromHeader(key, resp), nil
+}
+
+func (s *S3) Delete(ctx context.Context, key string) error {
+	// ⭐ S3 ตอบ 204 แม้ไม่มี object จึงต้อง HEAD ก่อนเพื่อคืน ErrNotFound ให้เหมือน backend อื่น
+	if _, err := s.Stat(ctx, key); err != nil {
+		return err
+	}
+	req, err := s.newRequest(ctx, http.MethodDelete, key, nil)
+	if err != nil {
+		return err
+	}
+	resp, err := s.do(req)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	return nil
+}
+
+// List pages through ListObjectsV2
+func (s *S3) List(ctx context.Context, prefix string) ([]Object, error) {
+	objects := make([]Object, 0)
+	token := ""
+	for {
+		params := map[string]string{"list-type": "2", "prefix": prefix}
+		if token != "" {
+			params["continuation-token"] = token
+		}
+		u := s.bucketURL()
+		u.RawQuery = encodeQuery(params)
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, u.String(), nil)
+		if err != nil {
+			return nil, err
+		}
+		resp, err := s.do(req)
+		if err != nil {
+			return nil, err
+		}
+
+		var page struct {
+			Contents []struct {
+				Key          string    `xml:"Key"`
+				Size         int64     `xml:"Size"`
+				LastModified time.Time `xml:"LastModified"`
+				ETag         string    `xml:"ETag"`
+			} `xml:"Contents"`
+			IsTruncated           bool   `xml:"IsTruncated"`
+			NextContinuationToken string `xml:"NextContinuationToken"`
+		}
+		err = xml.NewDecoder(resp.Body).Decode(&page)
+		resp.Body.Close()
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse S3 listing: %w", err)
+		}
+		for _, c := range page.Contents {
+			objects = append(objects, Object{Key: c.Key, Size: c.Size, ModTime: c.LastModified, ETag: c.ETag})
+		}
+		if !page.IsTruncated || page.NextContinuationToken == "" {
+			return objects, nil
+		}
+		token = page.NextContinuationToken
+	}
+}
+
+func (s *S3) URL(key string) string {
+	return joinURL(s.cfg.PublicURL, key)
+}
+
+// bucketURL returns the URL of the bucket itself, used for listing
+func (s *S3) bucketURL() *url.URL {
+	u := *s.endpoint
+	if s.cfg.PathStyle {
+		u.Path = strings.TrimRight(u.Path, "/") + "/" + s.cfg.Bucket + "/"
+		u.RawPath = ""
+	} else {
+		u.Host = s.cfg.Bucket + "." + u.Host
+		u.Path = strings.TrimRight(u.Path, "/") + "/"
+		u.RawPath = ""
+	}
+	return &u
+}
+
+// objectURL returns the request URL for key in path or virtual-hosted style
+func (s *S3) objectURL(key string) *url.URL {
+	u := *s.endpoint
+	if s.cfg.PathStyle {
+		u.Path = strings.TrimRight(u.Path, "/") + "/" + s.cfg.Bucket + "/" + key
+		u.RawPath = strings.TrimRight(s.endpoint.EscapedPath(), "/") + "/" + escapeKey(s.cfg.Bucket) + "/" + escapeKey(key)
+	} else {
+		u.Host = s.cfg.Bucket + "." + u.Host
+		u.Path = strings.TrimRight(u.Path, "/") + "/" + key
+		u.RawPath = strings.TrimRight(s.endpoint.EscapedPath(), "/") + "/" + escapeKey(key)
+	}
+	return &u
+}
+
+func (s *S3) newRequest(ctx context.Context, method, key string, body io.Reader) (*http.Request, error) {
+	if err := ValidateKey(key); err != nil {
+		return nil, err
+	}
+	return http.NewRequestWithContext(ctx, method, s.objectURL(key).String(), body)
+}
+
+func (s *S3) do(req *http.Request) (*http.Response, error) {
+	s.sign(req, time.Now().UTC())
+	resp, err := s.http.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+		return resp, nil
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode == http.StatusNotFound {
+		return nil, ErrNotFound
+	}
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+	return nil, &S3Error{StatusCode: resp.StatusCode, Body: string(body)}
+}
+
+// sign adds an AWS Signature V4 Authorization header. The payload is sent unsigned so
+// uploads can be streamed without hashing the body first; TLS protects it in transit.
+func (s *S3) sign(req *http.Request, now time.Time) {
+	amzDate := now.Format("20060102T150405Z")
+	date := now.Format("20060102")
+	req.Header.Set("X-Amz-Date", amzDate)
+	req.Header.Set("X-Amz-Content-Sha256", "UNSIGNED-PAYLOAD")
+
+	headers := map[string]string{
+		"host":                 req.URL.Host,
+		"x-amz-content-sha256": "UNSIGNED-PAYLOAD",
+		"x-amz-date":           amzDate,
+	}
+	if ct := req.Header.Get("Content-Type"); ct != "" {
+		headers["content-type"] = ct
+	}
+	names := make([]string, 0, len(headers))
+	for name := range headers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	var canonicalHeaders strings.Builder
+	for _, name := range names {
+		canonicalHeaders.WriteString(name + ":" + strings.TrimSpace(headers[name]) + "\n")
+	}
+	signedHeaders := strings.Join(names, ";")
+
+	canonicalRequest := strings.Join([]string{
+		req.Method,
+		req.URL.EscapedPath(),
+		req.URL.RawQuery,
+		canonicalHeaders.String(),
+		signedHeaders,
+		"UNSIGNED-PAYLOAD",
+	}, "\n")
+
+	scope := date + "/" + s.cfg.Region + "/s3/aws4_request"
+	hashed := sha256.Sum256([]byte(canonicalRequest))
+	stringToSign := "AWS4-HMAC-SHA256\n" + amzDate + "\n" + scope + "\n" + hex.EncodeToString(hashed[:])
+
+	signingKey := hmacSHA256([]byte("AWS4"+s.cfg.SecretAccessKey), date)
+	signingKey = hmacSHA256(signingKey, s.cfg.Region)
+	signingKey = hmacSHA256(signingKey, "s3")
+	signingKey = hmacSHA256(signingKey, "aws4_request")
+	signature := hex.EncodeToString(hmacSHA256(signingKey, stringToSign))
+
+	req.Header.Set("Authorization", fmt.Sprintf("AWS4-HMAC-SHA256 Credential=%s/%s, SignedHeaders=%s, Signature=%s",
+		s.cfg.AccessKeyID, scope, signedHeaders, signature))
+}
+
+func hmacSHA256(key []byte, data string) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(data))
+	return mac.Sum(nil)
+}
+
+func objectFromHeader(key string, resp *http.Response) *Object {
+	obj := &Object{
+		Key:         key,
+		ContentType: resp.Header.Get("Content-Type"),
+		ETag:        resp.Header.Get("ETag"),
+	}
+	if size, err := strconv.ParseInt(resp.Header.Get("Content-Length"), 10, 64); err == nil {
+		obj.Size = size
+	}
+	if t, err := http.ParseTime(resp.Header.Get("Last-Modified")); err == nil {
+		obj.ModTime = t
+	}
+	return obj
+}
+
+// encodeQuery builds a canonical query string: keys sorted, names and values encoded
+// like path segments (url.Values.Encode would turn spaces into "+", which V4 rejects)
+func encodeQuery(params map[string]string) string {
+	names := make([]string, 0, len(params))
+	for name := range params {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	pairs := make([]string, 0, len(names))
+	for _, name := range names {
+		pairs = append(pairs, escapeKey(name)+"="+strings.ReplaceAll(escapeKey(params[name]), "/", "%2F"))
+	}
+	return strings.Join(pairs, "&")
+}
+
+// escapeKey percent-encodes every byte of each path segment except the unreserved
+// characters, as required for the canonical URI of Signature V4
+func escapeKey(key string) string {
+	segments := strings.Split(key, "/")
+	for i, segment := range segments {
+		var b strings.Builder
+		for _, c := range []byte(segment) {
+			if ('A' <= c && c <= 'Z') || ('a' <= c && c <= 'z') || ('0' <= c && c <= '9') ||
+				c == '-' || c == '_' || c == '.' || c == '~' {
+				b.WriteByte(c)
+			} else {
+				fmt.Fprintf(&b, "%%%02X", c)
+			}
+		}
+		segments[i] = b.String()
+	}
+	return strings.Join(segments, "/")
+}
@@ -0,0 +1,107 @@
+// Package storage keeps uploaded files (product images and later attachments) outside the
+// container filesystem. Objects are addressed by a slash-separated key such as
+// "products/product_1a2b3c4d_1700000000.jpg". The local backend writes to a directory,
+// the S3 backend talks to any S3-compatible service (AWS S3, MinIO, Cloudflare R2) and the
+// memory backend is an in-process fake for development and tests.
+package storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"strings"
+	"time"
+)
+
+var (
+	ErrNotFound   = errors.New("object not found")
+	ErrInvalidKey = errors.New("invalid object key")
+)
+
+// Object describes a stored file
+type Object struct {
+	Key         string
+	Size        int64
+	ContentType string
+	ModTime     time.Time
+	ETag        string
+}
+
+// Storage stores and serves files by key. Implementations must be safe for concurrent use.
+type Storage interface {
+	// Put stores size bytes read from r under key, replacing any existing object
+	Put(ctx context.Context, key string, r io.Reader, size int64, contentType string) error
+	// Open returns the object content; the caller must close it
+	Open(ctx context.Context, key string) (io.ReadCloser, *Object, error)
+	Stat(ctx context.Context, key string) (*Object, error)
+	// Delete removes the object, returning ErrNotFound when it does not exist
+	Delete(ctx context.Context, key string) error
+	// List returns every object whose key starts with prefix, ordered by key
+	List(ctx context.Context, prefix string) ([]Object, error)
+	// URL returns the address clients use to download the object
+	URL(key string) string
+}
+
+// ValidateKey rejects keys that could escape the storage root or are not portable
+// between backends
+func ValidateKey(key string) error {
+	if key == "" || len(key) > 512 || strings.HasPrefix(key, "/") || strings.HasSuffix(key, "/") ||
+		strings.ContainsAny(key, "\\\x00") {
+		return ErrInvalidKey
+	}
+	for _, segment := range strings.Split(key, "/") {
+		if segment == "" || segment == "." || segment == ".." {
+			return ErrInvalidKey
+		}
+	}
+	return nil
+}
+
+// joinURL appends an object key to a public base URL
+func joinURL(base, key string) string {
+	return strings.TrimRight(base, "/") + "/" + escapeKey(key)
+}
+
+// NewFromEnv builds the backend selected by STORAGE_DRIVER (local, s3 or memory).
+// Files are served to clients at STORAGE_PUBLIC_URL, which defaults to BASE_URL/uploads
+// where the API streams them from the configured backend.
+func NewFromEnv() (Storage, error) {
+	publicURL := os.Getenv("STORAGE_PUBLIC_URL")
+	if publicURL == "" {
+		baseURL := os.Getenv("BASE_URL")
+		if baseURL == "" {
+			baseURL = "http://localhost:8080"
+		}
+		publicURL = strings.TrimRight(baseURL, "/") + "/uploads"
+	}
+
+	switch driver := strings.ToLower(os.Getenv("STORAGE_DRIVER")); driver {
+	case "", "local":
+		dir := os.Getenv("STORAGE_LOCAL_DIR")
+		if dir == "" {
+			dir = "uploads"
+		}
+		log.Printf("🗄️ Storage: local directory %s", dir)
+		return NewLocal(dir, publicURL)
+	case "s3":
+		cfg := S3Config{
+			Endpoint:        os.Getenv("S3_ENDPOINT"),
+			Region:          os.Getenv("S3_REGION"),
+			Bucket:          os.Getenv("S3_BUCKET"),
+			AccessKeyID:     os.Getenv("S3_ACCESS_KEY_ID"),
+			SecretAccessKey: os.Getenv("S3_SECRET_ACCESS_KEY"),
+			PathStyle:       strings.EqualFold(os.Getenv("S3_USE_PATH_STYLE"), "true"),
+			PublicURL:       publicURL,
+		}
+		log.Printf("🗄️ Storage: S3 bucket %s (%s)", cfg.Bucket, cfg.Endpoint)
+		return NewS3(cfg)
+	case "memory":
+		log.Println("🗄️ Storage: in-memory (files are lost on restart)")
+		return NewMemory(publicURL), nil
+	default:
+		return nil, fmt.Errorf("unknown STORAGE_DRIVER %q (use local, s3 or memory)", driver)
+	}
+}